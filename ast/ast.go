@@ -23,6 +23,10 @@ type Node interface {
 	TokenLiteral() string
 	// Sirve para ver el AST generado por el Nodo.
 	String() string
+	// Pos es la posición del primer carácter del nodo en el código fuente
+	// y End la posición inmediatamente después del último.
+	Pos() token.Position
+	End() token.Position
 }
 
 // Interface Statement => Implementa implicitamente a la interface Node.
@@ -57,6 +61,10 @@ type Program struct {
 	// es un dato contenido dentro del campo Expression del tipo ExpressionStatement.
 	// program.Statements[0] hace referencia al dato AST ExpressionStatement.
 	Statements []Statement
+	// Comments son todos los comentarios del programa, en orden.
+	Comments []*Comment
+	// EndComments son los comentarios posteriores a la última sentencia.
+	EndComments []*Comment
 }
 
 // Implementa la función String() de la interface Node.
@@ -77,6 +85,20 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // Estructura LetStatement => se encargará de crear el AST para la gramática:
 // letStatement = 'let' identifier expression
 type LetStatement struct {
@@ -86,6 +108,7 @@ type LetStatement struct {
 	Name *Identifier
 	// AST que implementa la interface Expression.
 	Value Expression
+	Comments
 }
 
 // Cumple con la interface Statement.
@@ -96,6 +119,17 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos() }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End()
+}
+
 // Implementa la función String() de la interface Node.
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -116,6 +150,17 @@ type Identifier struct {
 	Token token.Token
 	// string literal del indentificador.
 	Value string
+	// Binding es la posición de la variable local a la que se refiere el
+	// identificador. La asigna el resolver del evaluador; nil significa
+	// que es una variable global y se busca por nombre.
+	Binding *Binding
+}
+
+// Binding ubica una variable local: Depth entornos de función hacia
+// afuera del actual, en la posición Index de ese entorno.
+type Binding struct {
+	Depth int
+	Index int
 }
 
 // Cumple con la interface Expression.
@@ -126,6 +171,9 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position { return i.Token.Pos() }
+func (i *Identifier) End() token.Position { return i.Token.End() }
+
 // Implementa la función String() de la interface Node.
 func (i *Identifier) String() string {
 	return i.Value
@@ -137,6 +185,7 @@ type ReturnStatement struct {
 	// El token asociado: token.Type = RETURN, token.Literal = 'return'
 	Token       token.Token
 	ReturnValue Expression
+	Comments
 }
 
 // Cumple con la interface Statement.
@@ -147,6 +196,9 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos() }
+func (rs *ReturnStatement) End() token.Position { return endOf(rs.ReturnValue, rs.Token) }
+
 // Implementa la función String() de la interface Node.
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -172,6 +224,7 @@ type ExpressionStatement struct {
 	// que castearlo a su tipo original. Ejemplo: es.Expression.(*LetStatement) convierte el campo
 	// Expression a un AST de tipo LetStatement.
 	Expression Expression
+	Comments
 }
 
 // Cumple con la interface Statement.
@@ -182,6 +235,9 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position { return posOf(es.Expression, es.Token) }
+func (es *ExpressionStatement) End() token.Position { return endOf(es.Expression, es.Token) }
+
 // Implementa el método String()
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -203,6 +259,9 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos() }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End() }
+
 // Implementa el método String
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
@@ -229,6 +288,9 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos() }
+func (pe *PrefixExpression) End() token.Position { return endOf(pe.Right, pe.Token) }
+
 // Implementa el método String
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -256,6 +318,10 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Un operador infijo empieza donde empieza su operando izquierdo.
+func (ie *InfixExpression) Pos() token.Position { return posOf(ie.Left, ie.Token) }
+func (ie *InfixExpression) End() token.Position { return endOf(ie.Right, ie.Token) }
+
 // Cumple con la interface Node
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -276,6 +342,8 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos() }
+func (b *Boolean) End() token.Position  { return b.Token.End() }
 
 // ast.IfExpression
 type IfExpression struct {
@@ -287,6 +355,16 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos() }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return endOf(ie.Condition, ie.Token)
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -302,12 +380,17 @@ func (ie *IfExpression) String() string {
 
 // BlockStatement
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // '{'
 	Statements []Statement
+	Rbrace     token.Token // '}'
+	// EndComments son los comentarios entre la última sentencia y '}'.
+	EndComments []*Comment
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos() }
+func (bs *BlockStatement) End() token.Position  { return closeEnd(bs.Rbrace, bs.Token) }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -324,10 +407,20 @@ type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	// Locals son los nombres de las variables locales de la función: primero
+	// los parámetros y luego los let del cuerpo. Lo llena el resolver.
+	Locals []string
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos() }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End()
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
@@ -344,19 +437,29 @@ func (fl *FunctionLiteral) String() string {
 
 // CallExpression -> Llamadas a funciones
 type CallExpression struct {
-	Token     token.Token
-	Function  Expression // identificador o función literal
+	Token     token.Token // '('
+	Function  Expression  // identificador o función literal
 	Arguments []Expression
+	Keywords  []*KeywordArgument // siempre después de los posicionales
+	Rparen    token.Token        // ')'
+	// Tail indica que la función que contiene la llamada retorna su
+	// resultado sin usarlo. Lo marca el resolver del evaluador.
+	Tail bool
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return posOf(ce.Function, ce.Token) }
+func (ce *CallExpression) End() token.Position  { return closeEnd(ce.Rparen, ce.Token) }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, k := range ce.Keywords {
+		args = append(args, k.String())
+	}
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
@@ -364,6 +467,25 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// KeywordArgument -> argumento con nombre en una llamada: draw(x: 1).
+// No es una expresión: solo aparece en CallExpression.Keywords.
+type KeywordArgument struct {
+	Token token.Token // el nombre
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) Pos() token.Position  { return ka.Token.Pos() }
+func (ka *KeywordArgument) End() token.Position  { return endOf(ka.Value, ka.Token) }
+func (ka *KeywordArgument) String() string {
+	value := ""
+	if ka.Value != nil {
+		value = ka.Value.String()
+	}
+	return ka.Name.String() + ": " + value
+}
+
 // String
 type StringLiteral struct {
 	Token token.Token
@@ -373,15 +495,20 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos() }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End() }
 
 // Array Literal
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // '['
 	Elements []Expression
+	Rbracket token.Token // ']'
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
-func (al *ArrayLiteral) TokenLiteral() string { return al.TokenLiteral() }
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos() }
+func (al *ArrayLiteral) End() token.Position  { return closeEnd(al.Rbracket, al.Token) }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 	elements := []string{}
@@ -396,13 +523,16 @@ func (al *ArrayLiteral) String() string {
 
 // Array Index Operator Expression
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token // '['
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // ']'
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return posOf(ie.Left, ie.Token) }
+func (ie *IndexExpression) End() token.Position  { return closeEnd(ie.Rbracket, ie.Token) }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -414,22 +544,101 @@ func (ie *IndexExpression) String() string {
 
 }
 
+// MemberExpression es el acceso a un miembro: Object.Property. Property
+// no es una variable sino el nombre del miembro.
+type MemberExpression struct {
+	Token    token.Token // '.'
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() token.Position  { return posOf(me.Object, me.Token) }
+func (me *MemberExpression) End() token.Position {
+	if me.Property == nil {
+		return me.Token.End()
+	}
+	return me.Property.End()
+}
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
 // Hash Maps
 type HashLiteral struct {
-	Token token.Token
+	Token token.Token // '{'
 	Pairs map[Expression]Expression
+	// Llaves en el orden en que aparecen en el código fuente.
+	Keys   []Expression
+	Rbrace token.Token // '}'
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos() }
+func (hl *HashLiteral) End() token.Position  { return closeEnd(hl.Rbrace, hl.Token) }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
 	return out.String()
 }
+
+// BadExpression ocupa el lugar de una expresión con errores de sintaxis.
+// Va desde Token hasta To, el último token leído antes de detectar el
+// error.
+type BadExpression struct {
+	Token token.Token
+	To    token.Token
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+func (be *BadExpression) Pos() token.Position  { return be.Token.Pos() }
+func (be *BadExpression) End() token.Position  { return closeEnd(be.To, be.Token) }
+
+// BadStatement ocupa el lugar de una sentencia con errores de sintaxis.
+type BadStatement struct {
+	Token token.Token
+	To    token.Token
+	Comments
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+func (bs *BadStatement) Pos() token.Position  { return bs.Token.Pos() }
+func (bs *BadStatement) End() token.Position  { return closeEnd(bs.To, bs.Token) }
+
+// posOf retorna la posición de n, o la de tok si n falta (por ejemplo,
+// tras un error de análisis).
+func posOf(n Node, tok token.Token) token.Position {
+	if n == nil {
+		return tok.Pos()
+	}
+	return n.Pos()
+}
+
+// endOf retorna el final de n, o el de tok si n falta.
+func endOf(n Node, tok token.Token) token.Position {
+	if n == nil {
+		return tok.End()
+	}
+	return n.End()
+}
+
+// closeEnd retorna el final del token que cierra un nodo ('}', ')' o
+// ']'). Si no se registró, se usa el del token que lo abre.
+func closeEnd(close, open token.Token) token.Position {
+	if close.Pos().IsValid() {
+		return close.End()
+	}
+	return open.End()
+}
@@ -0,0 +1,721 @@
+// Package astjson convierte el AST de Monkey a JSON y viceversa, para que
+// herramientas escritas en otros lenguajes (visualizadores, linters)
+// puedan usar el resultado del parser.
+//
+// Cada nodo es un objeto cuyo primer campo, "type", es el nombre del tipo
+// en el paquete ast (por ejemplo "InfixExpression"). Le siguen "pos" y
+// "end" con la posición del nodo ({"offset", "line", "column"}) y luego
+// sus hijos, con los nombres de los campos de ast en minúscula:
+//
+//	{"type": "InfixExpression", "pos": {...}, "end": {...},
+//	 "operator": "+", "left": {...}, "right": {...}}
+//
+// Los datos que calcula el resolver del evaluador (Binding, Locals) no se
+// incluyen.
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+)
+
+// Marshal retorna la forma JSON de node.
+func Marshal(node ast.Node) ([]byte, error) {
+	obj, err := encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// MarshalIndent es como Marshal pero con sangría, igual que
+// json.MarshalIndent.
+func MarshalIndent(node ast.Node, prefix, indent string) ([]byte, error) {
+	data, err := Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, prefix, indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// object es un objeto JSON que conserva el orden de sus campos, para que
+// la salida sea estable y "type" aparezca siempre primero.
+type object []field
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		out.Write(key)
+		out.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+type position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func encodePos(pos token.Position) interface{} {
+	if !pos.IsValid() {
+		return nil
+	}
+	return position{Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+}
+
+func encode(node ast.Node) (object, error) {
+	if node == nil {
+		return nil, nil
+	}
+	var typ string
+	var fields []field
+	switch node := node.(type) {
+	case *ast.Program:
+		typ = "Program"
+		statements, err := encodeStatements(node.Statements)
+		if err != nil {
+			return nil, err
+		}
+		fields = []field{{"statements", statements}}
+	case *ast.LetStatement:
+		typ = "LetStatement"
+		fields = []field{{"name", node.Name}, {"value", node.Value}}
+	case *ast.ReturnStatement:
+		typ = "ReturnStatement"
+		fields = []field{{"returnValue", node.ReturnValue}}
+	case *ast.ExpressionStatement:
+		typ = "ExpressionStatement"
+		fields = []field{{"expression", node.Expression}}
+	case *ast.BlockStatement:
+		typ = "BlockStatement"
+		statements, err := encodeStatements(node.Statements)
+		if err != nil {
+			return nil, err
+		}
+		fields = []field{{"statements", statements}}
+	case *ast.Identifier:
+		typ = "Identifier"
+		fields = []field{{"value", node.Value}}
+	case *ast.IntegerLiteral:
+		typ = "IntegerLiteral"
+		fields = []field{{"value", node.Value}}
+	case *ast.StringLiteral:
+		typ = "StringLiteral"
+		fields = []field{{"value", node.Value}}
+	case *ast.Boolean:
+		typ = "Boolean"
+		fields = []field{{"value", node.Value}}
+	case *ast.PrefixExpression:
+		typ = "PrefixExpression"
+		fields = []field{{"operator", node.Operator}, {"right", node.Right}}
+	case *ast.InfixExpression:
+		typ = "InfixExpression"
+		fields = []field{
+			{"operator", node.Operator},
+			{"operatorPos", encodePos(node.Token.Pos())},
+			{"left", node.Left},
+			{"right", node.Right},
+		}
+	case *ast.IfExpression:
+		typ = "IfExpression"
+		fields = []field{{"condition", node.Condition}, {"consequence", node.Consequence}}
+		if node.Alternative != nil {
+			fields = append(fields, field{"alternative", node.Alternative})
+		}
+	case *ast.FunctionLiteral:
+		typ = "FunctionLiteral"
+		params := make([]ast.Node, len(node.Parameters))
+		for i, param := range node.Parameters {
+			params[i] = param
+		}
+		parameters, err := encodeList(params)
+		if err != nil {
+			return nil, err
+		}
+		fields = []field{{"parameters", parameters}, {"body", node.Body}}
+	case *ast.CallExpression:
+		typ = "CallExpression"
+		arguments, err := encodeExpressions(node.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		fields = []field{
+			{"lparen", encodePos(node.Token.Pos())},
+			{"function", node.Function},
+			{"arguments", arguments},
+		}
+		if len(node.Keywords) > 0 {
+			args := make([]ast.Node, len(node.Keywords))
+			for i, arg := range node.Keywords {
+				args[i] = arg
+			}
+			keywords, err := encodeList(args)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field{"keywords", keywords})
+		}
+	case *ast.KeywordArgument:
+		typ = "KeywordArgument"
+		fields = []field{{"name", node.Name}, {"value", node.Value}}
+	case *ast.ArrayLiteral:
+		typ = "ArrayLiteral"
+		elements, err := encodeExpressions(node.Elements)
+		if err != nil {
+			return nil, err
+		}
+		fields = []field{{"elements", elements}}
+	case *ast.IndexExpression:
+		typ = "IndexExpression"
+		fields = []field{
+			{"lbracket", encodePos(node.Token.Pos())},
+			{"left", node.Left},
+			{"index", node.Index},
+		}
+	case *ast.MemberExpression:
+		typ = "MemberExpression"
+		fields = []field{
+			{"dot", encodePos(node.Token.Pos())},
+			{"object", node.Object},
+			{"property", node.Property},
+		}
+	case *ast.HashLiteral:
+		typ = "HashLiteral"
+		pairs := []object{}
+		for _, key := range node.Keys {
+			k, err := encode(key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := encode(node.Pairs[key])
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, object{{"key", k}, {"value", v}})
+		}
+		fields = []field{{"pairs", pairs}}
+	case *ast.BadExpression:
+		typ = "BadExpression"
+		fields = []field{{"literal", node.Token.Literal}}
+	case *ast.BadStatement:
+		typ = "BadStatement"
+		fields = []field{{"literal", node.Token.Literal}}
+	default:
+		return nil, fmt.Errorf("astjson: unsupported node type %T", node)
+	}
+
+	obj := object{{"type", typ}, {"pos", encodePos(node.Pos())}, {"end", encodePos(node.End())}}
+	for _, f := range fields {
+		// Los hijos se convierten aquí para que un nodo en nil sea null y
+		// no un objeto vacío.
+		if child, ok := f.value.(ast.Node); ok {
+			encoded, err := encodeChild(child)
+			if err != nil {
+				return nil, err
+			}
+			f.value = encoded
+		}
+		obj = append(obj, f)
+	}
+	return obj, nil
+}
+
+// encodeChild convierte un hijo que puede ser un puntero en nil dentro de
+// la interface (por ejemplo un *ast.BlockStatement que falta).
+func encodeChild(node ast.Node) (interface{}, error) {
+	switch n := node.(type) {
+	case *ast.Identifier:
+		if n == nil {
+			return nil, nil
+		}
+	case *ast.BlockStatement:
+		if n == nil {
+			return nil, nil
+		}
+	}
+	obj, err := encode(node)
+	if obj == nil {
+		return nil, err
+	}
+	return obj, err
+}
+
+func encodeList(nodes []ast.Node) ([]interface{}, error) {
+	list := make([]interface{}, len(nodes))
+	for i, node := range nodes {
+		encoded, err := encodeChild(node)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = encoded
+	}
+	return list, nil
+}
+
+func encodeStatements(statements []ast.Statement) ([]interface{}, error) {
+	nodes := make([]ast.Node, len(statements))
+	for i, s := range statements {
+		nodes[i] = s
+	}
+	return encodeList(nodes)
+}
+
+func encodeExpressions(expressions []ast.Expression) ([]interface{}, error) {
+	nodes := make([]ast.Node, len(expressions))
+	for i, e := range expressions {
+		nodes[i] = e
+	}
+	return encodeList(nodes)
+}
+
+// Unmarshal reconstruye el nodo guardado con Marshal. Los tokens de los
+// nodos se recrean a partir de sus valores y posiciones.
+func Unmarshal(data []byte) (ast.Node, error) {
+	return decode(json.RawMessage(data))
+}
+
+// rawNode es un nodo todavía sin convertir.
+type rawNode map[string]json.RawMessage
+
+func (r rawNode) pos(key string) token.Position {
+	var p *position
+	if err := json.Unmarshal(r[key], &p); err != nil || p == nil {
+		return token.Position{}
+	}
+	return token.Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+func (r rawNode) string(key string) (string, error) {
+	var s string
+	if err := json.Unmarshal(r[key], &s); err != nil {
+		return "", fmt.Errorf("astjson: field %q: %s", key, err)
+	}
+	return s, nil
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+func decode(data json.RawMessage) (ast.Node, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+	var r rawNode
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("astjson: %s", err)
+	}
+	typ, err := r.string("type")
+	if err != nil {
+		return nil, err
+	}
+	pos, end := r.pos("pos"), r.pos("end")
+
+	switch typ {
+	case "Program":
+		statements, err := decodeStatements(r["statements"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Program{Statements: statements}, nil
+	case "LetStatement":
+		name, err := decodeIdentifier(r["name"])
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeExpression(r["value"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LetStatement{Token: newToken(token.LET, "let", pos), Name: name, Value: value}, nil
+	case "ReturnStatement":
+		value, err := decodeExpression(r["returnValue"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ReturnStatement{Token: newToken(token.RETURN, "return", pos), ReturnValue: value}, nil
+	case "ExpressionStatement":
+		exp, err := decodeExpression(r["expression"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ExpressionStatement{Token: startToken(exp, pos), Expression: exp}, nil
+	case "BlockStatement":
+		statements, err := decodeStatements(r["statements"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.BlockStatement{
+			Token:      newToken(token.LBRACE, "{", pos),
+			Statements: statements,
+			Rbrace:     closeToken(token.RBRACE, "}", end),
+		}, nil
+	case "Identifier":
+		value, err := r.string("value")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Identifier{Token: newToken(token.IDENT, value, pos), Value: value}, nil
+	case "IntegerLiteral":
+		var value int64
+		if err := json.Unmarshal(r["value"], &value); err != nil {
+			return nil, fmt.Errorf("astjson: field \"value\": %s", err)
+		}
+		return &ast.IntegerLiteral{Token: newToken(token.INT, strconv.FormatInt(value, 10), pos), Value: value}, nil
+	case "StringLiteral":
+		value, err := r.string("value")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.StringLiteral{Token: newToken(token.STRING, value, pos), Value: value}, nil
+	case "Boolean":
+		var value bool
+		if err := json.Unmarshal(r["value"], &value); err != nil {
+			return nil, fmt.Errorf("astjson: field \"value\": %s", err)
+		}
+		if value {
+			return &ast.Boolean{Token: newToken(token.TRUE, "true", pos), Value: true}, nil
+		}
+		return &ast.Boolean{Token: newToken(token.FALSE, "false", pos), Value: false}, nil
+	case "PrefixExpression":
+		operator, err := r.string("operator")
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeExpression(r["right"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.PrefixExpression{
+			Token:    newToken(token.LookupOperator(operator), operator, pos),
+			Operator: operator,
+			Right:    right,
+		}, nil
+	case "InfixExpression":
+		operator, err := r.string("operator")
+		if err != nil {
+			return nil, err
+		}
+		left, err := decodeExpression(r["left"])
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeExpression(r["right"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.InfixExpression{
+			Token:    newToken(token.LookupOperator(operator), operator, r.pos("operatorPos")),
+			Operator: operator,
+			Left:     left,
+			Right:    right,
+		}, nil
+	case "IfExpression":
+		condition, err := decodeExpression(r["condition"])
+		if err != nil {
+			return nil, err
+		}
+		consequence, err := decodeBlock(r["consequence"])
+		if err != nil {
+			return nil, err
+		}
+		alternative, err := decodeBlock(r["alternative"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.IfExpression{
+			Token:       newToken(token.IF, "if", pos),
+			Condition:   condition,
+			Consequence: consequence,
+			Alternative: alternative,
+		}, nil
+	case "FunctionLiteral":
+		var raw []json.RawMessage
+		if err := json.Unmarshal(r["parameters"], &raw); err != nil {
+			return nil, fmt.Errorf("astjson: field \"parameters\": %s", err)
+		}
+		params := []*ast.Identifier{}
+		for _, data := range raw {
+			param, err := decodeIdentifier(data)
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, param)
+		}
+		body, err := decodeBlock(r["body"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.FunctionLiteral{Token: newToken(token.FUNCTION, "fn", pos), Parameters: params, Body: body}, nil
+	case "CallExpression":
+		function, err := decodeExpression(r["function"])
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := decodeExpressions(r["arguments"])
+		if err != nil {
+			return nil, err
+		}
+		var keywords []*ast.KeywordArgument
+		if !isNull(r["keywords"]) {
+			var raw []json.RawMessage
+			if err := json.Unmarshal(r["keywords"], &raw); err != nil {
+				return nil, fmt.Errorf("astjson: field \"keywords\": %s", err)
+			}
+			for _, data := range raw {
+				node, err := decode(data)
+				if err != nil {
+					return nil, err
+				}
+				arg, ok := node.(*ast.KeywordArgument)
+				if !ok {
+					return nil, fmt.Errorf("astjson: %T is not a keyword argument", node)
+				}
+				keywords = append(keywords, arg)
+			}
+		}
+		return &ast.CallExpression{
+			Token:     newToken(token.LPAREN, "(", r.pos("lparen")),
+			Function:  function,
+			Arguments: arguments,
+			Keywords:  keywords,
+			Rparen:    closeToken(token.RPAREN, ")", end),
+		}, nil
+	case "KeywordArgument":
+		name, err := decodeIdentifier(r["name"])
+		if err != nil {
+			return nil, err
+		}
+		if name == nil {
+			return nil, fmt.Errorf("astjson: keyword argument without name")
+		}
+		value, err := decodeExpression(r["value"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.KeywordArgument{Token: name.Token, Name: name, Value: value}, nil
+	case "ArrayLiteral":
+		elements, err := decodeExpressions(r["elements"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ArrayLiteral{
+			Token:    newToken(token.LBRACKET, "[", pos),
+			Elements: elements,
+			Rbracket: closeToken(token.RBRACKET, "]", end),
+		}, nil
+	case "IndexExpression":
+		left, err := decodeExpression(r["left"])
+		if err != nil {
+			return nil, err
+		}
+		index, err := decodeExpression(r["index"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.IndexExpression{
+			Token:    newToken(token.LBRACKET, "[", r.pos("lbracket")),
+			Left:     left,
+			Index:    index,
+			Rbracket: closeToken(token.RBRACKET, "]", end),
+		}, nil
+	case "MemberExpression":
+		object, err := decodeExpression(r["object"])
+		if err != nil {
+			return nil, err
+		}
+		property, err := decodeIdentifier(r["property"])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.MemberExpression{
+			Token:    newToken(token.DOT, ".", r.pos("dot")),
+			Object:   object,
+			Property: property,
+		}, nil
+	case "HashLiteral":
+		var pairs []struct {
+			Key   json.RawMessage `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(r["pairs"], &pairs); err != nil {
+			return nil, fmt.Errorf("astjson: field \"pairs\": %s", err)
+		}
+		hash := &ast.HashLiteral{
+			Token:  newToken(token.LBRACE, "{", pos),
+			Pairs:  make(map[ast.Expression]ast.Expression),
+			Rbrace: closeToken(token.RBRACE, "}", end),
+		}
+		for _, pair := range pairs {
+			key, err := decodeExpression(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeExpression(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			hash.Pairs[key] = value
+			hash.Keys = append(hash.Keys, key)
+		}
+		return hash, nil
+	case "BadExpression", "BadStatement":
+		literal, err := r.string("literal")
+		if err != nil {
+			return nil, err
+		}
+		from, to := newToken(token.ILLEGAL, literal, pos), newToken(token.ILLEGAL, "", end)
+		if typ == "BadStatement" {
+			return &ast.BadStatement{Token: from, To: to}, nil
+		}
+		return &ast.BadExpression{Token: from, To: to}, nil
+	}
+	return nil, fmt.Errorf("astjson: unknown node type %q", typ)
+}
+
+func decodeExpression(data json.RawMessage) (ast.Expression, error) {
+	node, err := decode(data)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	exp, ok := node.(ast.Expression)
+	if !ok {
+		return nil, fmt.Errorf("astjson: %T is not an expression", node)
+	}
+	return exp, nil
+}
+
+func decodeIdentifier(data json.RawMessage) (*ast.Identifier, error) {
+	node, err := decode(data)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("astjson: %T is not an identifier", node)
+	}
+	return ident, nil
+}
+
+func decodeBlock(data json.RawMessage) (*ast.BlockStatement, error) {
+	node, err := decode(data)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	block, ok := node.(*ast.BlockStatement)
+	if !ok {
+		return nil, fmt.Errorf("astjson: %T is not a block", node)
+	}
+	return block, nil
+}
+
+func decodeExpressions(data json.RawMessage) ([]ast.Expression, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("astjson: %s", err)
+	}
+	list := []ast.Expression{}
+	for _, item := range raw {
+		exp, err := decodeExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, exp)
+	}
+	return list, nil
+}
+
+func decodeStatements(data json.RawMessage) ([]ast.Statement, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("astjson: %s", err)
+	}
+	list := []ast.Statement{}
+	for _, item := range raw {
+		node, err := decode(item)
+		if err != nil {
+			return nil, err
+		}
+		stmt, ok := node.(ast.Statement)
+		if !ok {
+			return nil, fmt.Errorf("astjson: %T is not a statement", node)
+		}
+		list = append(list, stmt)
+	}
+	return list, nil
+}
+
+func newToken(typ token.TokenType, literal string, pos token.Position) token.Token {
+	return token.Token{Type: typ, Literal: literal, Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+// closeToken recrea el token de un solo carácter que termina en end.
+func closeToken(typ token.TokenType, literal string, end token.Position) token.Token {
+	if !end.IsValid() {
+		return token.Token{}
+	}
+	end.Offset--
+	end.Column--
+	return newToken(typ, literal, end)
+}
+
+// startToken recrea el primer token de exp, que es el token de la
+// sentencia que la contiene.
+func startToken(exp ast.Expression, pos token.Position) token.Token {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return startToken(exp.Left, pos)
+	case *ast.CallExpression:
+		return startToken(exp.Function, pos)
+	case *ast.IndexExpression:
+		return startToken(exp.Left, pos)
+	case *ast.MemberExpression:
+		return startToken(exp.Object, pos)
+	case *ast.Identifier:
+		return exp.Token
+	case *ast.IntegerLiteral:
+		return exp.Token
+	case *ast.StringLiteral:
+		return exp.Token
+	case *ast.Boolean:
+		return exp.Token
+	case *ast.PrefixExpression:
+		return exp.Token
+	case *ast.IfExpression:
+		return exp.Token
+	case *ast.FunctionLiteral:
+		return exp.Token
+	case *ast.ArrayLiteral:
+		return exp.Token
+	case *ast.HashLiteral:
+		return exp.Token
+	case *ast.BadExpression:
+		return exp.Token
+	}
+	return newToken(token.ILLEGAL, "", pos)
+}
@@ -0,0 +1,83 @@
+package astjson
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(parse(t, "-x + 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"Program","pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"statements":[{"type":"ExpressionStatement","pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"expression":{"type":"InfixExpression","pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"operator":"+","operatorPos":{"offset":3,"line":1,"column":4},` +
+		`"left":{"type":"PrefixExpression","pos":{"offset":0,"line":1,"column":1},"end":{"offset":2,"line":1,"column":3},` +
+		`"operator":"-","right":{"type":"Identifier","pos":{"offset":1,"line":1,"column":2},"end":{"offset":2,"line":1,"column":3},"value":"x"}},` +
+		`"right":{"type":"IntegerLiteral","pos":{"offset":5,"line":1,"column":6},"end":{"offset":6,"line":1,"column":7},"value":1}}}]}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nexpected=%s\ngot=     %s", expected, data)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let add = fn(a, b) { return a + b; };\nadd(1, 2) * 3;",
+		`let h = {"one": 1, true: [1, "dos", !false]}; h["one"];`,
+		"if (x < 10) { puts(x) } else { fn() {} }",
+		"let f = fn(x) {\n  if (x) { x[0] }\n};",
+		`draw(1, y: 2, color: "red");`,
+		"db.query(1).rows[0];",
+	}
+	for _, input := range inputs {
+		program := parse(t, input)
+		data, err := Marshal(program)
+		if err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		node, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		if node.String() != program.String() {
+			t.Errorf("%q: wrong String(). expected=%q, got=%q", input, program.String(), node.String())
+		}
+		again, err := Marshal(node)
+		if err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("%q: JSON changed after a round trip.\nfirst= %s\nsecond=%s", input, data, again)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"type": "Loop"}`, `astjson: unknown node type "Loop"`},
+		{`{"type": "Program", "statements": [{"type": "Identifier", "value": "x"}]}`, "astjson: *ast.Identifier is not a statement"},
+		{`[1]`, "astjson: json: cannot unmarshal array into Go value of type astjson.rawNode"},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal([]byte(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
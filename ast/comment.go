@@ -0,0 +1,34 @@
+package ast
+
+import "monkey/token"
+
+// Comment es un comentario del código fuente: desde // hasta el fin de
+// la línea. Text incluye las dos barras.
+type Comment struct {
+	Token token.Token
+	Text  string
+}
+
+func (c *Comment) Pos() token.Position { return c.Token.Pos() }
+func (c *Comment) End() token.Position { return c.Token.End() }
+
+// Comments son los comentarios que el parser asocia a una sentencia. Las
+// sentencias lo embeben, así que se accede como stmt.Leading.
+type Comments struct {
+	// Leading son los comentarios anteriores a la sentencia que no
+	// pertenecen a la sentencia previa.
+	Leading []*Comment
+	// Trailing es el comentario que sigue a la sentencia en su misma
+	// línea, si lo hay.
+	Trailing *Comment
+}
+
+// StatementComments retorna los comentarios de la sentencia para que se
+// puedan leer o cambiar sin conocer su tipo (ver Commented).
+func (c *Comments) StatementComments() *Comments { return c }
+
+// Commented lo implementan las sentencias que pueden tener comentarios.
+type Commented interface {
+	Statement
+	StatementComments() *Comments
+}
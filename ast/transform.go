@@ -0,0 +1,138 @@
+package ast
+
+import "fmt"
+
+// TransformFunc recibe un nodo cuyos hijos ya fueron transformados y
+// retorna el nodo que lo reemplaza (el mismo nodo si no hay cambios).
+type TransformFunc func(Node) Node
+
+// Transform reescribe el árbol de abajo hacia arriba: primero reemplaza
+// cada hijo de node por Transform(hijo, fn) y luego retorna fn(node). Lo
+// usan las pasadas que reemplazan nodos, como el plegado de constantes o
+// la expansión de macros.
+//
+// Los nodos se modifican en su lugar. El reemplazo debe poder ocupar el
+// lugar del nodo original: una Expression donde había una Expression, un
+// *Identifier donde había un *Identifier, etc. (también *BlockStatement y
+// *KeywordArgument); si no, Transform hace panic.
+func Transform(node Node, fn TransformFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		transformStatements(node.Statements, fn)
+	case *LetStatement:
+		node.Name = transformIdentifier(node.Name, fn)
+		node.Value = transformExpression(node.Value, fn)
+	case *ReturnStatement:
+		node.ReturnValue = transformExpression(node.ReturnValue, fn)
+	case *ExpressionStatement:
+		node.Expression = transformExpression(node.Expression, fn)
+	case *BlockStatement:
+		transformStatements(node.Statements, fn)
+	case *PrefixExpression:
+		node.Right = transformExpression(node.Right, fn)
+	case *InfixExpression:
+		node.Left = transformExpression(node.Left, fn)
+		node.Right = transformExpression(node.Right, fn)
+	case *IfExpression:
+		node.Condition = transformExpression(node.Condition, fn)
+		node.Consequence = transformBlock(node.Consequence, fn)
+		node.Alternative = transformBlock(node.Alternative, fn)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = transformIdentifier(param, fn)
+		}
+		node.Body = transformBlock(node.Body, fn)
+	case *CallExpression:
+		node.Function = transformExpression(node.Function, fn)
+		transformExpressions(node.Arguments, fn)
+		for i, arg := range node.Keywords {
+			node.Keywords[i] = transformKeyword(arg, fn)
+		}
+	case *KeywordArgument:
+		node.Name = transformIdentifier(node.Name, fn)
+		node.Value = transformExpression(node.Value, fn)
+	case *ArrayLiteral:
+		transformExpressions(node.Elements, fn)
+	case *IndexExpression:
+		node.Left = transformExpression(node.Left, fn)
+		node.Index = transformExpression(node.Index, fn)
+	case *MemberExpression:
+		// Property es un nombre, no una variable: no se transforma.
+		node.Object = transformExpression(node.Object, fn)
+	case *HashLiteral:
+		pairs := make(map[Expression]Expression, len(node.Pairs))
+		for i, key := range node.Keys {
+			value := transformExpression(node.Pairs[key], fn)
+			key = transformExpression(key, fn)
+			node.Keys[i] = key
+			pairs[key] = value
+		}
+		node.Pairs = pairs
+	}
+	return fn(node)
+}
+
+func transformStatements(statements []Statement, fn TransformFunc) {
+	for i, stmt := range statements {
+		if stmt == nil {
+			continue
+		}
+		result := Transform(stmt, fn)
+		s, ok := result.(Statement)
+		if !ok {
+			panic(fmt.Sprintf("ast.Transform: cannot replace a statement with %T", result))
+		}
+		statements[i] = s
+	}
+}
+
+func transformExpressions(expressions []Expression, fn TransformFunc) {
+	for i, exp := range expressions {
+		expressions[i] = transformExpression(exp, fn)
+	}
+}
+
+func transformExpression(exp Expression, fn TransformFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	result := Transform(exp, fn)
+	e, ok := result.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("ast.Transform: cannot replace an expression with %T", result))
+	}
+	return e
+}
+
+func transformIdentifier(ident *Identifier, fn TransformFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	result := Transform(ident, fn)
+	i, ok := result.(*Identifier)
+	if !ok {
+		panic(fmt.Sprintf("ast.Transform: cannot replace an identifier with %T", result))
+	}
+	return i
+}
+
+func transformKeyword(arg *KeywordArgument, fn TransformFunc) *KeywordArgument {
+	result := Transform(arg, fn)
+	k, ok := result.(*KeywordArgument)
+	if !ok {
+		panic(fmt.Sprintf("ast.Transform: cannot replace a keyword argument with %T", result))
+	}
+	return k
+}
+
+func transformBlock(block *BlockStatement, fn TransformFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	result := Transform(block, fn)
+	b, ok := result.(*BlockStatement)
+	if !ok {
+		panic(fmt.Sprintf("ast.Transform: cannot replace a block with %T", result))
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package ast
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func integer(value int64, literal string) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: value}
+}
+
+func TestTransform(t *testing.T) {
+	// let f = fn(x) { x + one }; f([one, {one: one}][0])
+	key := ident("one")
+	body := &BlockStatement{Statements: []Statement{
+		&ExpressionStatement{Expression: &InfixExpression{Operator: "+", Left: ident("x"), Right: ident("one")}},
+	}}
+	program := &Program{Statements: []Statement{
+		&LetStatement{Name: ident("f"), Value: &FunctionLiteral{Parameters: []*Identifier{ident("x")}, Body: body}},
+		&ExpressionStatement{Expression: &CallExpression{
+			Function: ident("f"),
+			Arguments: []Expression{&IndexExpression{
+				Left: &ArrayLiteral{Elements: []Expression{
+					ident("one"),
+					&HashLiteral{Keys: []Expression{key}, Pairs: map[Expression]Expression{key: ident("one")}},
+				}},
+				Index: integer(0, "0"),
+			}},
+		}},
+	}}
+	program.Statements[0].(*LetStatement).Value.(*FunctionLiteral).Token.Literal = "fn"
+	program.Statements[0].(*LetStatement).Token.Literal = "let"
+
+	var order []string
+	result := Transform(program, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok {
+			order = append(order, ident.Value)
+			if ident.Value == "one" {
+				return integer(1, "1")
+			}
+		}
+		return node
+	})
+
+	expected := "let f = fn(x) (x + 1);f(([1, {1:1}][0]))"
+	if result.String() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, result.String())
+	}
+	// Los hijos se visitan antes que sus padres y en el orden del código.
+	if got := len(order); got != 8 {
+		t.Errorf("wrong number of identifiers visited. got=%d (%v)", got, order)
+	}
+	if order[0] != "f" || order[1] != "x" || order[2] != "x" {
+		t.Errorf("wrong visit order. got=%v", order)
+	}
+}
+
+func TestTransformReplacesRoot(t *testing.T) {
+	exp := &PrefixExpression{Operator: "-", Right: integer(5, "5")}
+	result := Transform(exp, func(node Node) Node {
+		if prefix, ok := node.(*PrefixExpression); ok {
+			if lit, ok := prefix.Right.(*IntegerLiteral); ok {
+				return integer(-lit.Value, "-5")
+			}
+		}
+		return node
+	})
+	if lit, ok := result.(*IntegerLiteral); !ok || lit.Value != -5 {
+		t.Errorf("root not replaced. got=%T (%v)", result, result)
+	}
+}
+
+func TestTransformPanicsOnInvalidReplacement(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	stmt := &ExpressionStatement{Expression: ident("x")}
+	Transform(stmt, func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &BlockStatement{}
+		}
+		return node
+	})
+}
@@ -0,0 +1,87 @@
+package ast
+
+// Inspect recorre el árbol en profundidad y en el orden del código
+// fuente: llama a fn(node) y, si retorna true, recorre cada hijo de node
+// con Inspect y al final llama a fn(nil). La llamada con nil permite
+// saber cuándo termina un nodo, por ejemplo para salir de un ámbito. A
+// diferencia de Transform, no modifica el árbol: lo usan las herramientas
+// que solo lo leen, como el servidor de lenguaje.
+func Inspect(node Node, fn func(Node) bool) {
+	if isNil(node) || !fn(node) {
+		return
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, fn)
+		}
+	case *LetStatement:
+		Inspect(node.Name, fn)
+		Inspect(node.Value, fn)
+	case *ReturnStatement:
+		Inspect(node.ReturnValue, fn)
+	case *ExpressionStatement:
+		Inspect(node.Expression, fn)
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, fn)
+		}
+	case *PrefixExpression:
+		Inspect(node.Right, fn)
+	case *InfixExpression:
+		Inspect(node.Left, fn)
+		Inspect(node.Right, fn)
+	case *IfExpression:
+		Inspect(node.Condition, fn)
+		Inspect(node.Consequence, fn)
+		Inspect(node.Alternative, fn)
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, fn)
+		}
+		Inspect(node.Body, fn)
+	case *CallExpression:
+		Inspect(node.Function, fn)
+		for _, arg := range node.Arguments {
+			Inspect(arg, fn)
+		}
+		for _, arg := range node.Keywords {
+			Inspect(arg, fn)
+		}
+	case *KeywordArgument:
+		Inspect(node.Name, fn)
+		Inspect(node.Value, fn)
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			Inspect(el, fn)
+		}
+	case *IndexExpression:
+		Inspect(node.Left, fn)
+		Inspect(node.Index, fn)
+	case *MemberExpression:
+		Inspect(node.Object, fn)
+		Inspect(node.Property, fn)
+	case *HashLiteral:
+		for _, key := range node.Keys {
+			Inspect(key, fn)
+			Inspect(node.Pairs[key], fn)
+		}
+	}
+	fn(nil)
+}
+
+// isNil indica si node es nil, también cuando es un puntero nil guardado
+// en la interfaz (como el Alternative de un if sin else).
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return node == nil
+	case *BlockStatement:
+		return node == nil
+	case *KeywordArgument:
+		return node == nil
+	}
+	return false
+}
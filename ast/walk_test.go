@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// let f = fn(x) { if (x) { x.y } }; f(-1, [2], {"k": x[3]})
+	key := &StringLiteral{Value: "k"}
+	program := &Program{Statements: []Statement{
+		&LetStatement{Name: ident("f"), Value: &FunctionLiteral{
+			Parameters: []*Identifier{ident("x")},
+			Body: &BlockStatement{Statements: []Statement{
+				&ExpressionStatement{Expression: &IfExpression{
+					Condition: ident("x"),
+					Consequence: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &MemberExpression{Object: ident("x"), Property: ident("y")}},
+					}},
+				}},
+			}},
+		}},
+		&ExpressionStatement{Expression: &CallExpression{
+			Function: ident("f"),
+			Arguments: []Expression{
+				&PrefixExpression{Operator: "-", Right: integer(1, "1")},
+				&ArrayLiteral{Elements: []Expression{integer(2, "2")}},
+				&HashLiteral{Keys: []Expression{key}, Pairs: map[Expression]Expression{
+					key: &IndexExpression{Left: ident("x"), Index: integer(3, "3")},
+				}},
+			},
+		}},
+	}}
+
+	var visited []string
+	depth, maxDepth := 0, 0
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		switch node := node.(type) {
+		case *Identifier:
+			visited = append(visited, node.Value)
+		case *IntegerLiteral:
+			visited = append(visited, node.Token.Literal)
+		case *StringLiteral:
+			visited = append(visited, node.Value)
+		case *FunctionLiteral:
+			// El cuerpo de la función no se recorre.
+			depth--
+			return false
+		}
+		return true
+	})
+	if got, want := strings.Join(visited, " "), "f f 1 2 k x 3"; got != want {
+		t.Errorf("wrong order. want=%q, got=%q", want, got)
+	}
+	if depth != 0 || maxDepth != 6 {
+		t.Errorf("unbalanced calls: depth=%d, maxDepth=%d", depth, maxDepth)
+	}
+
+	visited = nil
+	Inspect(program.Statements[0], func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			visited = append(visited, ident.Value)
+		}
+		return true
+	})
+	if want := []string{"f", "x", "x", "x", "y"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("wrong identifiers. want=%v, got=%v", want, visited)
+	}
+}
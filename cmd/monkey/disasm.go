@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"monkey/compiler"
+	"monkey/parser"
+	"os"
+	"strings"
+)
+
+// disasm implementa `monkey disasm archivo`: muestra el bytecode de un
+// script (.mk) o de un programa ya compilado (.monkeyc). Retorna el
+// código de salida.
+func disasm(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey disasm file.mk|file.monkeyc")
+		return 2
+	}
+	bytecode, err := loadBytecode(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		return 1
+	}
+	if err := bytecode.Disassemble(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// loadBytecode lee un programa compilado o compila el script en path.
+func loadBytecode(path string) (*compiler.Bytecode, error) {
+	if strings.HasSuffix(path, ".monkeyc") {
+		return compiler.LoadFile(path)
+	}
+	program, err := parser.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	comp := compiler.New()
+	comp.SetFoldConstants(true)
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+	return comp.Bytecode(), nil
+}
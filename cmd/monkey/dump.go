@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/ast/astjson"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"os"
+	"strings"
+)
+
+// dump implementa --dump-tokens y --dump-ast: muestra los tokens o el
+// AST del script en path sin ejecutarlo, como texto o, con asJSON, como
+// JSON. Retorna el código de salida.
+func dump(path string, tokens, asJSON bool) int {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if tokens {
+		dumpTokens(out, string(source), asJSON)
+		return 0
+	}
+	// El AST se muestra aunque tenga errores: es lo que armó el parser.
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	data, err := astjson.Marshal(program)
+	if err == nil {
+		if asJSON {
+			out.Write(data)
+			out.WriteString("\n")
+		} else {
+			err = dumpTree(out, data)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, parser.ErrorList(errors))
+		return 1
+	}
+	return 0
+}
+
+// dumpTokens escribe un token por línea, hasta EOF inclusive.
+func dumpTokens(out io.Writer, source string, asJSON bool) {
+	enc := json.NewEncoder(out)
+	l := lexer.New(source)
+	for {
+		tok := l.NextToken()
+		if asJSON {
+			enc.Encode(struct {
+				Type    string `json:"type"`
+				Literal string `json:"literal"`
+				Line    int    `json:"line"`
+				Column  int    `json:"column"`
+				Offset  int    `json:"offset"`
+			}{tok.Type.String(), tok.Literal, tok.Line, tok.Column, tok.Offset})
+		} else {
+			fmt.Fprintf(out, "%s\t%s\t%q\n", tok.Pos(), tok.Type, tok.Literal)
+		}
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+// dumpTree escribe como un árbol indentado el AST en la forma de astjson:
+// cada nodo con su tipo y posición y, debajo, sus campos.
+func dumpTree(out io.Writer, data []byte) error {
+	d := &treeDumper{out: out, dec: json.NewDecoder(strings.NewReader(string(data)))}
+	d.dec.UseNumber()
+	return d.value("", "")
+}
+
+type treeDumper struct {
+	out io.Writer
+	dec *json.Decoder
+}
+
+// value escribe el siguiente valor de dec con la sangría indent,
+// precedido por label ("campo: " o "").
+func (d *treeDumper) value(indent, label string) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			return d.object(indent, label)
+		}
+		if !d.dec.More() {
+			fmt.Fprintf(d.out, "%s%s[]\n", indent, label)
+			_, err := d.dec.Token()
+			return err
+		}
+		fmt.Fprintf(d.out, "%s%s\n", indent, strings.TrimSuffix(label, " "))
+		for d.dec.More() {
+			if err := d.value(indent+"  ", ""); err != nil {
+				return err
+			}
+		}
+		_, err := d.dec.Token()
+		return err
+	case nil:
+		fmt.Fprintf(d.out, "%s%snull\n", indent, label)
+	case string:
+		fmt.Fprintf(d.out, "%s%s%q\n", indent, label, tok)
+	default:
+		fmt.Fprintf(d.out, "%s%s%v\n", indent, label, tok)
+	}
+	return nil
+}
+
+// object escribe el resto de un objeto cuya "{" ya se leyó. Los nodos
+// empiezan con "type" y "pos"; las posiciones se escriben como línea:columna
+// y los demás objetos, como los pares de un hash, campo por campo.
+func (d *treeDumper) object(indent, label string) error {
+	var header []string
+	printed := false
+	printHeader := func() {
+		if !printed {
+			if len(header) == 0 {
+				header = []string{"-"}
+			}
+			fmt.Fprintf(d.out, "%s%s%s\n", indent, label, strings.Join(header, " "))
+			printed = true
+		}
+	}
+	var pos struct{ Line, Column int }
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		switch key {
+		case "type":
+			var typ string
+			if err := d.dec.Decode(&typ); err != nil {
+				return err
+			}
+			header = append(header, typ)
+		case "pos":
+			if err := d.dec.Decode(&pos); err != nil {
+				return err
+			}
+			header = append(header, fmt.Sprintf("%d:%d", pos.Line, pos.Column))
+		case "end":
+			var end json.RawMessage
+			if err := d.dec.Decode(&end); err != nil {
+				return err
+			}
+		case "offset", "line", "column":
+			// Un objeto que empieza con "offset" es una posición.
+			var n int
+			if err := d.dec.Decode(&n); err != nil {
+				return err
+			}
+			if key == "line" {
+				pos.Line = n
+			} else if key == "column" {
+				pos.Column = n
+				header = append(header, fmt.Sprintf("%d:%d", pos.Line, pos.Column))
+			}
+		default:
+			printHeader()
+			if err := d.value(indent+"  ", key+": "); err != nil {
+				return err
+			}
+		}
+	}
+	printHeader()
+	_, err := d.dec.Token()
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"monkey/ast/astjson"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+const dumpSource = "let x = 1;\nx"
+
+func TestDumpTokens(t *testing.T) {
+	var out bytes.Buffer
+	dumpTokens(&out, dumpSource, false)
+	expected := "1:1\tLET\t\"let\"\n" +
+		"1:5\tIDENT\t\"x\"\n" +
+		"1:7\t=\t\"=\"\n" +
+		"1:9\tINT\t\"1\"\n" +
+		"1:10\t;\t\";\"\n" +
+		"2:1\tIDENT\t\"x\"\n" +
+		"2:2\tEOF\t\"\"\n"
+	if got := out.String(); got != expected {
+		t.Errorf("wrong tokens.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestDumpTokensJSON(t *testing.T) {
+	var out bytes.Buffer
+	dumpTokens(&out, dumpSource, true)
+	type tok struct {
+		Type    string `json:"type"`
+		Literal string `json:"literal"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Offset  int    `json:"offset"`
+	}
+	var got []tok
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var tk tok
+		if err := dec.Decode(&tk); err != nil {
+			t.Fatalf("invalid JSON: %s", err)
+		}
+		got = append(got, tk)
+	}
+	if len(got) != 7 {
+		t.Fatalf("wrong number of tokens. want=7, got=%d", len(got))
+	}
+	if want := (tok{"IDENT", "x", 2, 1, 11}); got[5] != want {
+		t.Errorf("wrong token. want=%+v, got=%+v", want, got[5])
+	}
+	if got[6].Type != "EOF" {
+		t.Errorf("last token is not EOF. got=%+v", got[6])
+	}
+}
+
+func TestDumpTree(t *testing.T) {
+	data, err := astjson.Marshal(parser.New(lexer.New(dumpSource)).ParseProgram())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := dumpTree(&out, data); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Program 1:1
+  statements:
+    LetStatement 1:1
+      name: Identifier 1:5
+        value: "x"
+      value: IntegerLiteral 1:9
+        value: 1
+    ExpressionStatement 2:1
+      expression: Identifier 2:1
+        value: "x"
+`
+	if got := out.String(); got != expected {
+		t.Errorf("wrong tree.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/lint"
+	"monkey/parser"
+	"os"
+)
+
+// lintFiles implementa `monkey lint archivo.mk...`: muestra los
+// diagnósticos del paquete lint de cada script, uno por línea con el
+// formato archivo:línea:columna: mensaje (chequeo). Retorna 1 si encontró
+// alguno o si un script no se pudo analizar.
+func lintFiles(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey lint script.mk...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	code := 0
+	for _, path := range flags.Args() {
+		program, err := parser.ParseFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			code = 1
+			continue
+		}
+		for _, diag := range lint.Lint(program) {
+			fmt.Printf("%s:%s\n", path, diag)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/lsp"
+	"os"
+)
+
+// serveLSP implementa `monkey lsp`: atiende a un editor por la entrada y
+// la salida estándar con el Language Server Protocol. Retorna el código
+// de salida.
+func serveLSP(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	// Los clientes de LSP suelen pasar --stdio; es el único transporte.
+	flags.Bool("stdio", true, "communicate through stdin and stdout (the default)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey lsp [--stdio]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
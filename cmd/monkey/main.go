@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/repl"
+	"monkey/vm"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	engineName := flag.String("engine", "eval", "execution engine: eval or vm")
+	trace := flag.Bool("trace", false, "print each VM instruction to stderr (requires --engine=vm)")
+	var source string
+	flag.StringVar(&source, "e", "", "run `program` and exit; the arguments are passed to args()")
+	flag.StringVar(&source, "eval", "", "same as -e")
+	prompt := flag.String("prompt", repl.PROMPT, "REPL prompt")
+	quiet := flag.Bool("quiet", false, "do not print the banner nor the monkey face of syntax errors")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	noColor := flag.Bool("no-color", false, "do not use colors in the REPL (also set by NO_COLOR)")
+	dumpTokens := flag.Bool("dump-tokens", false, "print the tokens of a script instead of running it")
+	dumpAST := flag.Bool("dump-ast", false, "print the syntax tree of a script instead of running it")
+	dumpJSON := flag.Bool("json", false, "print --dump-tokens and --dump-ast as JSON")
+	flag.Parse()
+	engine, err := repl.ParseEngine(*engineName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *showVersion {
+		fmt.Println("monkey", repl.VERSION)
+		os.Exit(0)
+	}
+	config := repl.Config{Engine: engine, Prompt: *prompt, NoMonkeyFace: *quiet, NoColor: *noColor}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HistoryFile = filepath.Join(home, ".monkey_history")
+		config.RCFile = filepath.Join(home, ".monkeyrc")
+	}
+	if rc, ok := os.LookupEnv("MONKEY_RC"); ok {
+		config.RCFile = rc
+	}
+	if *trace {
+		if engine != repl.EngineVM {
+			fmt.Fprintln(os.Stderr, "--trace requires --engine=vm")
+			os.Exit(2)
+		}
+		config.Trace = os.Stderr
+	}
+	if *dumpTokens || *dumpAST {
+		if flag.NArg() != 1 || *dumpTokens && *dumpAST {
+			fmt.Fprintln(os.Stderr, "usage: monkey --dump-tokens|--dump-ast [--json] script.mk")
+			os.Exit(2)
+		}
+		os.Exit(dump(flag.Arg(0), *dumpTokens, *dumpJSON))
+	}
+	opts := vm.Options{Trace: config.Trace}
+	if evalFlag() {
+		opts.Args = flag.Args()
+		os.Exit(runReader("-e", strings.NewReader(source), engine, opts))
+	}
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "disasm":
+			os.Exit(disasm(flag.Args()[1:]))
+		case "transpile":
+			os.Exit(transpile(flag.Args()[1:]))
+		case "playground":
+			os.Exit(servePlayground(flag.Args()[1:]))
+		case "lsp":
+			os.Exit(serveLSP(flag.Args()[1:]))
+		case "lint":
+			os.Exit(lintFiles(flag.Args()[1:]))
+		default:
+			os.Exit(runScript(flag.Arg(0), flag.Args()[1:], engine, opts))
+		}
+	}
+	// Un programa que llega por una tubería se ejecuta sin el REPL.
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		os.Exit(runReader("<stdin>", os.Stdin, engine, opts))
+	}
+
+	if !*quiet {
+		user, err := user.Current()
+		if err != nil {
+			panic(err)
+		}
+		config.Banner = repl.DefaultBanner(user.Username, engine)
+	}
+	os.Exit(repl.StartWithConfig(os.Stdin, os.Stdout, config))
+}
+
+// evalFlag indica si se usó -e o --eval, aunque sea con un programa vacío.
+func evalFlag() bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "e" || f.Name == "eval" {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/playground"
+	"net/http"
+	"os"
+	"time"
+)
+
+// servePlayground implementa `monkey playground [-addr dirección]`: sirve
+// una página para ejecutar programas y el endpoint /run que la atiende.
+// Retorna el código de salida.
+func servePlayground(args []string) int {
+	flags := flag.NewFlagSet("playground", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8080", "listen on `address`")
+	timeout := flags.Duration("timeout", 5*time.Second, "maximum running time of each program")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey playground [-addr address] [-timeout duration]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	fmt.Printf("Monkey playground listening on http://%s/\n", *addr)
+	if err := http.ListenAndServe(*addr, playground.NewHandler(*timeout)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/repl"
+	"monkey/vm"
+	"os"
+	"strings"
+)
+
+// runScript implementa `monkey script.mk [args...]`: ejecuta el script con
+// el motor engine y pasa args al builtin args(). Un programa compilado
+// (.monkeyc) siempre se ejecuta en la VM. Retorna el código de salida: el
+// que se pidió con exit(code), 1 si hubo un error o 0.
+func runScript(path string, args []string, engine repl.Engine, opts vm.Options) int {
+	opts.Args = args
+	if strings.HasSuffix(path, ".monkeyc") {
+		bytecode, err := compiler.LoadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			return 1
+		}
+		return runBytecode(path, bytecode, opts)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	return runReader(path, f, engine, opts)
+}
+
+// runReader ejecuta el programa que se lee de r como runScript. name
+// identifica al programa en los mensajes de error.
+func runReader(name string, r io.Reader, engine repl.Engine, opts vm.Options) int {
+	program, err := parser.ParseReader(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return 1
+	}
+	if engine != repl.EngineVM {
+		return exitCode(evaluator.EvalWithOptions(program, object.NewEnvironment(), evaluator.Options{Args: opts.Args}))
+	}
+	comp := compiler.New()
+	comp.SetFoldConstants(true)
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return 1
+	}
+	return runBytecode(name, comp.Bytecode(), opts)
+}
+
+// runBytecode ejecuta bytecode en la VM.
+func runBytecode(name string, bytecode *compiler.Bytecode, opts vm.Options) (status int) {
+	// Load no deja pasar bytecode que la VM no pueda ejecutar, pero si
+	// igual algo falla se informa como un error del programa.
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, r)
+			status = 1
+		}
+	}()
+	machine := vm.NewWithOptions(bytecode.Optimize().Fuse(), opts)
+	if err := machine.Run(); err != nil {
+		if rt, ok := err.(*vm.RuntimeError); ok {
+			return exitCode(rt.Object())
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return 1
+	}
+	return exitCode(machine.LastPoppedStackElem())
+}
+
+// exitCode retorna el código de salida de un programa cuyo último valor
+// es result. Los errores se muestran en la salida de errores.
+func exitCode(result object.Object) int {
+	switch result := result.(type) {
+	case *object.Exit:
+		return result.Code
+	case *object.Error:
+		fmt.Fprintln(os.Stderr, result.Inspect())
+		return 1
+	}
+	return 0
+}
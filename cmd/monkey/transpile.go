@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/transpiler"
+	"os"
+)
+
+// transpile implementa `monkey transpile [-target go|js] [-o salida]
+// archivo.mk`: traduce un script a un programa Go o JavaScript. Sin -o, el
+// programa se escribe en la salida estándar. Retorna el código de salida.
+func transpile(args []string) int {
+	flags := flag.NewFlagSet("transpile", flag.ContinueOnError)
+	output := flags.String("o", "", "write the program to `file` instead of stdout")
+	target := flags.String("target", "go", "output language: go or js")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey transpile [-target go|js] [-o file] script.mk")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	toSource, ok := map[string]func(*ast.Program) (string, error){
+		"go": transpiler.ToGo,
+		"js": transpiler.ToJS,
+	}[*target]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown target %q (want go or js)\n", *target)
+		return 2
+	}
+	path := flags.Arg(0)
+	program, err := parser.ParseFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+	src, err := toSource(program)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, err)
+		return 1
+	}
+	if *output == "" {
+		fmt.Print(src)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(src), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
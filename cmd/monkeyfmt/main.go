@@ -0,0 +1,60 @@
+// monkeyfmt formatea código Monkey con el formato canónico del paquete
+// printer. Sin archivos lee de la entrada estándar; con -w reescribe los
+// archivos en vez de mostrar el resultado.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/printer"
+	"os"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write result to the source files instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkeyfmt [-w] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err == nil {
+			err = format("<stdin>", string(src), false)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	status := 0
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = format(path, string(src), *write)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func format(path, src string, write bool) error {
+	out, err := printer.FormatSource(src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if !write {
+		_, err := io.WriteString(os.Stdout, out)
+		return err
+	}
+	if out == src {
+		return nil
+	}
+	return os.WriteFile(path, []byte(out), 0644)
+}
@@ -0,0 +1,60 @@
+//go:build js && wasm
+
+// monkeywasm compila el intérprete a WebAssembly para usarlo desde una
+// página web sin instalar Go. Al cargarse define el objeto global monkey:
+//
+//	const result = await monkey.run('puts("hola"); 1 + 2');
+//	// {output: "hola\n", value: "3", error: ""}
+//
+// monkey.run retorna una Promise: el programa se ejecuta en otra goroutine
+// para no bloquear la página, y con ella los builtins que esperan (sleep,
+// recv...). Los programas se ejecutan con los límites de playground.Run. Para
+// compilarlo y probar la página de ejemplo:
+//
+//	GOOS=js GOARCH=wasm go build -o cmd/monkeywasm/monkey.wasm ./cmd/monkeywasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" cmd/monkeywasm/
+//	python3 -m http.server -d cmd/monkeywasm
+package main
+
+import (
+	"monkey/playground"
+	"syscall/js"
+)
+
+func main() {
+	js.Global().Set("monkey", js.ValueOf(map[string]interface{}{
+		"run": js.FuncOf(run),
+	}))
+	// El programa tiene que seguir vivo para atender las llamadas.
+	select {}
+}
+
+// run implementa monkey.run(source). Retorna una Promise que se resuelve
+// con el resultado del programa.
+func run(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return js.Global().Get("Promise").Call("resolve", resultValue(playground.Result{Error: "usage: monkey.run(source)"}))
+	}
+	source := args[0].String()
+	// Si run esperara al programa bloquearía el event loop de JavaScript,
+	// del que dependen las goroutines para avanzar.
+	executor := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolve := args[0]
+		go func() {
+			resolve.Invoke(resultValue(playground.Run(source)))
+		}()
+		return nil
+	})
+	// Promise llama a executor antes de retornar.
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// resultValue convierte result en un objeto de JavaScript.
+func resultValue(result playground.Result) js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"output": result.Output,
+		"value":  result.Value,
+		"error":  result.Error,
+	})
+}
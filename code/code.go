@@ -38,6 +38,25 @@ const (
 	OpCall
 	OpReturnValue
 	OpReturn
+	OpGetLocal
+	OpSetLocal
+	OpGetBuiltin
+	OpPow
+	OpClosure
+	OpGetFree
+	OpCurrentClosure
+	OpTailCall
+	OpAddConstant
+	OpSubConstant
+	OpAddLocals
+	OpMember
+	OpCallKeywords
+	OpYield
+	OpLessThan
+	OpMakeCell
+	OpGetLocalCell
+	OpSetLocalCell
+	OpGetFreeCell
 )
 
 // Tipo Definition
@@ -68,9 +87,45 @@ var definitions = map[Opcode]*Definition{
 	OpArray:         {"OpArray", []int{2}},
 	OpHash:          {"OpHash", []int{2}},
 	OpIndex:         {"OpIndex", []int{}},
-	OpCall:          {"OpCall", []int{}},
+	OpCall:          {"OpCall", []int{1}},
 	OpReturnValue:   {"OpReturnValue", []int{}},
 	OpReturn:        {"OpReturn", []int{}},
+	OpGetLocal:      {"OpGetLocal", []int{1}},
+	OpSetLocal:      {"OpSetLocal", []int{1}},
+	OpGetBuiltin:    {"OpGetBuiltin", []int{1}},
+	OpPow:           {"OpPow", []int{}},
+	// Constant index of the function and number of free variables.
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	// Like OpCall, but the caller returns whatever the callee returns, so
+	// the callee can reuse the caller's frame.
+	OpTailCall: {"OpTailCall", []int{1}},
+	// Superinstructions (see compiler.Bytecode.Fuse): OpConstant followed
+	// by OpAdd or OpSub, and OpGetLocal, OpGetLocal, OpAdd.
+	OpAddConstant: {"OpAddConstant", []int{2}},
+	OpSubConstant: {"OpSubConstant", []int{2}},
+	OpAddLocals:   {"OpAddLocals", []int{1, 1}},
+	// Member access: the operand is the constant index of the name.
+	OpMember: {"OpMember", []int{2}},
+	// Call with keyword arguments: the number of positional arguments and
+	// of keyword arguments, which are on the stack after them as name and
+	// value pairs.
+	OpCallKeywords: {"OpCallKeywords", []int{1, 1}},
+	// The body of the yield function of a fiber: pauses the fiber with the
+	// first argument of the call, and the value the fiber is resumed with
+	// becomes the result of the call.
+	OpYield: {"OpYield", []int{}},
+	// Compiled with the operands in source order, unlike > with them
+	// swapped, so that errors show the operator that was written.
+	OpLessThan: {"OpLessThan", []int{}},
+	// Locals that closures capture and a later let changes live in a
+	// cell: OpMakeCell puts the value of a local in one, and the others
+	// read or write through the cell of a local or free variable.
+	OpMakeCell:     {"OpMakeCell", []int{1}},
+	OpGetLocalCell: {"OpGetLocalCell", []int{1}},
+	OpSetLocalCell: {"OpSetLocalCell", []int{1}},
+	OpGetFreeCell:  {"OpGetFreeCell", []int{1}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -102,6 +157,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
 		}
 		offset += width
 	}
@@ -141,6 +198,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operadCount for %s\n", def.Name)
@@ -154,6 +213,8 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
 		}
 
 		offset += width
@@ -165,3 +226,7 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
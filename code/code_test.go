@@ -13,6 +13,8 @@ func TestMake(t *testing.T) {
 	}{
 		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
 	}
 
 	for _, tt := range tests {
@@ -33,12 +35,16 @@ func TestMake(t *testing.T) {
 func TestInstructionsString(t *testing.T) {
 	instructions := []Instructions{
 		Make(OpAdd),
+		Make(OpGetLocal, 1),
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
 	}
 	expected := `0000 OpAdd
-0001 OpConstant 2
-0004 OpConstant 65535
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
 `
 	concatted := Instructions{}
 	for _, ins := range instructions {
@@ -57,6 +63,8 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
 	}
 
 	for _, tt := range tests {
@@ -79,3 +87,33 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestLineTable(t *testing.T) {
+	var lt LineTable
+	lt = lt.Add(0, 1)
+	lt = lt.Add(3, 1)
+	lt = lt.Add(4, 0)
+	lt = lt.Add(5, 2)
+	lt = lt.Add(5, 3)
+	lt = lt.Add(8, 5)
+
+	tests := []struct {
+		offset int
+		line   int
+	}{
+		{0, 1}, {4, 1}, {5, 3}, {7, 3}, {8, 5}, {100, 5},
+	}
+	for _, tt := range tests {
+		if got := lt.Line(tt.offset); got != tt.line {
+			t.Errorf("Line(%d) wrong. want=%d, got=%d", tt.offset, tt.line, got)
+		}
+	}
+
+	lt = lt.Truncate(5)
+	if len(lt) != 1 || lt.Line(8) != 1 {
+		t.Errorf("Truncate(5) wrong. got=%v", lt)
+	}
+	if (LineTable(nil)).Line(0) != 0 {
+		t.Errorf("empty table should give line 0")
+	}
+}
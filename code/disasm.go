@@ -0,0 +1,97 @@
+package code
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// LineEntry says that the instructions from Offset on come from source
+// line Line, up to the next entry.
+type LineEntry struct {
+	Offset int
+	Line   int
+}
+
+// LineTable maps instruction offsets to source lines. Entries are sorted
+// by offset and only added when the line changes.
+type LineTable []LineEntry
+
+// Add records that the instruction at offset comes from line. Offsets
+// must not decrease from one call to the next; lines <= 0 are unknown
+// and ignored.
+func (lt LineTable) Add(offset, line int) LineTable {
+	if line <= 0 {
+		return lt
+	}
+	if n := len(lt); n > 0 {
+		if lt[n-1].Line == line {
+			return lt
+		}
+		if lt[n-1].Offset == offset {
+			lt[n-1].Line = line
+			return lt
+		}
+	}
+	return append(lt, LineEntry{Offset: offset, Line: line})
+}
+
+// Truncate drops the entries for offsets >= offset, to follow
+// instructions that were removed from the end.
+func (lt LineTable) Truncate(offset int) LineTable {
+	i := sort.Search(len(lt), func(i int) bool { return lt[i].Offset >= offset })
+	return lt[:i]
+}
+
+// Line returns the source line of the instruction at offset, or 0 if it
+// is unknown.
+func (lt LineTable) Line(offset int) int {
+	i := sort.Search(len(lt), func(i int) bool { return lt[i].Offset > offset })
+	if i == 0 {
+		return 0
+	}
+	return lt[i-1].Line
+}
+
+// Disassemble writes ins to w, one instruction per line: its offset, the
+// source line from lines ("|" when it is the same as the previous one)
+// and the instruction with its operands. lines may be nil. If annotate is
+// not nil, what it returns for an instruction is appended as a comment,
+// e.g. the value of a constant.
+func Disassemble(w io.Writer, ins Instructions, lines LineTable, annotate func(op Opcode, operands []int) string) error {
+	bw := bufio.NewWriter(w)
+	lastLine := -1
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			return err
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return fmt.Errorf("truncated instruction %s at %d", def.Name, i)
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		line := "   ?"
+		if l := lines.Line(i); l == lastLine {
+			line = "   |"
+		} else if l > 0 {
+			line = fmt.Sprintf("%4d", l)
+			lastLine = l
+		}
+		text := ins.fmtInstruction(def, operands)
+		if annotate != nil {
+			if note := annotate(Opcode(ins[i]), operands); note != "" {
+				text = fmt.Sprintf("%-20s ; %s", text, note)
+			}
+		}
+		fmt.Fprintf(bw, "%04d %s %s\n", i, line, text)
+
+		i += 1 + read
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,92 @@
+package compiler
+
+import "monkey/ast"
+
+// The evaluator runs a function in an environment that the closures it
+// creates keep a reference to, so they see the lets that run after they
+// were created. The compiler gets the same result by defining every let
+// of a function before compiling its body, so a closure can refer to a
+// later one, and by keeping in a cell the locals that closures capture
+// and a let can change afterwards.
+
+// letNames returns the names node defines with let, in the order they
+// appear, without the ones of nested functions.
+func letNames(node ast.Node) []string {
+	var names []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			names = append(names, n.Name.Value)
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// capturedNames returns the names that the functions nested in node use
+// from outside them.
+func capturedNames(node ast.Node) map[string]bool {
+	captured := map[string]bool{}
+	uses(node, func(name string, nested bool) {
+		if nested {
+			captured[name] = true
+		}
+	})
+	return captured
+}
+
+// freeNames returns the names fl uses without defining them, including
+// those its nested functions use. self is the name fl was defined with,
+// which inside fl refers to fl itself.
+func freeNames(fl *ast.FunctionLiteral, self string) map[string]bool {
+	bound := map[string]bool{self: true}
+	for _, param := range fl.Parameters {
+		bound[param.Value] = true
+	}
+	for _, name := range letNames(fl.Body) {
+		bound[name] = true
+	}
+	free := map[string]bool{}
+	uses(fl.Body, func(name string, nested bool) {
+		if !bound[name] {
+			free[name] = true
+		}
+	})
+	return free
+}
+
+// uses calls fn with each name node reads. nested tells whether the name
+// is read by a function nested in node, which then uses it from outside.
+func uses(node ast.Node, fn func(name string, nested bool)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			fn(n.Value, false)
+		case *ast.LetStatement:
+			// The name is defined, not read.
+			if fl, ok := n.Value.(*ast.FunctionLiteral); ok {
+				for name := range freeNames(fl, n.Name.Value) {
+					fn(name, true)
+				}
+			} else {
+				uses(n.Value, fn)
+			}
+			return false
+		case *ast.FunctionLiteral:
+			for name := range freeNames(n, "") {
+				fn(name, true)
+			}
+			return false
+		case *ast.KeywordArgument:
+			// The name is a parameter of the function called.
+			uses(n.Value, fn)
+			return false
+		case *ast.MemberExpression:
+			uses(n.Object, fn)
+			return false
+		}
+		return true
+	})
+}
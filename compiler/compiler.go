@@ -4,12 +4,22 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/code"
+	"monkey/evaluator"
 	"monkey/object"
-	"sort"
+	"monkey/optimizer"
+)
+
+// Operands of OpCall, OpGetLocal, OpSetLocal, OpGetFree and the free
+// count of OpClosure are one byte wide.
+const (
+	maxArguments = 255
+	maxLocals    = 256
+	maxFree      = 255
 )
 
 type CompilationScope struct {
 	instructions        code.Instructions
+	lines               code.LineTable
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
 }
@@ -21,6 +31,17 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	// Source line of the node being compiled, recorded for every
+	// emitted instruction.
+	line int
+
+	// Name the function literal being compiled is bound to by a let, so
+	// the function can refer to itself.
+	functionName string
+
+	// foldConstants is set by SetFoldConstants.
+	foldConstants bool
 }
 
 type EmittedInstruction struct {
@@ -35,14 +56,25 @@ func New() *Compiler {
 		previousInstruction: EmittedInstruction{},
 	}
 
+	symbolTable := NewSymbolTable()
+	DefineBuiltins(symbolTable)
+
 	return &Compiler{
 		constants:   []object.Object{},
-		symbolTable: NewSymbolTable(),
+		symbolTable: symbolTable,
 		scopes:      []CompilationScope{mainScope},
 		scopeIndex:  0,
 	}
 }
 
+// DefineBuiltins adds the evaluator builtins to s, numbered the way the
+// VM looks them up (see evaluator.BuiltinNames).
+func DefineBuiltins(s *SymbolTable) {
+	for i, name := range evaluator.BuiltinNames() {
+		s.DefineBuiltin(i, name)
+	}
+}
+
 func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 	compiler := New()
 	compiler.symbolTable = s
@@ -50,8 +82,27 @@ func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 	return compiler
 }
 
+// SetFoldConstants makes c compile constant expressions, such as
+// `60 * 60 * 24`, as their value (see optimizer.Constant). The AST is not
+// changed, so a program can be folded and still be printed or evaluated
+// as written. It is off by default.
+func (c *Compiler) SetFoldConstants(fold bool) {
+	c.foldConstants = fold
+}
+
+// fold returns the literal exp folds to, or nil if folding is off or exp
+// is not constant.
+func (c *Compiler) fold(exp ast.Expression) ast.Expression {
+	if !c.foldConstants {
+		return nil
+	}
+	return optimizer.Constant(exp)
+}
+
 // Nuestro método Compile :)
 func (c *Compiler) Compile(node ast.Node) error {
+	defer c.setLine(node)()
+
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
@@ -62,20 +113,142 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.LetStatement:
+		// A function can call itself by name, so its name is defined
+		// before compiling the body. Anything else can't see the name yet.
+		var symbol Symbol
+		_, isFunction := node.Value.(*ast.FunctionLiteral)
+		if isFunction {
+			symbol = c.symbolTable.Define(node.Name.Value)
+			c.functionName = node.Name.Value
+		}
 		err := c.Compile(node.Value)
 		if err != nil {
 			return err
 		}
-		symbol := c.symbolTable.Define(node.Name.Value)
-		c.emit(code.OpSetGlobal, symbol.Index)
+		if !isFunction {
+			symbol = c.symbolTable.Define(node.Name.Value)
+		}
+		switch {
+		case symbol.Scope == GlobalScope:
+			c.emit(code.OpSetGlobal, symbol.Index)
+		case symbol.Cell:
+			c.emit(code.OpSetLocalCell, symbol.Index)
+		default:
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
 
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			// The evaluator looks names up when the code runs, so a name
+			// may be defined by a later let, as in mutual recursion, or
+			// never. It gets a global slot, and the VM reports it as not
+			// found if it has no value when read.
+			symbol = c.symbolTable.DefineGlobal(node.Value)
+		}
+
+		c.loadSymbol(symbol)
+
+	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			c.emit(code.OpReturn)
+			return nil
+		}
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.FunctionLiteral:
+		name := c.functionName
+		c.functionName = ""
+
+		c.enterScope()
+
+		if name != "" {
+			c.symbolTable.DefineFunctionName(name)
+		}
+
+		for _, p := range node.Parameters {
+			c.symbolTable.DefineParameter(p.Value)
+		}
+		c.defineLets(node.Body)
+
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
 		}
 
-		c.emit(code.OpGetGlobal, symbol.Index)
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		locals := c.symbolTable.localNames()
+		lines := c.scopes[c.scopeIndex].lines
+		instructions := c.leaveScope()
+		markTailCalls(instructions)
+		if numLocals > maxLocals {
+			return fmt.Errorf("too many local variables")
+		}
+		if len(freeSymbols) > maxFree {
+			return fmt.Errorf("too many free variables")
+		}
+
+		for _, s := range freeSymbols {
+			c.loadCapture(s)
+		}
+
+		params := make([]string, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p.Value
+		}
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			Lines:         lines,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+			Parameters:    params,
+			Locals:        locals,
+			Name:          name,
+		}
+		fnIndex := c.addConstant(compiledFn)
+		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	case *ast.CallExpression:
+		if len(node.Arguments) > maxArguments || len(node.Keywords) > maxArguments {
+			return fmt.Errorf("too many arguments")
+		}
+
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(node.Keywords) == 0 {
+			c.emit(code.OpCall, len(node.Arguments))
+			return nil
+		}
+		// The VM matches each name with a parameter when it makes the call.
+		for _, k := range node.Keywords {
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: k.Name.Value}))
+			if err := c.Compile(k.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCallKeywords, len(node.Arguments), len(node.Keywords))
 
 	case *ast.ExpressionStatement:
 		err := c.Compile(node.Expression)
@@ -85,6 +258,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop)
 
 	case *ast.PrefixExpression:
+		if folded := c.fold(node); folded != nil {
+			return c.Compile(folded)
+		}
 		err := c.Compile(node.Right)
 		if err != nil {
 			return err
@@ -100,20 +276,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.InfixExpression:
-		if node.Operator == "<" {
-			err := c.Compile(node.Right)
-			if err != nil {
-				return err
-			}
-
-			err = c.Compile(node.Left)
-			if err != nil {
-				return err
-			}
-			c.emit(code.OpGreaterThan)
-			return nil
+		if folded := c.fold(node); folded != nil {
+			return c.Compile(folded)
 		}
-
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -133,8 +298,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "**":
+			c.emit(code.OpPow)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case "<":
+			c.emit(code.OpLessThan)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
@@ -168,9 +337,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
-		if c.lastInstructionIsPop() {
-			c.removeLastPop()
-		}
+		c.blockValue()
 
 		// Emit an `OpJump` with a bogus value
 		jumpPos := c.emit(code.OpJump, 9999)
@@ -186,9 +353,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 
-			if c.lastInstructionIsPop() {
-				c.removeLastPop()
-			}
+			c.blockValue()
 		}
 
 		afterAlternativePos := len(c.currentInstructions())
@@ -217,15 +382,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpArray, len(node.Elements))
 
 	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, k := range keys {
+		for _, k := range node.Keys {
 			err := c.Compile(k)
 			if err != nil {
 				return err
@@ -250,6 +407,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		c.emit(code.OpIndex)
+
+	case *ast.MemberExpression:
+		err := c.Compile(node.Object)
+		if err != nil {
+			return err
+		}
+
+		name := &object.String{Value: node.Property.Value}
+		c.emit(code.OpMember, c.addConstant(name))
+
+	case *ast.BadExpression, *ast.BadStatement:
+		return fmt.Errorf("cannot compile invalid syntax at %s", node.Pos())
 	}
 
 	return nil
@@ -271,7 +440,118 @@ func (c *Compiler) changeOperand(opPos int, operand int) {
 }
 
 func (c *Compiler) lastInstructionIsPop() bool {
-	return c.scopes[c.scopeIndex].lastInstruction.Opcode == code.OpPop
+	return c.lastInstructionIs(code.OpPop)
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// blockValue leaves the value of the block just compiled on the stack:
+// the value of its last expression statement, or null if the block is
+// empty or ends with another kind of statement.
+func (c *Compiler) blockValue() {
+	if c.lastInstructionIsPop() {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+// markTailCalls turns the calls of a function body whose result is
+// returned right away into OpTailCall: those followed by OpReturnValue
+// or by a jump to one, as at the end of the branches of an if. Both
+// opcodes have the same width, so ins is rewritten in place.
+func markTailCalls(ins code.Instructions) {
+	isReturn := func(offset int) bool {
+		return offset < len(ins) && code.Opcode(ins[offset]) == code.OpReturnValue
+	}
+
+	for i := 0; i < len(ins); {
+		op := code.Opcode(ins[i])
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return
+		}
+		_, read := code.ReadOperands(def, ins[i+1:])
+		next := i + 1 + read
+
+		if op == code.OpCall && next < len(ins) {
+			switch code.Opcode(ins[next]) {
+			case code.OpReturnValue:
+				ins[i] = byte(code.OpTailCall)
+			case code.OpJump:
+				if isReturn(int(code.ReadUint16(ins[next+1:]))) {
+					ins[i] = byte(code.OpTailCall)
+				}
+			}
+		}
+		i = next
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		if s.Cell {
+			c.emit(code.OpGetLocalCell, s.Index)
+		} else {
+			c.emit(code.OpGetLocal, s.Index)
+		}
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		if s.Cell {
+			c.emit(code.OpGetFreeCell, s.Index)
+		} else {
+			c.emit(code.OpGetFree, s.Index)
+		}
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+// loadCapture pushes the value of s that a closure captures: the cell
+// itself if s is kept in one.
+func (c *Compiler) loadCapture(s Symbol) {
+	switch {
+	case s.Cell && s.Scope == LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case s.Cell && s.Scope == FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
+// defineLets defines the lets of body, the body of the function being
+// compiled, before compiling it, and puts in a cell the ones that nested
+// functions capture (see capture.go). A parameter that no let redefines
+// keeps its value, so the closures can capture it as is.
+func (c *Compiler) defineLets(body *ast.BlockStatement) {
+	lets := letNames(body)
+	for _, name := range lets {
+		c.symbolTable.Define(name)
+	}
+	captured := capturedNames(body)
+	for _, name := range lets {
+		if captured[name] && !c.symbolTable.store[name].Cell {
+			symbol := c.symbolTable.defineCell(name)
+			c.emit(code.OpMakeCell, symbol.Index)
+		}
+	}
 }
 
 func (c *Compiler) removeLastPop() {
@@ -282,6 +562,7 @@ func (c *Compiler) removeLastPop() {
 	new := old[:last.Position]
 
 	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lines = c.scopes[c.scopeIndex].lines.Truncate(last.Position)
 	c.scopes[c.scopeIndex].lastInstruction = previous
 }
 
@@ -291,20 +572,38 @@ func (c *Compiler) addConstant(obj object.Object) int {
 }
 
 func (c *Compiler) Bytecode() *Bytecode {
+	globals := c.symbolTable
+	for globals.Outer != nil {
+		globals = globals.Outer
+	}
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
+		Lines:        c.scopes[c.scopeIndex].lines,
 		Constants:    c.constants,
+		NumGlobals:   globals.numDefinitions,
+		GlobalNames:  globals.GlobalNames(),
 	}
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
-	Constants    []object.Object
+	// Lines maps Instructions to source lines. Functions in Constants
+	// carry their own table.
+	Lines     code.LineTable
+	Constants []object.Object
+	// NumGlobals is the number of global slots the program uses. Every
+	// OpGetGlobal and OpSetGlobal operand is below it, so the VM checks
+	// the size of its globals once instead of on each access.
+	NumGlobals int
+	// GlobalNames are the names of the global slots, by index, for the
+	// errors of the VM.
+	GlobalNames []string
 }
 
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
+	c.scopes[c.scopeIndex].lines = c.scopes[c.scopeIndex].lines.Add(pos, c.line)
 
 	c.setLastInstruction(op, pos)
 
@@ -328,6 +627,16 @@ func (c *Compiler) addInstruction(ins []byte) int {
 	return posNewInstruction
 }
 
+// setLine makes node's line the current one and returns a function that
+// restores the previous line, for use with defer.
+func (c *Compiler) setLine(node ast.Node) func() {
+	previous := c.line
+	if line := node.Pos().Line; line > 0 {
+		c.line = line
+	}
+	return func() { c.line = previous }
+}
+
 func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
@@ -340,6 +649,8 @@ func (c *Compiler) enterScope() {
 	}
 	c.scopes = append(c.scopes, scope)
 	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
 }
 
 func (c *Compiler) leaveScope() code.Instructions {
@@ -348,5 +659,7 @@ func (c *Compiler) leaveScope() code.Instructions {
 	c.scopes = c.scopes[:len(c.scopes)-1]
 	c.scopeIndex--
 
+	c.symbolTable = c.symbolTable.Outer
+
 	return instructions
 }
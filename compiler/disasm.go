@@ -0,0 +1,59 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"monkey/code"
+	"monkey/evaluator"
+	"monkey/object"
+	"strconv"
+)
+
+// Disassemble writes a listing of b to w: the main program followed by
+// each compiled function in the constant pool. Every instruction shows
+// its offset, source line and operands, and the value of the constant
+// or the name of the builtin it refers to.
+func (b *Bytecode) Disassemble(w io.Writer) error {
+	builtins := evaluator.BuiltinNames()
+	annotate := func(op code.Opcode, operands []int) string {
+		switch op {
+		case code.OpConstant, code.OpClosure, code.OpAddConstant, code.OpSubConstant, code.OpMember:
+			if operands[0] < len(b.Constants) {
+				return describeConstant(operands[0], b.Constants[operands[0]])
+			}
+		case code.OpGetBuiltin:
+			if operands[0] < len(builtins) {
+				return builtins[operands[0]]
+			}
+		}
+		return ""
+	}
+
+	fmt.Fprintln(w, "== main ==")
+	if err := code.Disassemble(w, b.Instructions, b.Lines, annotate); err != nil {
+		return err
+	}
+	for i, c := range b.Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "\n== %s: %d parameters, %d locals ==\n",
+			describeConstant(i, fn), fn.NumParameters, fn.NumLocals)
+		if err := code.Disassemble(w, fn.Instructions, fn.Lines, annotate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeConstant(index int, c object.Object) string {
+	switch c := c.(type) {
+	case *object.String:
+		return strconv.Quote(c.Value)
+	case *object.CompiledFunction:
+		return fmt.Sprintf("fn#%d", index)
+	default:
+		return c.Inspect()
+	}
+}
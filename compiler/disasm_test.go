@@ -0,0 +1,44 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	input := `let greet = fn(name) {
+  "hi " + name
+};
+len(greet("x"))`
+
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	symbol, _ := comp.symbolTable.Resolve("len")
+
+	var out bytes.Buffer
+	if err := comp.Bytecode().Disassemble(&out); err != nil {
+		t.Fatalf("Disassemble failed: %s", err)
+	}
+	expected := `== main ==
+0000    1 OpClosure 1 0        ; fn#1
+0004    | OpSetGlobal 0
+0007    4 ` + fmt.Sprintf("%-20s", fmt.Sprintf("OpGetBuiltin %d", symbol.Index)) + ` ; len
+0009    | OpGetGlobal 0
+0012    | OpConstant 2         ; "x"
+0015    | OpCall 1
+0017    | OpCall 1
+0019    | OpPop
+
+== fn#1: 1 parameters, 1 locals ==
+0000    2 OpConstant 0         ; "hi "
+0003    | OpGetLocal 0
+0005    | OpAdd
+0006    | OpReturnValue
+`
+	if out.String() != expected {
+		t.Errorf("wrong listing.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"monkey/code"
+	"monkey/object"
+)
+
+// Fuse returns a copy of b where common sequences of instructions are
+// replaced by a single superinstruction, so the VM dispatches fewer
+// opcodes on arithmetic:
+//
+//	OpConstant c, OpAdd              -> OpAddConstant c
+//	OpConstant c, OpSub              -> OpSubConstant c
+//	OpGetLocal a, OpGetLocal b, OpAdd -> OpAddLocals a b
+//
+// Sequences where a jump lands after the first instruction are left
+// alone. It is meant to run after Optimize, which folds constants first.
+// b is not modified.
+func (b *Bytecode) Fuse() *Bytecode {
+	constants := make([]object.Object, len(b.Constants))
+	for i, c := range b.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fused := *fn
+			fused.Instructions, fused.Lines = fuse(fn.Instructions, fn.Lines)
+			c = &fused
+		}
+		constants[i] = c
+	}
+	instructions, lines := fuse(b.Instructions, b.Lines)
+
+	return &Bytecode{
+		Instructions: instructions,
+		Lines:        lines,
+		Constants:    constants,
+		NumGlobals:   b.NumGlobals,
+		GlobalNames:  b.GlobalNames,
+	}
+}
+
+func fuse(ins code.Instructions, lines code.LineTable) (code.Instructions, code.LineTable) {
+	list, ok := decode(ins, lines)
+	if !ok {
+		return ins, lines
+	}
+	targets := jumpTargets(list)
+
+	for i := 0; i < len(list); i++ {
+		in := list[i]
+		switch {
+		case in.op == code.OpGetLocal && i+2 < len(list) &&
+			list[i+1].op == code.OpGetLocal && list[i+2].op == code.OpAdd &&
+			!targets[i+1] && !targets[i+2]:
+			in.op = code.OpAddLocals
+			in.operands = []int{in.operands[0], list[i+1].operands[0]}
+			list[i+1].removed, list[i+2].removed = true, true
+			i += 2
+
+		case in.op == code.OpConstant && i+1 < len(list) && !targets[i+1] &&
+			(list[i+1].op == code.OpAdd || list[i+1].op == code.OpSub):
+			in.op = code.OpAddConstant
+			if list[i+1].op == code.OpSub {
+				in.op = code.OpSubConstant
+			}
+			list[i+1].removed = true
+			i++
+		}
+	}
+	return encode(compact(list))
+}
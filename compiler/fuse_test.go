@@ -0,0 +1,90 @@
+package compiler
+
+import (
+	"monkey/code"
+	"testing"
+)
+
+func TestFuse(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let x = 1; x + 2; x - 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpAddConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSubConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a, b) { a + b - 1 }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpAddLocals, 0, 1),
+					code.Make(code.OpSubConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// The jump of the if lands on the OpAdd, so it stays.
+			input:             "let x = 1; x + if (true) { 2 } else { 3 }; 1 * 4",
+			expectedConstants: []interface{}{1, 2, 3, 1, 4},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpTrue),
+				// 0010
+				code.Make(code.OpJumpNotTruthy, 19),
+				// 0013
+				code.Make(code.OpConstant, 1),
+				// 0016
+				code.Make(code.OpJump, 22),
+				// 0019
+				code.Make(code.OpConstant, 2),
+				// 0022
+				code.Make(code.OpAdd),
+				// 0023
+				code.Make(code.OpPop),
+				// 0024
+				code.Make(code.OpConstant, 3),
+				// 0027
+				code.Make(code.OpConstant, 4),
+				// 0030
+				code.Make(code.OpMul),
+				// 0031
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := compiler.Bytecode().Fuse()
+
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Errorf("%q: testInstructions failed: %s", tt.input, err)
+		}
+		if err := testConstants(t, tt.expectedConstants, bytecode.Constants); err != nil {
+			t.Errorf("%q: testConstants failed: %s", tt.input, err)
+		}
+	}
+}
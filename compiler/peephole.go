@@ -0,0 +1,259 @@
+package compiler
+
+import (
+	"math"
+	"monkey/code"
+	"monkey/object"
+)
+
+// Optimize returns a copy of b simplified by a peephole pass over the
+// instructions of the program and of each compiled function:
+//
+//   - a value pushed with no side effects and popped right away is
+//     removed, e.g. the `1` in `1; 2`;
+//   - OpConstant, OpConstant, OpAdd (or OpSub, OpMul) on integers, and
+//     OpAdd on strings, becomes a single OpConstant with the result;
+//   - an OpJump to the next instruction is removed.
+//
+// The last OpPop of the program is kept, since it leaves the value shown
+// by the REPL. Integer operations that would overflow are not folded, as
+// in optimizer.FoldConstants. b is not modified.
+func (b *Bytecode) Optimize() *Bytecode {
+	constants := make([]object.Object, len(b.Constants))
+	copy(constants, b.Constants)
+	p := &peephole{constants: constants}
+
+	for i, c := range b.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			optimized := *fn
+			optimized.Instructions, optimized.Lines = p.optimize(fn.Instructions, fn.Lines, false)
+			p.constants[i] = &optimized
+		}
+	}
+	instructions, lines := p.optimize(b.Instructions, b.Lines, true)
+
+	return &Bytecode{
+		Instructions: instructions,
+		Lines:        lines,
+		Constants:    p.constants,
+		NumGlobals:   b.NumGlobals,
+		GlobalNames:  b.GlobalNames,
+	}
+}
+
+type peephole struct {
+	constants []object.Object
+}
+
+// instruction is a decoded instruction. The operand of a jump is the
+// index of its target in the instruction list, not an offset.
+type instruction struct {
+	op       code.Opcode
+	operands []int
+	line     int
+	removed  bool
+}
+
+func isJump(op code.Opcode) bool {
+	return op == code.OpJump || op == code.OpJumpNotTruthy
+}
+
+// pushesPureValue reports whether op only pushes a value.
+func pushesPureValue(op code.Opcode) bool {
+	switch op {
+	case code.OpConstant, code.OpTrue, code.OpFalse, code.OpNull,
+		code.OpGetGlobal, code.OpGetLocal, code.OpGetBuiltin,
+		code.OpGetFree, code.OpCurrentClosure:
+		return true
+	}
+	return false
+}
+
+func (p *peephole) optimize(ins code.Instructions, lines code.LineTable, isMain bool) (code.Instructions, code.LineTable) {
+	list, ok := decode(ins, lines)
+	if !ok {
+		return ins, lines
+	}
+	for p.pass(list, isMain) {
+		list = compact(list)
+	}
+	return encode(list)
+}
+
+// pass applies the rewrites once, marking the instructions to drop. It
+// reports whether anything changed.
+func (p *peephole) pass(list []*instruction, isMain bool) bool {
+	targets := jumpTargets(list)
+
+	changed := false
+	for i := 0; i < len(list); i++ {
+		in := list[i]
+		switch {
+		case pushesPureValue(in.op) && i+1 < len(list) &&
+			list[i+1].op == code.OpPop && !targets[i+1] &&
+			!(isMain && i+1 == len(list)-1):
+			in.removed, list[i+1].removed = true, true
+			i++
+			changed = true
+
+		case in.op == code.OpConstant && i+2 < len(list) &&
+			list[i+1].op == code.OpConstant && !targets[i+1] && !targets[i+2]:
+			result := p.fold(in.operands[0], list[i+1].operands[0], list[i+2].op)
+			if result == nil {
+				continue
+			}
+			in.operands = []int{p.addConstant(result)}
+			list[i+1].removed, list[i+2].removed = true, true
+			i += 2
+			changed = true
+
+		case in.op == code.OpJump && in.operands[0] == i+1:
+			in.removed = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// jumpTargets returns the indexes of the instructions some jump goes to.
+func jumpTargets(list []*instruction) map[int]bool {
+	targets := map[int]bool{}
+	for _, in := range list {
+		if isJump(in.op) {
+			targets[in.operands[0]] = true
+		}
+	}
+	return targets
+}
+
+// fold returns the result of applying op to the constants left and
+// right, or nil if it can't be computed at compile time.
+func (p *peephole) fold(left, right int, op code.Opcode) object.Object {
+	switch l := p.constants[left].(type) {
+	case *object.Integer:
+		r, ok := p.constants[right].(*object.Integer)
+		if !ok {
+			return nil
+		}
+		a, b := l.Value, r.Value
+		var result int64
+		switch op {
+		case code.OpAdd:
+			result = a + b
+			if (b > 0 && result < a) || (b < 0 && result > a) {
+				return nil
+			}
+		case code.OpSub:
+			result = a - b
+			if (b > 0 && result > a) || (b < 0 && result < a) {
+				return nil
+			}
+		case code.OpMul:
+			result = a * b
+			if a != 0 && (result/a != b || (a == -1 && b == math.MinInt64)) {
+				return nil
+			}
+		default:
+			return nil
+		}
+		return object.NewInteger(result)
+	case *object.String:
+		r, ok := p.constants[right].(*object.String)
+		if !ok || op != code.OpAdd {
+			return nil
+		}
+		return &object.String{Value: l.Value + r.Value}
+	}
+	return nil
+}
+
+func (p *peephole) addConstant(obj object.Object) int {
+	p.constants = append(p.constants, obj)
+	return len(p.constants) - 1
+}
+
+// decode splits ins into instructions and turns jump offsets into
+// indexes. It fails if ins is malformed or a jump lands in the middle of
+// an instruction.
+func decode(ins code.Instructions, lines code.LineTable) ([]*instruction, bool) {
+	var list []*instruction
+	index := map[int]int{}
+	for offset := 0; offset < len(ins); {
+		def, err := code.Lookup(ins[offset])
+		if err != nil {
+			return nil, false
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if offset+1+width > len(ins) {
+			return nil, false
+		}
+		operands, read := code.ReadOperands(def, ins[offset+1:])
+		index[offset] = len(list)
+		list = append(list, &instruction{
+			op:       code.Opcode(ins[offset]),
+			operands: operands,
+			line:     lines.Line(offset),
+		})
+		offset += 1 + read
+	}
+	index[len(ins)] = len(list)
+
+	for _, in := range list {
+		if isJump(in.op) {
+			target, ok := index[in.operands[0]]
+			if !ok {
+				return nil, false
+			}
+			in.operands[0] = target
+		}
+	}
+	return list, true
+}
+
+// compact drops the removed instructions. A jump to a removed one now
+// lands on the next instruction that is kept.
+func compact(list []*instruction) []*instruction {
+	newIndex := make([]int, len(list)+1)
+	n := 0
+	for i, in := range list {
+		newIndex[i] = n
+		if !in.removed {
+			n++
+		}
+	}
+	newIndex[len(list)] = n
+
+	kept := make([]*instruction, 0, n)
+	for _, in := range list {
+		if in.removed {
+			continue
+		}
+		if isJump(in.op) {
+			in.operands[0] = newIndex[in.operands[0]]
+		}
+		kept = append(kept, in)
+	}
+	return kept
+}
+
+func encode(list []*instruction) (code.Instructions, code.LineTable) {
+	offsets := make([]int, len(list)+1)
+	for i, in := range list {
+		offsets[i+1] = offsets[i] + len(code.Make(in.op, in.operands...))
+	}
+
+	ins := code.Instructions{}
+	var lines code.LineTable
+	for i, in := range list {
+		operands := in.operands
+		if isJump(in.op) {
+			operands = []int{offsets[in.operands[0]]}
+		}
+		lines = lines.Add(offsets[i], in.line)
+		ins = append(ins, code.Make(in.op, operands...)...)
+	}
+	return ins, lines
+}
@@ -0,0 +1,124 @@
+package compiler
+
+import (
+	"monkey/code"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1; 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 + 2 * 3",
+			expectedConstants: []interface{}{1, 2, 3, 6, 7},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"a" + "b" + "c"`,
+			expectedConstants: []interface{}{"a", "b", "c", "ab", "abc"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// Neither divisions nor overflowing operations are folded.
+			input:             "6 / 2; 9223372036854775807 + 1",
+			expectedConstants: []interface{}{6, 2, 9223372036854775807, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { 1 + 1 }; 3",
+			expectedConstants: []interface{}{1, 1, 3, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 3),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 2),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a) { a; 2 * 5 }",
+			expectedConstants: []interface{}{
+				2,
+				5,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpReturnValue),
+				},
+				10,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		comp := New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		original := comp.Bytecode()
+		before := string(original.Instructions)
+
+		bytecode := original.Optimize()
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Errorf("%q: testInstructions failed: %s", tt.input, err)
+		}
+		if err := testConstants(t, tt.expectedConstants, bytecode.Constants); err != nil {
+			t.Errorf("%q: testConstants failed: %s", tt.input, err)
+		}
+		if string(original.Instructions) != before {
+			t.Errorf("%q: Optimize modified the original bytecode", tt.input)
+		}
+	}
+}
+
+func TestOptimizeRemovesJumpToNext(t *testing.T) {
+	ins := concatInstructions([]code.Instructions{
+		code.Make(code.OpJump, 3),
+		code.Make(code.OpTrue),
+		code.Make(code.OpPop),
+	})
+	bytecode := (&Bytecode{Instructions: ins}).Optimize()
+	expected := []code.Instructions{
+		code.Make(code.OpTrue),
+		code.Make(code.OpPop),
+	}
+	if err := testInstructions(expected, bytecode.Instructions); err != nil {
+		t.Errorf("testInstructions failed: %s", err)
+	}
+}
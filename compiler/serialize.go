@@ -0,0 +1,503 @@
+package compiler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/code"
+	"monkey/evaluator"
+	"monkey/object"
+	"os"
+)
+
+// A .monkeyc file holds a compiled program:
+//
+//	magic    "MONKEYC" followed by the format version byte
+//	builtins count, then each name the program may refer to
+//	main     the instructions of the program, their line table and the
+//	         names of the global variables
+//	consts   count, then each constant as a tag byte and its value
+//
+// A compiled function is stored as its number of locals and parameters,
+// the name of each parameter, its name, the name of each local, its
+// instructions and their line table.
+//
+// Counts, lengths and integers are varints; strings and instructions are
+// a length followed by their bytes. A line table is its number of
+// entries followed by the offset and line of each one. OpGetBuiltin operands index the
+// builtins table of the file, so Load remaps them to the builtins of
+// the running binary.
+const (
+	bytecodeMagic   = "MONKEYC"
+	bytecodeVersion = 9
+)
+
+// Constant tags.
+const (
+	tagInteger byte = iota + 1
+	tagString
+	tagCompiledFunction
+)
+
+// Save writes b to w in the .monkeyc format.
+func (b *Bytecode) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw}
+
+	e.bytes([]byte(bytecodeMagic))
+	e.byte(bytecodeVersion)
+
+	names := evaluator.BuiltinNames()
+	e.uvarint(uint64(len(names)))
+	for _, name := range names {
+		e.string(name)
+	}
+
+	e.instructions(b.Instructions)
+	e.lines(b.Lines)
+	e.uvarint(uint64(b.NumGlobals))
+	for i := 0; i < b.NumGlobals; i++ {
+		name := ""
+		if i < len(b.GlobalNames) {
+			name = b.GlobalNames[i]
+		}
+		e.string(name)
+	}
+
+	e.uvarint(uint64(len(b.Constants)))
+	for _, c := range b.Constants {
+		switch c := c.(type) {
+		case *object.Integer:
+			e.byte(tagInteger)
+			e.varint(c.Value)
+		case *object.String:
+			e.byte(tagString)
+			e.string(c.Value)
+		case *object.CompiledFunction:
+			e.byte(tagCompiledFunction)
+			e.uvarint(uint64(c.NumLocals))
+			e.uvarint(uint64(c.NumParameters))
+			for _, name := range c.Parameters {
+				e.string(name)
+			}
+			e.string(c.Name)
+			for i := 0; i < c.NumLocals; i++ {
+				name := ""
+				if i < len(c.Locals) {
+					name = c.Locals[i]
+				}
+				e.string(name)
+			}
+			e.instructions(c.Instructions)
+			e.lines(c.Lines)
+		default:
+			return fmt.Errorf("cannot serialize constant of type %s", c.Type())
+		}
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// SaveFile writes b to the file path, replacing it if it exists.
+func (b *Bytecode) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads a program written by Save.
+func Load(r io.Reader) (*Bytecode, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := d.bytes(len(bytecodeMagic))
+	if d.err == nil && string(magic) != bytecodeMagic {
+		return nil, errors.New("not a monkey bytecode file")
+	}
+	if version := d.byte(); d.err == nil && version != bytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d", version)
+	}
+
+	// builtins maps the builtin indexes of the file to the current ones.
+	current := map[string]int{}
+	for i, name := range evaluator.BuiltinNames() {
+		current[name] = i
+	}
+	builtins := make([]int, d.count())
+	for i := range builtins {
+		name := d.string()
+		index, ok := current[name]
+		if !ok && d.err == nil {
+			return nil, fmt.Errorf("bytecode uses unknown builtin %q", name)
+		}
+		builtins[i] = index
+	}
+
+	bytecode := &Bytecode{Instructions: d.instructions(), Lines: d.lines()}
+	bytecode.NumGlobals = d.count()
+	for i := 0; i < bytecode.NumGlobals && d.err == nil; i++ {
+		bytecode.GlobalNames = append(bytecode.GlobalNames, d.string())
+	}
+
+	bytecode.Constants = make([]object.Object, d.count())
+	for i := range bytecode.Constants {
+		switch tag := d.byte(); tag {
+		case tagInteger:
+			bytecode.Constants[i] = object.NewInteger(d.varint())
+		case tagString:
+			bytecode.Constants[i] = &object.String{Value: d.string()}
+		case tagCompiledFunction:
+			fn := &object.CompiledFunction{}
+			fn.NumLocals = d.count()
+			fn.NumParameters = d.count()
+			for j := 0; j < fn.NumParameters && d.err == nil; j++ {
+				fn.Parameters = append(fn.Parameters, d.string())
+			}
+			fn.Name = d.string()
+			for j := 0; j < fn.NumLocals && d.err == nil; j++ {
+				fn.Locals = append(fn.Locals, d.string())
+			}
+			fn.Instructions = d.instructions()
+			fn.Lines = d.lines()
+			bytecode.Constants[i] = fn
+		default:
+			if d.err == nil {
+				return nil, fmt.Errorf("invalid constant tag %d", tag)
+			}
+		}
+		if d.err != nil {
+			break
+		}
+	}
+
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if err := linkProgram(bytecode, builtins); err != nil {
+		return nil, err
+	}
+	return bytecode, nil
+}
+
+// LoadFile reads the program saved in the file path.
+func LoadFile(path string) (*Bytecode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// linkProgram links the instructions of b and of its functions (see
+// link), and checks that each function only reads the free variables
+// that every OpClosure creating it provides.
+func linkProgram(b *Bytecode, builtins []int) error {
+	free := map[*object.CompiledFunction]int{}
+	main := &object.CompiledFunction{Instructions: b.Instructions}
+	maxFree := map[*object.CompiledFunction]int{}
+	for _, fn := range append([]object.Object{main}, b.Constants...) {
+		fn, ok := fn.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		if fn.NumParameters > fn.NumLocals {
+			return fmt.Errorf("function %q has %d parameters but %d locals", fn.Name, fn.NumParameters, fn.NumLocals)
+		}
+		n, err := link(fn.Instructions, b, builtins, fn.NumLocals, free)
+		if err != nil {
+			return err
+		}
+		maxFree[fn] = n
+	}
+	for fn, n := range maxFree {
+		if n > 0 && n > free[fn] {
+			return fmt.Errorf("function %q uses free variable %d but has %d", fn.Name, n-1, free[fn])
+		}
+	}
+	return nil
+}
+
+// link rewrites the OpGetBuiltin operands of ins in place using builtins,
+// which maps old indexes to new ones. It also checks that ins is a
+// well-formed sequence of instructions whose operands are valid for b,
+// since the VM trusts them: constant indexes below len(b.Constants),
+// OpClosure on a function and OpMember on a string, global indexes below
+// b.NumGlobals, local indexes below numLocals, jumps to the start of an
+// instruction and enough values on the stack (see checkStack). It records in free the lowest number of free
+// variables each function is created with, and returns how many free
+// variables ins reads.
+func link(ins code.Instructions, b *Bytecode, builtins []int, numLocals int, free map[*object.CompiledFunction]int) (int, error) {
+	starts := map[int]bool{len(ins): true}
+	var jumps []int
+	numFree := 0
+	for i := 0; i < len(ins); {
+		starts[i] = true
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return 0, err
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return 0, fmt.Errorf("truncated instruction %s at %d", def.Name, i)
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		switch code.Opcode(ins[i]) {
+		case code.OpGetBuiltin:
+			if operands[0] >= len(builtins) {
+				return 0, fmt.Errorf("invalid builtin index %d at %d", operands[0], i)
+			}
+			copy(ins[i:], code.Make(code.OpGetBuiltin, builtins[operands[0]]))
+		case code.OpGetGlobal, code.OpSetGlobal:
+			if operands[0] >= b.NumGlobals {
+				return 0, fmt.Errorf("invalid global index %d at %d", operands[0], i)
+			}
+		case code.OpConstant, code.OpAddConstant, code.OpSubConstant:
+			if operands[0] >= len(b.Constants) {
+				return 0, fmt.Errorf("invalid constant index %d at %d", operands[0], i)
+			}
+		case code.OpMember:
+			if operands[0] >= len(b.Constants) {
+				return 0, fmt.Errorf("invalid constant index %d at %d", operands[0], i)
+			}
+			if _, ok := b.Constants[operands[0]].(*object.String); !ok {
+				return 0, fmt.Errorf("constant %d is not a string at %d", operands[0], i)
+			}
+		case code.OpClosure:
+			if operands[0] >= len(b.Constants) {
+				return 0, fmt.Errorf("invalid constant index %d at %d", operands[0], i)
+			}
+			fn, ok := b.Constants[operands[0]].(*object.CompiledFunction)
+			if !ok {
+				return 0, fmt.Errorf("constant %d is not a function at %d", operands[0], i)
+			}
+			if n, seen := free[fn]; !seen || operands[1] < n {
+				free[fn] = operands[1]
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpAddLocals,
+			code.OpMakeCell, code.OpGetLocalCell, code.OpSetLocalCell:
+			for _, local := range operands {
+				if local >= numLocals {
+					return 0, fmt.Errorf("invalid local index %d at %d", local, i)
+				}
+			}
+		case code.OpGetFree, code.OpGetFreeCell:
+			if operands[0]+1 > numFree {
+				numFree = operands[0] + 1
+			}
+		case code.OpJump, code.OpJumpNotTruthy:
+			jumps = append(jumps, i)
+		}
+		i += 1 + read
+	}
+	for _, i := range jumps {
+		if target := int(code.ReadUint16(ins[i+1:])); !starts[target] {
+			return 0, fmt.Errorf("invalid jump target %d at %d", target, i)
+		}
+	}
+	if err := checkStack(ins); err != nil {
+		return 0, err
+	}
+	return numFree, nil
+}
+
+// checkStack checks that no instruction of ins, which link already found
+// well-formed, takes more values than the stack holds on every path that
+// reaches it. The VM doesn't check it and would index below the stack.
+func checkStack(ins code.Instructions) error {
+	// depth holds, for each instruction reached, the fewest values the
+	// stack has there over the paths seen so far.
+	depth := map[int]int{0: 0}
+	work := []int{0}
+	reach := func(target, d int) {
+		if seen, ok := depth[target]; !ok || d < seen {
+			depth[target] = d
+			work = append(work, target)
+		}
+	}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i == len(ins) {
+			continue
+		}
+		d := depth[i]
+		op := code.Opcode(ins[i])
+		def, _ := code.Lookup(ins[i])
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		pop, push := 0, 1
+		switch op {
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpPow,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan, code.OpIndex:
+			pop = 2
+		case code.OpMinus, code.OpBang, code.OpAddConstant, code.OpSubConstant, code.OpMember:
+			pop = 1
+		case code.OpPop, code.OpSetGlobal, code.OpSetLocal, code.OpSetLocalCell,
+			code.OpJumpNotTruthy, code.OpReturnValue:
+			pop, push = 1, 0
+		case code.OpJump, code.OpReturn, code.OpMakeCell:
+			push = 0
+		case code.OpArray, code.OpHash:
+			pop = operands[0]
+		case code.OpClosure:
+			pop = operands[1]
+		case code.OpCall, code.OpTailCall:
+			pop = operands[0] + 1
+		case code.OpCallKeywords:
+			pop = operands[0] + 2*operands[1] + 1
+		case code.OpYield:
+			return fmt.Errorf("unexpected OpYield at %d", i)
+		}
+		if pop > d {
+			return fmt.Errorf("%s at %d takes %d values but the stack has %d", def.Name, i, pop, d)
+		}
+		d += push - pop
+		switch op {
+		case code.OpJump:
+			reach(int(code.ReadUint16(ins[i+1:])), d)
+		case code.OpJumpNotTruthy:
+			reach(int(code.ReadUint16(ins[i+1:])), d)
+			reach(i+1+read, d)
+		case code.OpReturnValue, code.OpReturn:
+		default:
+			reach(i+1+read, d)
+		}
+	}
+	return nil
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) byte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+func (e *encoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.buf[:], x)
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) varint(x int64) {
+	n := binary.PutVarint(e.buf[:], x)
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) instructions(ins code.Instructions) {
+	e.uvarint(uint64(len(ins)))
+	e.bytes(ins)
+}
+
+func (e *encoder) lines(lt code.LineTable) {
+	e.uvarint(uint64(len(lt)))
+	for _, entry := range lt {
+		e.uvarint(uint64(entry.Offset))
+		e.uvarint(uint64(entry.Line))
+	}
+}
+
+// decoder reads the values written by encoder. After the first error
+// every method returns a zero value and err keeps that error.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+// Limit for counts and lengths, so a corrupt file can't make Load
+// allocate huge slices.
+const maxCount = 1 << 24
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	var b byte
+	b, d.err = d.r.ReadByte()
+	return b
+}
+
+func (d *decoder) count() int {
+	if d.err != nil {
+		return 0
+	}
+	var x uint64
+	x, d.err = binary.ReadUvarint(d.r)
+	if d.err == nil && x > maxCount {
+		d.err = fmt.Errorf("invalid length %d", x)
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(x)
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	var x int64
+	x, d.err = binary.ReadVarint(d.r)
+	return x
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.count()))
+}
+
+func (d *decoder) instructions() code.Instructions {
+	return code.Instructions(d.bytes(d.count()))
+}
+
+func (d *decoder) lines() code.LineTable {
+	n := d.count()
+	if n == 0 {
+		return nil
+	}
+	lt := make(code.LineTable, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		lt = append(lt, code.LineEntry{Offset: d.count(), Line: d.count()})
+	}
+	return lt
+}
@@ -0,0 +1,242 @@
+package compiler
+
+import (
+	"bytes"
+	"io"
+	"monkey/code"
+	"monkey/object"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	input := `let greet = fn(name) { "hello " + name }; let n = -42; len(greet("x")) + n`
+
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := comp.Bytecode()
+
+	var buf bytes.Buffer
+	if err := original.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	if err := testInstructions([]code.Instructions{original.Instructions}, loaded.Instructions); err != nil {
+		t.Errorf("instructions differ: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Lines, original.Lines) {
+		t.Errorf("line tables differ. got=%v, want=%v", loaded.Lines, original.Lines)
+	}
+	if loaded.NumGlobals != 2 || !reflect.DeepEqual(loaded.GlobalNames, []string{"greet", "n"}) {
+		t.Errorf("wrong globals. got=%d %v, want=2 [greet n]", loaded.NumGlobals, loaded.GlobalNames)
+	}
+	if len(loaded.Constants) != len(original.Constants) {
+		t.Fatalf("wrong number of constants. got=%d, want=%d",
+			len(loaded.Constants), len(original.Constants))
+	}
+	for i, c := range original.Constants {
+		got := loaded.Constants[i]
+		switch c := c.(type) {
+		case *object.CompiledFunction:
+			fn, ok := got.(*object.CompiledFunction)
+			if !ok {
+				t.Errorf("constant %d - not a function: %T", i, got)
+				continue
+			}
+			if fn.NumLocals != c.NumLocals || fn.NumParameters != c.NumParameters ||
+				fn.Name != c.Name || !reflect.DeepEqual(fn.Parameters, c.Parameters) ||
+				!reflect.DeepEqual(fn.Lines, c.Lines) {
+				t.Errorf("constant %d - wrong function. got=%+v, want=%+v", i, fn, c)
+			}
+			if err := testInstructions([]code.Instructions{c.Instructions}, fn.Instructions); err != nil {
+				t.Errorf("constant %d - %s", i, err)
+			}
+		default:
+			if got.Type() != c.Type() || got.Inspect() != c.Inspect() {
+				t.Errorf("constant %d - got=%s, want=%s", i, got.Inspect(), c.Inspect())
+			}
+		}
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	comp := New()
+	if err := comp.Compile(parse(`"x"`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "program.monkeyc")
+	if err := comp.Bytecode().SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %s", err)
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %s", err)
+	}
+	if len(loaded.Constants) != 1 || loaded.Constants[0].Inspect() != "x" {
+		t.Errorf("wrong constants: %v", loaded.Constants)
+	}
+}
+
+func TestLoadRemapsBuiltins(t *testing.T) {
+	// A file whose builtins table only has "len", so its index is 0.
+	var buf bytes.Buffer
+	buf.WriteString(bytecodeMagic)
+	buf.WriteByte(bytecodeVersion)
+	buf.Write([]byte{1, 3})
+	buf.WriteString("len")
+	ins := code.Make(code.OpGetBuiltin, 0)
+	buf.WriteByte(byte(len(ins)))
+	buf.Write(ins)
+	buf.Write([]byte{0, 0, 0})
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+	symbol, ok := New().symbolTable.Resolve("len")
+	if !ok {
+		t.Fatalf("len is not defined")
+	}
+	expected := []code.Instructions{code.Make(code.OpGetBuiltin, symbol.Index)}
+	if err := testInstructions(expected, loaded.Instructions); err != nil {
+		t.Errorf("builtin not remapped: %s", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	var valid bytes.Buffer
+	if err := (&Bytecode{Instructions: code.Make(code.OpTrue)}).Save(&valid); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", io.ErrUnexpectedEOF.Error()},
+		{"#!/bin/monkey", "not a monkey bytecode file"},
+		{bytecodeMagic + "\x0a", "unsupported bytecode version 10"},
+		{valid.String()[:valid.Len()-1], io.ErrUnexpectedEOF.Error()},
+		{bytecodeMagic + "\x09\x01\x03foo", `bytecode uses unknown builtin "foo"`},
+		{bytecodeMagic + "\x09\x00\x01\xff\x00\x00\x00", "opcode 255 undefined"},
+		{bytecodeMagic + "\x09\x00\x02\x00\x01\x00\x00\x00", "truncated instruction OpConstant at 0"},
+		{bytecodeMagic + "\x09\x00\x00\x00\x00\x01\x07", "invalid constant tag 7"},
+		{bytecodeMagic + "\x09\x00\x03" + string(code.Make(code.OpGetGlobal, 0)) + "\x00\x00\x00", "invalid global index 0 at 0"},
+	}
+	for _, tt := range tests {
+		_, err := Load(strings.NewReader(tt.input))
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// Load rejects operands the VM would use to index past its constants,
+// locals or free variables, or to jump into the middle of an instruction.
+func TestLoadInvalidOperands(t *testing.T) {
+	concat := func(ins ...code.Instructions) code.Instructions {
+		var out code.Instructions
+		for _, i := range ins {
+			out = append(out, i...)
+		}
+		return out
+	}
+	fn := func(numLocals int, ins ...code.Instructions) *object.CompiledFunction {
+		return &object.CompiledFunction{Instructions: concat(ins...), NumLocals: numLocals}
+	}
+	one := object.NewInteger(1)
+	tests := []struct {
+		bytecode *Bytecode
+		expected string
+	}{
+		{
+			&Bytecode{Instructions: code.Make(code.OpConstant, 7), Constants: []object.Object{one}},
+			"invalid constant index 7 at 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpAddConstant, 1), Constants: []object.Object{one}},
+			"invalid constant index 1 at 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpClosure, 0, 0), Constants: []object.Object{one}},
+			"constant 0 is not a function at 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpMember, 0), Constants: []object.Object{one}},
+			"constant 0 is not a string at 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpGetLocal, 0)},
+			"invalid local index 0 at 0",
+		},
+		{
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{fn(1, code.Make(code.OpAddLocals, 0, 1))},
+			},
+			"invalid local index 1 at 0",
+		},
+		{
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{fn(0, code.Make(code.OpGetFree, 0), code.Make(code.OpReturnValue))},
+			},
+			`function "" uses free variable 0 but has 0`,
+		},
+		{
+			&Bytecode{Instructions: concat(code.Make(code.OpTrue), code.Make(code.OpJump, 2))},
+			"invalid jump target 2 at 1",
+		},
+		{
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{&object.CompiledFunction{NumParameters: 2, Parameters: []string{"a", "b"}}},
+			},
+			`function "" has 2 parameters but 0 locals`,
+		},
+		{
+			&Bytecode{Instructions: concat(code.Make(code.OpNull), code.Make(code.OpCall, 3))},
+			"OpCall at 1 takes 4 values but the stack has 1",
+		},
+		{
+			// Only one of the paths to OpAdd leaves two values.
+			&Bytecode{Instructions: concat(
+				code.Make(code.OpTrue),
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 6),
+				code.Make(code.OpTrue),
+				code.Make(code.OpAdd),
+			)},
+			"OpAdd at 6 takes 2 values but the stack has 1",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpYield)},
+			"unexpected OpYield at 0",
+		},
+	}
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.bytecode.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(&buf)
+		if err == nil {
+			t.Errorf("%d: expected an error", i)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%d: wrong error. want=%q, got=%q", i, tt.expected, err.Error())
+		}
+	}
+}
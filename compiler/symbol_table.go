@@ -1,35 +1,198 @@
 package compiler
 
+import (
+	"monkey/object"
+	"sort"
+)
+
 type SymbolScope string
 
 const (
-	GlobalScope SymbolScope = "GLOBAL"
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
 )
 
 type Symbol struct {
 	Name  string
 	Scope SymbolScope
 	Index int
+	// Cell is set on a local, or a free variable that captures one, kept
+	// in a cell that the closures capturing it share (see capture.go).
+	Cell bool
 }
 
 type SymbolTable struct {
+	Outer *SymbolTable
+
 	store          map[string]Symbol
 	numDefinitions int
+
+	// FreeSymbols are the symbols of enclosing functions used here, in
+	// the order of their FreeScope indexes.
+	FreeSymbols []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
 	s := make(map[string]Symbol)
-	return &SymbolTable{store: s}
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
 }
 
+// Names returns the names defined in this table, not in the outer ones,
+// sorted.
+func (s *SymbolTable) Names() []string {
+	names := make([]string, 0, len(s.store))
+	for name := range s.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Copy returns a copy of s that can define new names without changing s.
+// The enclosing tables are shared.
+func (s *SymbolTable) Copy() *SymbolTable {
+	store := make(map[string]Symbol, len(s.store))
+	for name, symbol := range s.store {
+		store[name] = symbol
+	}
+	return &SymbolTable{
+		Outer:          s.Outer,
+		store:          store,
+		numDefinitions: s.numDefinitions,
+		FreeSymbols:    append([]Symbol{}, s.FreeSymbols...),
+	}
+}
+
+// DeleteUnset deletes the global names of s whose slot in globals has no
+// value, the ones of a program that stopped with an error before their
+// let ran. Using them afterwards is then a compile error, as it is for
+// the evaluator, instead of reading a nil global.
+func (s *SymbolTable) DeleteUnset(globals []object.Object) {
+	for name, symbol := range s.store {
+		if symbol.Scope == GlobalScope && (symbol.Index >= len(globals) || globals[symbol.Index] == nil) {
+			delete(s.store, name)
+		}
+	}
+}
+
+// Define binds name in this table. Defining a name again in the same
+// table reuses its slot, so `let x = 1; let x = 2;` overwrites x instead
+// of leaving the old value behind.
 func (s *SymbolTable) Define(name string) Symbol {
-	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: GlobalScope}
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineParameter binds name to the next local slot. A parameter that
+// repeats the name of an earlier one gets its own slot and hides it, as
+// in the evaluator.
+func (s *SymbolTable) DefineParameter(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: LocalScope}
 	s.store[name] = symbol
 	s.numDefinitions++
 	return symbol
 }
 
+// defineCell marks the local name of this table as kept in a cell.
+func (s *SymbolTable) defineCell(name string) Symbol {
+	symbol := s.store[name]
+	symbol.Cell = true
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineGlobal binds name in the outermost table, the one of the
+// globals, whatever table s is.
+func (s *SymbolTable) DefineGlobal(name string) Symbol {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s.Define(name)
+}
+
+// GlobalNames returns the names of the globals of s, indexed by slot.
+func (s *SymbolTable) GlobalNames() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == GlobalScope {
+			names[symbol.Index] = name
+		}
+	}
+	return names
+}
+
+// localNames returns the names of the locals of s, indexed by slot. The
+// slot of a parameter hidden by a later one with its name has no name.
+func (s *SymbolTable) localNames() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == LocalScope {
+			names[symbol.Index] = name
+		}
+	}
+	return names
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName binds name to the function whose body this table
+// belongs to, so the function can call itself.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Cell: original.Cell}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Resolve looks name up in this table and then in the enclosing ones. A
+// local of an enclosing function becomes a free variable of this one.
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
 	return obj, ok
 }
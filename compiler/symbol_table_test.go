@@ -1,6 +1,10 @@
 package compiler
 
-import "testing"
+import (
+	"monkey/object"
+	"reflect"
+	"testing"
+)
 
 func TestDefine(t *testing.T) {
 	expected := map[string]Symbol{
@@ -44,3 +48,110 @@ func TestResolveGlobal(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("b")
+	local.Define("c")
+	local.Define("b")
+
+	nested := NewEnclosedSymbolTable(local)
+	nested.Define("d")
+
+	tests := []struct {
+		table    *SymbolTable
+		expected Symbol
+	}{
+		{local, Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{local, Symbol{Name: "b", Scope: LocalScope, Index: 0}},
+		{local, Symbol{Name: "c", Scope: LocalScope, Index: 1}},
+		{nested, Symbol{Name: "len", Scope: BuiltinScope, Index: 0}},
+		{nested, Symbol{Name: "d", Scope: LocalScope, Index: 0}},
+	}
+	for _, tt := range tests {
+		result, ok := tt.table.Resolve(tt.expected.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.expected.Name)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v",
+				tt.expected.Name, tt.expected, result)
+		}
+	}
+
+	// A local of the enclosing function becomes a free variable.
+	free, ok := nested.Resolve("b")
+	expected := Symbol{Name: "b", Scope: FreeScope, Index: 0}
+	if !ok || free != expected {
+		t.Errorf("expected b to resolve to %+v, got=%+v", expected, free)
+	}
+	if len(nested.FreeSymbols) != 1 || nested.FreeSymbols[0] != (Symbol{Name: "b", Scope: LocalScope, Index: 0}) {
+		t.Errorf("wrong free symbols. got=%+v", nested.FreeSymbols)
+	}
+	if _, ok := nested.Resolve("e"); ok {
+		t.Errorf("e should not resolve")
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok || result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestNames(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("b")
+	global.Define("a")
+	local := NewEnclosedSymbolTable(global)
+	local.Define("c")
+
+	if got := global.Names(); !reflect.DeepEqual(got, []string{"a", "b", "len"}) {
+		t.Errorf("wrong global names. got=%v", got)
+	}
+	if got := local.Names(); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("wrong local names. got=%v", got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	copied := global.Copy()
+	copied.Define("b")
+	global.Define("c")
+
+	if got := global.Names(); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("wrong names in the original. got=%v", got)
+	}
+	if got := copied.Names(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("wrong names in the copy. got=%v", got)
+	}
+	if symbol, _ := copied.Resolve("b"); symbol.Index != 1 {
+		t.Errorf("wrong index for b. want=1, got=%d", symbol.Index)
+	}
+}
+
+func TestDeleteUnset(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("a")
+	global.Define("b")
+	global.Define("c")
+	global.DeleteUnset([]object.Object{&object.Integer{Value: 1}, nil})
+
+	if got := global.Names(); !reflect.DeepEqual(got, []string{"a", "len"}) {
+		t.Errorf("wrong names. got=%v", got)
+	}
+}
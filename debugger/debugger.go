@@ -0,0 +1,336 @@
+// Package debugger permite ejecutar un script paso a paso: detenerse en
+// puntos de ruptura por línea, avanzar con step/next/continue e
+// inspeccionar las variables y la pila de llamadas. Se apoya en los
+// Hooks del evaluador.
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PROMPT se muestra cada vez que el debugger espera un comando.
+const PROMPT = "(debug) "
+
+// ErrQuit lo retorna Run cuando el usuario abandona la ejecución con quit.
+var ErrQuit = errors.New("debugger: quit")
+
+// Frame es una llamada a función activa.
+type Frame struct {
+	Function string
+	Line     int
+}
+
+type mode int
+
+const (
+	modeStep     mode = iota // detenerse en la siguiente sentencia
+	modeNext                 // detenerse en la siguiente sentencia sin entrar en llamadas
+	modeContinue             // detenerse solo en los puntos de ruptura
+	modeQuit                 // no detenerse más
+)
+
+// Debugger implementa evaluator.Hooks. Los comandos se leen de in y la
+// salida se escribe en out. Si el script usa spawn o async, las demás
+// goroutines esperan mientras el debugger está detenido.
+type Debugger struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        mode
+	nextDepth   int
+	cancel      context.CancelFunc
+	lines       []string
+
+	// Línea y profundidad de la última sentencia, para que un punto de
+	// ruptura no se dispare dos veces en la misma línea.
+	line  int
+	depth int
+
+	calls []Frame // llamadas en curso (CallExpression) aún sin resolver
+	stack []Frame // llamadas a funciones Monkey activas
+
+	// printing vale 1 mientras se evalúa un comando print. Los hooks que
+	// se disparen entonces (por ejemplo desde un builtin que llama a una
+	// función) se ignoran: d.mu ya está tomado.
+	printing int32
+}
+
+func New(in *bufio.Scanner, out io.Writer) *Debugger {
+	return &Debugger{in: in, out: out, breakpoints: make(map[int]bool)}
+}
+
+// Break agrega un punto de ruptura en la línea indicada.
+func (d *Debugger) Break(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[line] = true
+}
+
+// Run evalúa source en env deteniéndose antes de la primera sentencia.
+// Retorna el resultado del programa, o ErrQuit si el usuario lo abandonó.
+func (d *Debugger) Run(source string, env *object.Environment) (object.Object, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.mu.Lock()
+	d.lines = strings.Split(source, "\n")
+	d.mode = modeStep
+	d.cancel = cancel
+	d.calls, d.stack = nil, nil
+	d.mu.Unlock()
+
+	result := evaluator.EvalWithOptions(program, env, evaluator.Options{
+		Hooks:   d,
+		Context: ctx,
+		// input lee de la misma entrada que los comandos.
+		Stdin: evaluator.LineReader(d.in),
+	})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.mode == modeQuit {
+		return nil, ErrQuit
+	}
+	return result, nil
+}
+
+// Stack retorna la pila de llamadas, de la más externa a la más interna.
+func (d *Debugger) Stack() []Frame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Frame(nil), d.stack...)
+}
+
+func (d *Debugger) OnEnterNode(node ast.Node, env *object.Environment) {
+	if atomic.LoadInt32(&d.printing) == 1 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if call, ok := node.(*ast.CallExpression); ok {
+		d.calls = append(d.calls, Frame{Function: callName(call), Line: call.Token.Line})
+		return
+	}
+	line, ok := statementLine(node)
+	if !ok {
+		return
+	}
+	newLine := line != d.line || len(d.stack) != d.depth
+	d.line, d.depth = line, len(d.stack)
+
+	switch {
+	case d.mode == modeQuit:
+		return
+	case d.mode == modeStep,
+		d.mode == modeNext && len(d.stack) <= d.nextDepth,
+		d.breakpoints[line] && newLine:
+		d.pause(line, env)
+	}
+}
+
+func (d *Debugger) OnExitNode(node ast.Node, env *object.Environment, result object.Object) {
+	if atomic.LoadInt32(&d.printing) == 1 {
+		return
+	}
+	if _, ok := node.(*ast.CallExpression); !ok {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.calls) > 0 {
+		d.calls = d.calls[:len(d.calls)-1]
+	}
+}
+
+func (d *Debugger) OnCall(fn object.Object, args []object.Object) {
+	if atomic.LoadInt32(&d.printing) == 1 {
+		return
+	}
+	if fn.Type() != object.FUNCTION_OBJ {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	frame := Frame{Function: "<anonymous>"}
+	if len(d.calls) > 0 {
+		frame = d.calls[len(d.calls)-1]
+	}
+	d.stack = append(d.stack, frame)
+}
+
+func (d *Debugger) OnReturn(fn object.Object, result object.Object) {
+	if atomic.LoadInt32(&d.printing) == 1 {
+		return
+	}
+	if fn.Type() != object.FUNCTION_OBJ {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.stack) > 0 {
+		d.stack = d.stack[:len(d.stack)-1]
+	}
+}
+
+// pause muestra la sentencia actual y atiende comandos hasta que uno de
+// ellos reanuda la ejecución. Se llama con d.mu tomado.
+func (d *Debugger) pause(line int, env *object.Environment) {
+	fmt.Fprintf(d.out, "> %d: %s\n", line, d.sourceLine(line))
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.in.Scan() {
+			// Sin más comandos la ejecución sigue hasta el final.
+			d.mode = modeContinue
+			d.breakpoints = make(map[int]bool)
+			return
+		}
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(d.in.Text(), fields[0]))
+		switch cmd {
+		case "s", "step":
+			d.mode = modeStep
+			return
+		case "n", "next":
+			d.mode = modeNext
+			d.nextDepth = len(d.stack)
+			return
+		case "c", "continue":
+			d.mode = modeContinue
+			return
+		case "q", "quit":
+			d.mode = modeQuit
+			d.cancel()
+			return
+		case "b", "break":
+			d.breakCommand(arg)
+		case "d", "delete":
+			if n, err := strconv.Atoi(arg); err == nil && d.breakpoints[n] {
+				delete(d.breakpoints, n)
+				fmt.Fprintf(d.out, "breakpoint at line %d deleted\n", n)
+			} else {
+				fmt.Fprintf(d.out, "no breakpoint at line %s\n", arg)
+			}
+		case "l", "locals":
+			for _, name := range env.Names() {
+				val, _ := env.Get(name)
+				fmt.Fprintf(d.out, "%s = %s\n", name, val.Inspect())
+			}
+		case "p", "print":
+			d.printCommand(arg, env)
+		case "bt", "stack":
+			for i := len(d.stack) - 1; i >= 0; i-- {
+				fmt.Fprintf(d.out, "at %s (line %d)\n", d.stack[i].Function, d.stack[i].Line)
+			}
+			fmt.Fprintln(d.out, "at <program>")
+		case "h", "help":
+			fmt.Fprint(d.out, helpText)
+		default:
+			fmt.Fprintf(d.out, "unknown command: %s (type help)\n", cmd)
+		}
+	}
+}
+
+func (d *Debugger) breakCommand(arg string) {
+	if arg == "" {
+		lines := make([]int, 0, len(d.breakpoints))
+		for line := range d.breakpoints {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			fmt.Fprintf(d.out, "breakpoint at line %d\n", line)
+		}
+		return
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		fmt.Fprintf(d.out, "invalid line: %s\n", arg)
+		return
+	}
+	d.breakpoints[n] = true
+	fmt.Fprintf(d.out, "breakpoint at line %d\n", n)
+}
+
+// printCommand evalúa una expresión en el entorno detenido. Se evalúa sin
+// hooks para que el debugger no se detenga dentro de ella.
+func (d *Debugger) printCommand(input string, env *object.Environment) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(d.out, "parse errors: %s\n", strings.Join(p.Errors(), "; "))
+		return
+	}
+	atomic.StoreInt32(&d.printing, 1)
+	result := evaluator.Eval(program, env.WithExec(nil))
+	atomic.StoreInt32(&d.printing, 0)
+	if result == nil {
+		fmt.Fprintln(d.out, "null")
+		return
+	}
+	fmt.Fprintln(d.out, result.Inspect())
+}
+
+func (d *Debugger) sourceLine(line int) string {
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	return strings.TrimSpace(d.lines[line-1])
+}
+
+func statementLine(node ast.Node) (int, bool) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token.Line, true
+	case *ast.ReturnStatement:
+		return node.Token.Line, true
+	case *ast.ExpressionStatement:
+		return node.Token.Line, true
+	}
+	return 0, false
+}
+
+func callName(call *ast.CallExpression) string {
+	switch fn := call.Function.(type) {
+	case *ast.Identifier:
+		return fn.Value
+	case *ast.MemberExpression:
+		return fn.Property.Value
+	}
+	return "<anonymous>"
+}
+
+const helpText = `commands:
+  s, step          run until the next statement
+  n, next          run until the next statement in this function
+  c, continue      run until the next breakpoint
+  b, break [LINE]  set a breakpoint, or list them
+  d, delete LINE   remove a breakpoint
+  l, locals        show the variables of the current scope
+  p, print EXPR    evaluate EXPR in the current scope
+  bt, stack        show the call stack
+  q, quit          stop the program
+`
@@ -0,0 +1,80 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+const script = `let square = fn(x) {
+  let y = x * x;
+  y
+};
+let a = square(3);
+let b = square(4);
+a + b;`
+
+func run(t *testing.T, commands string) (object.Object, string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	d := New(bufio.NewScanner(strings.NewReader(commands)), &out)
+	result, err := d.Run(script, object.NewEnvironment())
+	return result, out.String(), err
+}
+
+func TestBreakpointLocalsAndStack(t *testing.T) {
+	commands := "b 3\nc\nl\np y + 1\nbt\nd 3\nc\n"
+	result, out, err := run(t, commands)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "25" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+	for _, expected := range []string{
+		"> 1: let square = fn(x) {",
+		"breakpoint at line 3",
+		"> 3: y",
+		"x = 3\ny = 9\n",
+		"(debug) 10\n",
+		"at square (line 5)\nat <program>\n",
+		"breakpoint at line 3 deleted",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output does not contain %q. got:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "> 3:") != 1 {
+		t.Errorf("deleted breakpoint was hit again. got:\n%s", out)
+	}
+}
+
+func TestStepAndNext(t *testing.T) {
+	// step entra en square; next avanza por las sentencias del programa.
+	_, out, _ := run(t, "n\ns\ns\nn\nn\nn\nn\n")
+	expected := []string{"> 1:", "> 5:", "> 2:", "> 3:", "> 6:", "> 7:"}
+	last := 0
+	for _, e := range expected {
+		i := strings.Index(out[last:], e)
+		if i < 0 {
+			t.Fatalf("expected %q after position %d. got:\n%s", e, last, out)
+		}
+		last += i + len(e)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	_, _, err := run(t, "q\n")
+	if err != ErrQuit {
+		t.Errorf("expected ErrQuit. got=%v", err)
+	}
+}
+
+func TestInputEndsRunsToCompletion(t *testing.T) {
+	result, _, err := run(t, "")
+	if err != nil || result.Inspect() != "25" {
+		t.Errorf("expected program to finish. got=%v, %v", result, err)
+	}
+}
@@ -1,10 +1,49 @@
 package evaluator
 
 import (
-	"fmt"
+	"bufio"
+	"io"
 	"monkey/object"
+	"os"
+	"sync"
 )
 
+// Stdin es el lector usado por el builtin `input` en las ejecuciones sin
+// Options.Stdin.
+var Stdin = bufio.NewScanner(os.Stdin)
+
+// stdinMu serializa las lecturas de Stdin.
+var stdinMu sync.Mutex
+
+// LineReader retorna un io.Reader que lee de scanner de a una línea, para
+// usarlo como Options.Stdin cuando el programa comparte la entrada con
+// quien lo ejecuta (el REPL, el debugger): input no consume más líneas
+// que las que lee.
+func LineReader(scanner *bufio.Scanner) io.Reader {
+	return &lineReader{scanner: scanner}
+}
+
+type lineReader struct {
+	scanner *bufio.Scanner
+	// pending es lo que queda de la última línea.
+	pending []byte
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.pending = []byte(r.scanner.Text() + "\n")
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -13,9 +52,11 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return object.NewInteger(int64(len(arg.Value)))
 			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}
+				return object.NewInteger(int64(len(arg.Elements)))
+			case *object.Set:
+				return object.NewInteger(int64(len(arg.Elements)))
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 
@@ -80,6 +121,9 @@ var builtins = map[string]*object.Builtin{
 				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
 			}
 			arr := args[0].(*object.Array)
+			if arr.Frozen {
+				return newError("cannot push to frozen ARRAY")
+			}
 			length := len(arr.Elements)
 
 			newElements := make([]object.Object, length+1, length+1)
@@ -91,10 +135,61 @@ var builtins = map[string]*object.Builtin{
 	},
 	"puts": {
 		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+			return putsTo(os.Stdout)(args...)
+		},
+	},
+	"eputs": {
+		Fn: func(args ...object.Object) object.Object {
+			return putsTo(os.Stderr)(args...)
+		},
+	},
+	"exit": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
 			}
-			return NULL
+			if len(args) == 0 {
+				return &object.Exit{Code: 0}
+			}
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `exit` must be INTEGER, got %s", args[0].Type())
+			}
+			return &object.Exit{Code: int(code.Value)}
+		},
+	},
+	"input": {
+		Fn: func(args ...object.Object) object.Object {
+			return inputFrom(Stdin, &stdinMu, os.Stdout)(args...)
 		},
 	},
 }
+
+// Cantidad de argumentos de los builtins que siempre reciben los mismos.
+// Los que aceptan varias cantidades, como range o exec, no aparecen aquí,
+// y tampoco los agregados con RegisterBuiltin.
+var builtinArities = map[string]int{
+	"args": 0, "clock": 0, "mutex": 0, "none": 0, "now": 0, "uuid": 0,
+
+	"atomic_load": 1, "await": 1, "base64_decode": 1, "base64_encode": 1,
+	"clone": 1, "close": 1, "csv_stringify": 1, "enumerate": 1, "fiber": 1,
+	"first": 1, "freeze": 1, "hex_decode": 1, "hex_encode": 1, "is_done": 1,
+	"is_frozen": 1, "is_none": 1, "is_some": 1, "keys": 1, "last": 1,
+	"len": 1, "md5": 1, "memo": 1, "recv": 1, "rest": 1, "sha256": 1,
+	"sleep": 1, "some": 1, "unwrap": 1, "values": 1,
+
+	"add": 2, "atomic_add": 2, "contains": 2, "difference": 2, "drop": 2,
+	"format_time": 2, "hmac_sha256": 2, "intersect": 2, "lazy_filter": 2,
+	"lazy_map": 2, "lock": 2, "push": 2, "regex_find_all": 2,
+	"regex_match": 2, "send": 2, "take": 2, "union": 2, "unwrap_or": 2,
+	"zip": 2,
+
+	"regex_replace": 3,
+}
+
+// BuiltinArity retorna la cantidad de argumentos que recibe el builtin
+// name, si es siempre la misma.
+func BuiltinArity(name string) (int, bool) {
+	arity, ok := builtinArities[name]
+	return arity, ok
+}
@@ -0,0 +1,98 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["assert"] = &object.Builtin{Fn: assert}
+	builtins["assert_eq"] = &object.Builtin{Fn: assertEq}
+}
+
+// assert(cond, msg) retorna un error con msg cuando cond no es verdadera.
+func assert(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	if isTruthy(args[0]) {
+		return NULL
+	}
+	if len(args) == 2 {
+		return newError("assertion failed: %s (got %s)", inspectString(args[1]), args[0].Inspect())
+	}
+	return newError("assertion failed (got %s)", args[0].Inspect())
+}
+
+// assert_eq(a, b) retorna un error que muestra ambos valores cuando no son
+// iguales. Los ARRAY y HASH se comparan elemento por elemento.
+func assertEq(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	if objectsEqual(args[0], args[1]) {
+		return NULL
+	}
+	left := args[0].Inspect() + " (" + string(args[0].Type()) + ")"
+	right := args[1].Inspect() + " (" + string(args[1].Type()) + ")"
+	if len(args) == 3 {
+		return newError("assertion failed: %s: %s != %s", inspectString(args[2]), left, right)
+	}
+	return newError("assertion failed: %s != %s", left, right)
+}
+
+// objectsEqual compara dos objetos por valor.
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value == b.(*object.Integer).Value
+	case *object.Boolean:
+		return a.Value == b.(*object.Boolean).Value
+	case *object.String:
+		return a.Value == b.(*object.String).Value
+	case *object.Null:
+		return true
+	case *object.Array:
+		other := b.(*object.Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !objectsEqual(el, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other := b.(*object.Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	case *object.Set:
+		other := b.(*object.Set)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for key := range a.Elements {
+			if _, ok := other.Elements[key]; !ok {
+				return false
+			}
+		}
+		return true
+	case *object.Option:
+		other := b.(*object.Option)
+		if !a.IsSome() || !other.IsSome() {
+			return a.IsSome() == other.IsSome()
+		}
+		return objectsEqual(a.Value, other.Value)
+	default:
+		return a == b
+	}
+}
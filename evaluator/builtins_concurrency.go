@@ -0,0 +1,260 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["spawn"] = &object.Builtin{Fn: spawn}
+	builtins["channel"] = &object.Builtin{Fn: channel}
+	builtins["send"] = callerBuiltin(send)
+	builtins["recv"] = callerBuiltin(recv)
+	builtins["close"] = &object.Builtin{Fn: closeChannel}
+	builtins["async"] = &object.Builtin{Fn: async}
+	builtins["await"] = callerBuiltin(await)
+	builtins["mutex"] = &object.Builtin{Fn: mutex}
+	builtins["lock"] = callerBuiltin(lock)
+	builtins["atomic"] = &object.Builtin{Fn: newAtomic}
+	builtins["atomic_add"] = &object.Builtin{Fn: atomicAdd}
+	builtins["atomic_load"] = &object.Builtin{Fn: atomicLoad}
+}
+
+// spawn(fn, args...) ejecuta fn(args...) en una goroutine y retorna de
+// inmediato un CHANNEL por el que llegará el resultado (o el error).
+// Cada llamada tiene su propio entorno, pero las variables globales y
+// los arrays y hashes que reciba se comparten con el resto del programa:
+// para coordinarse hay que usar canales, mutex() o atomic().
+func spawn(args ...object.Object) object.Object {
+	if errObj := checkSpawnArgs("spawn", args); errObj != nil {
+		return errObj
+	}
+	result := make(chan object.Object, 1)
+	go func() {
+		result <- applyInGoroutine(args[0], args[1:])
+		close(result)
+	}()
+	return &object.Channel{Value: result}
+}
+
+// applyInGoroutine llama a fn(args...) en la goroutine de spawn, async o
+// fiber, donde un panic de Go terminaría el proceso: se convierte en un error del script.
+func applyInGoroutine(fn object.Object, args []object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("%v", r)
+		}
+	}()
+	return applyFunction(fn, args)
+}
+
+// async(fn, args...) es como spawn pero retorna un FUTURE, cuyo resultado
+// se obtiene con await.
+func async(args ...object.Object) object.Object {
+	if errObj := checkSpawnArgs("async", args); errObj != nil {
+		return errObj
+	}
+	future := object.NewFuture()
+	go func() {
+		future.Resolve(applyInGoroutine(args[0], args[1:]))
+	}()
+	return future
+}
+
+// await(future) espera a que termine la función del FUTURE y retorna su
+// resultado. Se puede llamar varias veces: siempre retorna lo mismo.
+func await(caller *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	future, ok := args[0].(*object.Future)
+	if !ok {
+		return newError("argument to `await` must be FUTURE, got %s", args[0].Type())
+	}
+	ex := execOf(caller)
+	select {
+	case <-future.Done():
+		return future.Wait()
+	case <-ex.cancelled():
+		return ex.cancelError()
+	}
+}
+
+// Valida los argumentos (fn, args...) de spawn y async. Se revisa aquí
+// la cantidad de argumentos porque un panic en la goroutine no se
+// podría convertir en un error del script.
+func checkSpawnArgs(name string, args []object.Object) *object.Error {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	fnArgs := args[1:]
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if len(fnArgs) < len(fn.Parameters) {
+			return newError("wrong number of arguments to spawned function. got=%d, want=%d",
+				len(fnArgs), len(fn.Parameters))
+		}
+	case *object.Builtin, object.Callable:
+	default:
+		return newError("first argument to `%s` must be FUNCTION, got %s", name, args[0].Type())
+	}
+	return nil
+}
+
+// channel() crea un CHANNEL sin buffer; channel(n) uno con capacidad n.
+func channel(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	size := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `channel` must be INTEGER, got %s", args[0].Type())
+		}
+		if integer.Value < 0 {
+			return newError("argument to `channel` must not be negative, got %d", integer.Value)
+		}
+		size = integer.Value
+	}
+	return &object.Channel{Value: make(chan object.Object, size)}
+}
+
+// send(ch, value) envía value por el canal, esperando a que haya lugar.
+func send(caller *object.Environment, args ...object.Object) (result object.Object) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("first argument to `send` must be CHANNEL, got %s", args[0].Type())
+	}
+	// Enviar por un canal cerrado hace panic en Go.
+	defer func() {
+		if recover() != nil {
+			result = newError("send on closed channel")
+		}
+	}()
+	ex := execOf(caller)
+	select {
+	case ch.Value <- args[1]:
+		return NULL
+	case <-ex.cancelled():
+		return ex.cancelError()
+	}
+}
+
+// recv(ch) espera el siguiente valor del canal. Retorna null si el
+// canal está cerrado y vacío.
+func recv(caller *object.Environment, args ...object.Object) object.Object {
+	ch, errObj := singleChannel("recv", args)
+	if errObj != nil {
+		return errObj
+	}
+	ex := execOf(caller)
+	select {
+	case val, ok := <-ch.Value:
+		if !ok {
+			return NULL
+		}
+		return val
+	case <-ex.cancelled():
+		return ex.cancelError()
+	}
+}
+
+// close(ch) cierra el canal: los recv pendientes reciben null.
+func closeChannel(args ...object.Object) (result object.Object) {
+	ch, errObj := singleChannel("close", args)
+	if errObj != nil {
+		return errObj
+	}
+	defer func() {
+		if recover() != nil {
+			result = newError("close of closed channel")
+		}
+	}()
+	close(ch.Value)
+	return NULL
+}
+
+func singleChannel(name string, args []object.Object) (*object.Channel, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return nil, newError("argument to `%s` must be CHANNEL, got %s", name, args[0].Type())
+	}
+	return ch, nil
+}
+
+// mutex() crea un MUTEX para usar con lock.
+func mutex(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Mutex{}
+}
+
+// lock(m, fn) ejecuta fn() con el mutex tomado y retorna su resultado.
+// El mutex se libera aunque fn termine con un error.
+func lock(caller *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	m, ok := args[0].(*object.Mutex)
+	if !ok {
+		return newError("first argument to `lock` must be MUTEX, got %s", args[0].Type())
+	}
+	if args[1].Type() != object.FUNCTION_OBJ && args[1].Type() != object.BUILTIN_OBJ {
+		return newError("second argument to `lock` must be FUNCTION, got %s", args[1].Type())
+	}
+	ex := execOf(caller)
+	if !m.Lock(ex.cancelled()) {
+		return ex.cancelError()
+	}
+	defer m.Unlock()
+	return callFunction(args[1], nil, caller)
+}
+
+// atomic(n) crea un contador ATOMIC con valor inicial n (0 si se omite).
+func newAtomic(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	initial := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `atomic` must be INTEGER, got %s", args[0].Type())
+		}
+		initial = integer.Value
+	}
+	return object.NewAtomic(initial)
+}
+
+// atomic_add(ref, n) suma n al contador y retorna el nuevo valor.
+func atomicAdd(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	ref, ok := args[0].(*object.Atomic)
+	if !ok {
+		return newError("first argument to `atomic_add` must be ATOMIC, got %s", args[0].Type())
+	}
+	delta, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to `atomic_add` must be INTEGER, got %s", args[1].Type())
+	}
+	return object.NewInteger(ref.Add(delta.Value))
+}
+
+// atomic_load(ref) retorna el valor actual del contador.
+func atomicLoad(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	ref, ok := args[0].(*object.Atomic)
+	if !ok {
+		return newError("argument to `atomic_load` must be ATOMIC, got %s", args[0].Type())
+	}
+	return object.NewInteger(ref.Load())
+}
@@ -0,0 +1,141 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestSpawnAndChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`recv(spawn(fn(a, b) { a + b }, 2, 3))`, 5},
+		{`recv(spawn(len, "hola"))`, 4},
+		{`
+let ch = channel();
+let worker = fn(n) { send(ch, n * n) };
+spawn(worker, 2);
+spawn(worker, 3);
+recv(ch) + recv(ch);`, 13},
+		{`
+let ch = channel(3);
+send(ch, 1); send(ch, 2);
+close(ch);
+[recv(ch), recv(ch), recv(ch)]`, "[1, 2, null]"},
+		// Los let de la función lanzada no se filtran al entorno global.
+		{`let x = 1; recv(spawn(fn() { let x = 2; x })) * 10 + x`, 21},
+		{`let ch = channel(); close(ch); send(ch, 1)`, "send on closed channel"},
+		{`let ch = channel(); close(ch); close(ch)`, "close of closed channel"},
+		{`spawn(1)`, "first argument to `spawn` must be FUNCTION, got INTEGER"},
+		{`spawn(fn(a) { a })`, "wrong number of arguments to spawned function. got=0, want=1"},
+		{`recv(spawn(fn() { 1 + true }))`, "type mismatch: INTEGER + BOOLEAN"},
+		{`recv(spawn(fn() { 1 / 0 }))`, "division by zero"},
+		{`recv(spawn(fn() {}))`, "null"},
+		{`recv([])`, "argument to `recv` must be CHANNEL, got ARRAY"},
+		{`channel(-1)`, "argument to `channel` must not be negative, got -1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result. expected=%s, got=%s", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestMutexAndAtomicBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`
+let counter = atomic();
+let repeat = fn(n, f) { if (n > 0) { f(); repeat(n - 1, f) } };
+let worker = fn() { repeat(50, fn() { atomic_add(counter, 1) }) };
+let done = [spawn(worker), spawn(worker), spawn(worker), spawn(worker)];
+recv(done[0]); recv(done[1]); recv(done[2]); recv(done[3]);
+atomic_load(counter);`, 200},
+		{`atomic_add(atomic(5), -2)`, 3},
+		{`let m = mutex(); lock(m, fn() { 42 })`, 42},
+		{`lock(1, fn() { 1 })`, "first argument to `lock` must be MUTEX, got INTEGER"},
+		{`lock(mutex(), 1)`, "second argument to `lock` must be FUNCTION, got INTEGER"},
+		{`atomic_add(1, 1)`, "first argument to `atomic_add` must be ATOMIC, got INTEGER"},
+		{`atomic("1")`, "argument to `atomic` must be INTEGER, got STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLockReleasesMutexOnError(t *testing.T) {
+	m := &object.Mutex{}
+	fn := testEval(`fn() { 1 + true }`)
+	result := lock(nil, m, fn)
+	if _, ok := result.(*object.Error); !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", result, result)
+	}
+	if !m.TryLock() {
+		t.Errorf("mutex still locked after lock() returned an error")
+	}
+}
+
+func TestAsyncAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`await(async(fn(a, b) { a * b }, 6, 7))`, 42},
+		{`
+let slow = fn(n) { sleep(10); n };
+let futures = [async(slow, 1), async(slow, 2), async(slow, 3)];
+await(futures[0]) + await(futures[1]) + await(futures[2]);`, 6},
+		{`let f = async(fn() { 5 }); await(f) + await(f)`, 10},
+		{`await(async(fn() { 1 + true }))`, "type mismatch: INTEGER + BOOLEAN"},
+		{`await(async(fn() { 1 / 0 }))`, "division by zero"},
+		{`await(async(fn() {}))`, "null"},
+		{`let f = async(fn() {}); await(f); f`, "future(null)"},
+		{`await(1)`, "argument to `await` must be FUTURE, got INTEGER"},
+		{`async("f")`, "first argument to `async` must be FUNCTION, got STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result. expected=%s, got=%s", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package evaluator
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"monkey/object"
+)
+
+func init() {
+	builtins["sha256"] = &object.Builtin{Fn: sha256Sum}
+	builtins["md5"] = &object.Builtin{Fn: md5Sum}
+	builtins["hmac_sha256"] = &object.Builtin{Fn: hmacSHA256}
+	builtins["uuid"] = &object.Builtin{Fn: uuid}
+}
+
+// sha256(s) retorna el hash SHA-256 de s en hexadecimal.
+func sha256Sum(args ...object.Object) object.Object {
+	s, errObj := singleString("sha256", args)
+	if errObj != nil {
+		return errObj
+	}
+	sum := sha256.Sum256([]byte(s))
+	return &object.String{Value: hex.EncodeToString(sum[:])}
+}
+
+// md5(s) retorna el hash MD5 de s en hexadecimal.
+func md5Sum(args ...object.Object) object.Object {
+	s, errObj := singleString("md5", args)
+	if errObj != nil {
+		return errObj
+	}
+	sum := md5.Sum([]byte(s))
+	return &object.String{Value: hex.EncodeToString(sum[:])}
+}
+
+// hmac_sha256(key, msg) retorna el HMAC-SHA256 de msg en hexadecimal.
+func hmacSHA256(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	key, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `hmac_sha256` must be STRING, got %s", args[0].Type())
+	}
+	msg, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `hmac_sha256` must be STRING, got %s", args[1].Type())
+	}
+	mac := hmac.New(sha256.New, []byte(key.Value))
+	mac.Write([]byte(msg.Value))
+	return &object.String{Value: hex.EncodeToString(mac.Sum(nil))}
+}
+
+// uuid() genera un identificador aleatorio RFC 4122 versión 4.
+func uuid(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return newError("uuid: %s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // versión 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variante RFC 4122
+	return &object.String{Value: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])}
+}
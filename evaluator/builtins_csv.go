@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"monkey/object"
+	"strings"
+)
+
+func init() {
+	builtins["csv_parse"] = &object.Builtin{Fn: csvParse}
+	builtins["csv_stringify"] = &object.Builtin{Fn: csvStringify}
+}
+
+// csv_parse(s) retorna un ARRAY de filas, cada una un ARRAY de STRING.
+// csv_parse(s, true) usa la primera fila como encabezado y retorna un
+// ARRAY de HASH indexados por el nombre de cada columna, en el orden
+// del encabezado.
+func csvParse(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	input, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `csv_parse` must be STRING, got %s", args[0].Type())
+	}
+	header := false
+	if len(args) == 2 {
+		b, ok := args[1].(*object.Boolean)
+		if !ok {
+			return newError("second argument to `csv_parse` must be BOOLEAN, got %s", args[1].Type())
+		}
+		header = b.Value
+	}
+
+	records, err := csv.NewReader(strings.NewReader(input.Value)).ReadAll()
+	if err != nil {
+		return newError("csv_parse: %s", err)
+	}
+
+	if !header {
+		rows := make([]object.Object, len(records))
+		for i, record := range records {
+			rows[i] = stringsToArray(record)
+		}
+		return &object.Array{Elements: rows}
+	}
+
+	rows := []object.Object{}
+	if len(records) == 0 {
+		return &object.Array{Elements: rows}
+	}
+	columns := records[0]
+	for _, record := range records[1:] {
+		row := object.NewHash()
+		for i, column := range columns {
+			hashSet(row, column, &object.String{Value: record[i]})
+		}
+		rows = append(rows, row)
+	}
+	return &object.Array{Elements: rows}
+}
+
+// csv_stringify(rows) convierte un ARRAY de ARRAY en texto CSV. Los
+// valores que no son STRING se escriben usando su Inspect().
+func csvStringify(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	rows, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `csv_stringify` must be ARRAY, got %s", args[0].Type())
+	}
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	for _, row := range rows.Elements {
+		fields, ok := row.(*object.Array)
+		if !ok {
+			return newError("rows passed to `csv_stringify` must be ARRAY, got %s", row.Type())
+		}
+		record := make([]string, len(fields.Elements))
+		for i, field := range fields.Elements {
+			record[i] = inspectString(field)
+		}
+		if err := w.Write(record); err != nil {
+			return newError("csv_stringify: %s", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return newError("csv_stringify: %s", err)
+	}
+	return &object.String{Value: out.String()}
+}
+
+func stringsToArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &object.String{Value: s}
+	}
+	return &object.Array{Elements: elements}
+}
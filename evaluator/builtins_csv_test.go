@@ -0,0 +1,36 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestCSVBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`csv_parse("a,b
+1,2
+")`, "[[a, b], [1, 2]]"},
+		{`csv_parse("nombre,edad
+Ana,30
+Luis,25
+", true)[1]["nombre"]`, "Luis"},
+		{`csv_parse("", true)`, "[]"},
+		{`csv_stringify([["a", "b,c"], [1, true]])`, "a,\"b,c\"\n1,true\n"},
+		{`csv_stringify(csv_parse("x,y
+1,2
+"))`, "x,y\n1,2\n"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("unexpected error for %s: %s", tt.input, errObj.Message)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
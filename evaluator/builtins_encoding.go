@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"monkey/object"
+)
+
+func init() {
+	builtins["base64_encode"] = &object.Builtin{Fn: base64Encode}
+	builtins["base64_decode"] = &object.Builtin{Fn: base64Decode}
+	builtins["hex_encode"] = &object.Builtin{Fn: hexEncode}
+	builtins["hex_decode"] = &object.Builtin{Fn: hexDecode}
+}
+
+// Los STRING de Monkey guardan bytes arbitrarios, así que estos builtins
+// trabajan directamente sobre ellos.
+
+func base64Encode(args ...object.Object) object.Object {
+	s, errObj := singleString("base64_encode", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: base64.StdEncoding.EncodeToString([]byte(s))}
+}
+
+func base64Decode(args ...object.Object) object.Object {
+	s, errObj := singleString("base64_decode", args)
+	if errObj != nil {
+		return errObj
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return newError("base64_decode: %s", err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+func hexEncode(args ...object.Object) object.Object {
+	s, errObj := singleString("hex_encode", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: hex.EncodeToString([]byte(s))}
+}
+
+func hexDecode(args ...object.Object) object.Object {
+	s, errObj := singleString("hex_decode", args)
+	if errObj != nil {
+		return errObj
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return newError("hex_decode: %s", err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+// Valida que el builtin reciba un solo argumento de tipo STRING.
+func singleString(name string, args []object.Object) (string, *object.Error) {
+	if len(args) != 1 {
+		return "", newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return str.Value, nil
+}
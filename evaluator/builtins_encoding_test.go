@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"regexp"
+	"testing"
+)
+
+func TestEncodingBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`base64_encode("monkey")`, "bW9ua2V5"},
+		{`base64_decode("bW9ua2V5")`, "monkey"},
+		{`base64_decode(base64_encode("ñandú"))`, "ñandú"},
+		{`hex_encode("Go")`, "476f"},
+		{`hex_decode("476f")`, "Go"},
+		{`hex_decode("zz")`, "hex_decode: encoding/hex: invalid byte: U+007A 'z'"},
+		{`base64_encode(1)`, "argument to `base64_encode` must be STRING, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch obj := evaluated.(type) {
+		case *object.String:
+			if obj.Value != tt.expected {
+				t.Errorf("wrong value for %s. expected=%q, got=%q", tt.input, tt.expected, obj.Value)
+			}
+		case *object.Error:
+			if obj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, obj.Message)
+			}
+		default:
+			t.Errorf("unexpected object. got=%T (%+v)", evaluated, evaluated)
+		}
+	}
+}
+
+func TestCryptoBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sha256("")`, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{`sha256("abc")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`md5("")`, "d41d8cd98f00b204e9800998ecf8427e"},
+		{`md5("monkey")`, "d0763edaa9d9bd2a9516280e9044d885"},
+		{`hmac_sha256("key", "The quick brown fox jumps over the lazy dog")`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong hash for %s. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestUUIDBuiltin(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first := testEval(`uuid()`).Inspect()
+	if !pattern.MatchString(first) {
+		t.Errorf("not a v4 uuid. got=%q", first)
+	}
+	if second := testEval(`uuid()`).Inspect(); first == second {
+		t.Errorf("uuid() returned the same value twice: %q", first)
+	}
+}
@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"os"
+)
+
+// Args son los argumentos de línea de comandos que retorna args() cuando
+// la ejecución no tiene Options.Args.
+var Args []string
+
+func init() {
+	RegisterBuiltin("env", getEnv, CapEnv)
+	RegisterBuiltin("set_env", setEnv, CapEnv)
+	builtins["args"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return argsFrom(Args)(args...)
+	}}
+}
+
+// env(name) retorna el valor de la variable de entorno o null si no existe.
+func getEnv(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `env` must be STRING, got %s", args[0].Type())
+	}
+	value, ok := os.LookupEnv(name.Value)
+	if !ok {
+		return NULL
+	}
+	return &object.String{Value: value}
+}
+
+// set_env(name, value) asigna la variable de entorno del proceso.
+func setEnv(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `set_env` must be STRING, got %s", args[0].Type())
+	}
+	value, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `set_env` must be STRING, got %s", args[1].Type())
+	}
+	if err := os.Setenv(name.Value, value.Value); err != nil {
+		return newError("set_env: %s", err)
+	}
+	return NULL
+}
+
+// args() retorna un ARRAY con los argumentos del script, scriptArgs.
+func argsFrom(scriptArgs []string) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return newError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		elements := make([]object.Object, len(scriptArgs))
+		for i, arg := range scriptArgs {
+			elements[i] = &object.String{Value: arg}
+		}
+		return &object.Array{Elements: elements}
+	}
+}
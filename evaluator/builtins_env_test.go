@@ -0,0 +1,42 @@
+package evaluator
+
+import (
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+)
+
+func TestEnvBuiltins(t *testing.T) {
+	evaluated := testEval(`set_env("MONKEY_TEST_VAR", "banana"); env("MONKEY_TEST_VAR")`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "banana" {
+		t.Errorf("wrong value. got=%q", str.Value)
+	}
+
+	testNullObject(t, testEval(`env("MONKEY_TEST_UNDEFINED_VAR")`))
+}
+
+func TestArgsBuiltin(t *testing.T) {
+	Args = []string{"uno", "dos"}
+	defer func() { Args = nil }()
+
+	evaluated := testEval(`args()`)
+	if got := evaluated.Inspect(); got != "[uno, dos]" {
+		t.Errorf("wrong args. got=%s", got)
+	}
+}
+
+func TestArgsOption(t *testing.T) {
+	Args = []string{"uno"}
+	defer func() { Args = nil }()
+
+	program := parser.New(lexer.New(`args()`)).ParseProgram()
+	evaluated := EvalWithOptions(program, object.NewEnvironment(), Options{Args: []string{"dos", "tres"}})
+	if got := evaluated.Inspect(); got != "[dos, tres]" {
+		t.Errorf("wrong args. got=%s", got)
+	}
+}
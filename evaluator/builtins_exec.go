@@ -0,0 +1,61 @@
+package evaluator
+
+import (
+	"bytes"
+	"monkey/object"
+	"os/exec"
+)
+
+// AllowExec habilita el builtin `exec`. Ejecutar comandos del sistema es
+// demasiado peligroso para tenerlo activo por defecto, así que el programa
+// que embebe el intérprete debe pedirlo explícitamente. Sandbox tiene
+// prioridad sobre este valor.
+var AllowExec = false
+
+func init() {
+	RegisterBuiltin("exec", execCommand, CapExec)
+}
+
+// exec(cmd, args) ejecuta el comando y retorna un HASH
+// {"stdout": STRING, "stderr": STRING, "code": INTEGER}.
+func execCommand(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `exec` must be STRING, got %s", args[0].Type())
+	}
+	cmdArgs := []string{}
+	if len(args) == 2 {
+		arr, ok := args[1].(*object.Array)
+		if !ok {
+			return newError("second argument to `exec` must be ARRAY, got %s", args[1].Type())
+		}
+		for _, el := range arr.Elements {
+			str, ok := el.(*object.String)
+			if !ok {
+				return newError("arguments to `exec` must be STRING, got %s", el.Type())
+			}
+			cmdArgs = append(cmdArgs, str.Value)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name.Value, cmdArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	code := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return newError("exec: %s", err)
+		}
+		code = exitErr.ExitCode()
+	}
+	return newStringHash(map[string]object.Object{
+		"stdout": &object.String{Value: stdout.String()},
+		"stderr": &object.String{Value: stderr.String()},
+		"code":   object.NewInteger(int64(code)),
+	})
+}
@@ -0,0 +1,36 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestExecBuiltin(t *testing.T) {
+	evaluated := testEval(`exec("echo", ["hola"])`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("exec should be disabled by default. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "`exec` is disabled" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	AllowExec = true
+	defer func() { AllowExec = false }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`exec("echo", ["hola", "mundo"])["stdout"]`, "hola mundo\n"},
+		{`exec("sh", ["-c", "echo oops >&2; exit 3"])["stderr"]`, "oops\n"},
+		{`exec("sh", ["-c", "exit 3"])["code"]`, "3"},
+		{`exec("true")["code"]`, "0"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%+v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
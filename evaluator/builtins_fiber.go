@@ -0,0 +1,145 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["fiber"] = callerBuiltin(newFiber)
+	builtins["resume"] = &object.Builtin{Fn: resume}
+	builtins["is_done"] = &object.Builtin{Fn: isDone}
+}
+
+// fiber(fn) retorna un FIBER que ejecuta fn(yield, value) de a partes:
+// cada resume la continúa hasta que llame a yield(x), y entonces resume
+// retorna x. yield retorna el valor que se pase al resume siguiente.
+// Cuando fn termina, resume retorna su resultado y el fiber queda
+// terminado. Por ejemplo, un generador:
+//
+//	let counter = fiber(fn(yield) { yield(1); yield(2); 3 });
+//	resume(counter); resume(counter); resume(counter) // 1, 2, 3
+//
+// En el evaluador fn corre en su propia goroutine, pero nunca al mismo
+// tiempo que quien llama a resume. La goroutine de un fiber que no
+// termina queda esperando hasta que se cancela la ejecución que lo creó;
+// entonces yield y resume retornan el error de la cancelación.
+func newFiber(caller *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fn := args[0]
+	if fn.Type() != object.FUNCTION_OBJ && fn.Type() != object.BUILTIN_OBJ {
+		return newError("argument to `fiber` must be FUNCTION, got %s", fn.Type())
+	}
+
+	type step struct {
+		value object.Object
+		done  bool
+	}
+	in := make(chan object.Object)
+	out := make(chan step)
+	fiber := &object.Fiber{}
+	ex := execOf(caller)
+
+	yield := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if !fiber.Running {
+			return newError("yield called outside its fiber")
+		}
+		var value object.Object = NULL
+		if len(args) > 0 {
+			value = args[0]
+		}
+		select {
+		case out <- step{value: value}:
+		case <-ex.cancelled():
+			return ex.cancelError()
+		}
+		select {
+		case value := <-in:
+			return value
+		case <-ex.cancelled():
+			return ex.cancelError()
+		}
+	}}
+
+	started := false
+	fiber.Copy = func() *object.Fiber {
+		return newFiber(caller, fn).(*object.Fiber)
+	}
+	fiber.Resume = func(value object.Object) (object.Object, bool) {
+		if !started {
+			started = true
+			fiber.Copy = nil
+			go func() {
+				result := applyInGoroutine(fn, []object.Object{yield, value})
+				select {
+				case out <- step{value: result, done: true}:
+				case <-ex.cancelled():
+				}
+			}()
+		} else {
+			select {
+			case in <- value:
+			case <-ex.cancelled():
+				return ex.cancelError(), true
+			}
+		}
+		select {
+		case s := <-out:
+			return s.value, s.done
+		case <-ex.cancelled():
+			return ex.cancelError(), true
+		}
+	}
+	return fiber
+}
+
+// fiberArg retorna el FIBER en args[0] para el builtin name.
+func fiberArg(name string, args []object.Object) (*object.Fiber, *object.Error) {
+	if len(args) < 1 {
+		return nil, newError("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	fiber, ok := args[0].(*object.Fiber)
+	if !ok {
+		return nil, newError("argument to `%s` must be FIBER, got %s", name, args[0].Type())
+	}
+	return fiber, nil
+}
+
+// resume(f) o resume(f, value) continúa el fiber f (ver fiber) y retorna
+// el próximo valor que ceda, o el resultado de su función si termina.
+func resume(args ...object.Object) object.Object {
+	if len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	fiber, errObj := fiberArg("resume", args)
+	if errObj != nil {
+		return errObj
+	}
+	switch {
+	case fiber.Done:
+		return newError("cannot resume a finished fiber")
+	case fiber.Running:
+		return newError("cannot resume a running fiber")
+	}
+	var value object.Object = NULL
+	if len(args) == 2 {
+		value = args[1]
+	}
+
+	fiber.Running = true
+	result, done := fiber.Resume(value)
+	fiber.Running = false
+	fiber.Done = done
+	return result
+}
+
+// is_done(f) indica si la función del fiber f ya terminó.
+func isDone(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fiber, errObj := fiberArg("is_done", args)
+	if errObj != nil {
+		return errObj
+	}
+	return nativeBoolToBooleanObject(fiber.Done)
+}
@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"context"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFiberBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result or error message
+	}{
+		{`let f = fiber(fn(yield) { yield(1); yield(2); 3 }); [resume(f), resume(f), resume(f), is_done(f)]`, "[1, 2, 3, true]"},
+		{`let f = fiber(fn(yield, x) { let y = yield(x + 1); y * 2 }); [resume(f, 10), is_done(f), resume(f, 5), is_done(f)]`, "[11, false, 10, true]"},
+		{
+			`let naturals = fiber(fn(yield) { let loop = fn(i) { yield(i); loop(i + 1) }; loop(0) });
+			resume(naturals); resume(naturals); resume(naturals)`,
+			"2",
+		},
+		{`let f = fiber(fn(yield) { yield() }); resume(f)`, "null"},
+		{`let f = fiber(fn(yield) { 1 }); [f, resume(f), f]`, "[fiber(done), 1, fiber(done)]"},
+		{`let f = fiber(fn(yield) { 1 }); f`, "fiber(suspended)"},
+		{`let f = fiber(fn(yield) { 1 }); resume(f); resume(f)`, "cannot resume a finished fiber"},
+		{`let f = fiber(fn(yield) { resume(f) }); resume(f)`, "cannot resume a running fiber"},
+		{`let y = 0; let f = fiber(fn(yield) { let y = yield; 1 }); resume(f); y`, "0"},
+		{`let f = fiber(fn(yield) { yield }); let y = resume(f); y(1)`, "yield called outside its fiber"},
+		{`let f = fiber(fn(yield) { 1 + true }); resume(f)`, "type mismatch: INTEGER + BOOLEAN"},
+		{`let f = fiber(fn(yield) { 1 / 0 }); resume(f)`, "division by zero"},
+		{`resume(fiber(fn(y) {}))`, "null"},
+		{`fiber(1)`, "argument to `fiber` must be FUNCTION, got INTEGER"},
+		{`resume(1)`, "argument to `resume` must be FIBER, got INTEGER"},
+		{`is_done()`, "wrong number of arguments. got=0, want=1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestUnfinishedFibersStopWithTheirExecution(t *testing.T) {
+	before := runtime.NumGoroutine()
+	input := `
+let start = fn(n) {
+	if (n > 0) {
+		resume(fiber(fn(yield) { yield(n); n }));
+		start(n - 1)
+	}
+};
+start(500);
+1`
+	ctx, cancel := context.WithCancel(context.Background())
+	evaluated := EvalWithContext(ctx, parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+	testIntegerObject(t, evaluated, 1)
+	if n := runtime.NumGoroutine(); n < before+500 {
+		t.Fatalf("expected the paused fibers to be waiting. goroutines=%d, before=%d", n, before)
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before+10 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before+10 {
+		t.Errorf("fiber goroutines still running after cancel. goroutines=%d, before=%d", n, before)
+	}
+}
@@ -0,0 +1,206 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Sandbox deshabilita los builtins que acceden al sistema anfitrión
+// (archivos, red, variables de entorno). Los programas que embeben el
+// intérprete pueden activarlo antes de evaluar scripts que no son de confianza.
+// Para elegir capacidades por ejecución ver Options.Sandboxed.
+var Sandbox = false
+
+func init() {
+	RegisterBuiltin("read_file", readFile, CapFS)
+	RegisterBuiltin("write_file", writeFile, CapFS)
+	RegisterBuiltin("append_file", appendFile, CapFS)
+	RegisterBuiltin("file_exists", fileExists, CapFS)
+	RegisterBuiltin("list_dir", listDir, CapFS)
+	RegisterBuiltin("mkdir", makeDir, CapFS)
+	RegisterBuiltin("remove", removePath, CapFS)
+	restrictedBuiltins["remove"] = restrictedRemove
+
+	modules["path"] = newModule(map[string]object.BuiltinFunction{
+		"join":     pathJoin,
+		"basename": pathBasename,
+		"dirname":  pathDirname,
+		"ext":      pathExt,
+	})
+}
+
+// read_file(path) retorna el contenido del archivo como STRING.
+func readFile(args ...object.Object) object.Object {
+	path, errObj := singleString("read_file", args)
+	if errObj != nil {
+		return errObj
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return newError("read_file: %s", err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+// write_file(path, contents) crea o reemplaza el archivo.
+func writeFile(args ...object.Object) object.Object {
+	path, contents, errObj := pathAndContents("write_file", args)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		return newError("write_file: %s", err)
+	}
+	return NULL
+}
+
+// append_file(path, contents) agrega contents al final del archivo,
+// creándolo si no existe.
+func appendFile(args ...object.Object) object.Object {
+	path, contents, errObj := pathAndContents("append_file", args)
+	if errObj != nil {
+		return errObj
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return newError("append_file: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(contents); err != nil {
+		return newError("append_file: %s", err)
+	}
+	return NULL
+}
+
+// file_exists(path) retorna true si la ruta existe.
+func fileExists(args ...object.Object) object.Object {
+	path, errObj := singleString("file_exists", args)
+	if errObj != nil {
+		return errObj
+	}
+	_, err := os.Stat(path)
+	return nativeBoolToBooleanObject(err == nil)
+}
+
+// list_dir(path) retorna un ARRAY ordenado con los nombres de las
+// entradas del directorio.
+func listDir(args ...object.Object) object.Object {
+	path, errObj := singleString("list_dir", args)
+	if errObj != nil {
+		return errObj
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return newError("list_dir: %s", err)
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return newError("list_dir: %s", err)
+	}
+	sort.Strings(names)
+	elements := make([]object.Object, len(names))
+	for i, name := range names {
+		elements[i] = &object.String{Value: name}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// mkdir(path) crea el directorio junto con los directorios padres
+// que falten.
+func makeDir(args ...object.Object) object.Object {
+	path, errObj := singleString("mkdir", args)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return newError("mkdir: %s", err)
+	}
+	return NULL
+}
+
+// remove(path) elimina un archivo o un directorio vacío.
+// remove(set, value) es la operación sobre SET (ver setRemove).
+func removePath(args ...object.Object) object.Object {
+	if len(args) > 0 && args[0].Type() == object.SET_OBJ {
+		return setRemove(args...)
+	}
+	path, errObj := singleString("remove", args)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.Remove(path); err != nil {
+		return newError("remove: %s", err)
+	}
+	return NULL
+}
+
+// restrictedRemove es remove sin acceso a archivos: solo admite SET. El
+// error es nuevo en cada llamada, porque el evaluador le agrega la
+// posición y la pila de llamadas.
+func restrictedRemove(denied *object.Error) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) > 0 && args[0].Type() == object.SET_OBJ {
+			return setRemove(args...)
+		}
+		return newError("%s", denied.Message)
+	}
+}
+
+// path["join"](parts...) une las partes con el separador del sistema.
+func pathJoin(args ...object.Object) object.Object {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return newError("arguments to `path.join` must be STRING, got %s", arg.Type())
+		}
+		parts[i] = str.Value
+	}
+	return &object.String{Value: filepath.Join(parts...)}
+}
+
+// path["basename"](path) retorna el último elemento de la ruta.
+func pathBasename(args ...object.Object) object.Object {
+	path, errObj := singleString("path.basename", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: filepath.Base(path)}
+}
+
+// path["dirname"](path) retorna la ruta sin su último elemento.
+func pathDirname(args ...object.Object) object.Object {
+	path, errObj := singleString("path.dirname", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: filepath.Dir(path)}
+}
+
+// path["ext"](path) retorna la extensión del archivo, incluyendo el punto.
+func pathExt(args ...object.Object) object.Object {
+	path, errObj := singleString("path.ext", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: filepath.Ext(path)}
+}
+
+// Valida los argumentos (path, contents) de los builtins de escritura.
+func pathAndContents(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return "", "", newError("first argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	contents, ok := args[1].(*object.String)
+	if !ok {
+		return "", "", newError("second argument to `%s` must be STRING, got %s", name, args[1].Type())
+	}
+	return path.Value, contents.Value, nil
+}
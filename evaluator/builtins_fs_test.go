@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`file_exists("` + path + `")`, false},
+		{`write_file("` + path + `", "hola")`, nil},
+		{`file_exists("` + path + `")`, true},
+		{`append_file("` + path + `", " mundo")`, nil},
+		{`read_file("` + path + `")`, "hola mundo"},
+		{`read_file(1)`, "argument to `read_file` must be STRING, got INTEGER"},
+		{`write_file("` + path + `")`, "wrong number of arguments. got=1, want=2"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong contents. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("unexpected object. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestFileBuiltinsSandbox(t *testing.T) {
+	Sandbox = true
+	defer func() { Sandbox = false }()
+
+	evaluated := testEval(`read_file("/etc/hostname")`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "`read_file` is disabled in sandbox mode" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestDirectoryBuiltins(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	input := `
+let dir = "` + dir + `";
+mkdir(dir);
+write_file(path["join"](dir, "uno.txt"), "1");
+write_file(path["join"](dir, "dos.mk"), "2");
+let before = list_dir(dir);
+remove(path["join"](dir, "uno.txt"));
+[before, list_dir(dir)];
+`
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if got := result.Elements[0].Inspect(); got != "[dos.mk, uno.txt]" {
+		t.Errorf("wrong listing before remove. got=%s", got)
+	}
+	if got := result.Elements[1].Inspect(); got != "[dos.mk]" {
+		t.Errorf("wrong listing after remove. got=%s", got)
+	}
+}
+
+func TestPathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`path["join"]("a", "b", "c.mk")`, filepath.Join("a", "b", "c.mk")},
+		{`path["basename"]("/tmp/script.mk")`, "script.mk"},
+		{`path["dirname"]("/tmp/script.mk")`, "/tmp"},
+		{`path["ext"]("/tmp/script.mk")`, ".mk"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong value for %s. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
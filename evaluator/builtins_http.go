@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"monkey/object"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+func init() {
+	RegisterBuiltin("serve", serve, CapNet)
+}
+
+// serve(port, handler) levanta un servidor HTTP que atiende cada petición
+// llamando a handler con un HASH {method, path, query, headers, body}.
+// handler debe retornar un HASH {status, headers, body} o un STRING
+// que se usa como body con status 200. La llamada bloquea mientras el
+// servidor esté activo.
+func serve(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	port, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("first argument to `serve` must be INTEGER, got %s", args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin, object.Callable:
+	default:
+		return newError("second argument to `serve` must be FUNCTION, got %s", args[1].Type())
+	}
+	addr := ":" + strconv.FormatInt(port.Value, 10)
+	if err := http.ListenAndServe(addr, newHTTPHandler(args[1])); err != nil {
+		return newError("serve: %s", err)
+	}
+	return NULL
+}
+
+// httpHandler adapta una función Monkey a http.Handler. El evaluador no
+// es seguro para usarse desde varias goroutines (los Environment son
+// maps sin sincronizar), así que las peticiones se atienden de una en una.
+type httpHandler struct {
+	mu sync.Mutex
+	fn object.Object
+}
+
+func newHTTPHandler(fn object.Object) *httpHandler {
+	return &httpHandler{fn: fn}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request := newStringHash(map[string]object.Object{
+		"method":  &object.String{Value: r.Method},
+		"path":    &object.String{Value: r.URL.Path},
+		"query":   &object.String{Value: r.URL.RawQuery},
+		"headers": headersToHash(r.Header),
+		"body":    &object.String{Value: string(body)},
+	})
+
+	h.mu.Lock()
+	result := applyFunction(h.fn, []object.Object{request})
+	h.mu.Unlock()
+
+	writeHTTPResponse(w, result)
+}
+
+func headersToHash(header http.Header) *object.Hash {
+	members := make(map[string]object.Object, len(header))
+	for name := range header {
+		members[name] = &object.String{Value: header.Get(name)}
+	}
+	return newStringHash(members)
+}
+
+func writeHTTPResponse(w http.ResponseWriter, result object.Object) {
+	switch result := result.(type) {
+	case *object.Error:
+		http.Error(w, result.Message, http.StatusInternalServerError)
+	case *object.String:
+		io.WriteString(w, result.Value)
+	case *object.Hash:
+		if headers, ok := hashGet(result, "headers"); ok {
+			if headers, ok := headers.(*object.Hash); ok {
+				for _, pair := range headers.OrderedPairs() {
+					w.Header().Set(inspectString(pair.Key), inspectString(pair.Value))
+				}
+			}
+		}
+		status := http.StatusOK
+		if s, ok := hashGet(result, "status"); ok {
+			code, ok := s.(*object.Integer)
+			if !ok {
+				http.Error(w, fmt.Sprintf("response status must be INTEGER, got %s", s.Type()), http.StatusInternalServerError)
+				return
+			}
+			status = int(code.Value)
+		}
+		w.WriteHeader(status)
+		if body, ok := hashGet(result, "body"); ok {
+			io.WriteString(w, inspectString(body))
+		}
+	default:
+		http.Error(w, fmt.Sprintf("handler must return HASH or STRING, got %s", typeOf(result)), http.StatusInternalServerError)
+	}
+}
+
+// Retorna el valor de un STRING sin adornos o el Inspect() de cualquier
+// otro objeto.
+func inspectString(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return str.Value
+	}
+	return obj.Inspect()
+}
+
+// Retorna el tipo del objeto tolerando nil (funciones sin valor de retorno).
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
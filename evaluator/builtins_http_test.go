@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	tests := []struct {
+		handler        string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			`fn(req) { req["method"] + " " + req["path"] + "?" + req["query"] + " " + req["body"] }`,
+			200,
+			"POST /hola?x=1 monkey",
+		},
+		{
+			`fn(req) { {"status": 201, "headers": {"X-Monkey": "si"}, "body": len(req["body"])} }`,
+			201,
+			"6",
+		},
+		{
+			`fn(req) { 1 + true }`,
+			500,
+			"type mismatch: INTEGER + BOOLEAN\n",
+		},
+		{
+			`fn(req) { 5 }`,
+			500,
+			"handler must return HASH or STRING, got INTEGER\n",
+		},
+	}
+	for _, tt := range tests {
+		handler := newHTTPHandler(testEval(tt.handler))
+		req := httptest.NewRequest("POST", "/hola?x=1", strings.NewReader("monkey"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.expectedStatus {
+			t.Errorf("wrong status for %s. expected=%d, got=%d", tt.handler, tt.expectedStatus, rec.Code)
+		}
+		if rec.Body.String() != tt.expectedBody {
+			t.Errorf("wrong body for %s. expected=%q, got=%q", tt.handler, tt.expectedBody, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["range"] = &object.Builtin{Fn: rangeBuiltin}
+	builtins["enumerate"] = &object.Builtin{Fn: enumerate}
+	builtins["zip"] = &object.Builtin{Fn: zip}
+}
+
+// maxRangeLength es la cantidad máxima de elementos que retorna range.
+const maxRangeLength = 1 << 24
+
+// range(stop), range(start, stop) o range(start, stop, step) retorna un
+// ARRAY con los enteros desde start (inclusive) hasta stop (exclusive).
+func rangeBuiltin(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+	}
+	values := make([]int64, len(args))
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newError("arguments to `range` must be INTEGER, got %s", arg.Type())
+		}
+		values[i] = integer.Value
+	}
+	var start, stop, step int64 = 0, 0, 1
+	switch len(values) {
+	case 1:
+		stop = values[0]
+	case 2:
+		start, stop = values[0], values[1]
+	case 3:
+		start, stop, step = values[0], values[1], values[2]
+	}
+	if step == 0 {
+		return newError("`range` step must not be zero")
+	}
+	// La cantidad se calcula antes, sin signo, porque i += step puede
+	// pasarse de los límites de int64 y dar la vuelta.
+	var count uint64
+	if step > 0 && start < stop {
+		count = (uint64(stop)-uint64(start)-1)/uint64(step) + 1
+	} else if step < 0 && start > stop {
+		count = (uint64(start)-uint64(stop)-1)/(-uint64(step)) + 1
+	}
+	if count > maxRangeLength {
+		return newError("`range` would have %d elements, more than %d", count, maxRangeLength)
+	}
+	elements := make([]object.Object, count)
+	for i := range elements {
+		elements[i] = object.NewInteger(start + int64(i)*step)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// enumerate(xs) retorna un ARRAY de pares [índice, elemento] para
+// cualquier objeto iterable.
+func enumerate(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	it, errObj := iterate("enumerate", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	pairs := []object.Object{}
+	for i := int64(0); ; i++ {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		if isError(el) {
+			return el
+		}
+		pairs = append(pairs, &object.Array{Elements: []object.Object{object.NewInteger(i), el}})
+	}
+	return &object.Array{Elements: pairs}
+}
+
+// zip(a, b) retorna un ARRAY de pares [a[i], b[i]] tan largo como el
+// más corto de los dos iterables.
+func zip(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, errObj := iterate("zip", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	b, errObj := iterate("zip", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	pairs := []object.Object{}
+	for {
+		x, ok := a.Next()
+		if !ok {
+			break
+		}
+		if isError(x) {
+			return x
+		}
+		y, ok := b.Next()
+		if !ok {
+			break
+		}
+		if isError(y) {
+			return y
+		}
+		pairs = append(pairs, &object.Array{Elements: []object.Object{x, y}})
+	}
+	return &object.Array{Elements: pairs}
+}
+
+// iterate obtiene el iterador de obj. Todos los builtins que recorren
+// colecciones pasan por aquí para que la semántica de iteración sea la
+// misma en todo el lenguaje.
+func iterate(name string, obj object.Object) (object.Iterator, *object.Error) {
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return nil, newError("argument to `%s` must be iterable, got %s", name, obj.Type())
+	}
+	return iterable.Iterator(), nil
+}
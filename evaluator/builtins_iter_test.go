@@ -0,0 +1,45 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestIterationBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`range(4)`, "[0, 1, 2, 3]"},
+		{`range(2, 5)`, "[2, 3, 4]"},
+		{`range(0, 10, 3)`, "[0, 3, 6, 9]"},
+		{`range(5, 0, -2)`, "[5, 3, 1]"},
+		{`range(3, 1)`, "[]"},
+		{`range(9223372036854775800, 9223372036854775807, 5)`, "[9223372036854775800, 9223372036854775805]"},
+		{`range(-9223372036854775807 - 1, -9223372036854775800, 4)`, "[-9223372036854775808, -9223372036854775804]"},
+		{`range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1)`, "[9223372036854775807, -1]"},
+		{`range(9223372036854775807)`, "`range` would have 9223372036854775807 elements, more than 16777216"},
+		{`range(1, 2, 0)`, "`range` step must not be zero"},
+		{`range("a")`, "arguments to `range` must be INTEGER, got STRING"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{`enumerate([])`, "[]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip([1], 2)`, "argument to `zip` must be iterable, got INTEGER"},
+		{`enumerate("ab")`, "[[0, a], [1, b]]"},
+		{`zip("abc", range(2))`, "[[a, 0], [b, 1]]"},
+		{`enumerate({"k": 1})`, "[[0, k]]"},
+		{`enumerate(set([7]))`, "[[0, 7]]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
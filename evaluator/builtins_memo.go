@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func init() {
+	builtins["memo"] = callerBuiltin(memo)
+}
+
+// memo(fn) retorna una función que se comporta como fn pero guarda el
+// resultado de cada combinación de argumentos. Solo tiene sentido con
+// funciones puras. Las llamadas con argumentos que no sirven como llave
+// de hash (arrays, funciones...) y las que terminan en error no se guardan.
+//
+//	let fib = memo(fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } });
+func memo(_ *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fn := args[0]
+	if fn.Type() != object.FUNCTION_OBJ && fn.Type() != object.BUILTIN_OBJ {
+		return newError("argument to `memo` must be FUNCTION, got %s", fn.Type())
+	}
+
+	var mu sync.Mutex
+	cache := make(map[string]object.Object)
+	return callerBuiltin(func(caller *object.Environment, args ...object.Object) object.Object {
+		key, ok := memoKey(args)
+		if !ok {
+			return callFunction(fn, args, caller)
+		}
+		mu.Lock()
+		result, found := cache[key]
+		mu.Unlock()
+		if found {
+			return result
+		}
+		// Sin el candado durante la llamada: una función recursiva vuelve
+		// a entrar aquí antes de terminar.
+		result = callFunction(fn, args, caller)
+		if !isError(result) {
+			mu.Lock()
+			cache[key] = result
+			mu.Unlock()
+		}
+		return result
+	})
+}
+
+// memoKey combina los HashKey de los argumentos en una sola llave.
+func memoKey(args []object.Object) (string, bool) {
+	var b strings.Builder
+	for _, arg := range args {
+		hashable, ok := arg.(object.Hashable)
+		if !ok {
+			return "", false
+		}
+		key := hashable.HashKey()
+		b.WriteString(string(key.Type))
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatUint(key.Value, 16))
+		b.WriteByte(',')
+	}
+	return b.String(), true
+}
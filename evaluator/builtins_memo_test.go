@@ -0,0 +1,44 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestMemoBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// Sin memo esta llamada tardaría muchísimo.
+		{`let fib = memo(fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }); fib(80)`, 23416728348467685},
+		{`let calls = atomic();
+let sq = memo(fn(x) { atomic_add(calls, 1); x * x });
+sq(4) + sq(4) + sq(5);
+atomic_load(calls)`, 2},
+		{`let calls = atomic();
+let fib = memo(fn(n) { atomic_add(calls, 1); if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } });
+fib(30);
+atomic_load(calls)`, 31},
+		{`let add = memo(fn(a, b) { a + b }); add(1, 2) + add(2, 1)`, 6},
+		// Argumentos que no son llaves de hash se pasan sin guardar.
+		{`let first2 = memo(fn(xs) { first(xs) }); first2([7]) + first2([8])`, 15},
+		{`memo(1)`, "argument to `memo` must be FUNCTION, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["clone"] = &object.Builtin{Fn: clone}
+	builtins["freeze"] = &object.Builtin{Fn: freeze}
+	builtins["is_frozen"] = &object.Builtin{Fn: isFrozen}
+	builtins["keys"] = &object.Builtin{Fn: hashKeys}
+	builtins["values"] = &object.Builtin{Fn: hashValues}
+}
+
+// clone(obj) retorna una copia profunda de ARRAY, HASH y SET. La copia
+// nunca está congelada aunque el original sí lo esté. Las
+// funciones se comparten (su Environment no se copia) y el resto de
+// valores son inmutables, así que se retornan tal cual.
+func clone(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return deepCopy(args[0])
+}
+
+func deepCopy(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Array:
+		elements := make([]object.Object, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = deepCopy(el)
+		}
+		return &object.Array{Elements: elements}
+	case *object.Hash:
+		hash := object.NewHash()
+		for _, key := range obj.Keys {
+			pair := obj.Pairs[key]
+			hash.Set(key, object.HashPair{Key: pair.Key, Value: deepCopy(pair.Value)})
+		}
+		return hash
+	case *object.Set:
+		return copySet(obj)
+	default:
+		return obj
+	}
+}
+
+// freeze(obj) marca un ARRAY o HASH como inmutable y lo retorna. Las
+// operaciones que lo modificarían (como push) retornan un error.
+// El congelamiento no es recursivo. Un valor ya congelado no se toca,
+// porque puede ser compartido por otros intérpretes, como los módulos.
+func freeze(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch obj := args[0].(type) {
+	case *object.Array:
+		if !obj.Frozen {
+			obj.Frozen = true
+		}
+	case *object.Hash:
+		if !obj.Frozen {
+			obj.Frozen = true
+		}
+	default:
+		return newError("argument to `freeze` must be ARRAY or HASH, got %s", args[0].Type())
+	}
+	return args[0]
+}
+
+// is_frozen(obj) retorna true si obj fue congelado con freeze.
+func isFrozen(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch obj := args[0].(type) {
+	case *object.Array:
+		return nativeBoolToBooleanObject(obj.Frozen)
+	case *object.Hash:
+		return nativeBoolToBooleanObject(obj.Frozen)
+	default:
+		return FALSE
+	}
+}
+
+// keys(hash) retorna un ARRAY con las llaves en orden de inserción.
+func hashKeys(args ...object.Object) object.Object {
+	hash, errObj := singleHash("keys", args)
+	if errObj != nil {
+		return errObj
+	}
+	elements := make([]object.Object, len(hash.Keys))
+	for i, pair := range hash.OrderedPairs() {
+		elements[i] = pair.Key
+	}
+	return &object.Array{Elements: elements}
+}
+
+// values(hash) retorna un ARRAY con los valores en orden de inserción.
+func hashValues(args ...object.Object) object.Object {
+	hash, errObj := singleHash("values", args)
+	if errObj != nil {
+		return errObj
+	}
+	elements := make([]object.Object, len(hash.Keys))
+	for i, pair := range hash.OrderedPairs() {
+		elements[i] = pair.Value
+	}
+	return &object.Array{Elements: elements}
+}
+
+func singleHash(name string, args []object.Object) (*object.Hash, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+	return hash, nil
+}
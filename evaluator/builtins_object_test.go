@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestCloneBuiltin(t *testing.T) {
+	evaluated := testEval(`
+let original = {"a": [1, [2, 3]], "b": set([1])};
+let copy = clone(original);
+[original, copy];
+`)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	original := result.Elements[0].(*object.Hash)
+	copy := result.Elements[1].(*object.Hash)
+	if !objectsEqual(original, copy) {
+		t.Fatalf("clone is not equal to original. got=%s", copy.Inspect())
+	}
+
+	key := (&object.String{Value: "a"}).HashKey()
+	origArr := original.Pairs[key].Value.(*object.Array)
+	copyArr := copy.Pairs[key].Value.(*object.Array)
+	if origArr == copyArr {
+		t.Errorf("nested array was not copied")
+	}
+	if origArr.Elements[1] == copyArr.Elements[1] {
+		t.Errorf("deeply nested array was not copied")
+	}
+
+	fn := testEval(`let f = fn(x) { x }; clone(f) == f`)
+	testBooleanObject(t, fn, true)
+}
+
+func TestFreezeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`is_frozen([1])`, false},
+		{`is_frozen(freeze([1]))`, true},
+		{`is_frozen(freeze({"a": 1}))`, true},
+		{`let a = [1]; freeze(a); is_frozen(a)`, true},
+		{`is_frozen(clone(freeze([1])))`, false},
+		{`is_frozen(1)`, false},
+		{`push(freeze([1]), 2)`, "cannot push to frozen ARRAY"},
+		{`freeze(1)`, "argument to `freeze` must be ARRAY or HASH, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, 10: 3, true: 4}`, "{z: 1, a: 2, 10: 3, true: 4}"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`keys({"z": 1, "a": 2, "m": 3})`, "[z, a, m]"},
+		{`values({"z": 1, "a": 2, "m": 3})`, "[1, 2, 3]"},
+		{`enumerate({"y": 1, "x": 2})`, "[[0, y], [1, x]]"},
+		{`clone({"y": 1, "x": 2})`, "{y: 1, x: 2}"},
+		{`csv_parse("b,a
+1,2
+", true)`, "[{b: 1, a: 2}]"},
+		{`keys([1])`, "argument to `keys` must be HASH, got ARRAY"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package evaluator
+
+import "monkey/object"
+
+// NONE es el único valor none(); como NULL, no hace falta crear uno nuevo
+// cada vez.
+var NONE = &object.Option{}
+
+func init() {
+	builtins["some"] = &object.Builtin{Fn: some}
+	builtins["none"] = &object.Builtin{Fn: none}
+	builtins["is_some"] = &object.Builtin{Fn: isSome}
+	builtins["is_none"] = &object.Builtin{Fn: isNone}
+	builtins["unwrap"] = &object.Builtin{Fn: unwrap}
+	builtins["unwrap_or"] = &object.Builtin{Fn: unwrapOr}
+}
+
+// some(x) retorna un OPTION con el valor x.
+func some(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return &object.Option{Value: args[0]}
+}
+
+// none() retorna el OPTION sin valor.
+func none(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return NONE
+}
+
+// optionArg retorna el OPTION en args[0] para el builtin name, que recibe
+// want argumentos.
+func optionArg(name string, want int, args []object.Object) (*object.Option, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	opt, ok := args[0].(*object.Option)
+	if !ok {
+		return nil, newError("argument to `%s` must be OPTION, got %s", name, args[0].Type())
+	}
+	return opt, nil
+}
+
+// is_some(o) indica si o tiene un valor.
+func isSome(args ...object.Object) object.Object {
+	opt, errObj := optionArg("is_some", 1, args)
+	if errObj != nil {
+		return errObj
+	}
+	return nativeBoolToBooleanObject(opt.IsSome())
+}
+
+// is_none(o) indica si o no tiene valor.
+func isNone(args ...object.Object) object.Object {
+	opt, errObj := optionArg("is_none", 1, args)
+	if errObj != nil {
+		return errObj
+	}
+	return nativeBoolToBooleanObject(!opt.IsSome())
+}
+
+// unwrap(o) retorna el valor de o, o un error si o es none().
+func unwrap(args ...object.Object) object.Object {
+	opt, errObj := optionArg("unwrap", 1, args)
+	if errObj != nil {
+		return errObj
+	}
+	if !opt.IsSome() {
+		return newError("unwrap of none")
+	}
+	return opt.Value
+}
+
+// unwrap_or(o, default) retorna el valor de o, o default si o es none().
+func unwrapOr(args ...object.Object) object.Object {
+	opt, errObj := optionArg("unwrap_or", 2, args)
+	if errObj != nil {
+		return errObj
+	}
+	if !opt.IsSome() {
+		return args[1]
+	}
+	return opt.Value
+}
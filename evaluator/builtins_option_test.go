@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestOptionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`some(5)`, "some(5)"},
+		{`some(some("a"))`, "some(some(a))"},
+		{`none()`, "none"},
+		{`is_some(some(1))`, true},
+		{`is_some(none())`, false},
+		{`is_none(none())`, true},
+		{`unwrap(some(7))`, 7},
+		{`unwrap(none())`, "unwrap of none"},
+		{`unwrap_or(none(), 3)`, 3},
+		{`unwrap_or(some(1), 3)`, 1},
+		{`let first = fn(xs) { if (len(xs) > 0) { some(xs[0]) } else { none() } }; unwrap_or(first([]), -1)`, -1},
+		{`assert_eq(some([1, 2]), some([1, 2]))`, "null"},
+		{`assert_eq(some(1), none())`, "assertion failed: some(1) (OPTION) != none (OPTION)"},
+		{`unwrap(5)`, "argument to `unwrap` must be OPTION, got INTEGER"},
+		{`some()`, "wrong number of arguments. got=0, want=1"},
+		{`none(1)`, "wrong number of arguments. got=1, want=0"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
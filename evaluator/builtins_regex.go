@@ -0,0 +1,137 @@
+package evaluator
+
+import (
+	"container/list"
+	"monkey/object"
+	"regexp"
+	"sync"
+)
+
+// regexCacheSize es la cantidad de patrones compilados que se guardan.
+const regexCacheSize = 256
+
+// Caché de expresiones regulares compiladas. Los scripts suelen usar el
+// mismo patrón dentro de un ciclo, así que cada patrón se compila una sola
+// vez. Un script que arma patrones distintos no la hace crecer sin límite:
+// se descarta el patrón usado hace más tiempo.
+var regexCache = newRegexLRU(regexCacheSize)
+
+// regexLRU guarda los últimos size patrones usados.
+type regexLRU struct {
+	mu   sync.Mutex
+	size int
+	// order tiene los patrones del más al menos usado recientemente.
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type regexEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexLRU(size int) *regexLRU {
+	return &regexLRU{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get retorna el patrón compilado, si está guardado, y lo marca como el
+// usado más recientemente.
+func (c *regexLRU) get(pattern string) (*regexp.Regexp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[pattern]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*regexEntry).re, true
+}
+
+// add guarda re, descartando el patrón menos usado si ya hay size.
+func (c *regexLRU) add(pattern string, re *regexp.Regexp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[pattern] = c.order.PushFront(&regexEntry{pattern, re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexEntry).pattern)
+	}
+}
+
+func init() {
+	builtins["regex_match"] = &object.Builtin{Fn: regexMatch}
+	builtins["regex_find_all"] = &object.Builtin{Fn: regexFindAll}
+	builtins["regex_replace"] = &object.Builtin{Fn: regexReplace}
+}
+
+// Retorna el patrón compilado, compilándolo la primera vez que se usa.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.add(pattern, re)
+	return re, nil
+}
+
+// Valida los argumentos de los builtins regex_*: el patrón seguido de
+// want-1 argumentos STRING.
+func regexArgs(name string, want int, args []object.Object) (*regexp.Regexp, []string, *object.Error) {
+	if len(args) != want {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, nil, newError("arguments to `%s` must be STRING, got %s", name, arg.Type())
+		}
+		strs[i] = str.Value
+	}
+	re, err := compileRegex(strs[0])
+	if err != nil {
+		return nil, nil, newError("%s: %s", name, err)
+	}
+	return re, strs[1:], nil
+}
+
+// regex_match(pattern, s) retorna true si s contiene alguna coincidencia.
+func regexMatch(args ...object.Object) object.Object {
+	re, strs, errObj := regexArgs("regex_match", 2, args)
+	if errObj != nil {
+		return errObj
+	}
+	return nativeBoolToBooleanObject(re.MatchString(strs[0]))
+}
+
+// regex_find_all(pattern, s) retorna un ARRAY con todas las coincidencias.
+func regexFindAll(args ...object.Object) object.Object {
+	re, strs, errObj := regexArgs("regex_find_all", 2, args)
+	if errObj != nil {
+		return errObj
+	}
+	matches := re.FindAllString(strs[0], -1)
+	elements := make([]object.Object, len(matches))
+	for i, m := range matches {
+		elements[i] = &object.String{Value: m}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// regex_replace(pattern, s, repl) reemplaza todas las coincidencias.
+// repl puede usar $1, ${name}, etc. para referirse a los grupos.
+func regexReplace(args ...object.Object) object.Object {
+	re, strs, errObj := regexArgs("regex_replace", 3, args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: re.ReplaceAllString(strs[0], strs[1])}
+}
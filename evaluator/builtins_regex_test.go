@@ -0,0 +1,68 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"regexp"
+	"testing"
+)
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`regex_match("^[a-z]+$", "monkey")`, true},
+		{`regex_match("^[a-z]+$", "Monkey")`, false},
+		{`regex_find_all("[0-9]+", "a1 b22 c333")`, "[1, 22, 333]"},
+		{`regex_find_all("x", "abc")`, "[]"},
+		{`regex_replace("([a-z]+)@([a-z]+)", "yo@casa", "$2 de $1")`, "casa de yo"},
+		{`regex_match("(", "x")`, "regex_match: error parsing regexp: missing closing ): `(`"},
+		{`regex_match("a", 1)`, "arguments to `regex_match` must be STRING, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+
+	if _, ok := regexCache.get("[0-9]+"); !ok {
+		t.Errorf("pattern was not cached")
+	}
+}
+
+func TestRegexLRU(t *testing.T) {
+	cache := newRegexLRU(2)
+	for _, pattern := range []string{"a", "b", "a", "c"} {
+		if _, ok := cache.get(pattern); !ok {
+			cache.add(pattern, regexp.MustCompile(pattern))
+		}
+	}
+	tests := []struct {
+		pattern string
+		cached  bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := cache.get(tt.pattern); ok != tt.cached {
+			t.Errorf("wrong cache state for %q. want=%t, got=%t", tt.pattern, tt.cached, ok)
+		}
+	}
+	if cache.order.Len() != 2 || len(cache.entries) != 2 {
+		t.Errorf("cache grew past its size. got=%d", cache.order.Len())
+	}
+}
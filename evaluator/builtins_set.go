@@ -0,0 +1,160 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["set"] = &object.Builtin{Fn: newSet}
+	builtins["add"] = &object.Builtin{Fn: setAdd}
+	builtins["contains"] = &object.Builtin{Fn: setContains}
+	builtins["union"] = &object.Builtin{Fn: setUnion}
+	builtins["intersect"] = &object.Builtin{Fn: setIntersect}
+	builtins["difference"] = &object.Builtin{Fn: setDifference}
+}
+
+// Al igual que push, los builtins que modifican un SET retornan uno nuevo
+// y dejan intacto el original.
+
+// set() o set(xs) crea un SET con los elementos del iterable xs.
+func newSet(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	set := object.NewSet()
+	if len(args) == 0 {
+		return set
+	}
+	it, errObj := iterate("set", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	for {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		if isError(el) {
+			return el
+		}
+		key, ok := el.(object.Hashable)
+		if !ok {
+			return newError("unusable as set element: %s", el.Type())
+		}
+		set.Elements[key.HashKey()] = el
+	}
+	return set
+}
+
+// add(s, value) retorna un SET con value agregado.
+func setAdd(args ...object.Object) object.Object {
+	set, key, errObj := setAndElement("add", args)
+	if errObj != nil {
+		return errObj
+	}
+	result := copySet(set)
+	result.Elements[key] = args[1]
+	return result
+}
+
+// remove(s, value) retorna un SET sin value. Lo despacha removePath
+// cuando el primer argumento es un SET.
+func setRemove(args ...object.Object) object.Object {
+	set, key, errObj := setAndElement("remove", args)
+	if errObj != nil {
+		return errObj
+	}
+	result := copySet(set)
+	delete(result.Elements, key)
+	return result
+}
+
+// contains(s, value) retorna true si value pertenece a s.
+func setContains(args ...object.Object) object.Object {
+	set, key, errObj := setAndElement("contains", args)
+	if errObj != nil {
+		return errObj
+	}
+	_, ok := set.Elements[key]
+	return nativeBoolToBooleanObject(ok)
+}
+
+// union(a, b) retorna los elementos que están en a o en b.
+func setUnion(args ...object.Object) object.Object {
+	a, b, errObj := twoSets("union", args)
+	if errObj != nil {
+		return errObj
+	}
+	result := copySet(a)
+	for key, el := range b.Elements {
+		result.Elements[key] = el
+	}
+	return result
+}
+
+// intersect(a, b) retorna los elementos que están en a y en b.
+func setIntersect(args ...object.Object) object.Object {
+	a, b, errObj := twoSets("intersect", args)
+	if errObj != nil {
+		return errObj
+	}
+	result := object.NewSet()
+	for key, el := range a.Elements {
+		if _, ok := b.Elements[key]; ok {
+			result.Elements[key] = el
+		}
+	}
+	return result
+}
+
+// difference(a, b) retorna los elementos de a que no están en b.
+func setDifference(args ...object.Object) object.Object {
+	a, b, errObj := twoSets("difference", args)
+	if errObj != nil {
+		return errObj
+	}
+	result := object.NewSet()
+	for key, el := range a.Elements {
+		if _, ok := b.Elements[key]; !ok {
+			result.Elements[key] = el
+		}
+	}
+	return result
+}
+
+func copySet(set *object.Set) *object.Set {
+	result := object.NewSet()
+	for key, el := range set.Elements {
+		result.Elements[key] = el
+	}
+	return result
+}
+
+// Valida los argumentos (set, value) y calcula el HashKey de value.
+func setAndElement(name string, args []object.Object) (*object.Set, object.HashKey, *object.Error) {
+	if len(args) != 2 {
+		return nil, object.HashKey{}, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	set, ok := args[0].(*object.Set)
+	if !ok {
+		return nil, object.HashKey{}, newError("first argument to `%s` must be SET, got %s", name, args[0].Type())
+	}
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return nil, object.HashKey{}, newError("unusable as set element: %s", args[1].Type())
+	}
+	return set, key.HashKey(), nil
+}
+
+func twoSets(name string, args []object.Object) (*object.Set, *object.Set, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, ok := args[0].(*object.Set)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be SET, got %s", name, args[0].Type())
+	}
+	b, ok := args[1].(*object.Set)
+	if !ok {
+		return nil, nil, newError("second argument to `%s` must be SET, got %s", name, args[1].Type())
+	}
+	return a, b, nil
+}
@@ -0,0 +1,48 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestSetBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`set([3, 1, 2, 1])`, "set(1, 2, 3)"},
+		{`set()`, "set()"},
+		{`set("abca")`, "set(a, b, c)"},
+		{`set(1)`, "argument to `set` must be iterable, got INTEGER"},
+		{`len(set(["a", "b", "a"]))`, 2},
+		{`add(set([1]), "x")`, "set(1, x)"},
+		{`let s = set([1, 2]); add(s, 3); s`, "set(1, 2)"},
+		{`remove(set([1, 2, 3]), 2)`, "set(1, 3)"},
+		{`contains(set([1, 2]), 2)`, true},
+		{`contains(set([1, 2]), "2")`, false},
+		{`union(set([1, 2]), set([2, 3]))`, "set(1, 2, 3)"},
+		{`intersect(set([1, 2]), set([2, 3]))`, "set(2)"},
+		{`difference(set([1, 2]), set([2, 3]))`, "set(1)"},
+		{`set([[1]])`, "unusable as set element: ARRAY"},
+		{`union(set([1]), [1])`, "second argument to `union` must be SET, got ARRAY"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package evaluator
+
+import "monkey/object"
+
+func init() {
+	builtins["naturals"] = &object.Builtin{Fn: naturals}
+	builtins["lazy_map"] = callerBuiltin(lazyMap)
+	builtins["lazy_filter"] = callerBuiltin(lazyFilter)
+	builtins["take"] = &object.Builtin{Fn: take}
+	builtins["drop"] = &object.Builtin{Fn: drop}
+}
+
+// naturals() o naturals(start) retorna el STREAM infinito start, start+1, ...
+func naturals(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	start := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `naturals` must be INTEGER, got %s", args[0].Type())
+		}
+		start = integer.Value
+	}
+	return &object.Stream{Generate: func() object.Iterator {
+		n := start
+		return object.IteratorFunc(func() (object.Object, bool) {
+			obj := object.NewInteger(n)
+			n++
+			return obj, true
+		})
+	}}
+}
+
+// lazy_map(xs, fn) retorna un STREAM con fn aplicada a cada elemento de xs.
+// fn se invoca recién cuando se consume el elemento, con la profundidad de
+// la llamada a lazy_map.
+func lazyMap(caller *object.Environment, args ...object.Object) object.Object {
+	source, fn, errObj := iterableAndFunction("lazy_map", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Stream{Generate: func() object.Iterator {
+		it := source.Iterator()
+		return object.IteratorFunc(func() (object.Object, bool) {
+			el, ok := it.Next()
+			if !ok {
+				return nil, false
+			}
+			if isError(el) {
+				return el, true
+			}
+			return callFunction(fn, []object.Object{el}, caller), true
+		})
+	}}
+}
+
+// lazy_filter(xs, fn) retorna un STREAM con los elementos de xs para los
+// que fn retorna un valor verdadero.
+func lazyFilter(caller *object.Environment, args ...object.Object) object.Object {
+	source, fn, errObj := iterableAndFunction("lazy_filter", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Stream{Generate: func() object.Iterator {
+		it := source.Iterator()
+		return object.IteratorFunc(func() (object.Object, bool) {
+			for {
+				el, ok := it.Next()
+				if !ok {
+					return nil, false
+				}
+				if isError(el) {
+					return el, true
+				}
+				keep := callFunction(fn, []object.Object{el}, caller)
+				if isError(keep) {
+					return keep, true
+				}
+				if isTruthy(keep) {
+					return el, true
+				}
+			}
+		})
+	}}
+}
+
+// take(xs, n) retorna un ARRAY con los primeros n elementos de xs. Es la
+// forma de materializar un STREAM infinito.
+func take(args ...object.Object) object.Object {
+	source, n, errObj := iterableAndCount("take", args)
+	if errObj != nil {
+		return errObj
+	}
+	it := source.Iterator()
+	elements := []object.Object{}
+	for i := int64(0); i < n; i++ {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		if isError(el) {
+			return el
+		}
+		elements = append(elements, el)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// drop(xs, n) retorna un STREAM con los elementos de xs a partir del n-ésimo.
+func drop(args ...object.Object) object.Object {
+	source, n, errObj := iterableAndCount("drop", args)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Stream{Generate: func() object.Iterator {
+		it := source.Iterator()
+		skipped := false
+		return object.IteratorFunc(func() (object.Object, bool) {
+			if !skipped {
+				skipped = true
+				for i := int64(0); i < n; i++ {
+					el, ok := it.Next()
+					if !ok {
+						return nil, false
+					}
+					if isError(el) {
+						return el, true
+					}
+				}
+			}
+			return it.Next()
+		})
+	}}
+}
+
+func iterableAndFunction(name string, args []object.Object) (object.Iterable, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	source, ok := args[0].(object.Iterable)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be iterable, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin, object.Callable:
+	default:
+		return nil, nil, newError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return source, args[1], nil
+}
+
+func iterableAndCount(name string, args []object.Object) (object.Iterable, int64, *object.Error) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	source, ok := args[0].(object.Iterable)
+	if !ok {
+		return nil, 0, newError("first argument to `%s` must be iterable, got %s", name, args[0].Type())
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return nil, 0, newError("second argument to `%s` must be INTEGER, got %s", name, args[1].Type())
+	}
+	return source, n.Value, nil
+}
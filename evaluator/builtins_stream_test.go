@@ -0,0 +1,40 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestStreamBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`take(naturals(), 5)`, "[0, 1, 2, 3, 4]"},
+		{`take(naturals(10), 2)`, "[10, 11]"},
+		{`take(lazy_map(naturals(), fn(x) { x * x }), 4)`, "[0, 1, 4, 9]"},
+		{`take(lazy_filter(naturals(1), fn(x) { x / 2 * 2 == x }), 3)`, "[2, 4, 6]"},
+		{`take(drop(naturals(), 3), 2)`, "[3, 4]"},
+		{`take(drop([1, 2], 5), 2)`, "[]"},
+		{`take([1, 2, 3], 10)`, "[1, 2, 3]"},
+		{`let s = naturals(); take(s, 2); take(s, 2)`, "[0, 1]"},
+		{`enumerate(take(lazy_map("ab", fn(c) { c + c }), 5))`, "[[0, aa], [1, bb]]"},
+		{`take(lazy_map(naturals(), fn(x) { x + "a" }), 3)`, "type mismatch: INTEGER + STRING"},
+		{`enumerate(lazy_map([1], fn(x) { -"a" }))`, "unknown operator: -STRING"},
+		{`zip([1], lazy_map([1], fn(x) { -"a" }))`, "unknown operator: -STRING"},
+		{`take(1, 3)`, "first argument to `take` must be iterable, got INTEGER"},
+		{`lazy_map(naturals(), 1)`, "second argument to `lazy_map` must be FUNCTION, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
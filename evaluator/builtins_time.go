@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"time"
+)
+
+// Instante de referencia para clock(). time.Since usa el reloj monotónico.
+var clockStart = time.Now()
+
+func init() {
+	builtins["now"] = &object.Builtin{Fn: now}
+	builtins["clock"] = &object.Builtin{Fn: clock}
+	builtins["sleep"] = callerBuiltin(sleep)
+	builtins["format_time"] = &object.Builtin{Fn: formatTime}
+}
+
+// now() retorna la hora actual en milisegundos desde la época unix.
+func now(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Integer{Value: time.Now().UnixNano() / int64(time.Millisecond)}
+}
+
+// clock() retorna los nanosegundos transcurridos según un reloj monotónico.
+// Solo sirve para medir intervalos, no para obtener la hora.
+func clock(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Integer{Value: int64(time.Since(clockStart))}
+}
+
+// sleep(ms) detiene la ejecución durante ms milisegundos, o hasta que se
+// cancele.
+func sleep(caller *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	ms, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to `sleep` must be INTEGER, got %s", args[0].Type())
+	}
+	if ms.Value < 0 {
+		return newError("argument to `sleep` must not be negative, got %d", ms.Value)
+	}
+	timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+	defer timer.Stop()
+	ex := execOf(caller)
+	select {
+	case <-timer.C:
+		return NULL
+	case <-ex.cancelled():
+		return ex.cancelError()
+	}
+}
+
+// format_time(ts, layout) da formato a ts (milisegundos unix, hora local)
+// usando un layout de Go, por ejemplo "2006-01-02 15:04:05".
+func formatTime(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	ts, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("first argument to `format_time` must be INTEGER, got %s", args[0].Type())
+	}
+	layout, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `format_time` must be STRING, got %s", args[1].Type())
+	}
+	t := time.Unix(0, ts.Value*int64(time.Millisecond))
+	return &object.String{Value: t.Format(layout.Value)}
+}
@@ -0,0 +1,40 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimeBuiltins(t *testing.T) {
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	evaluated := testEval(`now()`)
+	after := time.Now().UnixNano() / int64(time.Millisecond)
+	ts, ok := evaluated.(*object.Integer)
+	if !ok {
+		t.Fatalf("object is not Integer. got=%T (%+v)", evaluated, evaluated)
+	}
+	if ts.Value < before || ts.Value > after {
+		t.Errorf("now() out of range. got=%d, want between %d and %d", ts.Value, before, after)
+	}
+
+	evaluated = testEval(`let start = clock(); sleep(5); clock() - start`)
+	elapsed, ok := evaluated.(*object.Integer)
+	if !ok {
+		t.Fatalf("object is not Integer. got=%T (%+v)", evaluated, evaluated)
+	}
+	if elapsed.Value < int64(5*time.Millisecond) {
+		t.Errorf("sleep(5) took less than 5ms. got=%dns", elapsed.Value)
+	}
+
+	ms := time.Date(2021, 5, 9, 10, 30, 0, 0, time.Local).UnixNano() / int64(time.Millisecond)
+	evaluated = testEval(`format_time(` + strconv.FormatInt(ms, 10) + `, "2006-01-02 15:04")`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "2021-05-09 10:30" {
+		t.Errorf("wrong format. got=%q", str.Value)
+	}
+}
@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+// Capability es un permiso sobre el sistema anfitrión que necesita un
+// builtin. Se combinan como flags: CapFS | CapEnv.
+type Capability uint
+
+const (
+	CapFS   Capability = 1 << iota // leer y escribir archivos
+	CapNet                         // abrir conexiones o servidores
+	CapExec                        // ejecutar comandos del sistema
+	CapEnv                         // leer y cambiar variables de entorno
+
+	// CapNone no permite ningún builtin que acceda al anfitrión.
+	CapNone Capability = 0
+	CapAll             = CapFS | CapNet | CapExec | CapEnv
+)
+
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CapFS, "fs"},
+	{CapNet, "net"},
+	{CapExec, "exec"},
+	{CapEnv, "env"},
+}
+
+func (c Capability) String() string {
+	names := []string{}
+	for _, cn := range capabilityNames {
+		if c&cn.cap != 0 {
+			names = append(names, cn.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Capacidades que necesita cada builtin. Los que no aparecen aquí no
+// acceden al anfitrión y están siempre disponibles.
+var builtinCapabilities = map[string]Capability{}
+
+// RegisterBuiltin agrega un builtin que necesita las capacidades
+// requires (CapNone si no accede al anfitrión). Reemplaza al builtin del
+// mismo nombre, si existe.
+func RegisterBuiltin(name string, fn object.BuiltinFunction, requires Capability) {
+	builtins[name] = &object.Builtin{Fn: fn}
+	delete(builtinArities, name)
+	if requires == CapNone {
+		delete(builtinCapabilities, name)
+	} else {
+		builtinCapabilities[name] = requires
+	}
+}
+
+// Versiones reducidas de builtins que también tienen un uso que no
+// accede al anfitrión, como remove(set, value). Se usan en lugar del
+// builtin cuando faltan sus capacidades; denied es el error a retornar
+// para el resto de los usos.
+var restrictedBuiltins = map[string]func(denied *object.Error) object.BuiltinFunction{}
+
+// BuiltinCapabilities retorna las capacidades que necesita el builtin
+// name y si el builtin existe.
+func BuiltinCapabilities(name string) (Capability, bool) {
+	if _, ok := builtins[name]; !ok {
+		return CapNone, false
+	}
+	return builtinCapabilities[name], true
+}
+
+// checkCapabilities retorna un error si la ejecución de env no puede usar
+// el builtin name. Con Options.Sandboxed mandan Options.Capabilities; si
+// no, las variables Sandbox y AllowExec del paquete.
+func checkCapabilities(name string, env *object.Environment) *object.Error {
+	required, ok := builtinCapabilities[name]
+	if !ok {
+		return nil
+	}
+	if ex, ok := env.Exec().(*execution); ok && ex.opts.Sandboxed {
+		if missing := required &^ ex.opts.Capabilities; missing != 0 {
+			return newError("`%s` requires the %s capability", name, missing)
+		}
+		return nil
+	}
+	if Sandbox {
+		return newError("`%s` is disabled in sandbox mode", name)
+	}
+	if required&CapExec != 0 && !AllowExec {
+		return newError("`%s` is disabled", name)
+	}
+	return nil
+}
+
+// lookupBuiltin retorna el builtin name tal como lo puede usar la
+// ejecución de env: el builtin, su versión reducida o el error que
+// explica qué capacidad falta.
+func lookupBuiltin(name string, env *object.Environment) (object.Object, bool) {
+	builtin, ok := builtins[name]
+	if !ok {
+		return nil, false
+	}
+	if errObj := checkCapabilities(name, env); errObj != nil {
+		if restricted, ok := restrictedBuiltins[name]; ok {
+			return &object.Builtin{Fn: restricted(errObj)}, true
+		}
+		return errObj, true
+	}
+	if ex, ok := env.Exec().(*execution); ok {
+		if fn, ok := ioBuiltins[name]; ok && (ex.opts.Stdout != nil || ex.opts.Stderr != nil || ex.opts.Stdin != nil) {
+			return &object.Builtin{Fn: fn(ex)}, true
+		}
+		if name == "args" && ex.opts.Args != nil {
+			return &object.Builtin{Fn: argsFrom(ex.opts.Args)}, true
+		}
+	}
+	return builtin, true
+}
+
+// BuiltinNames retorna los nombres de todos los builtins en orden
+// alfabético. El compilador y la VM identifican cada builtin por su
+// posición en esta lista.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupBuiltin retorna el builtin name para código que se ejecuta fuera
+// del evaluador, como la VM. Se aplican las variables Sandbox y AllowExec
+// del paquete: si el builtin no está permitido se retorna su versión
+// reducida o el *object.Error que lo explica.
+func LookupBuiltin(name string) (object.Object, bool) {
+	return lookupBuiltin(name, object.NewEnvironment())
+}
+
+// BuiltinLookup retorna un LookupBuiltin que aplica las opciones opts en
+// vez de las variables del paquete: Sandboxed, Capabilities, Stdout,
+// Stderr, Stdin, Args y Context. Los builtins que retorna comparten la entrada,
+// igual que los de una ejecución del evaluador, y con Context los que
+// esperan (sleep, recv, lock...) dejan de esperar cuando se cancela.
+func BuiltinLookup(opts Options) func(name string) (object.Object, bool) {
+	env := object.NewEnvironment().WithExec(newExecution(opts))
+	return func(name string) (object.Object, bool) {
+		obj, ok := lookupBuiltin(name, env)
+		// Quien llama a Fn no tiene un entorno: se usa el de opts.
+		if builtin, isBuiltin := obj.(*object.Builtin); isBuiltin && builtin.FromCaller != nil && opts.Context != nil {
+			fromCaller := builtin.FromCaller
+			obj = &object.Builtin{
+				Fn:         func(args ...object.Object) object.Object { return fromCaller(env, args...) },
+				FromCaller: fromCaller,
+			}
+		}
+		return obj, ok
+	}
+}
@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandboxedCapabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+
+	tests := []struct {
+		input    string
+		caps     Capability
+		expected string
+	}{
+		{`write_file("` + path + `", "hola")`, CapNone, "`write_file` requires the fs capability"},
+		{`write_file("` + path + `", "hola"); read_file("` + path + `")`, CapFS, "hola"},
+		{`env("HOME")`, CapFS, "`env` requires the env capability"},
+		{`exec("true")`, CapAll &^ CapExec, "`exec` requires the exec capability"},
+		{`let f = read_file; 1`, CapNone, "`read_file` requires the fs capability"},
+		{`lazy_map(["` + path + `"], read_file)`, CapNone, "`read_file` requires the fs capability"},
+		// remove sigue funcionando sobre SET sin capacidades.
+		{`remove(set([1, 2]), 2)`, CapNone, "set(1)"},
+		{`remove("` + path + `")`, CapNone, "`remove` requires the fs capability"},
+		{`len("abc")`, CapNone, "3"},
+	}
+	for _, tt := range tests {
+		evaluated := evalWithOptions(tt.input, Options{Sandboxed: true, Capabilities: tt.caps})
+		var got string
+		switch obj := evaluated.(type) {
+		case *object.Error:
+			got = obj.Message
+		case *object.String:
+			got = obj.Value
+		default:
+			got = evaluated.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%s (caps %s): expected=%q, got=%q", tt.input, tt.caps, tt.expected, got)
+		}
+	}
+}
+
+func TestSandboxedIgnoresGlobalSwitches(t *testing.T) {
+	Sandbox = true
+	defer func() { Sandbox = false }()
+
+	path := filepath.Join(t.TempDir(), "data.txt")
+	input := `write_file("` + path + `", "x"); file_exists("` + path + `")`
+	testBooleanObject(t, evalWithOptions(input, Options{Sandboxed: true, Capabilities: CapFS}), true)
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	RegisterBuiltin("host_secret", func(args ...object.Object) object.Object {
+		return &object.String{Value: "42"}
+	}, CapEnv)
+	defer delete(builtins, "host_secret")
+	defer delete(builtinCapabilities, "host_secret")
+
+	if caps, ok := BuiltinCapabilities("host_secret"); !ok || caps != CapEnv {
+		t.Errorf("wrong capabilities. got=%s, %t", caps, ok)
+	}
+	if caps, ok := BuiltinCapabilities("len"); !ok || caps != CapNone {
+		t.Errorf("wrong capabilities for len. got=%s, %t", caps, ok)
+	}
+	if _, ok := BuiltinCapabilities("nope"); ok {
+		t.Errorf("unknown builtin reported as registered")
+	}
+
+	evaluated := evalWithOptions(`host_secret()`, Options{Sandboxed: true})
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "`host_secret` requires the env capability" {
+		t.Errorf("expected capability error. got=%T (%+v)", evaluated, evaluated)
+	}
+	evaluated = evalWithOptions(`host_secret()`, Options{Sandboxed: true, Capabilities: CapEnv})
+	if str, ok := evaluated.(*object.String); !ok || str.Value != "42" {
+		t.Errorf("expected 42. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestCapabilityString(t *testing.T) {
+	tests := []struct {
+		caps     Capability
+		expected string
+	}{
+		{CapNone, "none"},
+		{CapFS | CapEnv, "fs|env"},
+		{CapAll, "fs|net|exec|env"},
+	}
+	for _, tt := range tests {
+		if got := tt.caps.String(); got != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
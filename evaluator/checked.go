@@ -0,0 +1,79 @@
+package evaluator
+
+import (
+	"math"
+	"monkey/object"
+)
+
+// evalCheckedInfixExpression evalúa +, - y * entre enteros detectando el
+// desbordamiento (ver Options.CheckedArithmetic). Retorna false si la
+// operación no es aritmética entera y debe evaluarse normalmente.
+func evalCheckedInfixExpression(operator string, left, right object.Object) (object.Object, bool) {
+	l, ok := left.(*object.Integer)
+	if !ok {
+		return nil, false
+	}
+	r, ok := right.(*object.Integer)
+	if !ok {
+		return nil, false
+	}
+	a, b := l.Value, r.Value
+	var result int64
+	var overflow bool
+	switch operator {
+	case "+":
+		result = a + b
+		overflow = (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b)
+	case "-":
+		result = a - b
+		overflow = (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b)
+	case "*":
+		result, overflow = mulOverflow(a, b)
+	case "**":
+		if b < 0 {
+			return nil, false
+		}
+		result, overflow = intPow(a, b)
+	default:
+		return nil, false
+	}
+	if overflow {
+		return newError("integer overflow: %d %s %d", a, operator, b), true
+	}
+	return object.NewInteger(result), true
+}
+
+// intPow calcula a elevado a b (b >= 0) por cuadrados sucesivos. Si el
+// resultado no cabe en un int64 retorna el valor truncado y true.
+func intPow(a, b int64) (int64, bool) {
+	result, overflow := int64(1), false
+	for b > 0 {
+		if b&1 == 1 {
+			r, o := mulOverflow(result, a)
+			result, overflow = r, overflow || o
+		}
+		b >>= 1
+		if b > 0 {
+			sq, o := mulOverflow(a, a)
+			a, overflow = sq, overflow || o
+		}
+	}
+	return result, overflow
+}
+
+func mulOverflow(a, b int64) (int64, bool) {
+	result := a * b
+	return result, a != 0 && (result/a != b || (a == -1 && b == math.MinInt64))
+}
+
+// evalCheckedPrefixExpression verifica el - unario: -MinInt64 no existe.
+func evalCheckedPrefixExpression(operator string, right object.Object) (object.Object, bool) {
+	integer, ok := right.(*object.Integer)
+	if !ok || operator != "-" {
+		return nil, false
+	}
+	if integer.Value == math.MinInt64 {
+		return newError("integer overflow: -(%d)", integer.Value), true
+	}
+	return object.NewInteger(-integer.Value), true
+}
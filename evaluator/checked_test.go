@@ -0,0 +1,50 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"let min = -9223372036854775807 - 1; min * -1", "integer overflow: -9223372036854775808 * -1"},
+		{"let min = -9223372036854775807 - 1; -min", "integer overflow: -(-9223372036854775808)"},
+		{"9223372036854775806 + 1", 9223372036854775807},
+		{"-4611686018427387904 * 2", -9223372036854775808},
+		{"3 * -4 + 10 - 1", -3},
+		{"10 / 3", 3},
+		{"(1 + 1 == 2) == true", true},
+		{"2 ** 62", 4611686018427387904},
+		{"2 ** 63", "integer overflow: 2 ** 63"},
+		{"3 ** 40", "integer overflow: 3 ** 40"},
+		{"(-2) ** 63", -9223372036854775808},
+		{"2 ** -1", "negative exponent: 2 ** -1"},
+	}
+	for _, tt := range tests {
+		evaluated := evalWithOptions(tt.input, Options{CheckedArithmetic: true})
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// Sin la opción el resultado da la vuelta como en Go.
+	testIntegerObject(t, testEval("9223372036854775807 + 1"), -9223372036854775808)
+}
@@ -7,9 +7,9 @@ import (
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+	NULL  = object.NULL
 )
 
 func newError(format string, a ...interface{}) *object.Error {
@@ -23,7 +23,32 @@ func newError(format string, a ...interface{}) *object.Error {
 
 // Evaluador de AST
 
+// Eval evalúa el nodo y, si el resultado es un error que todavía no tiene
+// posición, le asigna la del nodo. Como los errores suben desde el nodo más
+// interno, la posición que queda es la del lugar exacto donde se produjo.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	hooks := hooksOf(env)
+	if hooks != nil {
+		hooks.OnEnterNode(node, env)
+	}
+	var result object.Object
+	if errObj := checkExecution(env); errObj != nil {
+		result = errObj
+	} else {
+		result = eval(node, env)
+	}
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		if pos := node.Pos(); pos.IsValid() {
+			err.Line, err.Column = pos.Line, pos.Column
+		}
+	}
+	if hooks != nil {
+		hooks.OnExitNode(node, env, result)
+	}
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Sentencias
 	case *ast.Program:
@@ -35,7 +60,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Name.Binding != nil {
+			env.SetLocal(node.Name.Binding.Index, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 
@@ -51,13 +80,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Body: body, Env: env, Locals: node.Locals}
 	// Expresiones
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
+		if checkedArithmetic(env) {
+			if result, ok := evalCheckedPrefixExpression(node.Operator, right); ok {
+				return result
+			}
+		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
@@ -69,10 +103,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
+		if errObj := allocateConcatenation(env, left, right); errObj != nil {
+			return errObj
+		}
+		if checkedArithmetic(env) {
+			if result, ok := evalCheckedInfixExpression(node.Operator, left, right); ok {
+				return result
+			}
+		}
 		return evalInfixExpression(node.Operator, left, right)
 
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return object.NewInteger(node.Value)
 
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
@@ -89,7 +131,27 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		if len(node.Keywords) > 0 {
+			args = bindKeywordArguments(function, args, node.Keywords, env)
+			if len(args) == 1 && isError(args[0]) {
+				return args[0]
+			}
+		}
+		// Una llamada a una función en posición de cola la hace
+		// invokeFunction en lugar de la llamada que la contiene, salvo que
+		// haya hooks que esperen ver cada llamada. Los builtins se llaman
+		// aquí, como en la VM.
+		if fn, ok := function.(*object.Function); ok && node.Tail && hooksOf(env) == nil {
+			return &tailCall{fn: fn, args: args}
+		}
+		result := callFunction(function, args, env)
+		if err, ok := result.(*object.Error); ok && function.Type() == object.FUNCTION_OBJ {
+			addStackFrame(err, node)
+		}
+		if _, ok := function.(*object.Builtin); ok {
+			return allocateResult(env, result, args)
+		}
+		return result
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.ArrayLiteral:
@@ -97,7 +159,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return allocate(env, &object.Array{Elements: elements})
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -108,15 +170,45 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	// Nodos que el parser deja en lugar del código con errores.
+	case *ast.BadExpression, *ast.BadStatement:
+		return newError("invalid syntax: %s", node.TokenLiteral())
 	}
 	return nil
 }
 
+// Cantidad máxima de llamadas que se guardan en la pila de un error. En
+// una recursión muy profunda solo se conservan las más internas.
+const maxStackFrames = 64
+
+// Agrega a la pila del error la llamada a función que lo propagó.
+func addStackFrame(err *object.Error, call *ast.CallExpression) {
+	if len(err.Stack) >= maxStackFrames {
+		return
+	}
+	pos := call.Pos()
+	name := "<anonymous>"
+	switch fn := call.Function.(type) {
+	case *ast.Identifier:
+		name = fn.Value
+	case *ast.MemberExpression:
+		name = fn.Property.Value
+	}
+	err.Stack = append(err.Stack, object.StackFrame{Function: name, Line: pos.Line, Column: pos.Column})
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	hash := object.NewHash()
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -129,10 +221,9 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
-	return &object.Hash{Pairs: pairs}
+	return allocate(env, hash)
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
@@ -142,10 +233,32 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
+		if indexable, ok := left.(object.Indexable); ok {
+			if result := indexable.Index(index); result != nil {
+				return result
+			}
+			return NULL
+		}
 		return newError("index operator not supported: %s", left.Type())
 	}
 }
 
+// evalMemberExpression evalúa obj.name: el miembro de un object.HasMembers
+// o, en un hash, lo mismo que obj["name"].
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		return evalHashIndexExpression(obj, &object.String{Value: name})
+	case object.HasMembers:
+		if member, ok := obj.Member(name); ok {
+			return member
+		}
+		return newError("unknown member %s.%s", obj.Type(), name)
+	default:
+		return newError("member access not supported: %s", obj.Type())
+	}
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
@@ -168,20 +281,104 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	}
 	return arrayObject.Elements[idx]
 }
+
+// applyFunction llama a fn desde un builtin: la llamada usa el estado de
+// ejecución del entorno donde se definió fn y su profundidad empieza de
+// nuevo. Solo sirve para las funciones que corren en otra goroutine, con
+// su propia pila, o que llama el anfitrión; los builtins que llaman a fn
+// antes de retornar usan callerBuiltin.
 func applyFunction(fn object.Object, args []object.Object) object.Object {
+	return callFunction(fn, args, nil)
+}
+
+// callerBuiltin crea un builtin que recibe el entorno de la llamada, si lo
+// llama el evaluador, o nil. Las funciones que fn llame con callFunction y
+// ese entorno siguen su profundidad: una recursión que pasa por el builtin
+// (memo, lock, lazy_map...) termina con el error de MaxDepth en vez de
+// agotar la pila de Go.
+func callerBuiltin(fn func(caller *object.Environment, args ...object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{
+		Fn:         func(args ...object.Object) object.Object { return fn(nil, args...) },
+		FromCaller: fn,
+	}
+}
+
+// callFunction llama a fn desde el código en caller, cuyo estado de
+// ejecución pasa al entorno de la llamada. caller puede ser nil.
+func callFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
+	var hooks Hooks
+	if caller != nil {
+		hooks = hooksOf(caller)
+	} else if f, ok := fn.(*object.Function); ok {
+		hooks = hooksOf(f.Env)
+	}
+	if hooks == nil {
+		return invokeFunction(fn, args, caller)
+	}
+	hooks.OnCall(fn, args)
+	result := invokeFunction(fn, args, caller)
+	hooks.OnReturn(fn, result)
+	return result
+}
+
+func invokeFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		for {
+			// Los argumentos de más se ignoran.
+			if len(args) < len(fn.Parameters) {
+				return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+			}
+			extendedEnv := extendFunctionEnv(fn, args)
+			if caller != nil {
+				extendedEnv.SetExec(caller.Exec())
+				extendedEnv.SetDepth(caller.Depth() + 1)
+			}
+			if errObj := checkDepth(extendedEnv); errObj != nil {
+				return errObj
+			}
+			result := unwrapReturnValue(Eval(fn.Body, extendedEnv))
+			// La llamada en posición de cola reemplaza a esta, con la
+			// misma profundidad, como OpTailCall en la VM: una función
+			// que se llama a sí misma al final no agota MaxDepth.
+			call, ok := result.(*tailCall)
+			if !ok {
+				return result
+			}
+			fn, args = call.fn, call.args
+		}
 	case *object.Builtin:
+		if fn.FromCaller != nil && caller != nil {
+			return fn.FromCaller(caller, args...)
+		}
 		return fn.Fn(args...)
+	case object.Callable:
+		return fn.Call(args...)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+// tailCall es una llamada en posición de cola que todavía no se hizo. La
+// retorna Eval como resultado del cuerpo de una función, y la hace
+// invokeFunction.
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	if fn.Locals != nil {
+		// Los parámetros ocupan las primeras posiciones (ver Resolve).
+		env := object.NewFunctionEnvironment(fn.Env, fn.Locals)
+		for paramIdx := range fn.Parameters {
+			env.SetLocal(paramIdx, args[paramIdx])
+		}
+		return env
+	}
 	env := object.NewEnclosedEnvironment(fn.Env)
 	for paramIdx, param := range fn.Parameters {
 		env.Set(param.Value, args[paramIdx])
@@ -189,9 +386,58 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	return env
 }
 
+// bindKeywordArguments evalúa los argumentos con nombre y los pone, junto
+// a los posicionales args, en el orden de los parámetros de fn. Todos los
+// parámetros tienen que recibir un valor. Como en evalExpressions, un
+// error se retorna como único elemento.
+func bindKeywordArguments(fn object.Object, args []object.Object, keywords []*ast.KeywordArgument, env *object.Environment) []object.Object {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return []object.Object{newError("keyword arguments not supported by %s", fn.Type())}
+	}
+	bound := make([]object.Object, len(function.Parameters))
+	if len(args) > len(bound) {
+		bound = make([]object.Object, len(args))
+	}
+	copy(bound, args)
+	for _, arg := range keywords {
+		name := arg.Name.Value
+		idx := -1
+		// Si un parámetro se repite, vale el último, igual que en Resolve.
+		for i, param := range function.Parameters {
+			if param.Value == name {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return []object.Object{newError("unknown keyword argument: %s", name)}
+		}
+		if bound[idx] != nil {
+			return []object.Object{newError("multiple values for argument: %s", name)}
+		}
+		value := Eval(arg.Value, env)
+		if isError(value) {
+			return []object.Object{value}
+		}
+		bound[idx] = value
+	}
+	for i, param := range function.Parameters {
+		if bound[i] == nil {
+			return []object.Object{newError("missing argument: %s", param.Value)}
+		}
+	}
+	return bound
+}
+
+// unwrapReturnValue da el valor de una llamada a partir del resultado del
+// cuerpo. Una función de cuerpo vacío no produce nada y retorna null, como
+// en la VM.
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+		obj = returnValue.Value
+	}
+	if obj == nil {
+		return NULL
 	}
 	return obj
 }
@@ -209,13 +455,20 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	if b := node.Binding; b != nil {
+		if val, ok := env.GetLocal(b.Depth, b.Index, node.Value); ok {
+			return val
+		}
+	} else if val, ok := env.Get(node.Value); ok {
 		return val
 	}
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := lookupBuiltin(node.Value, env); ok {
 		return builtin
 	}
-	return newError("identifier not found: " + node.Value)
+	if module, ok := modules[node.Value]; ok {
+		return module
+	}
+	return newError("identifier not found: %s", node.Value)
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
@@ -224,7 +477,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		result = Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.EXIT_OBJ {
 				return result
 			}
 		}
@@ -232,14 +485,20 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// isError también detecta los objetos Exit ya que ambos deben
+// interrumpir la evaluación y propagarse sin cambios.
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		rt := obj.Type()
+		return rt == object.ERROR_OBJ || rt == object.EXIT_OBJ
 	}
 	return false
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	if ex, ok := env.Exec().(*execution); !ok || !ex.opts.Resolved {
+		Resolve(program)
+	}
 	var result object.Object
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
@@ -248,6 +507,8 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Exit:
+			return result
 		}
 	}
 	return result
@@ -258,13 +519,17 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	if isError(condition) {
 		return condition
 	}
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
+		result = Eval(ie.Alternative, env)
+	}
+	// Un bloque vacío no tiene valor; el if vale null, como en la VM.
+	if result == nil {
 		return NULL
 	}
+	return result
 }
 
 func isTruthy(obj object.Object) bool {
@@ -311,13 +576,22 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	rightVal := right.(*object.Integer).Value
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return object.NewInteger(leftVal + rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return object.NewInteger(leftVal - rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return object.NewInteger(leftVal * rightVal)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return object.NewInteger(leftVal / rightVal)
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d ** %d", leftVal, rightVal)
+		}
+		result, _ := intPow(leftVal, rightVal)
+		return object.NewInteger(result)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -347,7 +621,7 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 		return newError("unknown operator: -%s", right.Type())
 	}
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.NewInteger(-value)
 }
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
@@ -355,6 +629,8 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 		return FALSE
 	case FALSE:
 		return TRUE
+	case NULL:
+		return TRUE
 	default:
 		return FALSE
 	}
@@ -362,11 +638,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 // retorna un boolean nativo a *object.Boolean
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
-	if input {
-		return TRUE
-	} else {
-		return FALSE
-	}
+	return object.NativeBoolToBooleanObject(input)
 }
 
 // Evalúa el slice se sentencias de un programa.
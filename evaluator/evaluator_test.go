@@ -1,9 +1,12 @@
 package evaluator
 
 import (
+	"bufio"
+	"fmt"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"strings"
 	"testing"
 )
 
@@ -27,6 +30,13 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 2", 4},
+		{"(2 ** 3) ** 2", 64},
+		{"7 ** 0", 1},
+		{"-3 ** 3", -27},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -76,6 +86,7 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"!(if (false) { 5 })", true},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -184,6 +195,10 @@ if (10 > 1) {
 			`999[1]`,
 			"index operator not supported: INTEGER",
 		},
+		{
+			"fn(a, b) { a }(1)",
+			"wrong number of arguments: want=2, got=1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -212,6 +227,7 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let número = 5; let año = número * 2; año;", 10},
 	}
 	for _, tt := range tests {
 		testIntegerObject(t, testEval(tt.input), tt.expected)
@@ -487,3 +503,299 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	}
 	return true
 }
+
+// TestBuiltinArities comprueba la tabla de BuiltinArity contra los
+// builtins: con un argumento de más todos deben quejarse de la cantidad, y
+// con la cantidad correcta ninguno.
+func TestBuiltinArities(t *testing.T) {
+	for name, arity := range builtinArities {
+		for _, n := range []int{arity, arity + 1} {
+			args := make([]object.Object, n)
+			for i := range args {
+				args[i] = NULL
+			}
+			errObj, ok := builtins[name].Fn(args...).(*object.Error)
+			wrong := ok && strings.HasPrefix(errObj.Message, "wrong number of arguments")
+			if wrong != (n != arity) {
+				t.Errorf("%s with %d arguments: want=%d, got=%v", name, n, arity, errObj)
+			}
+		}
+	}
+	if _, ok := BuiltinArity("range"); ok {
+		t.Errorf("range has no fixed arity")
+	}
+}
+
+func TestInputBuiltin(t *testing.T) {
+	oldStdin := Stdin
+	defer func() { Stdin = oldStdin }()
+	Stdin = bufio.NewScanner(strings.NewReader("42\nmonkey\n"))
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`input()`, "42"},
+		{`input("")`, "monkey"},
+		{`input()`, nil},
+		{`input(1)`, "argument to `input` must be STRING, got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch obj := evaluated.(type) {
+		case *object.String:
+			if obj.Value != tt.expected {
+				t.Errorf("wrong line. expected=%q, got=%q", tt.expected, obj.Value)
+			}
+		case *object.Error:
+			if obj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, obj.Message)
+			}
+		default:
+			if tt.expected != nil {
+				t.Errorf("unexpected object. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestExitBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{`exit()`, 0},
+		{`exit(3); 10`, 3},
+		{`let f = fn() { exit(4); 5 }; f(); 6`, 4},
+		{`let x = 1 + exit(5); x`, 5},
+		{`if (true) { puts(exit(6)) }; 7`, 6},
+		{`[1, exit(7), 3]`, 7},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		exit, ok := evaluated.(*object.Exit)
+		if !ok {
+			t.Errorf("object is not Exit for %s. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if exit.Code != tt.expected {
+			t.Errorf("wrong exit code for %s. expected=%d, got=%d", tt.input, tt.expected, exit.Code)
+		}
+	}
+}
+
+func TestAssertBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`assert(1 < 2)`, ""},
+		{`assert(1 > 2)`, "assertion failed (got false)"},
+		{`assert(len([]), "array vacío")`, ""},
+		{`assert(first([]), "debe existir")`, "assertion failed: debe existir (got null)"},
+		{`assert_eq(1 + 1, 2)`, ""},
+		{`assert_eq([1, [2]], [1, [2]])`, ""},
+		{`assert_eq({"a": 1}, {"a": 1})`, ""},
+		{`assert_eq(1, 2)`, "assertion failed: 1 (INTEGER) != 2 (INTEGER)"},
+		{`assert_eq("1", 1, "tipos")`, "assertion failed: tipos: 1 (STRING) != 1 (INTEGER)"},
+		{`assert_eq([1, 2], [1, 3])`, "assertion failed: [1, 2] (ARRAY) != [1, 3] (ARRAY)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if tt.expected == "" {
+			testNullObject(t, evaluated)
+			continue
+		}
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestErrorPositionsAndStack(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + foo
+};
+let outer = fn() {
+  1 + inner(1)
+};
+outer();`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Line != 2 || errObj.Column != 7 {
+		t.Errorf("wrong error position. want=2:7, got=%d:%d", errObj.Line, errObj.Column)
+	}
+	expectedStack := []object.StackFrame{
+		{Function: "inner", Line: 5, Column: 7},
+		{Function: "outer", Line: 7, Column: 1},
+	}
+	if len(errObj.Stack) != len(expectedStack) {
+		t.Fatalf("wrong stack length. want=%d, got=%d (%+v)", len(expectedStack), len(errObj.Stack), errObj.Stack)
+	}
+	for i, frame := range expectedStack {
+		if errObj.Stack[i] != frame {
+			t.Errorf("wrong stack frame %d. want=%+v, got=%+v", i, frame, errObj.Stack[i])
+		}
+	}
+
+	expectedInspect := "ERROR: identifier not found: foo (line 2, column 7)" +
+		"\n\tat inner (line 5, column 7)" +
+		"\n\tat outer (line 7, column 1)"
+	if errObj.Inspect() != expectedInspect {
+		t.Errorf("wrong Inspect(). want=%q, got=%q", expectedInspect, errObj.Inspect())
+	}
+
+	// A tail call replaces the call that makes it, as in the VM.
+	evaluated = testEval(strings.Replace(input, "1 + inner(1)", "inner(1)", 1))
+	errObj, ok = evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if len(errObj.Stack) != 1 || errObj.Stack[0] != (object.StackFrame{Function: "outer", Line: 7, Column: 1}) {
+		t.Errorf("wrong stack for a tail call. got=%+v", errObj.Stack)
+	}
+
+	evaluated = testEval("let a = 1;\nlen(a)")
+	errObj, ok = evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Line != 2 || errObj.Column != 1 || len(errObj.Stack) != 0 {
+		t.Errorf("wrong builtin error position. want=2:1 without stack, got=%d:%d %+v", errObj.Line, errObj.Column, errObj.Stack)
+	}
+}
+
+func TestEvalBadNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; let = 5;", "invalid syntax: let"},
+		{"1 + (2 * 3;", "invalid syntax: ("},
+		{"puts(1, ]);", "invalid syntax: ]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sub = fn(x, y) { x - y }; sub(y: 1, x: 10)", 9},
+		{"let sub = fn(x, y) { x - y }; sub(10, y: 1)", 9},
+		{"let sub = fn(x, y) { x - y }; sub(10, 1,)", 9},
+		{"let f = fn(a, b, c) { a * 100 + b * 10 + c }; f(1, c: 3, b: 2)", 123},
+		{"let y = 5; let f = fn(x, y) { x + y }; f(y: y * 2, x: y)", 15},
+		{"let f = fn(x) { fn(y) { x - y } }; f(x: 10)(y: 3)", 7},
+		{"let sub = fn(x, y) { x - y }; sub(1, 2, y: 3)", "multiple values for argument: y"},
+		{"let sub = fn(x, y) { x - y }; sub(x: 1, z: 2)", "unknown keyword argument: z"},
+		{"let sub = fn(x, y) { x - y }; sub(y: 1)", "missing argument: x"},
+		{"let sub = fn(x, y) { x - y }; sub(x: 1, y: foo)", "identifier not found: foo"},
+		{"len(x: [])", "keyword arguments not supported by BUILTIN"},
+	}
+	for _, tt := range tests {
+		// Con y sin el resolver, que asigna posiciones a los parámetros.
+		for _, resolve := range []bool{false, true} {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			if resolve {
+				Resolve(program)
+			}
+			evaluated := Eval(program, object.NewEnvironment())
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+					continue
+				}
+				if errObj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+				}
+			}
+		}
+	}
+}
+
+// counterType es un objeto del anfitrión para las pruebas: un contador
+// con métodos, índice y llamada.
+var counterType = &object.HostType{
+	Name: "COUNTER",
+	Methods: map[string]object.Method{
+		"add": func(self *object.Host, args ...object.Object) object.Object {
+			n := self.Value.(*int64)
+			*n += args[0].(*object.Integer).Value
+			return &object.Integer{Value: *n}
+		},
+		"get": func(self *object.Host, args ...object.Object) object.Object {
+			return &object.Integer{Value: *self.Value.(*int64)}
+		},
+	},
+	Inspect: func(self *object.Host) string {
+		return fmt.Sprintf("counter(%d)", *self.Value.(*int64))
+	},
+	Index: func(self *object.Host, index object.Object) object.Object {
+		return &object.Integer{Value: *self.Value.(*int64) * index.(*object.Integer).Value}
+	},
+	Call: func(self *object.Host, args ...object.Object) object.Object {
+		sum := *self.Value.(*int64)
+		for _, arg := range args {
+			sum += arg.(*object.Integer).Value
+		}
+		return &object.Integer{Value: sum}
+	},
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"a": {"b": 2}}; h.a.b`, "2"},
+		{`{"a": 1}.b`, "null"},
+		{`let h = {"twice": fn(x) { x * 2 }}; h.twice(21)`, "42"},
+		{"c", "counter(0)"},
+		{"c.add(5); c.add(2); c.get()", "7"},
+		{"let add = c.add; add(4); c", "counter(4)"},
+		{"c.add(2); c[3]", "6"},
+		{"c.add(1); c(2, 3)", "6"},
+		{"c.nope", "ERROR: unknown member COUNTER.nope"},
+		{"let f = fn(x) { x.y }; f(1)", "ERROR: member access not supported: INTEGER"},
+		{`"abc".len`, "ERROR: member access not supported: STRING"},
+		{"plain[0]", "ERROR: index operator not supported: PLAIN"},
+		{"plain()", "ERROR: not a function: PLAIN"},
+		{"plain", "<PLAIN>"},
+	}
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("c", counterType.New(new(int64)))
+		env.Set("plain", (&object.HostType{Name: "PLAIN"}).New(nil))
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		if got := evaluated.Inspect(); !strings.HasPrefix(got, tt.expected) {
+			t.Errorf("%q: want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package evaluator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/object"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// MaxDepth es la cantidad máxima de llamadas a función anidadas. Pasado
+// ese límite la llamada retorna un error en vez de agotar la pila de Go,
+// lo que terminaría el proceso. Options.MaxDepth lo cambia para una
+// ejecución en particular.
+var MaxDepth = 10000
+
+// Options configura una ejecución del evaluador (ver EvalWithOptions).
+// El valor cero no impone ningún límite, igual que Eval.
+type Options struct {
+	// MaxSteps es la cantidad máxima de nodos que se pueden evaluar antes
+	// de abortar con el error "fuel exhausted". 0 significa sin límite.
+	MaxSteps int64
+	// MaxMemory, si es mayor que 0, es cuántos bytes pueden reservar los
+	// strings, arrays y hashes que crea la ejecución antes de abortar con
+	// el error "memory limit exceeded". Es una cuenta aproximada de lo que
+	// reserva el programa (ver object.Allocations), no de lo que sigue
+	// vivo, y no depende de lo que hagan otras ejecuciones del proceso.
+	MaxMemory int64
+	// MaxDepth reemplaza al MaxDepth del paquete si es mayor que 0.
+	MaxDepth int
+	// CheckedArithmetic hace que +, -, * y el - unario sobre enteros
+	// retornen un error cuando el resultado no cabe en un int64, en vez
+	// de dar la vuelta en silencio.
+	CheckedArithmetic bool
+	// Hooks recibe avisos de cada nodo evaluado y de cada llamada.
+	Hooks Hooks
+	// Context, si no es nil, cancela la ejecución igual que en
+	// EvalWithContext.
+	Context context.Context
+	// Sandboxed limita los builtins que acceden al anfitrión a los que
+	// solo necesitan las capacidades de Capabilities (ver Capability). Si
+	// es false se aplican las variables Sandbox y AllowExec del paquete.
+	Sandboxed    bool
+	Capabilities Capability
+	// Stdout, si no es nil, recibe lo que escriben puts e input en lugar
+	// de la salida estándar.
+	Stdout io.Writer
+	// Stderr, si no es nil, recibe lo que escribe eputs en lugar de la
+	// salida de errores. Las escrituras en Stdout y Stderr se serializan,
+	// así que no hace falta que admitan escrituras concurrentes.
+	Stderr io.Writer
+	// Stdin, si no es nil, es lo que lee input en lugar de Stdin.
+	Stdin io.Reader
+	// Args, si no es nil, es lo que retorna args() en lugar de Args.
+	Args []string
+	// Resolved indica que el programa ya pasó por Resolve y no cambió
+	// desde entonces, así que no se vuelve a resolver. Como Resolve
+	// escribe en el AST, solo así se puede evaluar el mismo programa
+	// desde varias goroutines a la vez.
+	Resolved bool
+}
+
+// Builtins que usan la entrada o la salida. Con Options.Stdin u
+// Options.Stdout se usa la versión que devuelve esta tabla.
+var ioBuiltins = map[string]func(ex *execution) object.BuiltinFunction{
+	"puts": func(ex *execution) object.BuiltinFunction {
+		return putsTo(ex.stdout())
+	},
+	"eputs": func(ex *execution) object.BuiltinFunction {
+		return putsTo(ex.stderr())
+	},
+	"input": func(ex *execution) object.BuiltinFunction {
+		in, mu := ex.stdinScanner()
+		return inputFrom(in, mu, ex.stdout())
+	},
+}
+
+// putsTo retorna el builtin puts que escribe en w.
+func putsTo(w io.Writer) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		for _, arg := range args {
+			fmt.Fprintln(w, arg.Inspect())
+		}
+		return NULL
+	}
+}
+
+// inputFrom retorna el builtin input que lee las líneas de in y escribe
+// el mensaje en out. mu protege a in, que pueden leer varias goroutines.
+func inputFrom(in *bufio.Scanner, mu *sync.Mutex, out io.Writer) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) > 1 {
+			return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+		}
+		prompt := ""
+		if len(args) == 1 {
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `input` must be STRING, got %s", args[0].Type())
+			}
+			prompt = str.Value
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(out, prompt)
+		if !in.Scan() {
+			return NULL
+		}
+		return &object.String{Value: in.Text()}
+	}
+}
+
+// execution es el estado de una ejecución con opciones. Viaja en los
+// entornos (ver object.Environment.Exec) y lo comparten las funciones
+// lanzadas con spawn o async, por eso los contadores son atómicos.
+type execution struct {
+	opts  Options
+	steps int64
+	// done es opts.Context.Done(); nil si la ejecución no se puede cancelar.
+	done <-chan struct{}
+	// stdin lee opts.Stdin; nil si se usa Stdin.
+	stdin   *bufio.Scanner
+	stdinMu sync.Mutex
+	// outMu serializa las escrituras en opts.Stdout y opts.Stderr, que
+	// pueden venir de varias goroutines.
+	outMu sync.Mutex
+	// alloc cuenta lo que reserva la ejecución; nil si no hay
+	// opts.MaxMemory.
+	alloc *object.Allocations
+}
+
+// newExecution prepara una ejecución con las opciones opts.
+func newExecution(opts Options) *execution {
+	ex := &execution{opts: opts}
+	if opts.Stdin != nil {
+		ex.stdin = bufio.NewScanner(opts.Stdin)
+	}
+	if opts.Context != nil {
+		ex.done = opts.Context.Done()
+	}
+	if opts.MaxMemory > 0 {
+		ex.alloc = object.NewAllocations(opts.MaxMemory)
+	}
+	return ex
+}
+
+func (ex *execution) stdout() io.Writer {
+	if ex.opts.Stdout != nil {
+		return syncWriter{mu: &ex.outMu, w: ex.opts.Stdout}
+	}
+	return os.Stdout
+}
+
+func (ex *execution) stderr() io.Writer {
+	if ex.opts.Stderr != nil {
+		return syncWriter{mu: &ex.outMu, w: ex.opts.Stderr}
+	}
+	return os.Stderr
+}
+
+func (ex *execution) stdinScanner() (*bufio.Scanner, *sync.Mutex) {
+	if ex.stdin != nil {
+		return ex.stdin, &ex.stdinMu
+	}
+	return Stdin, &stdinMu
+}
+
+// syncWriter escribe en w con mu tomado, para que el programa que embebe
+// el intérprete pueda pasar un io.Writer que no admite escrituras
+// concurrentes, como un strings.Builder.
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (s syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// Cada cuántos pasos se revisa si el contexto fue cancelado.
+const cancelCheckInterval = 256
+
+// EvalWithOptions evalúa node igual que Eval pero aplicando opts. env no
+// se modifica salvo por las variables que defina el programa.
+func EvalWithOptions(node ast.Node, env *object.Environment, opts Options) object.Object {
+	return Eval(node, env.WithExec(newExecution(opts)))
+}
+
+// EvalWithContext evalúa node igual que Eval pero aborta con un error
+// cuando ctx se cancela o vence, lo que permite ponerle un tiempo máximo
+// a un script. El contexto se revisa periódicamente entre pasos de la
+// evaluación, y los builtins que esperan (sleep, recv, send, lock, await)
+// dejan de esperar cuando se cancela.
+func EvalWithContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return EvalWithOptions(node, env, Options{Context: ctx})
+}
+
+// execOf retorna la ejecución de env, o nil si env es nil o no tiene una.
+func execOf(env *object.Environment) *execution {
+	if env == nil {
+		return nil
+	}
+	ex, _ := env.Exec().(*execution)
+	return ex
+}
+
+// cancelled retorna el canal que se cierra cuando se cancela la
+// ejecución, o nil, que nunca se cierra, si ex es nil o no se puede
+// cancelar. Los builtins que se bloquean lo esperan junto con lo suyo.
+func (ex *execution) cancelled() <-chan struct{} {
+	if ex == nil {
+		return nil
+	}
+	return ex.done
+}
+
+// cancelError es el error de una ejecución cancelada.
+func (ex *execution) cancelError() *object.Error {
+	return newError("evaluation cancelled: %s", ex.opts.Context.Err())
+}
+
+// checkExecution cuenta un paso de la ejecución de env, si la tiene.
+func checkExecution(env *object.Environment) *object.Error {
+	if ex, ok := env.Exec().(*execution); ok {
+		return ex.step()
+	}
+	return nil
+}
+
+// checkDepth retorna un error si env, el entorno de una llamada, supera
+// la profundidad máxima permitida.
+func checkDepth(env *object.Environment) *object.Error {
+	limit := MaxDepth
+	if ex, ok := env.Exec().(*execution); ok && ex.opts.MaxDepth > 0 {
+		limit = ex.opts.MaxDepth
+	}
+	if env.Depth() > limit {
+		return newError("maximum recursion depth exceeded (%d)", limit)
+	}
+	return nil
+}
+
+// checkedArithmetic indica si la ejecución de env pide aritmética verificada.
+func checkedArithmetic(env *object.Environment) bool {
+	ex, ok := env.Exec().(*execution)
+	return ok && ex.opts.CheckedArithmetic
+}
+
+// allocations retorna la cuenta de lo que reserva la ejecución de env, o
+// nil si no tiene Options.MaxMemory.
+func allocations(env *object.Environment) *object.Allocations {
+	ex, ok := env.Exec().(*execution)
+	if !ok {
+		return nil
+	}
+	return ex.alloc
+}
+
+// memoryError es el error de una ejecución que pasó de Options.MaxMemory.
+func memoryError(alloc *object.Allocations) *object.Error {
+	return newError("memory limit exceeded: more than %d bytes", alloc.Max())
+}
+
+// allocateConcatenation cuenta el string left + right antes de crearlo, si
+// left y right son strings. Se cuenta antes para que un programa que
+// duplica un string en cada paso no alcance a quedarse sin memoria.
+func allocateConcatenation(env *object.Environment, left, right object.Object) *object.Error {
+	alloc := allocations(env)
+	if alloc == nil {
+		return nil
+	}
+	l, ok := left.(*object.String)
+	if !ok {
+		return nil
+	}
+	r, ok := right.(*object.String)
+	if !ok {
+		return nil
+	}
+	if !alloc.Add(int64(len(l.Value)) + int64(len(r.Value))) {
+		return memoryError(alloc)
+	}
+	return nil
+}
+
+// allocate cuenta obj, recién creado por la ejecución de env.
+func allocate(env *object.Environment, obj object.Object) object.Object {
+	if alloc := allocations(env); alloc != nil && !alloc.Add(object.Size(obj)) {
+		return memoryError(alloc)
+	}
+	return obj
+}
+
+// allocateResult cuenta result, lo que retornó un builtin llamado con args.
+func allocateResult(env *object.Environment, result object.Object, args []object.Object) object.Object {
+	if alloc := allocations(env); alloc != nil && !alloc.AddResult(result, args) {
+		return memoryError(alloc)
+	}
+	return result
+}
+
+// step cuenta un paso de evaluación y retorna un error si la ejecución
+// debe abortarse.
+func (ex *execution) step() *object.Error {
+	steps := atomic.AddInt64(&ex.steps, 1)
+	if ex.opts.MaxSteps > 0 && steps > ex.opts.MaxSteps {
+		return newError("fuel exhausted: more than %d evaluation steps", ex.opts.MaxSteps)
+	}
+	if ex.done != nil && steps%cancelCheckInterval == 0 {
+		select {
+		case <-ex.done:
+			return ex.cancelError()
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package evaluator
+
+import (
+	"context"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+	"time"
+)
+
+func evalWithOptions(input string, opts Options) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return EvalWithOptions(program, object.NewEnvironment(), opts)
+}
+
+func TestFuelExhausted(t *testing.T) {
+	loop := "let loop = fn() { loop() }; loop();"
+	evaluated := evalWithOptions(loop, Options{MaxSteps: 1000})
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "fuel exhausted") {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	// Con combustible suficiente el programa termina normalmente.
+	evaluated = evalWithOptions("let add = fn(a, b) { a + b }; add(1, 2);", Options{MaxSteps: 1000})
+	testIntegerObject(t, evaluated, 3)
+}
+
+func TestFuelIsSharedWithSpawnedFunctions(t *testing.T) {
+	input := "let loop = fn() { loop() }; recv(spawn(loop));"
+	evaluated := evalWithOptions(input, Options{MaxSteps: 1000})
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || !strings.HasPrefix(errObj.Message, "fuel exhausted") {
+		t.Fatalf("expected fuel exhausted error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestEvalWithOptionsKeepsGlobals(t *testing.T) {
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New("let x = 5;")).ParseProgram()
+	EvalWithOptions(program, env, Options{MaxSteps: 100})
+	if val, ok := env.Get("x"); !ok || val.Inspect() != "5" {
+		t.Errorf("global x not defined in env. got=%v", val)
+	}
+}
+
+func TestEvalWithContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Tarda varios segundos; el contexto lo corta mucho antes.
+	input := `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+fib(35);`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluated := EvalWithContext(ctx, program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "evaluation cancelled: context deadline exceeded" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	evaluated = EvalWithContext(context.Background(), parser.New(lexer.New("1 + 2")).ParseProgram(), object.NewEnvironment())
+	testIntegerObject(t, evaluated, 3)
+}
+
+func TestCancellationStopsBlockingBuiltins(t *testing.T) {
+	tests := []string{
+		"recv(channel())",
+		"send(channel(), 1)",
+		"sleep(100000)",
+		"await(async(fn() { recv(channel()) }))",
+		"let m = mutex(); lock(m, fn() { lock(m, fn() { 1 }) })",
+		"recv(spawn(fn() { sleep(100000) }))",
+	}
+	for _, input := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		evaluated := EvalWithContext(ctx, parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+		cancel()
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: took %s after a 20ms deadline", input, elapsed)
+		}
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "evaluation cancelled: context deadline exceeded" {
+			t.Errorf("%s: wrong result. got=%T (%+v)", input, evaluated, evaluated)
+		}
+	}
+}
+
+func TestRecursionDepthLimit(t *testing.T) {
+	input := `
+let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };
+count(DEPTH);`
+	tests := []struct {
+		depth    string
+		opts     Options
+		expected interface{}
+	}{
+		{"5000", Options{}, 5000},
+		{"100000", Options{}, "maximum recursion depth exceeded (10000)"},
+		{"50", Options{MaxDepth: 20}, "maximum recursion depth exceeded (20)"},
+		{"19", Options{MaxDepth: 20}, 19},
+	}
+	for _, tt := range tests {
+		evaluated := evalWithOptions(strings.Replace(input, "DEPTH", tt.depth, 1), tt.opts)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+			if len(errObj.Stack) > maxStackFrames {
+				t.Errorf("stack not truncated. got=%d frames", len(errObj.Stack))
+			}
+		}
+	}
+}
+
+// Una llamada en posición de cola reemplaza a la que la hace, así que no
+// cuenta para MaxDepth.
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)", 0},
+		{"let count = fn(n) { if (n == 0) { return 0; } return count(n - 1); }; count(50000)", 0},
+		{"let sum = fn(n, acc) { if (n > 0) { return sum(n - 1, acc + n) } acc }; sum(50000, 0)", 1250025000},
+		{"let even = fn(n) { if (n == 0) { 1 } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { 0 } else { even(n - 1) } }; even(30001)", 0},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, evalWithOptions(tt.input, Options{MaxDepth: 100}), tt.expected)
+	}
+}
+
+// Las llamadas que hace un builtin siguen la profundidad de quien llama
+// al builtin: si no, la recursión agotaría la pila de Go.
+func TestRecursionThroughBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(n) { take(lazy_map([n], f), 1) }; f(1)", "maximum recursion depth exceeded (10000)"},
+		{"let f = fn(n) { take(lazy_filter([n], f), 1) }; f(1)", "maximum recursion depth exceeded (10000)"},
+		{"let f = memo(fn(n) { if (n == 0) { 0 } else { f(n - 1) } }); f(200000)", "maximum recursion depth exceeded (10000)"},
+		{"let f = memo(fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }); f(3000)", 3000},
+		{"let f = fn(n) { if (n == 0) { 0 } else { lock(mutex(), fn() { f(n - 1) }) } }; f(200000)", "maximum recursion depth exceeded (10000)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStdoutOption(t *testing.T) {
+	var out strings.Builder
+	input := `let say = fn(x) { puts(x) }; say("hola"); puts(1, [2]); let p = puts; p(true)`
+	evalWithOptions(input, Options{Stdout: &out})
+	if got, want := out.String(), "hola\n1\n[2]\ntrue\n"; got != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, got)
+	}
+}
+
+func TestStdinOption(t *testing.T) {
+	var out strings.Builder
+	input := `let name = input("name? "); let age = input(); [name, age, input()]`
+	evaluated := evalWithOptions(input, Options{Stdin: strings.NewReader("Ana\n30\n"), Stdout: &out})
+	if got, want := evaluated.Inspect(), "[Ana, 30, null]"; got != want {
+		t.Errorf("wrong result. want=%q, got=%q", want, got)
+	}
+	if got, want := out.String(), "name? "; got != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, got)
+	}
+}
+
+func TestConcurrentIO(t *testing.T) {
+	// Las funciones lanzadas con spawn escriben y leen a la vez; out no
+	// admite escrituras concurrentes, así que la ejecución las serializa.
+	var out strings.Builder
+	input := `
+let worker = fn(n) { let line = input(); puts(line); eputs(n); line };
+let a = spawn(worker, 1); let b = spawn(worker, 2); let c = spawn(worker, 3); let d = spawn(worker, 4);
+len(recv(a) + recv(b) + recv(c) + recv(d))`
+	evaluated := evalWithOptions(input, Options{
+		Stdin:  strings.NewReader("a\nb\nc\nd\n"),
+		Stdout: &out,
+		Stderr: &out,
+	})
+	testIntegerObject(t, evaluated, 4)
+	if got := strings.Count(out.String(), "\n"); got != 8 {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestStderrOption(t *testing.T) {
+	var out, errOut strings.Builder
+	evalWithOptions(`puts("out"); eputs("err", 1)`, Options{Stdout: &out, Stderr: &errOut})
+	if got, want := out.String(), "out\n"; got != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, got)
+	}
+	if got, want := errOut.String(), "err\n1\n"; got != want {
+		t.Errorf("wrong error output. want=%q, got=%q", want, got)
+	}
+}
+
+func TestMaxMemory(t *testing.T) {
+	// Junta 2000 strings distintos de 4 KiB, unos 8 MiB.
+	input := `
+let double = fn(s, n) { if (n == 0) { s } else { double(s + s, n - 1) } };
+let s = double("x", 12);
+let grow = fn(a, n) { if (n == 0) { len(a) } else { grow(push(a, s + "!"), n - 1) } };
+grow([], 2000)`
+	evaluated := evalWithOptions(input, Options{MaxMemory: 1 << 20})
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "memory limit exceeded: more than 1048576 bytes" {
+		t.Fatalf("expected memory limit error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	evaluated = evalWithOptions(input, Options{MaxMemory: 1 << 30})
+	testIntegerObject(t, evaluated, 2000)
+}
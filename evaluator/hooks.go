@@ -0,0 +1,41 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// Hooks recibe avisos del evaluador mientras ejecuta un programa. Sirve
+// para construir profilers, depuradores o visualizadores paso a paso
+// sin modificar este paquete. Se activa con Options.Hooks.
+//
+// Los métodos se llaman desde la goroutine que evalúa, que no es siempre
+// la misma si el script usa spawn o async.
+type Hooks interface {
+	// OnEnterNode se llama antes de evaluar node en env.
+	OnEnterNode(node ast.Node, env *object.Environment)
+	// OnExitNode se llama después de evaluar node, con su resultado
+	// (que puede ser nil, por ejemplo en un let).
+	OnExitNode(node ast.Node, env *object.Environment, result object.Object)
+	// OnCall se llama antes de ejecutar una función o un builtin.
+	OnCall(fn object.Object, args []object.Object)
+	// OnReturn se llama cuando fn termina, con el valor que retornó.
+	OnReturn(fn object.Object, result object.Object)
+}
+
+// NoopHooks implementa Hooks sin hacer nada. Se puede incrustar en un
+// struct para implementar solo los avisos que interesan.
+type NoopHooks struct{}
+
+func (NoopHooks) OnEnterNode(ast.Node, *object.Environment)               {}
+func (NoopHooks) OnExitNode(ast.Node, *object.Environment, object.Object) {}
+func (NoopHooks) OnCall(object.Object, []object.Object)                   {}
+func (NoopHooks) OnReturn(object.Object, object.Object)                   {}
+
+// hooksOf retorna los hooks de la ejecución de env, o nil si no tiene.
+func hooksOf(env *object.Environment) Hooks {
+	if ex, ok := env.Exec().(*execution); ok {
+		return ex.opts.Hooks
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+type recordingHooks struct {
+	depth    int
+	maxDepth int
+	nodes    int
+	calls    []string
+}
+
+func (h *recordingHooks) OnEnterNode(node ast.Node, env *object.Environment) {
+	h.nodes++
+	h.depth++
+	if h.depth > h.maxDepth {
+		h.maxDepth = h.depth
+	}
+}
+
+func (h *recordingHooks) OnExitNode(node ast.Node, env *object.Environment, result object.Object) {
+	h.depth--
+}
+
+func (h *recordingHooks) OnCall(fn object.Object, args []object.Object) {
+	h.calls = append(h.calls, fmt.Sprintf("call %s %d", fn.Type(), len(args)))
+}
+
+func (h *recordingHooks) OnReturn(fn object.Object, result object.Object) {
+	h.calls = append(h.calls, "return "+result.Inspect())
+}
+
+func TestHooks(t *testing.T) {
+	hooks := &recordingHooks{}
+	input := `let double = fn(x) { x * 2 }; len([double(3)]);`
+	evaluated := evalWithOptions(input, Options{Hooks: hooks})
+	testIntegerObject(t, evaluated, 1)
+
+	if hooks.depth != 0 {
+		t.Errorf("OnEnterNode and OnExitNode are not balanced. depth=%d", hooks.depth)
+	}
+	if hooks.nodes == 0 || hooks.maxDepth < 3 {
+		t.Errorf("too few nodes visited. nodes=%d, maxDepth=%d", hooks.nodes, hooks.maxDepth)
+	}
+	expected := "call FUNCTION 1, return 6, call BUILTIN 1, return 1"
+	if got := strings.Join(hooks.calls, ", "); got != expected {
+		t.Errorf("wrong calls. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestNoopHooksCanBeEmbedded(t *testing.T) {
+	var hooks struct {
+		NoopHooks
+	}
+	evaluated := evalWithOptions("1 + 1", Options{Hooks: hooks})
+	testIntegerObject(t, evaluated, 2)
+}
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sort"
+)
+
+// modules contiene los módulos predefinidos. Un módulo es un HASH cuyas
+// llaves son los nombres de sus funciones, por ejemplo: path["join"]("a", "b").
+var modules = map[string]*object.Hash{}
+
+// Module retorna el módulo predefinido name, para código que se ejecuta
+// fuera del evaluador, como la VM.
+func Module(name string) (object.Object, bool) {
+	module, ok := modules[name]
+	if !ok {
+		return nil, false
+	}
+	return module, true
+}
+
+// Construye el HASH de un módulo a partir de sus funciones. Todas las
+// ejecuciones comparten el mismo HASH, así que nace congelado: ningún
+// programa lo puede modificar.
+func newModule(fns map[string]object.BuiltinFunction) *object.Hash {
+	members := make(map[string]object.Object, len(fns))
+	for name, fn := range fns {
+		members[name] = &object.Builtin{Fn: fn}
+	}
+	module := newStringHash(members)
+	module.Frozen = true
+	return module
+}
+
+// Construye un HASH con llaves de tipo STRING, ordenadas alfabéticamente
+// para que el resultado sea determinista.
+func newStringHash(members map[string]object.Object) *object.Hash {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := object.NewHash()
+	for _, name := range names {
+		hashSet(hash, name, members[name])
+	}
+	return hash
+}
+
+// Asigna el valor de una llave STRING de un HASH.
+func hashSet(hash *object.Hash, name string, value object.Object) {
+	key := &object.String{Value: name}
+	hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+}
+
+// Busca el valor asociado a una llave STRING de un HASH.
+func hashGet(hash *object.Hash, name string) (object.Object, bool) {
+	key := &object.String{Value: name}
+	pair, ok := hash.Pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
@@ -0,0 +1,182 @@
+package evaluator
+
+import "monkey/ast"
+
+// Resolve recorre el AST y asigna a cada variable local (parámetros y let
+// dentro de funciones) una posición fija en el entorno de su función.
+// Así el evaluador encuentra los locales por índice en vez de buscarlos
+// por nombre en un map en cada acceso. Las variables del nivel superior
+// siguen siendo globales y se buscan por nombre.
+//
+// Los bloques de un if no crean entorno propio, de modo que todos los let
+// de una función (fuera de funciones anidadas) son locales de esa función.
+// Resolve se puede llamar más de una vez sobre el mismo AST.
+func Resolve(node ast.Node) {
+	resolveNode(node, nil)
+}
+
+// scope son las variables locales de una función.
+type scope struct {
+	index  map[string]int
+	locals []string
+}
+
+func (s *scope) declare(name string) {
+	if _, ok := s.index[name]; ok {
+		return
+	}
+	s.index[name] = len(s.locals)
+	s.locals = append(s.locals, name)
+}
+
+// scopes va de la función más externa a la más interna.
+func resolveNode(node ast.Node, scopes []*scope) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		node.Binding = lookupBinding(node.Value, scopes)
+	case *ast.LetStatement:
+		resolveNode(node.Value, scopes)
+		node.Name.Binding = lookupBinding(node.Name.Value, scopes)
+	case *ast.FunctionLiteral:
+		resolveFunction(node, scopes)
+	case *ast.ReturnStatement:
+		resolveNode(node.ReturnValue, scopes)
+		if len(scopes) > 0 {
+			markTailCalls(node.ReturnValue)
+		}
+	default:
+		forEachChild(node, func(child ast.Node) {
+			resolveNode(child, scopes)
+		})
+	}
+}
+
+func resolveFunction(fl *ast.FunctionLiteral, scopes []*scope) {
+	s := &scope{index: make(map[string]int), locals: make([]string, 0, len(fl.Parameters))}
+	// Los parámetros ocupan las primeras posiciones en orden, aunque se
+	// repitan; el nombre repetido se queda con la última, como pasaba
+	// cuando cada parámetro se registraba por nombre.
+	for i, param := range fl.Parameters {
+		s.index[param.Value] = i
+		s.locals = append(s.locals, param.Value)
+	}
+	// Los let se declaran antes de resolver el cuerpo para que una función
+	// anidada pueda referirse a un let que aparece después de ella.
+	declareLets(fl.Body, s)
+
+	inner := append(scopes[:len(scopes):len(scopes)], s)
+	resolveNode(fl.Body, inner)
+	fl.Locals = s.locals
+	markTailBlock(fl.Body)
+}
+
+// markTailCalls marca las llamadas de exp que están en posición de cola:
+// exp misma o, si es un if, la última expresión de cada rama. Son las que
+// el compilador convierte en OpTailCall.
+func markTailCalls(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		exp.Tail = true
+	case *ast.IfExpression:
+		markTailBlock(exp.Consequence)
+		if exp.Alternative != nil {
+			markTailBlock(exp.Alternative)
+		}
+	}
+}
+
+// markTailBlock marca las llamadas en posición de cola del valor de block,
+// que es el de su última sentencia.
+func markTailBlock(block *ast.BlockStatement) {
+	if block == nil || len(block.Statements) == 0 {
+		return
+	}
+	if stmt, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement); ok {
+		markTailCalls(stmt.Expression)
+	}
+}
+
+func declareLets(node ast.Node, s *scope) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		s.declare(node.Name.Value)
+		declareLets(node.Value, s)
+	case *ast.FunctionLiteral:
+		// Los let de una función anidada son de esa función.
+	default:
+		forEachChild(node, func(child ast.Node) {
+			declareLets(child, s)
+		})
+	}
+}
+
+func lookupBinding(name string, scopes []*scope) *ast.Binding {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if index, ok := scopes[i].index[name]; ok {
+			return &ast.Binding{Depth: len(scopes) - 1 - i, Index: index}
+		}
+	}
+	return nil
+}
+
+// forEachChild llama a fn con cada hijo directo de node.
+func forEachChild(node ast.Node, fn func(ast.Node)) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			fn(stmt)
+		}
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			fn(stmt)
+		}
+	case *ast.ExpressionStatement:
+		fn(node.Expression)
+	case *ast.LetStatement:
+		fn(node.Name)
+		fn(node.Value)
+	case *ast.ReturnStatement:
+		fn(node.ReturnValue)
+	case *ast.PrefixExpression:
+		fn(node.Right)
+	case *ast.InfixExpression:
+		fn(node.Left)
+		fn(node.Right)
+	case *ast.IfExpression:
+		fn(node.Condition)
+		fn(node.Consequence)
+		if node.Alternative != nil {
+			fn(node.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		for _, param := range node.Parameters {
+			fn(param)
+		}
+		fn(node.Body)
+	case *ast.CallExpression:
+		fn(node.Function)
+		for _, arg := range node.Arguments {
+			fn(arg)
+		}
+		// El nombre de un argumento nombrado es un parámetro de la
+		// función llamada, no una variable.
+		for _, arg := range node.Keywords {
+			fn(arg.Value)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			fn(el)
+		}
+	case *ast.IndexExpression:
+		fn(node.Left)
+		fn(node.Index)
+	case *ast.MemberExpression:
+		// El nombre del miembro no es una variable.
+		fn(node.Object)
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			fn(key)
+			fn(node.Pairs[key])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+)
+
+func TestResolveBindings(t *testing.T) {
+	input := `let g = 1; fn(a, b) { let c = a; fn(d) { d + c + g } }`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	Resolve(program)
+
+	outer := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if got := outer.Locals; len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("wrong outer locals. got=%v", got)
+	}
+	inner := outer.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	sum := inner.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	left := sum.Left.(*ast.InfixExpression)
+
+	tests := []struct {
+		ident    *ast.Identifier
+		expected *ast.Binding
+	}{
+		{left.Left.(*ast.Identifier), &ast.Binding{Depth: 0, Index: 0}},
+		{left.Right.(*ast.Identifier), &ast.Binding{Depth: 1, Index: 2}},
+		{sum.Right.(*ast.Identifier), nil},
+	}
+	for _, tt := range tests {
+		got := tt.ident.Binding
+		if tt.expected == nil {
+			if got != nil {
+				t.Errorf("%s should be global. got=%+v", tt.ident.Value, got)
+			}
+			continue
+		}
+		if got == nil || *got != *tt.expected {
+			t.Errorf("wrong binding for %s. expected=%+v, got=%+v", tt.ident.Value, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveTailCalls(t *testing.T) {
+	input := `f(); fn() { g(); if (a) { h() } else { return i() + j(); k() }; l(m()) }`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	Resolve(program)
+
+	tail := map[string]bool{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpression); ok && call.Tail {
+			tail[call.Function.String()] = true
+		}
+		return true
+	})
+	for _, name := range []string{"f", "g", "h", "i", "j", "k", "m"} {
+		if tail[name] {
+			t.Errorf("%s() should not be a tail call", name)
+		}
+	}
+	if !tail["l"] {
+		t.Errorf("l() should be a tail call")
+	}
+}
+
+func TestLocalSlotSemantics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// Un parámetro oculta a la variable externa del mismo nombre.
+		{"let x = 1; let f = fn(x) { x * 10 }; f(2) + x;", 21},
+		// Leer un local antes de su let usa la variable externa.
+		{"let x = 5; let f = fn() { let y = x; let x = 2; y + x }; f();", 7},
+		// Una closure creada antes de un let ve el valor asignado después.
+		{"let f = fn() { let g = fn() { n }; let n = 3; g() }; f();", 3},
+		// Los let dentro de un if pertenecen a la función.
+		{"let f = fn(a) { if (a > 0) { let b = a * 2; } b }; f(4);", 8},
+		// Cada llamada tiene sus propias posiciones.
+		{"let counter = fn(n) { fn() { n } }; let a = counter(1); let b = counter(2); a() + b();", 3},
+		{"let f = fn(x, x) { x }; f(1, 2);", 2},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5);", 120},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func BenchmarkRecursiveFibonacci(b *testing.B) {
+	input := `
+let fib = fn(n) {
+  if (n < 2) { return n; }
+  fib(n - 1) + fib(n - 2)
+};
+fib(20);`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
@@ -0,0 +1,86 @@
+package evaluator
+
+import "monkey/object"
+
+// Funciones que usa el código Go generado por el paquete transpiler. Cada
+// una hace lo mismo que el evaluador con el nodo correspondiente, así un
+// programa traducido se comporta igual que uno interpretado.
+
+// Infix aplica el operador binario op a left y right.
+func Infix(op string, left, right object.Object) object.Object {
+	return evalInfixExpression(op, left, right)
+}
+
+// Prefix aplica el operador unario op a right.
+func Prefix(op string, right object.Object) object.Object {
+	return evalPrefixExpression(op, right)
+}
+
+// Index retorna left[index].
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// Member retorna obj.name.
+func Member(obj object.Object, name string) object.Object {
+	return evalMemberExpression(obj, name)
+}
+
+// Truthy indica si obj cuenta como verdadero en una condición.
+func Truthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+// IsError indica si obj es un error o un exit, que cortan la ejecución.
+func IsError(obj object.Object) bool {
+	return isError(obj)
+}
+
+// Apply llama a fn con args.
+func Apply(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
+// Lookup retorna val, el valor de la variable name, o el builtin o módulo
+// de ese nombre si la variable todavía no tiene valor.
+func Lookup(val object.Object, name string) object.Object {
+	if val != nil {
+		return val
+	}
+	if builtin, ok := LookupBuiltin(name); ok {
+		return builtin
+	}
+	if module, ok := modules[name]; ok {
+		return module
+	}
+	return newError("identifier not found: %s", name)
+}
+
+// NewHash crea un hash con los pares clave, valor de kv.
+func NewHash(kv ...object.Object) object.Object {
+	hash := object.NewHash()
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, value := kv[i], kv[i+1]
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+	}
+	return hash
+}
+
+// WrongArguments es el error de llamar a una función con menos argumentos
+// que parámetros.
+func WrongArguments(want, got int) object.Object {
+	return newError("wrong number of arguments: want=%d, got=%d", want, got)
+}
+
+// At le asigna al error obj la posición line:column si todavía no tiene
+// una. Retorna obj.
+func At(obj object.Object, line, column int) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line, err.Column = line, column
+	}
+	return obj
+}
@@ -0,0 +1,39 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestRuntimeHelpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   object.Object
+		expected string
+	}{
+		{"Infix", Infix("*", object.NewInteger(6), object.NewInteger(7)), "42"},
+		{"Infix error", Infix("+", TRUE, object.NewInteger(1)), "ERROR: type mismatch: BOOLEAN + INTEGER"},
+		{"Prefix", Prefix("!", NULL), "true"},
+		{"Index", Index(&object.Array{Elements: []object.Object{TRUE}}, object.NewInteger(0)), "true"},
+		{"Lookup value", Lookup(object.NewInteger(1), "len"), "1"},
+		{"Lookup builtin", Lookup(nil, "len"), "builtin function"},
+		{"Lookup missing", Lookup(nil, "nope"), "ERROR: identifier not found: nope"},
+		{"NewHash", NewHash(&object.String{Value: "a"}, object.NewInteger(1)), "{a: 1}"},
+		{"NewHash error", NewHash(&object.Array{}, object.NewInteger(1)), "ERROR: unusable as hash key: ARRAY"},
+		{"Apply", Apply(Lookup(nil, "len"), &object.String{Value: "abc"}), "3"},
+		{"WrongArguments", WrongArguments(2, 1), "ERROR: wrong number of arguments: want=2, got=1"},
+		{"At", At(WrongArguments(2, 1), 3, 4), "ERROR: wrong number of arguments: want=2, got=1 (line 3, column 4)"},
+		{"At keeps position", At(At(WrongArguments(2, 1), 3, 4), 5, 6), "ERROR: wrong number of arguments: want=2, got=1 (line 3, column 4)"},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+	if !Truthy(object.NewInteger(0)) || Truthy(FALSE) || Truthy(NULL) {
+		t.Errorf("wrong truthiness")
+	}
+	if !IsError(&object.Exit{}) || IsError(NULL) {
+		t.Errorf("IsError must accept errors and exits only")
+	}
+}
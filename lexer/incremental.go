@@ -0,0 +1,110 @@
+package lexer
+
+import (
+	"monkey/token"
+	"strings"
+)
+
+// Edit describe un cambio en el código: los bytes [Start, OldEnd) del
+// código anterior se reemplazaron por los bytes [Start, NewEnd) del nuevo.
+// Por ejemplo, insertar "ab" en la posición 10 es {10, 10, 12} y borrar
+// tres bytes desde allí es {10, 13, 10}.
+type Edit struct {
+	Start, OldEnd, NewEnd int
+}
+
+// Relex actualiza los tokens de un código después de un cambio, para que
+// un editor no tenga que volver a leer todo el archivo en cada tecla. old
+// son los tokens del código anterior tal como los retorna Tokenize, src es
+// el código nuevo y edit el cambio entre ambos. El resultado es igual al
+// de Tokenize(src), pero solo se vuelve a leer la zona afectada: desde el
+// primer token que toca el cambio hasta que el Lexer vuelve a encontrar un
+// token que ya existía. Los tokens posteriores se reutilizan, corriendo
+// sus posiciones.
+//
+// Los errores del Lexer no se retornan; para obtenerlos está Tokenize. Si
+// edit no es coherente con old y src, Relex lee todo de nuevo.
+func Relex(old []token.Token, src string, edit Edit) []token.Token {
+	if !validEdit(old, src, edit) {
+		tokens, _ := Tokenize(src)
+		return tokens
+	}
+	delta := edit.NewEnd - edit.OldEnd
+
+	// Se conservan los tokens que terminan antes del cambio, dejando al
+	// menos un espacio en medio: uno que termina justo en Start se podría
+	// unir con el texto insertado ("ab" + "c").
+	first := 0
+	for first < len(old) && old[first].Type != token.EOF && old[first].End().Offset < edit.Start {
+		first++
+	}
+	tokens := append([]token.Token{}, old[:first]...)
+
+	// Se retoma desde el final del último token conservado: así la línea
+	// y la columna salen bien sin conocer el espacio que sigue.
+	start := token.Position{Offset: 0, Line: 1, Column: 1}
+	if first > 0 {
+		start = old[first-1].End()
+	}
+	l := newLexerAt(src, start)
+
+	// next es el primer token viejo que todavía puede coincidir con uno
+	// nuevo.
+	next := first
+	for {
+		tok := l.NextToken()
+		if tok.Offset >= edit.NewEnd {
+			for next < len(old) && old[next].Offset+delta < tok.Offset {
+				next++
+			}
+			if next < len(old) && old[next].Offset >= edit.OldEnd && old[next].Offset+delta == tok.Offset {
+				return append(tokens, shiftTokens(old[next:], tok, old[next])...)
+			}
+		}
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// validEdit indica si edit puede ser un cambio del código de old a src.
+func validEdit(old []token.Token, src string, edit Edit) bool {
+	if len(old) == 0 || old[len(old)-1].Type != token.EOF {
+		return false
+	}
+	oldLen := old[len(old)-1].Offset
+	return 0 <= edit.Start && edit.Start <= edit.OldEnd && edit.Start <= edit.NewEnd &&
+		edit.OldEnd <= oldLen && edit.NewEnd <= len(src) &&
+		len(src)-edit.NewEnd == oldLen-edit.OldEnd
+}
+
+// shiftTokens retorna una copia de tokens, que empiezan en from, movidos
+// para que from quede en la posición de to. La columna solo cambia en la
+// línea de from; en las siguientes el cambio no la afecta.
+func shiftTokens(tokens []token.Token, to, from token.Token) []token.Token {
+	shifted := make([]token.Token, len(tokens))
+	for i, tok := range tokens {
+		if tok.Line == from.Line {
+			tok.Column += to.Column - from.Column
+		}
+		tok.Line += to.Line - from.Line
+		tok.Offset += to.Offset - from.Offset
+		shifted[i] = tok
+	}
+	return shifted
+}
+
+// newLexerAt crea un Lexer que empieza a leer src en la posición pos, que
+// tiene que ser el comienzo de un token o un espacio entre tokens.
+func newLexerAt(src string, pos token.Position) *Lexer {
+	l := &Lexer{
+		src:          strings.NewReader(src[pos.Offset:]),
+		base:         pos.Offset,
+		readPosition: pos.Offset,
+		line:         pos.Line,
+		lineStart:    pos.Offset - pos.Column + 1,
+	}
+	l.readChar()
+	return l
+}
@@ -0,0 +1,75 @@
+package lexer
+
+import (
+	"math/rand"
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+func TestRelex(t *testing.T) {
+	src := "let x = 10;\nlet name = \"monkey\"; // nombre\nputs(x ** 2, name);\n"
+	tests := []struct {
+		name        string
+		start, end  int
+		replacement string
+	}{
+		{"insert in identifier", 5, 5, "yz"},
+		{"join tokens", 9, 10, ""},
+		{"new line", 11, 11, "\n\n"},
+		{"open string", 23, 23, "\""},
+		{"comment out", 0, 0, "// "},
+		{"replace everything", 0, len(src), "1"},
+		{"append", len(src), len(src), "x"},
+		{"edit last line", 50, 51, "("},
+	}
+	for _, tt := range tests {
+		old, _ := Tokenize(src)
+		newSrc := src[:tt.start] + tt.replacement + src[tt.end:]
+		edit := Edit{Start: tt.start, OldEnd: tt.end, NewEnd: tt.start + len(tt.replacement)}
+		got := Relex(old, newSrc, edit)
+		expected, _ := Tokenize(newSrc)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: wrong tokens.\nexpected=%v\ngot=     %v", tt.name, expected, got)
+		}
+	}
+}
+
+// Relex tiene que dar siempre lo mismo que Tokenize, para cualquier cambio.
+func TestRelexRandomEdits(t *testing.T) {
+	pieces := []string{"let", " ", "\n", "x", "1", "\"", "//", "=", "*", "(", ")", "{", "}", "ab", "ñ", "@", ";"}
+	rnd := rand.New(rand.NewSource(1))
+	random := func(n int) string {
+		s := ""
+		for i := 0; i < n; i++ {
+			s += pieces[rnd.Intn(len(pieces))]
+		}
+		return s
+	}
+	for i := 0; i < 2000; i++ {
+		src := random(rnd.Intn(30))
+		start := rnd.Intn(len(src) + 1)
+		end := start + rnd.Intn(len(src)-start+1)
+		replacement := random(rnd.Intn(3))
+		newSrc := src[:start] + replacement + src[end:]
+
+		old, _ := Tokenize(src)
+		got := Relex(old, newSrc, Edit{Start: start, OldEnd: end, NewEnd: start + len(replacement)})
+		expected, _ := Tokenize(newSrc)
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%q -> %q: wrong tokens.\nexpected=%v\ngot=     %v", src, newSrc, expected, got)
+		}
+	}
+}
+
+func TestRelexInvalidEdit(t *testing.T) {
+	old, _ := Tokenize("let x = 1;")
+	got := Relex(old, "let y = 2;", Edit{Start: 4, OldEnd: 2, NewEnd: 5})
+	expected, _ := Tokenize("let y = 2;")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong tokens.\nexpected=%v\ngot=     %v", expected, got)
+	}
+	if got := Relex(nil, "x", Edit{}); len(got) != 2 || got[1].Type != token.EOF {
+		t.Errorf("wrong tokens without a previous stream. got=%v", got)
+	}
+}
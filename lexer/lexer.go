@@ -1,36 +1,159 @@
 package lexer
 
-import "monkey/token"
+import (
+	"fmt"
+	"io"
+	"monkey/token"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Cuántos bytes se piden a la vez al leer de un io.Reader.
+const chunkSize = 4096
 
 // Lexer estructura lexer
 type Lexer struct {
-	input        string
+	// El código todavía no descartado; buf[0] está en la posición base.
+	// Con NewFromReader se completa desde src a medida que hace falta.
+	buf          []byte
+	base         int
+	src          io.Reader
+	err          error
+	errors       []Error
+	// keepWhitespace hace que los espacios sean tokens WHITESPACE.
+	keepWhitespace bool
+	// Palabras reservadas propias; si es nil se usa token.LookupIdent.
+	keywords *token.Keywords
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
+	line         int  // current line (starting at 1)
+	lineStart    int  // position of the first char of the current line
 }
 
 //New function New que genera un nuevo Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{buf: []byte(input), line: 1}
+	l.readChar() // lee el primer caracter.
+	return l
+}
+
+// NewFromReader crea un Lexer que lee el código de r a medida que lo
+// necesita, así un script grande no tiene que estar entero en memoria.
+// Si r falla el código termina ahí, como en un EOF, y Err retorna el error.
+func NewFromReader(r io.Reader) *Lexer {
+	l := &Lexer{src: r, line: 1}
 	l.readChar() // lee el primer caracter.
 	return l
 }
+
+// Error es un problema en el código encontrado por el Lexer: un caracter
+// que no pertenece al lenguaje o un string sin cerrar. El Lexer igual
+// retorna un token (ILLEGAL o STRING) y sigue adelante.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+// Error retorna el mensaje con la posición, con el mismo formato que los
+// errores del parser.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// Errors retorna los errores encontrados hasta ahora, en orden.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+func (l *Lexer) addError(pos token.Position, format string, a ...interface{}) {
+	l.errors = append(l.errors, Error{Pos: pos, Message: fmt.Sprintf(format, a...)})
+}
+
+// Pos retorna la posición del próximo caracter a leer.
+func (l *Lexer) Pos() token.Position {
+	return token.Position{Offset: l.position, Line: l.line, Column: l.position - l.lineStart + 1}
+}
+
+// SetKeepWhitespace hace que el Lexer emita los espacios entre tokens
+// como tokens WHITESPACE en vez de descartarlos. Con los COMMENT, que se
+// emiten siempre, los tokens cubren todo el código: es lo que necesitan
+// las herramientas que reescriben código sin cambiar su formato.
+func (l *Lexer) SetKeepWhitespace(keep bool) {
+	l.keepWhitespace = keep
+}
+
+// SetKeywords hace que el Lexer use la tabla de palabras reservadas k en
+// vez de la tabla por omisión, para leer un dialecto del lenguaje.
+func (l *Lexer) SetKeywords(k *token.Keywords) {
+	l.keywords = k
+}
+
+// Err retorna el error que interrumpió la lectura del io.Reader, o nil.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
+	return l.byteAt(l.readPosition)
+}
+
+// byteAt retorna el caracter en la posición pos del código, o 0 si el
+// código termina antes.
+func (l *Lexer) byteAt(pos int) byte {
+	for pos-l.base >= len(l.buf) {
+		if !l.fill() {
+			return 0
+		}
 	}
+	return l.buf[pos-l.base]
+}
+
+// fill agrega a buf lo siguiente de src. Retorna false si no hay más.
+func (l *Lexer) fill() bool {
+	if l.src == nil {
+		return false
+	}
+	if len(l.buf) == cap(l.buf) {
+		buf := make([]byte, len(l.buf), 2*len(l.buf)+chunkSize)
+		copy(buf, l.buf)
+		l.buf = buf
+	}
+	n, err := l.src.Read(l.buf[len(l.buf):cap(l.buf)])
+	l.buf = l.buf[:len(l.buf)+n]
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		l.src = nil
+	}
+	return true
+}
+
+// discard libera lo anterior al caracter actual. Solo se llama entre
+// tokens, cuando ya no queda ningún literal a medio leer. Después del
+// final del código position sigue avanzando, pero buf no tiene más.
+func (l *Lexer) discard() {
+	n := l.position - l.base
+	if n > len(l.buf) {
+		n = len(l.buf)
+	}
+	l.buf = l.buf[n:]
+	l.base += n
+}
+
+// slice retorna el código entre las posiciones start y end.
+func (l *Lexer) slice(start, end int) string {
+	return string(l.buf[start-l.base : end-l.base])
 }
 
 //readChar
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
 	}
+	l.ch = l.byteAt(l.readPosition)
 	l.position = l.readPosition
 	l.readPosition++
 }
@@ -38,8 +161,19 @@ func (l *Lexer) readChar() {
 // NextToken is returns the next token
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
-	l.skipWhiteSpace()
+	if !l.keepWhitespace {
+		l.skipWhiteSpace()
+	}
+	l.discard()
+	pos := l.Pos()
+	line, column, offset := pos.Line, pos.Column, pos.Offset
 	switch l.ch {
+	case ' ', '\t', '\n', '\r': // solo con keepWhitespace
+		tok.Type = token.WHITESPACE
+		l.skipWhiteSpace()
+		tok.Literal = l.slice(offset, l.position)
+		tok.Line, tok.Column, tok.Offset = line, column, offset
+		return tok
 	case '=':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -63,9 +197,20 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
+		if l.peekChar() == '/' {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readComment()
+			tok.Line, tok.Column, tok.Offset = line, column, offset
+			return tok
+		}
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = token.Token{Type: token.POWER, Literal: "**"}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
@@ -74,6 +219,8 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -92,22 +239,41 @@ func (l *Lexer) NextToken() token.Token {
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
+		if l.ch != '"' {
+			l.addError(pos, "unterminated string literal")
+		}
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	default:
-		if isLetter(l.ch) {
+		if r, size := l.currentRune(); isLetter(r) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal)
+			if l.keywords != nil {
+				tok.Type = l.keywords.Lookup(tok.Literal)
+			} else {
+				tok.Type = token.LookupIdent(tok.Literal)
+			}
+			tok.Line, tok.Column, tok.Offset = line, column, offset
 			return tok
-		} else if isDigit(l.ch) {
+		} else if isDigit(r) {
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
+			tok.Line, tok.Column, tok.Offset = line, column, offset
 			return tok
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			// Un caracter de varios bytes es un solo token ILLEGAL.
+			for i := 1; i < size; i++ {
+				l.readChar()
+			}
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.slice(offset, l.position+1)}
+			if r == utf8.RuneError && size == 1 {
+				l.addError(pos, "invalid UTF-8 byte %#x", l.ch)
+			} else {
+				l.addError(pos, "illegal character %#U", r)
+			}
 		}
 	}
 	l.readChar()
+	tok.Line, tok.Column, tok.Offset = line, column, offset
 	return tok
 }
 
@@ -119,7 +285,16 @@ func (l *Lexer) readString() string {
 			break
 		}
 	}
-	return l.input[position:l.position]
+	return l.slice(position, l.position)
+}
+
+// Un comentario va desde // hasta el fin de la línea, sin incluirlo.
+func (l *Lexer) readComment() string {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return l.slice(position, l.position)
 }
 
 func (l *Lexer) skipWhiteSpace() {
@@ -127,26 +302,47 @@ func (l *Lexer) skipWhiteSpace() {
 		l.readChar()
 	}
 }
+// Un identificador empieza con una letra y puede continuar con dígitos,
+// por ejemplo: base64_encode. Las letras pueden ser de cualquier idioma:
+// número, año, λ.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
-		l.readChar()
+	for {
+		r, size := l.currentRune()
+		if !isLetter(r) && !isDigit(r) {
+			break
+		}
+		for i := 0; i < size; i++ {
+			l.readChar()
+		}
+	}
+	return l.slice(position, l.position)
+}
+
+// currentRune decodifica el caracter UTF-8 que empieza en la posición
+// actual y retorna también cuántos bytes ocupa.
+func (l *Lexer) currentRune() (rune, int) {
+	if l.ch < utf8.RuneSelf {
+		return rune(l.ch), 1
 	}
-	return l.input[position:l.position]
+	// Se asegura de que el caracter completo esté en buf.
+	l.byteAt(l.position + utf8.UTFMax - 1)
+	return utf8.DecodeRune(l.buf[l.position-l.base:])
 }
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isDigit(l.ch) {
+	for isDigit(rune(l.ch)) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.slice(position, l.position)
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' ||
+		r >= utf8.RuneSelf && unicode.IsLetter(r)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
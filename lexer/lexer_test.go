@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"errors"
+	"io"
 	"monkey/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -28,6 +32,8 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"}
+	sha256(x1)
+	2 ** 3 * 4
 	`
 	tests := []struct {
 		expectedType    token.TokenType
@@ -119,6 +125,15 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "sha256"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x1"},
+		{token.RPAREN, ")"},
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.ASTERISK, "*"},
+		{token.INT, "4"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -132,3 +147,253 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"hola\"\n\nfoo"
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{"let", 1, 1, 0},
+		{"x", 1, 5, 4},
+		{"=", 1, 7, 6},
+		{"5", 1, 9, 8},
+		{";", 1, 10, 9},
+		{"x", 2, 3, 13},
+		{"+", 2, 5, 15},
+		{"hola", 2, 7, 17},
+		{"foo", 4, 1, 25},
+		{"", 4, 4, 28},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong for %q. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+		if tok.Offset != tt.expectedOffset {
+			t.Errorf("tests[%d] - offset wrong for %q. expected=%d, got=%d",
+				i, tt.expectedLiteral, tt.expectedOffset, tok.Offset)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// cabecera\nlet x = 10 / 2; // mitad\n//"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// cabecera"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// mitad"},
+		{token.COMMENT, "//"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	input := "let s = \"" + strings.Repeat("x", 3*chunkSize) + "\"; // fin\nfoo(1 ** 2, bar);\n"
+	readers := map[string]io.Reader{
+		"whole":    strings.NewReader(input),
+		"one byte": iotest.OneByteReader(strings.NewReader(input)),
+		"half":     iotest.HalfReader(strings.NewReader(input)),
+	}
+	for name, r := range readers {
+		expected := New(input)
+		l := NewFromReader(r)
+		for {
+			want, got := expected.NextToken(), l.NextToken()
+			if got != want {
+				t.Fatalf("%s: wrong token. expected=%+v, got=%+v", name, want, got)
+			}
+			if got.Type == token.EOF {
+				break
+			}
+		}
+		if l.Err() != nil {
+			t.Errorf("%s: unexpected error: %s", name, l.Err())
+		}
+		// El buffer solo guarda lo necesario para el token actual.
+		if len(l.buf) > 0 {
+			t.Errorf("%s: buffer not released at EOF. len=%d", name, len(l.buf))
+		}
+	}
+}
+
+func TestNewFromReaderError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	l := NewFromReader(io.MultiReader(strings.NewReader("let x = 1"), iotest.ErrReader(readErr)))
+	var types []token.TokenType
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		types = append(types, tok.Type)
+	}
+	if len(types) != 4 {
+		t.Errorf("wrong number of tokens before the error. got=%v", types)
+	}
+	if l.Err() != readErr {
+		t.Errorf("wrong error. expected=%v, got=%v", readErr, l.Err())
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let número = año2 + λ; _ñ €x"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "número", 5},
+		{token.ASSIGN, "=", 13},
+		{token.IDENT, "año2", 15},
+		{token.PLUS, "+", 21},
+		{token.IDENT, "λ", 23},
+		{token.SEMICOLON, ";", 25},
+		{token.IDENT, "_ñ", 27},
+		{token.ILLEGAL, "€", 31},
+		{token.IDENT, "x", 34},
+		{token.EOF, "", 35},
+	}
+	// Las columnas cuentan bytes, igual que Offset.
+	l := NewFromReader(iotest.OneByteReader(strings.NewReader(input)))
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q col %d, got=%s %q col %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedColumn, tok.Type, tok.Literal, tok.Column)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = "ok";`, nil},
+		{"let x = 5 @ 3;", []string{"illegal character U+0040 '@' (line 1, column 11)"}},
+		{"a €\n  #", []string{
+			"illegal character U+20AC '€' (line 1, column 3)",
+			"illegal character U+0023 '#' (line 2, column 3)",
+		}},
+		{"\xff", []string{"invalid UTF-8 byte 0xff (line 1, column 1)"}},
+		{"puts(\"hola\n", []string{"unterminated string literal (line 1, column 6)"}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		var got []string
+		for _, err := range l.Errors() {
+			got = append(got, err.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: wrong errors.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestLexerPos(t *testing.T) {
+	l := New("ab\n  cd")
+	l.NextToken()
+	expected := token.Position{Offset: 2, Line: 1, Column: 3}
+	if l.Pos() != expected {
+		t.Errorf("wrong position. expected=%v, got=%v", expected, l.Pos())
+	}
+	l.NextToken()
+	expected = token.Position{Offset: 7, Line: 2, Column: 5}
+	if l.Pos() != expected {
+		t.Errorf("wrong position. expected=%v, got=%v", expected, l.Pos())
+	}
+}
+
+func TestKeepWhitespace(t *testing.T) {
+	input := "let x =\t5; // cinco\n\n  puts(\"a b\")\r\n"
+	l := New(input)
+	l.SetKeepWhitespace(true)
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.WHITESPACE, " "},
+		{token.IDENT, "x"},
+		{token.WHITESPACE, " "},
+		{token.ASSIGN, "="},
+		{token.WHITESPACE, "\t"},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.WHITESPACE, " "},
+		{token.COMMENT, "// cinco"},
+		{token.WHITESPACE, "\n\n  "},
+		{token.IDENT, "puts"},
+		{token.LPAREN, "("},
+		{token.STRING, "a b"},
+		{token.RPAREN, ")"},
+		{token.WHITESPACE, "\r\n"},
+		{token.EOF, ""},
+	}
+	// Juntando los tokens se recupera el código original.
+	var source strings.Builder
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Offset != source.Len() {
+			t.Fatalf("tests[%d] - wrong offset. expected=%d, got=%d", i, source.Len(), tok.Offset)
+		}
+		if tok.Type == token.STRING {
+			source.WriteString(`"` + tok.Literal + `"`)
+		} else {
+			source.WriteString(tok.Literal)
+		}
+	}
+	if source.String() != input {
+		t.Errorf("tokens do not cover the input. expected=%q, got=%q", input, source.String())
+	}
+}
+
+func TestSetKeywords(t *testing.T) {
+	k := token.NewKeywords()
+	k.Register("si", token.IF)
+	k.Register("sino", token.ELSE)
+	k.Register("funcion", token.FUNCTION)
+	k.Register("sea", token.LET)
+	k.Remove("fn")
+
+	l := New("sea f = funcion(x) { si (x) { fn } sino { 0 } }")
+	l.SetKeywords(k)
+	expected := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.FUNCTION, token.LPAREN, token.IDENT, token.RPAREN,
+		token.LBRACE, token.IF, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE, token.IDENT,
+		token.RBRACE, token.ELSE, token.LBRACE, token.INT, token.RBRACE, token.RBRACE, token.EOF,
+	}
+	for i, typ := range expected {
+		if tok := l.NextToken(); tok.Type != typ {
+			t.Fatalf("tests[%d] - wrong type. expected=%s, got=%s (%q)", i, typ, tok.Type, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package lexer
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// ErrorList son todos los errores del Lexer. Implementa error para
+// Tokenize.
+type ErrorList []Error
+
+// Error retorna el primer error, indicando cuántos más hay.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
+
+// Tokenize retorna todos los tokens de src, comentarios incluidos. El
+// último es siempre el EOF. Si hay caracteres inválidos o strings sin
+// cerrar el error es un ErrorList, pero igual se retornan todos los
+// tokens: un resaltador de sintaxis puede marcar los ILLEGAL.
+func Tokenize(src string) ([]token.Token, error) {
+	l := New(src)
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	if len(l.errors) != 0 {
+		return tokens, ErrorList(l.errors)
+	}
+	return tokens, nil
+}
+
+// Lookahead lee los tokens de un Lexer permitiendo mirar cualquier
+// cantidad de tokens hacia adelante sin consumirlos.
+type Lookahead struct {
+	l      *Lexer
+	tokens []token.Token // leídos del Lexer y todavía no consumidos
+}
+
+func NewLookahead(l *Lexer) *Lookahead {
+	return &Lookahead{l: l}
+}
+
+// Peek retorna el token n posiciones más adelante sin consumirlo; Peek(0)
+// es el que retornará Next. Después del final siempre es el EOF.
+func (la *Lookahead) Peek(n int) token.Token {
+	for len(la.tokens) <= n {
+		la.tokens = append(la.tokens, la.l.NextToken())
+	}
+	return la.tokens[n]
+}
+
+// Next consume y retorna el siguiente token.
+func (la *Lookahead) Next() token.Token {
+	tok := la.Peek(0)
+	la.tokens = la.tokens[1:]
+	return tok
+}
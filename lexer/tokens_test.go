@@ -0,0 +1,62 @@
+package lexer
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize("let x = 1; // uno")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON, token.COMMENT, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d (%v)", len(expected), len(tokens), tokens)
+	}
+	for i, typ := range expected {
+		if tokens[i].Type != typ {
+			t.Errorf("tokens[%d] - wrong type. expected=%s, got=%s", i, typ, tokens[i].Type)
+		}
+	}
+
+	tokens, err = Tokenize("a @ \"b")
+	if len(tokens) != 4 || tokens[1].Type != token.ILLEGAL || tokens[3].Type != token.EOF {
+		t.Errorf("wrong tokens for invalid input. got=%v", tokens)
+	}
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 2 {
+		t.Fatalf("expected ErrorList with 2 errors. got=%T (%v)", err, err)
+	}
+	expectedMsg := "illegal character U+0040 '@' (line 1, column 3) (and 1 more errors)"
+	if err.Error() != expectedMsg {
+		t.Errorf("wrong error. expected=%q, got=%q", expectedMsg, err.Error())
+	}
+
+	tokens, err = Tokenize("")
+	if err != nil || len(tokens) != 1 || tokens[0].Type != token.EOF {
+		t.Errorf("wrong result for empty input. got=%v, %v", tokens, err)
+	}
+}
+
+func TestLookahead(t *testing.T) {
+	la := NewLookahead(New("fn(a) { a }"))
+	if tok := la.Peek(3); tok.Type != token.RPAREN {
+		t.Fatalf("Peek(3) wrong. expected=%s, got=%s", token.RPAREN, tok.Type)
+	}
+	if tok := la.Peek(0); tok.Type != token.FUNCTION {
+		t.Fatalf("Peek(0) wrong. expected=%s, got=%s", token.FUNCTION, tok.Type)
+	}
+	expected := []token.TokenType{token.FUNCTION, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE}
+	for i, typ := range expected {
+		if tok := la.Next(); tok.Type != typ {
+			t.Fatalf("Next() %d wrong. expected=%s, got=%s", i, typ, tok.Type)
+		}
+	}
+	if tok := la.Peek(10); tok.Type != token.EOF {
+		t.Errorf("Peek past the end wrong. expected=EOF, got=%s", tok.Type)
+	}
+	if tok := la.Next(); tok.Type != token.IDENT || tok.Column != 9 {
+		t.Errorf("Next() after Peek wrong. got=%+v", tok)
+	}
+}
@@ -0,0 +1,174 @@
+package lint
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/optimizer"
+)
+
+// checkUnused reporta los let cuya variable nunca se usa. Las variables
+// llamadas _ se ignoran: es la forma de descartar un valor a propósito.
+func (l *linter) checkUnused() {
+	used := map[*Definition]bool{}
+	for ident, def := range l.vars.Uses {
+		// Una función que solo se llama a sí misma no cuenta como usada.
+		if def.Let == nil || !contains(def.Let, ident) {
+			used[def] = true
+		}
+	}
+	for _, def := range l.vars.Defs {
+		if def.Let != nil && !used[def] && def.Name.Value != "_" {
+			l.report(def.Name, CheckUnused, "%s declared and not used", def.Name.Value)
+		}
+	}
+}
+
+// checkShadow reporta las variables que ocultan a una de un ámbito
+// exterior o a un builtin. Volver a definir una variable del mismo ámbito
+// con let es la forma de cambiar su valor, así que eso no se reporta.
+func (l *linter) checkShadow() {
+	for _, def := range l.vars.Defs {
+		name := def.Name.Value
+		if def.Shadows != nil {
+			l.report(def.Name, CheckShadow, "declaration of %s shadows the variable at %s", name, def.Shadows.Name.Pos())
+		} else if _, ok := evaluator.BuiltinCapabilities(name); ok {
+			l.report(def.Name, CheckShadow, "declaration of %s shadows the builtin %s", name, name)
+		}
+	}
+}
+
+// contains indica si ident está dentro de node.
+func contains(node ast.Node, ident *ast.Identifier) bool {
+	pos := ident.Pos().Offset
+	return node.Pos().Offset <= pos && pos < node.End().Offset
+}
+
+// checkUnreachable reporta las sentencias que siguen a un return en la
+// misma lista, todas juntas en un solo diagnóstico.
+func (l *linter) checkUnreachable(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		if _, ok := stmt.(*ast.ReturnStatement); ok && i < len(stmts)-1 {
+			l.reportRange(stmts[i+1].Pos(), stmts[len(stmts)-1].End(), CheckUnreachable, "unreachable code")
+			return
+		}
+	}
+}
+
+// checkCondition reporta la condición de exp si su valor no depende de la
+// ejecución.
+func (l *linter) checkCondition(exp *ast.IfExpression) {
+	if value, ok := truthiness(exp.Condition); ok {
+		l.report(exp.Condition, CheckConstantCondition, "condition is always %t", value)
+	}
+}
+
+// truthiness retorna si exp, de ser constante, es verdadera para un if. Las
+// funciones, los arreglos y los hashes literales siempre lo son.
+func truthiness(exp ast.Expression) (value, ok bool) {
+	switch exp.(type) {
+	case *ast.FunctionLiteral, *ast.ArrayLiteral, *ast.HashLiteral:
+		return true, true
+	}
+	switch constant := optimizer.Constant(exp).(type) {
+	case *ast.Boolean:
+		return constant.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+// checkArity compara los argumentos de call con los parámetros de la
+// función llamada, si se conoce: una función literal, una variable
+// definida con let como función o un builtin que siempre recibe la misma
+// cantidad de argumentos. Faltar argumentos es un error al ejecutarse; los
+// de más se ignoran en las funciones, lo que casi siempre es un error del
+// programa, y son un error en los builtins.
+func (l *linter) checkArity(call *ast.CallExpression) {
+	fn, name := l.callee(call)
+	if fn == nil {
+		if want, ok := l.builtinArity(call); ok && len(call.Keywords) == 0 {
+			l.checkCount(call, call.Function.String(), want)
+		}
+		return
+	}
+	params := fn.Parameters
+	if len(call.Keywords) == 0 {
+		l.checkCount(call, name, len(params))
+		return
+	}
+	// Igual que al ejecutarse: los posicionales llenan los primeros
+	// parámetros y cada argumento con nombre el parámetro que nombra.
+	bound := map[string]bool{}
+	for i := range call.Arguments {
+		if i < len(params) {
+			bound[params[i].Value] = true
+		}
+	}
+	for _, arg := range call.Keywords {
+		switch {
+		case !hasParameter(fn, arg.Name.Value):
+			l.report(arg, CheckArity, "unknown keyword argument in call to %s: %s", name, arg.Name.Value)
+		case bound[arg.Name.Value]:
+			l.report(arg, CheckArity, "multiple values for argument in call to %s: %s", name, arg.Name.Value)
+		default:
+			bound[arg.Name.Value] = true
+		}
+	}
+	for _, param := range params {
+		if !bound[param.Value] {
+			l.report(call, CheckArity, "missing argument in call to %s: %s", name, param.Value)
+			bound[param.Value] = true
+		}
+	}
+}
+
+// checkCount reporta call si no tiene want argumentos posicionales.
+func (l *linter) checkCount(call *ast.CallExpression, name string, want int) {
+	got := len(call.Arguments)
+	if got < want {
+		l.report(call, CheckArity, "not enough arguments in call to %s: want=%d, got=%d", name, want, got)
+	} else if got > want {
+		l.report(call, CheckArity, "too many arguments in call to %s: want=%d, got=%d", name, want, got)
+	}
+}
+
+// builtinArity retorna la cantidad de argumentos del builtin que llama
+// call, si la tiene fija y el programa no lo oculta con una variable.
+func (l *linter) builtinArity(call *ast.CallExpression) (int, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return 0, false
+	}
+	if _, defined := l.vars.Uses[ident]; defined {
+		return 0, false
+	}
+	return evaluator.BuiltinArity(ident.Value)
+}
+
+// callee retorna la función que llama call y el nombre con que se la
+// menciona en los mensajes, o nil si no se conoce.
+func (l *linter) callee(call *ast.CallExpression) (*ast.FunctionLiteral, string) {
+	switch callee := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		return callee, "function literal"
+	case *ast.Identifier:
+		def, ok := l.vars.Uses[callee]
+		if !ok || def.Let == nil {
+			return nil, ""
+		}
+		if fn, ok := def.Let.Value.(*ast.FunctionLiteral); ok {
+			return fn, callee.Value
+		}
+	}
+	return nil, ""
+}
+
+func hasParameter(fn *ast.FunctionLiteral, name string) bool {
+	for _, param := range fn.Parameters {
+		if param.Value == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Package lint busca en un programa Monkey errores probables que el
+// parser no detecta, sin ejecutarlo:
+//
+//   - let que nunca se usan (unused),
+//   - variables que ocultan a otra de un ámbito exterior o a un builtin
+//     (shadow),
+//   - sentencias después de un return, que nunca se ejecutan (unreachable),
+//   - condiciones de if que siempre valen lo mismo (constant-condition) y
+//   - llamadas a funciones conocidas o a builtins con una cantidad de
+//     argumentos que no coincide con la de sus parámetros (arity).
+//
+// Lo usan `monkey lint` y el servidor de lenguaje.
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"sort"
+)
+
+// Los nombres de los chequeos, en Diagnostic.Check.
+const (
+	CheckUnused            = "unused"
+	CheckShadow            = "shadow"
+	CheckUnreachable       = "unreachable"
+	CheckConstantCondition = "constant-condition"
+	CheckArity             = "arity"
+)
+
+// Diagnostic es un problema encontrado en el programa.
+type Diagnostic struct {
+	// Pos y End delimitan el código señalado.
+	Pos token.Position
+	End token.Position
+	// Check es el chequeo que lo encontró, por ejemplo CheckUnused.
+	Check string
+	// Message describe el problema, sin la posición.
+	Message string
+}
+
+// String retorna el diagnóstico con el formato línea:columna: mensaje
+// (chequeo).
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Check)
+}
+
+// Lint retorna los diagnósticos de program ordenados por posición. El
+// programa debe estar libre de errores de sintaxis; si no, los
+// diagnósticos pueden ser incorrectos.
+func Lint(program *ast.Program) []Diagnostic {
+	l := &linter{vars: Resolve(program)}
+	l.checkShadow()
+	l.checkUnused()
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			l.checkUnreachable(node.Statements)
+		case *ast.BlockStatement:
+			l.checkUnreachable(node.Statements)
+		case *ast.IfExpression:
+			l.checkCondition(node)
+		case *ast.CallExpression:
+			l.checkArity(node)
+		}
+		return true
+	})
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		return l.diags[i].Pos.Offset < l.diags[j].Pos.Offset
+	})
+	return l.diags
+}
+
+// linter guarda los diagnósticos y las variables de un programa.
+type linter struct {
+	diags []Diagnostic
+	vars  *Resolution
+}
+
+func (l *linter) report(node ast.Node, check, format string, args ...interface{}) {
+	l.reportRange(node.Pos(), node.End(), check, format, args...)
+}
+
+func (l *linter) reportRange(pos, end token.Position, check, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{
+		Pos:     pos,
+		End:     end,
+		Check:   check,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
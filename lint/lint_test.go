@@ -0,0 +1,108 @@
+package lint
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x);", nil},
+		{"let x = 1; let x = x + 1; x", nil},
+		{"let _ = 1;", nil},
+		// unused
+		{"let x = 1;", []string{"1:5: x declared and not used (unused)"}},
+		{"let f = fn(a) { let b = a; a }; f(1)", []string{"1:21: b declared and not used (unused)"}},
+		{"let f = fn(n) { f(n - 1) };", []string{"1:5: f declared and not used (unused)"}},
+		{"let f = fn() { g() }; let g = fn() { 1 }; f()", nil},
+		{"let p = {}; p.x", nil},
+		// shadow
+		{"let x = 1; let f = fn(x) { x }; f(x)", []string{"1:23: declaration of x shadows the variable at 1:5 (shadow)"}},
+		{"let x = 1; let f = fn() { let x = 2; x }; f() + x", []string{"1:31: declaration of x shadows the variable at 1:5 (shadow)"}},
+		{"let len = fn(s) { 0 }; len(1)", []string{"1:5: declaration of len shadows the builtin len (shadow)"}},
+		{"let f = fn() { x }; let x = 1; f() + x", nil},
+		{"let g = fn(x) { x }; let x = 1; g(x)", nil},
+		// unreachable
+		{"let f = fn() { return 1; puts(2); puts(3) }; f()", []string{"1:26: unreachable code (unreachable)"}},
+		{"return 1; 2", []string{"1:11: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1 } 2 }; f(true)", nil},
+		// constant-condition
+		{"if (true) { 1 }", []string{"1:5: condition is always true (constant-condition)"}},
+		{"if (1 > 2) { 1 }", []string{"1:5: condition is always false (constant-condition)"}},
+		{"if (!(1 < 2)) { 1 }", []string{"1:5: condition is always false (constant-condition)"}},
+		{"if ([]) { 1 }", []string{"1:5: condition is always true (constant-condition)"}},
+		{"let x = 1; if (x > 2) { 1 }", nil},
+		// arity
+		{"let sum = fn(a, b) { a + b }; sum(1)", []string{"1:31: not enough arguments in call to sum: want=2, got=1 (arity)"}},
+		{"let sum = fn(a, b) { a + b }; sum(1, 2, 3)", []string{"1:31: too many arguments in call to sum: want=2, got=3 (arity)"}},
+		{"fn(a) { a }()", []string{"1:1: not enough arguments in call to function literal: want=1, got=0 (arity)"}},
+		{"let sum = fn(a, b) { a + b }; sum(1, b: 2)", nil},
+		{"let sum = fn(a, b) { a + b }; sum(b: 1, c: 2)", []string{
+			"1:31: missing argument in call to sum: a (arity)",
+			"1:41: unknown keyword argument in call to sum: c (arity)",
+		}},
+		{"let sum = fn(a, b) { a + b }; sum(1, a: 2)", []string{
+			"1:31: missing argument in call to sum: b (arity)",
+			"1:38: multiple values for argument in call to sum: a (arity)",
+		}},
+		{"let f = fn(g) { g(1, 2) }; f(len)", nil},
+		{"len(1, 2)", []string{"1:1: too many arguments in call to len: want=1, got=2 (arity)"}},
+		{"regex_replace(\"a\", \"b\")", []string{"1:1: not enough arguments in call to regex_replace: want=3, got=2 (arity)"}},
+		{"range(1, 2, 3)", nil},
+		{"let f = fn(len) { len(1, 2) }; f(fn(a, b) { a })", []string{"1:12: declaration of len shadows the builtin len (shadow)"}},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+		}
+		var got []string
+		for _, diag := range Lint(program) {
+			got = append(got, diag.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong diagnostics for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDiagnosticRange(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn() {\n  return 1;\n  puts(2);\n  3\n}; f()")).ParseProgram()
+	diags := Lint(program)
+	if len(diags) != 1 {
+		t.Fatalf("wrong number of diagnostics. want=1, got=%d", len(diags))
+	}
+	diag := diags[0]
+	if diag.Check != CheckUnreachable || diag.Pos.String() != "3:3" || diag.End.String() != "4:4" {
+		t.Errorf("wrong diagnostic. got=%s-%s %s", diag.Pos, diag.End, diag.Check)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	program := parser.New(lexer.New("let x = 1; let f = fn(x) { x + y }; f(x)")).ParseProgram()
+	vars := Resolve(program)
+
+	var got []string
+	for _, ident := range vars.Idents {
+		def, ok := vars.Uses[ident]
+		switch {
+		case ok:
+			got = append(got, ident.Value+"->"+def.Name.Pos().String())
+		default:
+			got = append(got, ident.Value)
+		}
+	}
+	expected := []string{"x", "f", "x", "x->1:23", "y", "f->1:16", "x->1:5"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong identifiers.\nexpected=%q\ngot=%q", expected, got)
+	}
+	if len(vars.Defs) != 3 || vars.Defs[2].Shadows != vars.Defs[0] || vars.Defs[2].Func == nil {
+		t.Errorf("the parameter x should shadow the global x. got=%+v", vars.Defs)
+	}
+}
@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"sort"
+)
+
+// Definition es una variable: la define un let o es un parámetro.
+type Definition struct {
+	Name *ast.Identifier
+	// Let es la sentencia que la define; nil si es un parámetro de Func.
+	Let  *ast.LetStatement
+	Func *ast.FunctionLiteral
+	// Visible es desde dónde la ven los usos de su mismo ámbito. Un let
+	// se ve después de terminar, salvo que defina una función, que se
+	// puede llamar a sí misma.
+	Visible token.Position
+	// Shadows es la variable de un ámbito exterior que oculta, o nil.
+	Shadows *Definition
+}
+
+// Resolution son las variables de un programa y sus usos. La usan el
+// linter y el servidor de lenguaje.
+type Resolution struct {
+	// Defs son las variables en el orden en que se definen.
+	Defs []*Definition
+	// Uses asocia cada uso de una variable con su definición; no están
+	// los usos que no se encontraron, como los builtins.
+	Uses map[*ast.Identifier]*Definition
+	// Idents son los identificadores que nombran variables, en el orden
+	// del código fuente: las definiciones y los usos.
+	Idents []*ast.Identifier
+}
+
+// scope es el ámbito de un programa o de una función. Los bloques de un
+// if no crean un ámbito: un let dentro de ellos define una variable de la
+// función.
+type scope struct {
+	outer *scope
+	defs  []*Definition
+}
+
+// lookup busca la variable name que ve un uso en pos: la última definida
+// antes de pos en el ámbito más interno que la tenga.
+func (s *scope) lookup(name string, pos token.Position) *Definition {
+	for ; s != nil; s = s.outer {
+		var found *Definition
+		for _, def := range s.defs {
+			if def.Name.Value == name && def.Visible.Offset <= pos.Offset {
+				found = def
+			}
+		}
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// lookupLater busca la primera variable name de los ámbitos de s, aunque
+// esté definida después del uso. Es el caso de una función que llama a
+// otra definida más abajo: al ejecutarse la llamada ya existe.
+func (s *scope) lookupLater(name string) *Definition {
+	for ; s != nil; s = s.outer {
+		for _, def := range s.defs {
+			if def.Name.Value == name {
+				return def
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve encuentra las variables de program y la definición de cada
+// uso. Los usos se resuelven al final, cuando ya se conocen todas las
+// definiciones.
+func Resolve(program *ast.Program) *Resolution {
+	r := &Resolution{Uses: map[*ast.Identifier]*Definition{}}
+	type use struct {
+		ident *ast.Identifier
+		scope *scope
+	}
+	var uses []use
+	// notVariables son los identificadores que no nombran variables, como
+	// el miembro de obj.name, o que ya se registraron como definiciones.
+	notVariables := map[*ast.Identifier]bool{}
+	current := &scope{}
+	var stack []ast.Node
+	define := func(s *scope, def *Definition) {
+		def.Shadows = s.outer.lookup(def.Name.Value, def.Name.Pos())
+		s.defs = append(s.defs, def)
+		r.Defs = append(r.Defs, def)
+		r.Idents = append(r.Idents, def.Name)
+		notVariables[def.Name] = true
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			if _, ok := stack[len(stack)-1].(*ast.FunctionLiteral); ok {
+				current = current.outer
+			}
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		stack = append(stack, node)
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Name == nil {
+				break
+			}
+			def := &Definition{Name: node.Name, Let: node, Visible: node.End()}
+			if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+				def.Visible = node.Name.Pos()
+			}
+			define(current, def)
+		case *ast.FunctionLiteral:
+			current = &scope{outer: current}
+			for _, param := range node.Parameters {
+				define(current, &Definition{Name: param, Func: node, Visible: node.Pos()})
+			}
+		case *ast.MemberExpression:
+			notVariables[node.Property] = true
+		case *ast.KeywordArgument:
+			notVariables[node.Name] = true
+		case *ast.Identifier:
+			if !notVariables[node] {
+				r.Idents = append(r.Idents, node)
+				uses = append(uses, use{node, current})
+			}
+		}
+		return true
+	})
+
+	for _, u := range uses {
+		def := u.scope.lookup(u.ident.Value, u.ident.Pos())
+		if def == nil {
+			def = u.scope.lookupLater(u.ident.Value)
+		}
+		if def != nil {
+			r.Uses[u.ident] = def
+		}
+	}
+	sort.Slice(r.Idents, func(i, j int) bool {
+		return r.Idents[i].Pos().Offset < r.Idents[j].Pos().Offset
+	})
+	return r
+}
@@ -0,0 +1,107 @@
+package lsp
+
+// builtinInfo es la documentación de un builtin que muestra el hover.
+type builtinInfo struct {
+	signature string
+	text      string
+}
+
+// builtinDocs documenta los builtins del paquete evaluator. Los textos
+// están en inglés, como los mensajes de error que ve el usuario.
+var builtinDocs = map[string]builtinInfo{
+	"len":   {"len(x)", "Returns the length of a STRING (in bytes), an ARRAY or a SET."},
+	"first": {"first(array)", "Returns the first element of the array, or null if it is empty."},
+	"last":  {"last(array)", "Returns the last element of the array, or null if it is empty."},
+	"rest":  {"rest(array)", "Returns a new array without the first element, or null if it is empty."},
+	"push":  {"push(array, value)", "Returns a new array with value added at the end."},
+	"puts":  {"puts(values...)", "Prints each value on its own line."},
+	"eputs": {"eputs(values...)", "Prints each value on its own line to the standard error."},
+	"exit":  {"exit(code)", "Ends the program with the exit code (0 if omitted)."},
+	"input": {"input(prompt)", "Prints the optional prompt and returns the next line of input, or null at the end."},
+	"args":  {"args()", "Returns an ARRAY with the arguments of the script."},
+
+	"assert":    {"assert(cond, msg)", "Returns an error with msg when cond is not truthy."},
+	"assert_eq": {"assert_eq(a, b)", "Returns an error showing both values when they are not equal."},
+
+	"spawn":       {"spawn(fn, args...)", "Runs fn(args...) in a goroutine and returns a CHANNEL that receives its result."},
+	"async":       {"async(fn, args...)", "Like spawn, but returns a FUTURE whose result is read with await."},
+	"await":       {"await(future)", "Waits for the function of the FUTURE and returns its result."},
+	"channel":     {"channel(n)", "Creates a CHANNEL with capacity n (unbuffered if omitted)."},
+	"send":        {"send(ch, value)", "Sends value through the channel, waiting until there is room."},
+	"recv":        {"recv(ch)", "Waits for the next value of the channel. Returns null once it is closed."},
+	"close":       {"close(ch)", "Closes the channel: pending recv calls get null."},
+	"mutex":       {"mutex()", "Creates a MUTEX to use with lock."},
+	"lock":        {"lock(m, fn)", "Runs fn() holding the mutex and returns its result."},
+	"atomic":      {"atomic(n)", "Creates an ATOMIC counter starting at n (0 if omitted)."},
+	"atomic_add":  {"atomic_add(ref, n)", "Adds n to the counter and returns the new value."},
+	"atomic_load": {"atomic_load(ref)", "Returns the current value of the counter."},
+
+	"fiber":   {"fiber(fn)", "Returns a FIBER that runs fn(yield, value) in steps: each resume continues it until it calls yield(x), and resume returns x."},
+	"resume":  {"resume(f, value)", "Continues the fiber f; value is what its pending yield returns."},
+	"is_done": {"is_done(f)", "Reports whether the function of the fiber f has finished."},
+
+	"sha256":        {"sha256(s)", "Returns the SHA-256 hash of s in hexadecimal."},
+	"md5":           {"md5(s)", "Returns the MD5 hash of s in hexadecimal."},
+	"hmac_sha256":   {"hmac_sha256(key, msg)", "Returns the HMAC-SHA256 of msg in hexadecimal."},
+	"uuid":          {"uuid()", "Returns a random RFC 4122 version 4 identifier."},
+	"base64_encode": {"base64_encode(s)", "Encodes s in base64."},
+	"base64_decode": {"base64_decode(s)", "Decodes the base64 string s."},
+	"hex_encode":    {"hex_encode(s)", "Encodes s in hexadecimal."},
+	"hex_decode":    {"hex_decode(s)", "Decodes the hexadecimal string s."},
+
+	"csv_parse":     {"csv_parse(s, header)", "Returns an ARRAY of rows, each an ARRAY of STRING. With header true, returns an ARRAY of HASH keyed by the names in the first row."},
+	"csv_stringify": {"csv_stringify(rows)", "Converts an ARRAY of ARRAY into CSV text."},
+
+	"env":     {"env(name)", "Returns the value of the environment variable, or null if it isn't set."},
+	"set_env": {"set_env(name, value)", "Sets the environment variable of the process."},
+	"exec":    {"exec(cmd, args)", "Runs the command and returns a HASH {\"stdout\", \"stderr\", \"code\"}."},
+
+	"read_file":   {"read_file(path)", "Returns the contents of the file as a STRING."},
+	"write_file":  {"write_file(path, contents)", "Creates or replaces the file."},
+	"append_file": {"append_file(path, contents)", "Appends contents to the end of the file, creating it if needed."},
+	"file_exists": {"file_exists(path)", "Returns true if the path exists."},
+	"list_dir":    {"list_dir(path)", "Returns a sorted ARRAY with the names of the entries of the directory."},
+	"mkdir":       {"mkdir(path)", "Creates the directory along with any missing parents."},
+	"remove":      {"remove(path) or remove(set, value)", "Deletes a file or an empty directory, or returns a SET without value."},
+	"serve":       {"serve(port, handler)", "Starts an HTTP server that calls handler with a HASH {method, path, query, headers, body} for each request. Blocks while the server runs."},
+
+	"range":     {"range(start, stop, step)", "Returns an ARRAY with the integers from start (0 if omitted) up to stop, not included."},
+	"enumerate": {"enumerate(xs)", "Returns an ARRAY of [index, element] pairs."},
+	"zip":       {"zip(a, b)", "Returns an ARRAY of [a[i], b[i]] pairs, as long as the shortest."},
+	"memo":      {"memo(fn)", "Returns a function that behaves like fn but caches the result of each combination of arguments."},
+
+	"clone":     {"clone(obj)", "Returns a deep copy of an ARRAY, HASH or SET."},
+	"freeze":    {"freeze(obj)", "Marks an ARRAY or HASH as immutable and returns it."},
+	"is_frozen": {"is_frozen(obj)", "Returns true if obj was frozen with freeze."},
+	"keys":      {"keys(hash)", "Returns an ARRAY with the keys in insertion order."},
+	"values":    {"values(hash)", "Returns an ARRAY with the values in insertion order."},
+
+	"some":      {"some(x)", "Returns an OPTION holding x."},
+	"none":      {"none()", "Returns the OPTION without a value."},
+	"is_some":   {"is_some(o)", "Reports whether o holds a value."},
+	"is_none":   {"is_none(o)", "Reports whether o holds no value."},
+	"unwrap":    {"unwrap(o)", "Returns the value of o, or an error if o is none()."},
+	"unwrap_or": {"unwrap_or(o, default)", "Returns the value of o, or default if o is none()."},
+
+	"regex_match":    {"regex_match(pattern, s)", "Returns true if s contains a match of pattern."},
+	"regex_find_all": {"regex_find_all(pattern, s)", "Returns an ARRAY with all the matches of pattern in s."},
+	"regex_replace":  {"regex_replace(pattern, s, repl)", "Replaces all the matches of pattern in s with repl."},
+
+	"set":        {"set(xs)", "Creates a SET with the elements of the iterable xs (empty if omitted)."},
+	"add":        {"add(s, value)", "Returns a SET with value added."},
+	"contains":   {"contains(s, value)", "Returns true if value belongs to s."},
+	"union":      {"union(a, b)", "Returns the elements that are in a or in b."},
+	"intersect":  {"intersect(a, b)", "Returns the elements that are in both a and b."},
+	"difference": {"difference(a, b)", "Returns the elements of a that are not in b."},
+
+	"naturals":    {"naturals(start)", "Returns the infinite STREAM start, start+1, ... (0 if omitted)."},
+	"lazy_map":    {"lazy_map(xs, fn)", "Returns a STREAM with fn applied to each element of xs."},
+	"lazy_filter": {"lazy_filter(xs, fn)", "Returns a STREAM with the elements of xs for which fn is truthy."},
+	"take":        {"take(xs, n)", "Returns an ARRAY with the first n elements of xs."},
+	"drop":        {"drop(xs, n)", "Returns a STREAM with the elements of xs from the n-th on."},
+
+	"now":         {"now()", "Returns the current time in milliseconds since the Unix epoch."},
+	"clock":       {"clock()", "Returns the nanoseconds elapsed according to a monotonic clock."},
+	"sleep":       {"sleep(ms)", "Pauses the program for ms milliseconds."},
+	"format_time": {"format_time(ts, layout)", "Formats ts (Unix milliseconds, local time) with a Go layout such as \"2006-01-02 15:04:05\"."},
+}
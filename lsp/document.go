@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/lint"
+	"monkey/parser"
+	"monkey/token"
+	"sort"
+	"unicode/utf8"
+)
+
+// document es un archivo abierto en el editor, ya analizado.
+type document struct {
+	uri  string
+	text string
+	// lines son los offsets donde empieza cada línea de text.
+	lines []int
+
+	program *ast.Program
+	errors  []parser.ParseError
+
+	// idents son los identificadores que nombran variables, en el orden
+	// del código fuente: las definiciones y los usos.
+	idents []*ast.Identifier
+	// defs asocia cada identificador de idents con la variable que
+	// nombra; no están los usos que no se encontraron, como los builtins.
+	defs map[*ast.Identifier]*lint.Definition
+}
+
+// newDocument analiza text.
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, text: text, lines: []int{0}, defs: map[*ast.Identifier]*lint.Definition{}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	p := parser.New(lexer.New(text))
+	d.program = p.ParseProgram()
+	d.errors = p.ParseErrors()
+	d.resolve()
+	return d
+}
+
+// resolve llena idents y defs con las variables que encuentra
+// lint.Resolve.
+func (d *document) resolve() {
+	vars := lint.Resolve(d.program)
+	d.idents = vars.Idents
+	for ident, def := range vars.Uses {
+		d.defs[ident] = def
+	}
+	for _, def := range vars.Defs {
+		d.defs[def.Name] = def
+	}
+}
+
+// identAt retorna el identificador que está en offset, o nil. Un cursor
+// justo después del nombre también lo señala.
+func (d *document) identAt(offset int) *ast.Identifier {
+	i := sort.Search(len(d.idents), func(i int) bool {
+		return d.idents[i].End().Offset >= offset
+	})
+	if i < len(d.idents) && d.idents[i].Pos().Offset <= offset {
+		return d.idents[i]
+	}
+	return nil
+}
+
+// position convierte una posición de un token en una del protocolo.
+func (d *document) position(pos token.Position) Position {
+	line := pos.Line - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(d.lines) {
+		line = len(d.lines) - 1
+	}
+	start, end := d.lines[line], pos.Offset
+	if end > len(d.text) {
+		end = len(d.text)
+	}
+	if end < start {
+		end = start
+	}
+	return Position{Line: line, Character: utf16Len(d.text[start:end])}
+}
+
+// rangeOf retorna el rango de node.
+func (d *document) rangeOf(node ast.Node) Range {
+	return Range{Start: d.position(node.Pos()), End: d.position(node.End())}
+}
+
+// offset convierte una posición del protocolo en un offset de text.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	offset, units := d.lines[pos.Line], 0
+	for offset < len(d.text) && d.text[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		offset += size
+		units += utf16Units(r)
+	}
+	return offset
+}
+
+// utf16Len retorna cuántas unidades de UTF-16 ocupa s.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,168 @@
+package lsp
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"strings"
+	"testing"
+)
+
+const source = `let add = fn(a, b) { a + b };
+let x = 1;
+let x = add(x, 2);
+let loop = fn(n) { if (n > 0) { let m = n - 1; loop(m) } else { later() } };
+let later = fn() { len(x) };
+let s = "😀"; obj.x + s`
+
+// at retorna la posición de la n-ésima aparición (desde 0) de word en la
+// línea line de source.
+func at(t *testing.T, line int, word string, n int) Position {
+	t.Helper()
+	text := strings.Split(source, "\n")[line]
+	col := -1
+	for i := 0; i <= n; i++ {
+		next := strings.Index(text[col+1:], word)
+		if next < 0 {
+			t.Fatalf("%q not found %d times in line %d", word, n+1, line)
+		}
+		col += next + 1
+	}
+	return Position{Line: line, Character: utf16Len(text[:col])}
+}
+
+func TestDefinition(t *testing.T) {
+	doc := newDocument("file:///a.mk", source)
+	tests := []struct {
+		use  Position
+		want *Position // nil si no tiene definición
+	}{
+		{at(t, 0, "a", 2), ptr(at(t, 0, "a", 1))}, // a + b -> fn(a, b)
+		{at(t, 0, "b", 1), ptr(at(t, 0, "b", 0))},
+		{at(t, 2, "x", 1), ptr(at(t, 1, "x", 0))}, // add(x, 2) -> let x = 1
+		{at(t, 2, "x", 0), ptr(at(t, 2, "x", 0))}, // la definición misma
+		{at(t, 2, "add", 0), ptr(at(t, 0, "add", 0))},
+		{at(t, 3, "loop", 1), ptr(at(t, 3, "loop", 0))},
+		{at(t, 3, "m", 1), ptr(at(t, 3, "m", 0))},
+		{at(t, 3, "later", 0), ptr(at(t, 4, "later", 0))}, // definida después
+		{at(t, 4, "x", 0), ptr(at(t, 2, "x", 0))},
+		{at(t, 4, "len", 0), nil},
+		{at(t, 5, "obj", 0), nil},
+		{at(t, 5, "x", 0), nil}, // obj.x no es una variable
+		{at(t, 5, "s", 1), ptr(at(t, 5, "s", 0))},
+		{Position{Line: 5, Character: at(t, 5, "s", 1).Character + 1}, ptr(at(t, 5, "s", 0))}, // cursor al final
+		{at(t, 3, "if", 0), nil},
+	}
+	for _, tt := range tests {
+		loc := doc.definition(tt.use)
+		switch {
+		case tt.want == nil && loc != nil:
+			t.Errorf("%v: expected no definition. got=%v", tt.use, loc.Range.Start)
+		case tt.want != nil && loc == nil:
+			t.Errorf("%v: expected a definition at %v. got none", tt.use, *tt.want)
+		case tt.want != nil && (loc.Range.Start != *tt.want || loc.URI != "file:///a.mk"):
+			t.Errorf("%v: wrong definition. want=%v, got=%v", tt.use, *tt.want, loc.Range.Start)
+		}
+	}
+}
+
+func ptr(pos Position) *Position { return &pos }
+
+func TestHover(t *testing.T) {
+	doc := newDocument("file:///a.mk", source)
+	tests := []struct {
+		pos      Position
+		expected string // "" si no hay hover
+	}{
+		{at(t, 2, "add", 0), "```monkey\nlet add = fn(a, b)\n```"},
+		{at(t, 0, "b", 1), "```monkey\nb // parameter of fn(a, b)\n```"},
+		{at(t, 4, "x", 0), "```monkey\nlet x = add(x, 2)\n```"},
+		{at(t, 4, "len", 0), "```monkey\nlen(x)\n```\n\nReturns the length of a STRING (in bytes), an ARRAY or a SET."},
+		{at(t, 5, "obj", 0), ""},
+		{at(t, 0, "fn", 0), ""},
+	}
+	for _, tt := range tests {
+		hover := doc.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("%v: wrong hover. want=%q, got=%q", tt.pos, tt.expected, got)
+		}
+	}
+
+	hover := doc.hover(at(t, 5, "s", 1))
+	if want := (Range{Start: at(t, 5, "s", 1), End: Position{Line: 5, Character: at(t, 5, "s", 1).Character + 1}}); hover == nil || hover.Range != want {
+		t.Errorf("wrong hover range. want=%v, got=%v", want, hover)
+	}
+}
+
+func TestBuiltinDocs(t *testing.T) {
+	for _, name := range evaluator.BuiltinNames() {
+		if _, ok := builtinDocs[name]; !ok {
+			t.Errorf("builtin %s is not documented", name)
+		}
+	}
+	doc, _ := builtinDoc("read_file")
+	if !strings.HasSuffix(doc, "\n\nRequires the fs capability.") {
+		t.Errorf("missing capability in %q", doc)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	doc := newDocument("file:///a.mk", "let x = 1;\nlet = \"😀\" + 2;\nx +")
+	diags := doc.diagnostics()
+	if len(diags) == 0 {
+		t.Fatal("expected diagnostics")
+	}
+	want := Diagnostic{
+		Range:    Range{Start: Position{1, 4}, End: Position{1, 5}},
+		Severity: SeverityError,
+		Source:   "monkey",
+		Message:  "expected next token to be IDENT, got = instead.",
+	}
+	if diags[0] != want {
+		t.Errorf("wrong diagnostic.\nwant=%+v\ngot=%+v", want, diags[0])
+	}
+	if last := diags[len(diags)-1]; last.Range.Start.Line != 2 {
+		t.Errorf("expected a diagnostic at the end. got=%+v", last)
+	}
+
+	// Sin errores de sintaxis quedan las advertencias del linter.
+	var warnings []string
+	for _, diag := range newDocument("file:///a.mk", source).diagnostics() {
+		if diag.Severity != SeverityWarning {
+			t.Errorf("unexpected diagnostic: %+v", diag)
+		}
+		warnings = append(warnings, fmt.Sprintf("%d:%d %s", diag.Range.Start.Line, diag.Range.Start.Character, diag.Code))
+	}
+	if got, want := strings.Join(warnings, ", "), "0:4 shadow, 3:4 unused"; got != want {
+		t.Errorf("wrong warnings. want=%q, got=%q", want, got)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	symbols := newDocument("file:///a.mk", source).symbols()
+	var describe func([]DocumentSymbol) string
+	describe = func(symbols []DocumentSymbol) string {
+		parts := []string{}
+		for _, s := range symbols {
+			part := s.Name
+			if s.Kind == SymbolFunction {
+				part += " " + s.Detail
+			}
+			if len(s.Children) > 0 {
+				part += " {" + describe(s.Children) + "}"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+	if got, want := describe(symbols), "add fn(a, b), x, x, loop fn(n) {m}, later fn(), s"; got != want {
+		t.Errorf("wrong symbols.\nwant=%s\ngot=%s", want, got)
+	}
+	loop := symbols[3]
+	if loop.Range.Start != (Position{3, 0}) || loop.SelectionRange.Start != at(t, 3, "loop", 0) {
+		t.Errorf("wrong ranges: %+v", loop)
+	}
+}
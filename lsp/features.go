@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lint"
+	"strings"
+)
+
+// Largo máximo del valor de un let que se muestra en un hover.
+const maxHoverValue = 60
+
+// diagnostics retorna los errores de sintaxis del documento o, si no
+// tiene, las advertencias del linter.
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	for _, err := range d.errors {
+		start := d.position(err.Pos)
+		end := start
+		if err.Got.Literal != "" && err.Got.Offset == err.Pos.Offset {
+			end = d.position(err.Got.End())
+		}
+		diags = append(diags, Diagnostic{
+			Range:    Range{Start: start, End: end},
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+	if len(d.errors) != 0 {
+		// Con errores de sintaxis el árbol está incompleto y el linter
+		// reportaría problemas que no existen.
+		return diags
+	}
+	for _, diag := range lint.Lint(d.program) {
+		diags = append(diags, Diagnostic{
+			Range:    Range{Start: d.position(diag.Pos), End: d.position(diag.End)},
+			Severity: SeverityWarning,
+			Code:     diag.Check,
+			Source:   "monkey",
+			Message:  diag.Message,
+		})
+	}
+	return diags
+}
+
+// hover retorna la descripción de la variable o el builtin en pos, o nil
+// si no hay ninguno.
+func (d *document) hover(pos Position) *Hover {
+	ident := d.identAt(d.offset(pos))
+	if ident == nil {
+		return nil
+	}
+	var text string
+	if def, ok := d.defs[ident]; ok {
+		text = "```monkey\n" + describe(def) + "\n```"
+	} else if doc, ok := builtinDoc(ident.Value); ok {
+		text = doc
+	} else {
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    d.rangeOf(ident),
+	}
+}
+
+// describe muestra la definición de def como código Monkey.
+func describe(def *lint.Definition) string {
+	if def.Let == nil {
+		return fmt.Sprintf("%s // parameter of %s", def.Name.Value, signature(def.Func))
+	}
+	if fn, ok := def.Let.Value.(*ast.FunctionLiteral); ok {
+		return fmt.Sprintf("let %s = %s", def.Name.Value, signature(fn))
+	}
+	if def.Let.Value == nil {
+		return "let " + def.Name.Value
+	}
+	value := def.Let.Value.String()
+	if len(value) > maxHoverValue {
+		value = value[:maxHoverValue-3] + "..."
+	}
+	return fmt.Sprintf("let %s = %s", def.Name.Value, value)
+}
+
+// signature retorna fn(a, b) para una función con parámetros a y b.
+func signature(fn *ast.FunctionLiteral) string {
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = param.Value
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
+// builtinDoc retorna la documentación en Markdown del builtin name.
+func builtinDoc(name string) (string, bool) {
+	required, ok := evaluator.BuiltinCapabilities(name)
+	if !ok {
+		return "", false
+	}
+	// Los builtins que agrega el programa que embebe el intérprete no
+	// tienen documentación.
+	doc := builtinDocs[name]
+	if doc.signature == "" {
+		doc = builtinInfo{name + "(...)", "Builtin function."}
+	}
+	text := "```monkey\n" + doc.signature + "\n```\n\n" + doc.text
+	if required != evaluator.CapNone {
+		text += fmt.Sprintf("\n\nRequires the %s capability.", required)
+	}
+	return text, true
+}
+
+// definition retorna dónde se define la variable en pos, o nil.
+func (d *document) definition(pos Position) *Location {
+	ident := d.identAt(d.offset(pos))
+	if ident == nil {
+		return nil
+	}
+	def, ok := d.defs[ident]
+	if !ok {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.rangeOf(def.Name)}
+}
+
+// symbols retorna los let del programa. Los de una función aparecen como
+// hijos del let que la define.
+func (d *document) symbols() []DocumentSymbol {
+	return d.symbolsIn(d.program)
+}
+
+func (d *document) symbolsIn(node ast.Node) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	root := true
+	ast.Inspect(node, func(node ast.Node) bool {
+		if root {
+			// El nodo inicial, que puede ser la función de un let.
+			root = false
+			return true
+		}
+		let, ok := node.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			return true
+		}
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SymbolVariable,
+			Range:          d.rangeOf(let),
+			SelectionRange: d.rangeOf(let.Name),
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			symbol.Kind = SymbolFunction
+			symbol.Detail = signature(fn)
+			symbol.Children = d.symbolsIn(fn)
+		} else if let.Value != nil {
+			symbol.Children = d.symbolsIn(let.Value)
+		}
+		symbols = append(symbols, symbol)
+		return false
+	})
+	return symbols
+}
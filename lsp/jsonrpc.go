@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// conn lee y escribe los mensajes de JSON-RPC con el encabezado
+// Content-Length que usa LSP:
+//
+//	Content-Length: 52\r\n
+//	\r\n
+//	{"jsonrpc":"2.0","id":1,"method":"shutdown"}
+type conn struct {
+	in  *textproto.Reader
+	out io.Writer
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{in: textproto.NewReader(bufio.NewReader(in)), out: out}
+}
+
+// read retorna el contenido del mensaje siguiente. Retorna io.EOF si el
+// cliente cerró la conexión entre dos mensajes.
+func (c *conn) read() ([]byte, error) {
+	header, err := c.in.ReadMIMEHeader()
+	if err == io.EOF && len(header) == 0 {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lsp: reading header: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("lsp: invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.in.R, body); err != nil {
+		return nil, fmt.Errorf("lsp: reading message: %w", err)
+	}
+	return body, nil
+}
+
+// write envía msg codificado en JSON.
+func (c *conn) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.out.Write(body)
+	return err
+}
@@ -0,0 +1,205 @@
+// Package lsp implementa un servidor del Language Server Protocol para
+// programas Monkey, el que usa `monkey lsp`. Los editores lo ejecutan y
+// se comunican con él por la entrada y la salida estándar. Ofrece:
+//
+//   - diagnósticos con los errores de sintaxis y su posición o, si no hay
+//     ninguno, las advertencias del paquete lint,
+//   - hover con la definición de una variable o la documentación de un
+//     builtin,
+//   - los símbolos del documento (sus let) y
+//   - ir a la definición de una variable.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoShutdown es el error de Serve cuando el cliente pide exit sin
+// haber pedido shutdown antes. El protocolo indica terminar con el código
+// de salida 1 en ese caso.
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+// Serve atiende al cliente que escribe los mensajes en in y los lee de
+// out, hasta que pide exit o cierra in.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{conn: newConn(in, out), docs: map[string]*document{}}
+	return s.serve()
+}
+
+// server es el estado de una sesión: los documentos que el editor tiene
+// abiertos, ya analizados.
+type server struct {
+	conn        *conn
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+func (s *server) serve() error {
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			// Sin poder leer el ID la respuesta no puede indicar a qué
+			// pedido corresponde.
+			if err := s.reply(nil, nil, &responseError{codeParseError, err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "" {
+			// Una respuesta del cliente: el servidor no hace pedidos.
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle atiende un pedido o una notificación. Solo retorna un error si
+// no se pudo escribir la respuesta.
+func (s *server) handle(req request) error {
+	if req.ID == nil {
+		return s.notify(req)
+	}
+	var result interface{}
+	var rerr *responseError
+	switch {
+	case !s.initialized && req.Method != "initialize":
+		rerr = &responseError{codeServerNotInitialized, "server not initialized"}
+	case s.shutdown:
+		rerr = &responseError{codeInvalidRequest, "server is shutting down"}
+	default:
+		result, rerr = s.call(req)
+	}
+	return s.reply(req.ID, result, rerr)
+}
+
+// call ejecuta el pedido req y retorna su resultado.
+func (s *server) call(req request) (interface{}, *responseError) {
+	switch req.Method {
+	case "initialize":
+		s.initialized = true
+		var result InitializeResult
+		result.Capabilities = ServerCapabilities{
+			TextDocumentSync:       textDocumentSyncFull,
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			DocumentSymbolProvider: true,
+		}
+		result.ServerInfo.Name = "monkey"
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			if hover := doc.hover(params.Position); hover != nil {
+				return hover, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			if loc := doc.definition(params.Position); loc != nil {
+				return loc, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if doc, ok := s.docs[params.TextDocument.URI]; ok {
+			return doc.symbols(), nil
+		}
+		return []DocumentSymbol{}, nil
+	}
+	return nil, &responseError{codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{codeInvalidParams, err.Error()}
+}
+
+// notify atiende una notificación. Las que no conoce, o las que llegan
+// antes de initialize, se ignoran.
+func (s *server) notify(req request) error {
+	if !s.initialized {
+		return nil
+	}
+	switch req.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(req.Params, &params) == nil {
+			return s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(req.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			last := params.ContentChanges[len(params.ContentChanges)-1]
+			return s.update(params.TextDocument.URI, last.Text)
+		}
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(req.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+			return s.publish(params.TextDocument.URI, []Diagnostic{})
+		}
+	}
+	return nil
+}
+
+// update analiza el nuevo texto del documento uri y publica sus
+// diagnósticos.
+func (s *server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.publish(uri, doc.diagnostics())
+}
+
+func (s *server) publish(uri string, diags []Diagnostic) error {
+	return s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+}
+
+// reply responde al pedido id con result o, si no es nil, con rerr.
+func (s *server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = data
+	}
+	return s.conn.write(resp)
+}
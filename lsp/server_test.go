@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// session escribe los mensajes de msgs como lo haría un editor, ejecuta
+// Serve y retorna lo que respondió el servidor.
+func session(t *testing.T, msgs ...string) ([]map[string]interface{}, error) {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, msg := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	err := Serve(&in, &out)
+
+	var replies []map[string]interface{}
+	c := newConn(&out, nil)
+	for {
+		body, err := c.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading replies: %s", err)
+		}
+		var reply map[string]interface{}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatalf("invalid reply %s: %s", body, err)
+		}
+		replies = append(replies, reply)
+	}
+	return replies, err
+}
+
+func TestServe(t *testing.T) {
+	open, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/didOpen",
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "file:///a.mk", "languageId": "monkey", "version": 1, "text": "let f = fn(x) { x };\nf(1) +"},
+		},
+	})
+	replies, err := session(t,
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		string(open),
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/definition","params":{"textDocument":{"uri":"file:///a.mk"},"position":{"line":1,"character":0}}}`,
+		`{"jsonrpc":"2.0","id":"4","method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.mk"},"position":{"line":0,"character":16}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"file:///a.mk"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.mk"},"position":{"line":1,"character":4}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.mk"},"contentChanges":[{"text":"f"}]}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"textDocument/formatting","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"file:///a.mk"}}}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":8,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":9,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if err != nil {
+		t.Fatalf("Serve failed: %s", err)
+	}
+
+	expected := []string{
+		`{"error":{"code":-32002,"message":"server not initialized"},"id":1,"jsonrpc":"2.0"}`,
+		`{"id":2,"jsonrpc":"2.0","result":{"capabilities":{"definitionProvider":true,"documentSymbolProvider":true,"hoverProvider":true,"textDocumentSync":1},"serverInfo":{"name":"monkey"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[{"message":"no prefix parse function for EOF found","range":{"end":{"character":6,"line":1},"start":{"character":6,"line":1}},"severity":1,"source":"monkey"}],"uri":"file:///a.mk"}}`,
+		`{"id":3,"jsonrpc":"2.0","result":{"range":{"end":{"character":5,"line":0},"start":{"character":4,"line":0}},"uri":"file:///a.mk"}}`,
+		`{"id":"4","jsonrpc":"2.0","result":{"contents":{"kind":"markdown","value":"` + "```monkey\\nx // parameter of fn(x)\\n```" + `"},"range":{"end":{"character":17,"line":0},"start":{"character":16,"line":0}}}}`,
+		`{"id":5,"jsonrpc":"2.0","result":[{"detail":"fn(x)","kind":12,"name":"f","range":{"end":{"character":19,"line":0},"start":{"character":0,"line":0}},"selectionRange":{"end":{"character":5,"line":0},"start":{"character":4,"line":0}}}]}`,
+		`{"id":6,"jsonrpc":"2.0","result":null}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"file:///a.mk"}}`,
+		`{"error":{"code":-32601,"message":"method not found: textDocument/formatting"},"id":7,"jsonrpc":"2.0"}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"file:///a.mk"}}`,
+		`{"error":{"code":-32700,"message":"invalid character 'o' in literal null (expecting 'u')"},"id":null,"jsonrpc":"2.0"}`,
+		`{"id":8,"jsonrpc":"2.0","result":null}`,
+		`{"error":{"code":-32600,"message":"server is shutting down"},"id":9,"jsonrpc":"2.0"}`,
+	}
+	if len(replies) != len(expected) {
+		t.Fatalf("wrong number of replies. want=%d, got=%d: %v", len(expected), len(replies), replies)
+	}
+	for i, reply := range replies {
+		got, _ := json.Marshal(reply)
+		if string(got) != expected[i] {
+			t.Errorf("reply %d:\nwant=%s\ngot= %s", i, expected[i], got)
+		}
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	_, err := session(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if err != ErrNoShutdown {
+		t.Errorf("expected ErrNoShutdown. got=%v", err)
+	}
+
+	// Cerrar la entrada también termina la sesión.
+	if _, err := session(t); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	err = Serve(strings.NewReader("Content-Length: 10\r\n\r\n{}"), &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "lsp: reading message") {
+		t.Errorf("expected a truncated message error. got=%v", err)
+	}
+}
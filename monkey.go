@@ -0,0 +1,336 @@
+// Package monkey permite usar el lenguaje Monkey desde un programa Go sin
+// armar a mano el lexer, el parser y el evaluador:
+//
+//	interp := monkey.New()
+//	defer interp.Shutdown()
+//	result, err := interp.Run(`let doble = fn(x) { x * 2 }; doble(21)`)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	n, err := result.Int64() // 42
+//
+// Las variables globales de un Interpreter se conservan de un Run al
+// siguiente, como en el REPL.
+//
+// Los Interpreter no comparten estado entre sí, así que cada uno se puede
+// usar desde su propia goroutine (ver también Pool). Lo único común a
+// todos son las variables de configuración de los paquetes evaluator y
+// token, como evaluator.Sandbox o token.RegisterKeyword, que se deben
+// asignar antes de crear el primer Interpreter; las Options de cada
+// Interpreter las reemplazan.
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/vm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Engine es el motor que ejecuta los programas.
+type Engine int
+
+const (
+	// Eval recorre el AST (ver el paquete evaluator).
+	Eval Engine = iota
+	// VM compila a bytecode y lo ejecuta en la máquina virtual.
+	VM
+)
+
+// ErrShutdown es el error de Run después de Shutdown.
+var ErrShutdown = errors.New("monkey: interpreter is shut down")
+
+// Option configura un Interpreter (ver New).
+type Option func(*Interpreter)
+
+// WithEngine hace que el Interpreter use el motor engine. Por omisión usa
+// Eval.
+func WithEngine(engine Engine) Option {
+	return func(i *Interpreter) {
+		i.engine = engine
+	}
+}
+
+// Options son los límites y la salida de los programas de un
+// Interpreter. Los dos motores las aplican; el valor cero no impone
+// límites y usa la salida estándar.
+type Options struct {
+	// Stdout y Stderr, si no son nil, reciben lo que escriben puts e
+	// input, y eputs. No hace falta que admitan escrituras concurrentes.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Stdin, si no es nil, es lo que lee input en lugar de
+	// evaluator.Stdin.
+	Stdin io.Reader
+	// Args, si no es nil, es lo que retorna args() en lugar de
+	// evaluator.Args.
+	Args []string
+	// MaxMemory, si es mayor que 0, es cuántos bytes pueden reservar los
+	// strings, arrays y hashes que crea un Run. Es una cuenta aproximada
+	// (ver evaluator.Options).
+	MaxMemory int64
+	// MaxSteps, si es mayor que 0, es la cantidad de pasos que puede dar
+	// un Run: nodos evaluados con Eval, instrucciones con VM.
+	MaxSteps int64
+	// MaxDepth, si es mayor que 0, reemplaza a evaluator.MaxDepth como
+	// la cantidad máxima de llamadas anidadas.
+	MaxDepth int
+	// Timeout, si es mayor que 0, es el tiempo máximo de un Run.
+	Timeout time.Duration
+	// CheckedArithmetic hace que la aritmética entera que desborda un
+	// int64 sea un error (ver evaluator.Options).
+	CheckedArithmetic bool
+	// Sandboxed limita los builtins que acceden al anfitrión a los que
+	// solo necesitan las capacidades de Capabilities. Si es false se
+	// aplican las variables Sandbox y AllowExec del paquete evaluator.
+	Sandboxed    bool
+	Capabilities evaluator.Capability
+}
+
+// WithOptions hace que el Interpreter ejecute los programas con opts.
+func WithOptions(opts Options) Option {
+	return func(i *Interpreter) {
+		i.opts = opts
+	}
+}
+
+// Interpreter ejecuta programas Monkey. Un Interpreter ejecuta un programa
+// a la vez: si Run se llama desde varias goroutines, las llamadas esperan
+// su turno. Para ejecutar en paralelo se usa un Interpreter por
+// goroutine.
+type Interpreter struct {
+	engine Engine
+	opts   Options
+	// mu serializa las ejecuciones.
+	mu sync.Mutex
+	// ctx se cancela con Shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Variables globales del evaluador.
+	env *object.Environment
+	// Estado de la VM: los símbolos y las constantes de lo ya compilado y
+	// los valores de las variables globales.
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+}
+
+// New crea un Interpreter con las opciones options.
+func New(options ...Option) *Interpreter {
+	i := &Interpreter{env: object.NewEnvironment()}
+	i.ctx, i.cancel = context.WithCancel(context.Background())
+	for _, option := range options {
+		option(i)
+	}
+	if i.engine == VM {
+		i.symbolTable = compiler.NewSymbolTable()
+		compiler.DefineBuiltins(i.symbolTable)
+		i.globals = make([]object.Object, vm.GlobalsSize)
+	}
+	return i
+}
+
+// Result es lo que produjo un programa. Kind, Int64, String, Slice, Map y
+// los demás métodos leen Value sin tener que conocer los tipos del
+// paquete object.
+type Result struct {
+	// Value es el valor de la última expresión, o nil si el programa no
+	// termina en una (por ejemplo, si termina con un let).
+	Value object.Object
+	// Exited indica que el programa llamó a exit, con el código ExitCode.
+	Exited   bool
+	ExitCode int
+}
+
+// RuntimeError es un error que ocurrió al ejecutar un programa.
+type RuntimeError struct {
+	Message string
+	// Línea y columna donde ocurrió (0 si se desconocen).
+	Line   int
+	Column int
+	// Stack es la pila de llamadas, de la más interna a la más externa.
+	Stack []object.StackFrame
+}
+
+func (e *RuntimeError) Error() string {
+	obj := &object.Error{Message: e.Message, Line: e.Line, Column: e.Column, Stack: e.Stack}
+	return strings.TrimPrefix(obj.Inspect(), "ERROR: ")
+}
+
+// Run ejecuta el programa src. Si src tiene errores de sintaxis el error
+// es un parser.ErrorList; si falla al ejecutarse, un *RuntimeError.
+func (i *Interpreter) Run(src string) (Result, error) {
+	program, err := parser.ParseReader(strings.NewReader(src))
+	if err != nil {
+		return Result{}, err
+	}
+	return i.run(program, false)
+}
+
+// RunFile ejecuta el script en path, igual que Run.
+func (i *Interpreter) RunFile(path string) (Result, error) {
+	program, err := parser.ParseFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	return i.run(program, false)
+}
+
+// Program es un programa ya analizado por Compile, que se puede ejecutar
+// muchas veces con RunProgram sin volver a analizarlo. Ejecutarlo no lo
+// modifica, así que varias goroutines pueden ejecutar el mismo Program a
+// la vez, cada una con su Interpreter:
+//
+//	program, err := monkey.Compile(src)
+//	...
+//	// en cada pedido:
+//	result, err := monkey.New().RunProgram(program)
+type Program struct {
+	program *ast.Program
+}
+
+// Compile analiza src para ejecutarlo después con RunProgram. Si src
+// tiene errores de sintaxis el error es un parser.ErrorList.
+func Compile(src string) (*Program, error) {
+	program, err := parser.ParseReader(strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	evaluator.Resolve(program)
+	return &Program{program: program}, nil
+}
+
+// RunProgram ejecuta program igual que Run. Con el motor VM el programa se
+// compila a bytecode en cada llamada, porque el resultado depende de las
+// variables globales del Interpreter.
+func (i *Interpreter) RunProgram(program *Program) (Result, error) {
+	return i.run(program.program, true)
+}
+
+// Shutdown detiene el Interpreter: el programa que se está ejecutando
+// termina con un error y los Run siguientes retornan ErrShutdown.
+func (i *Interpreter) Shutdown() {
+	i.cancel()
+}
+
+// run ejecuta program. resolved indica que ya pasó por evaluator.Resolve
+// (ver evaluator.Options). Un panic de Go durante la ejecución, por
+// ejemplo en un builtin, se retorna como un *RuntimeError para no
+// terminar el programa que embebe el intérprete.
+func (i *Interpreter) run(program *ast.Program, resolved bool) (res Result, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.ctx.Err() != nil {
+		return Result{}, ErrShutdown
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if i.engine == VM {
+				i.symbolTable.DeleteUnset(i.globals)
+			}
+			res, err = Result{}, &RuntimeError{Message: fmt.Sprint(r)}
+		}
+	}()
+	ctx := i.ctx
+	if i.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.opts.Timeout)
+		defer cancel()
+	}
+	var value object.Object
+	if i.engine == VM {
+		if value, err = i.runVM(ctx, program); err != nil {
+			return Result{}, err
+		}
+	} else {
+		value = evaluator.EvalWithOptions(program, i.env, evaluator.Options{
+			MaxSteps:          i.opts.MaxSteps,
+			MaxMemory:         i.opts.MaxMemory,
+			MaxDepth:          i.opts.MaxDepth,
+			Context:           ctx,
+			CheckedArithmetic: i.opts.CheckedArithmetic,
+			Sandboxed:         i.opts.Sandboxed,
+			Capabilities:      i.opts.Capabilities,
+			Stdout:            i.opts.Stdout,
+			Stderr:            i.opts.Stderr,
+			Stdin:             i.opts.Stdin,
+			Args:              i.opts.Args,
+			Resolved:          resolved,
+		})
+	}
+	return result(value)
+}
+
+// runVM compila y ejecuta program con las variables globales del
+// Interpreter, hasta que termine o se cancele ctx.
+func (i *Interpreter) runVM(ctx context.Context, program *ast.Program) (object.Object, error) {
+	// Los símbolos de un programa que no compila no se conservan: sus
+	// variables globales nunca tendrían valor.
+	symbolTable := i.symbolTable.Copy()
+	comp := compiler.NewWithState(symbolTable, i.constants)
+	comp.SetFoldConstants(true)
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+	bytecode := comp.Bytecode().Optimize().Fuse()
+	i.symbolTable = symbolTable
+	i.constants = bytecode.Constants
+
+	machine := vm.NewWithOptions(bytecode, vm.Options{
+		Globals:           i.globals,
+		MaxSteps:          i.opts.MaxSteps,
+		MaxMemory:         i.opts.MaxMemory,
+		MaxFrames:         i.opts.MaxDepth,
+		Context:           ctx,
+		CheckedArithmetic: i.opts.CheckedArithmetic,
+		Sandboxed:         i.opts.Sandboxed,
+		Capabilities:      i.opts.Capabilities,
+		Stdout:            i.opts.Stdout,
+		Stderr:            i.opts.Stderr,
+		Stdin:             i.opts.Stdin,
+		Args:              i.opts.Args,
+	})
+	if err := machine.Run(); err != nil {
+		// Los let que no llegaron a ejecutarse no definen la variable.
+		i.symbolTable.DeleteUnset(i.globals)
+		var rt *vm.RuntimeError
+		if errors.As(err, &rt) {
+			return rt.Object(), nil
+		}
+		return nil, err
+	}
+	last := machine.LastPoppedStackElem()
+	if _, ok := last.(*object.Exit); ok {
+		i.symbolTable.DeleteUnset(i.globals)
+		return last, nil
+	}
+	// Igual que en el evaluador, un programa que termina con let no tiene
+	// valor.
+	if n := len(program.Statements); n == 0 {
+		return nil, nil
+	} else if _, ok := program.Statements[n-1].(*ast.LetStatement); ok {
+		return nil, nil
+	}
+	return last, nil
+}
+
+// result arma el Result o el error de un programa que terminó con value.
+func result(value object.Object) (Result, error) {
+	switch value := value.(type) {
+	case *object.Error:
+		return Result{}, &RuntimeError{Message: value.Message, Line: value.Line, Column: value.Column, Stack: value.Stack}
+	case *object.Exit:
+		return Result{Exited: true, ExitCode: value.Code}, nil
+	}
+	return Result{Value: value}, nil
+}
@@ -0,0 +1,333 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var engines = map[string]Engine{"eval": Eval, "vm": VM}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{`let doble = fn(x) { x * 2 }; doble(21)`, "42"},
+		{`len("hola")`, "4"},
+		{"let x = 1;", ""},
+	}
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		for _, tt := range tests {
+			result, err := interp.Run(tt.input)
+			if err != nil {
+				t.Errorf("%s: %q: %s", name, tt.input, err)
+				continue
+			}
+			got := ""
+			if result.Value != nil {
+				got = result.Value.Inspect()
+			}
+			if got != tt.expected {
+				t.Errorf("%s: %q: want=%q, got=%q", name, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestGlobalsPersist(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		if _, err := interp.Run("let contador = 10; let sumar = fn(n) { contador + n };"); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		result, err := interp.Run("sumar(5)")
+		if err != nil || result.Value.Inspect() != "15" {
+			t.Errorf("%s: wrong result. got=%v, %v", name, result.Value, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		_, err := interp.Run("let = 1;")
+		var parseErrors parser.ErrorList
+		if !errors.As(err, &parseErrors) {
+			t.Errorf("%s: expected a parser.ErrorList. got=%T (%v)", name, err, err)
+		}
+
+		_, err = interp.Run(`let f = fn() { 1 + "a" };
+f()`)
+		var rt *RuntimeError
+		if !errors.As(err, &rt) {
+			t.Fatalf("%s: expected a *RuntimeError. got=%T (%v)", name, err, err)
+		}
+		if rt.Message != "type mismatch: INTEGER + STRING" || rt.Line != 1 || len(rt.Stack) != 1 {
+			t.Errorf("%s: wrong error: %+v", name, rt)
+		}
+
+		result, err := interp.Run("exit(3); let x = 1;")
+		if err != nil || !result.Exited || result.ExitCode != 3 {
+			t.Errorf("%s: wrong exit result: %+v, %v", name, result, err)
+		}
+	}
+}
+
+// Los panics de Go al ejecutar son errores del programa, no del anfitrión.
+func TestRunRecoversPanics(t *testing.T) {
+	tests := []struct {
+		engine   Engine
+		input    string
+		expected string
+	}{
+		{Eval, "boom()", "boom"},
+		{VM, "boom()", "boom"},
+		{VM, "let a = 1; let b = boom();", "boom"},
+	}
+	panics := &object.Builtin{Fn: func(args ...object.Object) object.Object { panic("boom") }}
+	for _, tt := range tests {
+		interp := New(WithEngine(tt.engine), WithOptions(Options{Stdout: &strings.Builder{}}))
+		interp.define("boom", panics)
+		_, err := interp.Run(tt.input)
+		var rt *RuntimeError
+		if !errors.As(err, &rt) || rt.Message != tt.expected {
+			t.Errorf("%d: %q: wrong error. want=%q, got=%v", tt.engine, tt.input, tt.expected, err)
+			continue
+		}
+		if result, err := interp.Run("1 + 1"); err != nil || result.Value.Inspect() != "2" {
+			t.Errorf("%d: interpreter unusable after a panic. got=%v, %v", tt.engine, result.Value, err)
+		}
+	}
+}
+
+// Un programa que falla no deja variables sin valor: usarlas es un error y
+// no un valor nil.
+func TestFailedRunKeepsGlobals(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		if _, err := interp.Run("let a = 1; let b = nope;"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := interp.Run("let c = 2; let d = 1 + true;"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		for _, input := range []string{"b", "d + 1"} {
+			if _, err := interp.Run(input); err == nil {
+				t.Errorf("%s: %q: expected an error", name, input)
+			}
+		}
+		result, err := interp.Run("[c, c]")
+		if err != nil || result.Value.Inspect() != "[2, 2]" {
+			t.Errorf("%s: wrong result. got=%v, %v", name, result.Value, err)
+		}
+	}
+}
+
+// Los módulos predefinidos los comparten todos los intérpretes, así que
+// ningún programa los puede modificar.
+func TestModulesAreShared(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := New().Run("freeze(path); is_frozen(path)")
+			if err != nil || result.Value.Inspect() != "true" {
+				t.Errorf("wrong result. got=%v, %v", result.Value, err)
+			}
+		}()
+	}
+	wg.Wait()
+	result, err := New().Run(`[is_frozen(path), path["join"]("a", "b")]`)
+	if err != nil || result.Value.Inspect() != `[true, a/b]` {
+		t.Errorf("wrong result. got=%v, %v", result.Value, err)
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	os.WriteFile(path, []byte("let x = 6;\nx * 7\n"), 0o644)
+	result, err := New().RunFile(path)
+	if err != nil || result.Value.Inspect() != "42" {
+		t.Errorf("wrong result. got=%v, %v", result.Value, err)
+	}
+	if _, err := New().RunFile(path + ".missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error. got=%v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	interp := New()
+	interp.Shutdown()
+	if _, err := interp.Run("1"); err != ErrShutdown {
+		t.Errorf("expected ErrShutdown. got=%v", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	loop := "let loop = fn(n) { loop(n + 1) }; loop(0)"
+	tests := []struct {
+		input    string
+		opts     Options
+		stdin    string
+		expected string // prefijo del error, o la salida si no hay error
+	}{
+		{`puts("hola"); eputs("chau")`, Options{}, "", "hola\n|chau\n"},
+		{`puts(input("> ") + input())`, Options{}, "ho\nla\n", "> hola\n|"},
+		{loop, Options{MaxSteps: 1000}, "", "fuel exhausted"},
+		{loop, Options{Timeout: 20 * time.Millisecond}, "", "evaluation cancelled: context deadline exceeded"},
+		// The builtins that wait stop waiting at the timeout.
+		{"recv(channel())", Options{Timeout: 20 * time.Millisecond}, "", "evaluation cancelled: context deadline exceeded"},
+		{"sleep(100000)", Options{Timeout: 20 * time.Millisecond}, "", "evaluation cancelled: context deadline exceeded"},
+		{"let m = mutex(); lock(m, fn() { lock(m, fn() { 1 }) })", Options{Timeout: 20 * time.Millisecond}, "", "evaluation cancelled: context deadline exceeded"},
+		{"let f = fn(n) { 1 + f(n + 1) }; f(0)", Options{MaxDepth: 50}, "", "maximum recursion depth exceeded (50)"},
+		{`read_file("x.txt")`, Options{Sandboxed: true}, "", "`read_file` requires the fs capability"},
+		{`env("HOME"); puts("ok")`, Options{Sandboxed: true, Capabilities: evaluator.CapEnv}, "", "ok\n|"},
+		{"puts(9223372036854775807 + 1)", Options{}, "", "-9223372036854775808\n|"},
+		{"puts(9223372036854775807 + 1)", Options{CheckedArithmetic: true}, "", "integer overflow: 9223372036854775807 + 1"},
+		{"let max = 9223372036854775807; puts(max + 1)", Options{CheckedArithmetic: true}, "", "integer overflow: 9223372036854775807 + 1"},
+		{"let f = fn(a, b) { a + b }; puts(f(9223372036854775807, 1))", Options{CheckedArithmetic: true}, "", "integer overflow: 9223372036854775807 + 1"},
+		{"let f = fn(a) { a - 2 }; puts(f(-9223372036854775807))", Options{CheckedArithmetic: true}, "", "integer overflow: -9223372036854775807 - 2"},
+		{"let min = -9223372036854775807 - 1; puts(-min)", Options{CheckedArithmetic: true}, "", "integer overflow: -(-9223372036854775808)"},
+		{"let n = 3; puts(n ** 40)", Options{CheckedArithmetic: true}, "", "integer overflow: 3 ** 40"},
+		{"let n = 4611686018427387904; puts(n * 2)", Options{CheckedArithmetic: true}, "", "integer overflow: 4611686018427387904 * 2"},
+		{"let n = 2; puts(n ** 62 - 1 + n ** 62)", Options{CheckedArithmetic: true}, "", "9223372036854775807\n|"},
+	}
+	for name, engine := range engines {
+		for _, tt := range tests {
+			var out, errOut strings.Builder
+			tt.opts.Stdout, tt.opts.Stderr = &out, &errOut
+			tt.opts.Stdin = strings.NewReader(tt.stdin)
+			_, err := New(WithEngine(engine), WithOptions(tt.opts)).Run(tt.input)
+			got := out.String() + "|" + errOut.String()
+			if err != nil {
+				got = err.Error()
+			}
+			if !strings.HasPrefix(got, tt.expected) {
+				t.Errorf("%s: %q: want=%q, got=%q", name, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+// Cada llamada a un builtin restringido da un error nuevo, con su propia
+// pila de llamadas.
+func TestRestrictedBuiltinErrors(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine), WithOptions(Options{Sandboxed: true}))
+		if _, err := interp.Run("let r = remove; let h = fn(p) { r(p) };"); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		for _, input := range []string{`h("a")`, `h("b")`, `1; h("c")`} {
+			_, err := interp.Run(input)
+			var rt *RuntimeError
+			if !errors.As(err, &rt) {
+				t.Fatalf("%s: %q: expected a *RuntimeError. got=%v", name, input, err)
+			}
+			if len(rt.Stack) != 1 || rt.Line != 1 {
+				t.Errorf("%s: %q: wrong error: %+v", name, input, rt)
+			}
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	program, err := Compile(`
+let fib = fn(n) { let a = n - 1; if (n < 2) { n } else { fib(a) + fib(n - 2) } };
+fib(base + 10)`)
+	if err != nil {
+		t.Fatalf("Compile failed: %s", err)
+	}
+	for name, engine := range engines {
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(base int) {
+				defer wg.Done()
+				interp := New(WithEngine(engine))
+				if err := interp.RegisterFunc("ignored", func() {}); err != nil {
+					t.Error(err)
+				}
+				if _, err := interp.Run(fmt.Sprintf("let base = %d;", base)); err != nil {
+					t.Error(err)
+					return
+				}
+				for run := 0; run < 3; run++ {
+					result, err := interp.RunProgram(program)
+					if err != nil {
+						t.Errorf("%s: %s", name, err)
+						return
+					}
+					if got, want := result.Value.Inspect(), fmt.Sprint(fib(base+10)); got != want {
+						t.Errorf("%s: base=%d: want=%s, got=%s", name, base, want, got)
+					}
+				}
+			}(g % 3)
+		}
+		wg.Wait()
+	}
+
+	_, err = Compile("let = 1;")
+	var parseErrors parser.ErrorList
+	if !errors.As(err, &parseErrors) {
+		t.Errorf("expected parser.ErrorList. got=%T (%v)", err, err)
+	}
+}
+
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func TestHostObjects(t *testing.T) {
+	// Una "base de datos" de juguete: un map de Go con métodos.
+	dbType := &object.HostType{
+		Name: "DB",
+		Methods: map[string]object.Method{
+			"get": func(self *object.Host, args ...object.Object) object.Object {
+				value, ok := self.Value.(map[string]int64)[args[0].Inspect()]
+				if !ok {
+					return object.NULL
+				}
+				return &object.Integer{Value: value}
+			},
+		},
+		Inspect: func(self *object.Host) string { return "<db>" },
+		Index: func(self *object.Host, index object.Object) object.Object {
+			return &object.Integer{Value: self.Value.(map[string]int64)[index.Inspect()]}
+		},
+	}
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		err := interp.RegisterFunc("open", func(name string) object.Object {
+			return dbType.New(map[string]int64{"uno": 1, "dos": 2})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := interp.Run(`let db = open("test"); [db, db.get("dos"), db["uno"], db.get("tres")]`)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if got, want := result.Value.Inspect(), "[<db>, 2, 1, null]"; got != want {
+			t.Errorf("%s: want=%q, got=%q", name, want, got)
+		}
+		if _, err := interp.Run("db.put(1)"); err == nil || !strings.HasPrefix(err.Error(), "unknown member DB.put") {
+			t.Errorf("%s: wrong error. got=%v", name, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package object
+
+import "sync/atomic"
+
+// Allocations cuenta los bytes que reservó una ejecución para cortarla
+// cuando pasan de un máximo (ver evaluator.Options.MaxMemory). Cuenta lo
+// que crea el programa, no lo que sigue vivo: lo que libera el recolector
+// no se descuenta. Lo comparten las goroutines de una misma ejecución.
+type Allocations struct {
+	max  int64
+	used int64
+}
+
+// NewAllocations retorna una cuenta que admite hasta max bytes.
+func NewAllocations(max int64) *Allocations {
+	return &Allocations{max: max}
+}
+
+// Max retorna el máximo de bytes de la cuenta.
+func (a *Allocations) Max() int64 { return a.max }
+
+// Add suma size bytes a la cuenta y retorna false si con eso pasa del
+// máximo.
+func (a *Allocations) Add(size int64) bool {
+	if size > a.max {
+		return false
+	}
+	return atomic.AddInt64(&a.used, size) <= a.max
+}
+
+// AddResult suma el tamaño de result, el valor que retornó un builtin
+// llamado con args. Si result es uno de los argumentos no es nuevo y no se
+// cuenta.
+func (a *Allocations) AddResult(result Object, args []Object) bool {
+	for _, arg := range args {
+		if arg == result {
+			return true
+		}
+	}
+	return a.Add(Size(result))
+}
+
+// Size retorna los bytes aproximados que ocupa obj sin contar sus
+// elementos, que se cuentan cuando se crean. Solo los strings, arrays y
+// hashes, que son los que pueden crecer, ocupan algo.
+func Size(obj Object) int64 {
+	switch obj := obj.(type) {
+	case *String:
+		return int64(len(obj.Value))
+	case *Array:
+		return 16 * int64(len(obj.Elements))
+	case *Hash:
+		return 64 * int64(len(obj.Pairs))
+	default:
+		return 0
+	}
+}
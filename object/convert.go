@@ -0,0 +1,202 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+var objectType = reflect.TypeOf((*Object)(nil)).Elem()
+
+// FromGo convierte un valor de Go en un valor de Monkey: los enteros y
+// los floats sin decimales son INTEGER, los strings STRING, los bools
+// BOOLEAN, nil NULL, los slices y arrays ARRAY y los maps HASH, con sus
+// elementos convertidos de la misma forma. Un Object se retorna tal cual.
+// Un valor que se contiene a sí mismo, como un map guardado en uno de sus
+// elementos, es un error.
+func FromGo(v interface{}) (Object, error) {
+	c := &converter{visiting: map[visit]bool{}}
+	return c.fromValue(reflect.ValueOf(v))
+}
+
+// converter lleva los maps, slices y punteros que se están convirtiendo,
+// para detectar los ciclos como encoding/json.
+type converter struct {
+	visiting map[visit]bool
+}
+
+// visit identifica un map, slice o puntero. Dos slices del mismo arreglo
+// con distinto largo son valores distintos.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marca v como en conversión; retorna un error si ya lo estaba.
+// leave la quita cuando termina, así un valor que aparece dos veces sin
+// formar un ciclo se convierte dos veces.
+func (c *converter) enter(v reflect.Value) (leave func(), err error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if c.visiting[key] {
+		return nil, fmt.Errorf("cannot convert a cyclic %s to a Monkey value", v.Type())
+	}
+	c.visiting[key] = true
+	return func() { delete(c.visiting, key) }, nil
+}
+
+func (c *converter) fromValue(v reflect.Value) (Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type() == objectType || v.Type().Implements(objectType) && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(Object), nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInteger(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d does not fit in an INTEGER", v.Uint())
+		}
+		return NewInteger(int64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		// Monkey no tiene números con decimales.
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("%v is not an INTEGER", f)
+		}
+		return NewInteger(int64(f)), nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Bool:
+		return NativeBoolToBooleanObject(v.Bool()), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		if v.Kind() == reflect.Ptr {
+			leave, err := c.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return c.fromValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+			leave, err := c.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			el, err := c.fromValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		leave, err := c.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		hash := NewHash()
+		keys := v.MapKeys()
+		// El orden de un map de Go es aleatorio; se ordenan las llaves para
+		// que el hash sea siempre el mismo.
+		sortValues(keys)
+		for _, k := range keys {
+			key, err := c.fromValue(k)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := c.fromValue(v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(hashable.HashKey(), HashPair{Key: key, Value: value})
+		}
+		return hash, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Monkey value", v.Type())
+}
+
+// sortValues ordena las llaves de un map: los números por su valor y lo
+// demás por su representación.
+func sortValues(keys []reflect.Value) {
+	sort.Slice(keys, func(a, b int) bool {
+		ka, kb := keys[a], keys[b]
+		switch ka.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return ka.Int() < kb.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return ka.Uint() < kb.Uint()
+		}
+		return fmt.Sprint(ka.Interface()) < fmt.Sprint(kb.Interface())
+	})
+}
+
+// ToGo convierte obj en el valor de Go que le corresponde: int64, string,
+// bool, nil, []interface{} o map[string]interface{}. Los hashes tienen
+// que tener llaves STRING; las funciones y los demás objetos no se pueden
+// convertir.
+func ToGo(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+	case *Array:
+		list := make([]interface{}, len(obj.Elements))
+		for i, el := range obj.Elements {
+			v, err := ToGo(el)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case *Hash:
+		m := make(map[string]interface{}, len(obj.Keys))
+		for _, pair := range obj.OrderedPairs() {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return nil, fmt.Errorf("cannot use HASH with %s keys as a Go value", pair.Key.Type())
+			}
+			v, err := ToGo(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot use %s as a Go value", obj.Type())
+}
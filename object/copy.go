@@ -0,0 +1,80 @@
+package object
+
+// CopyState retorna obj con una copia de cada objeto de estado mutable
+// que contiene, directamente o dentro de arrays, hashes y options: los
+// ATOMIC y los FIBER que todavía no empezaron (ver Fiber.Copy). Los demás
+// objetos se comparten, porque no cambian o porque su estado no se puede
+// copiar: los CHANNEL, MUTEX y FUTURE, los FIBER ya empezados, los objetos
+// que capturan las funciones y los del programa que embebe el intérprete.
+//
+// copies asocia cada objeto copiado con su copia, así un objeto al que se
+// llega por varios caminos se copia una sola vez. Los arrays y hashes sin
+// nada que copiar se retornan tal cual.
+func CopyState(obj Object, copies map[Object]Object) Object {
+	if c, ok := copies[obj]; ok {
+		return c
+	}
+	var c Object
+	switch obj := obj.(type) {
+	case *Atomic:
+		c = NewAtomic(obj.Load())
+	case *Fiber:
+		c = obj
+		if obj.Copy != nil {
+			c = obj.Copy()
+		}
+	case *Array:
+		c = copyArrayState(obj, copies)
+	case *Hash:
+		c = copyHashState(obj, copies)
+	case *Option:
+		c = obj
+		if obj.Value != nil {
+			if value := CopyState(obj.Value, copies); value != obj.Value {
+				c = &Option{Value: value}
+			}
+		}
+	default:
+		return obj
+	}
+	copies[obj] = c
+	return c
+}
+
+func copyArrayState(arr *Array, copies map[Object]Object) Object {
+	var elements []Object
+	for i, el := range arr.Elements {
+		c := CopyState(el, copies)
+		if c != el && elements == nil {
+			elements = append(make([]Object, 0, len(arr.Elements)), arr.Elements[:i]...)
+		}
+		if elements != nil {
+			elements = append(elements, c)
+		}
+	}
+	if elements == nil {
+		return arr
+	}
+	return &Array{Elements: elements, Frozen: arr.Frozen}
+}
+
+func copyHashState(hash *Hash, copies map[Object]Object) Object {
+	var pairs map[HashKey]HashPair
+	for key, pair := range hash.Pairs {
+		value := CopyState(pair.Value, copies)
+		if value == pair.Value {
+			continue
+		}
+		if pairs == nil {
+			pairs = make(map[HashKey]HashPair, len(hash.Pairs))
+			for k, p := range hash.Pairs {
+				pairs[k] = p
+			}
+		}
+		pairs[key] = HashPair{Key: pair.Key, Value: value}
+	}
+	if pairs == nil {
+		return hash
+	}
+	return &Hash{Pairs: pairs, Keys: append([]HashKey(nil), hash.Keys...), Frozen: hash.Frozen}
+}
@@ -1,34 +1,253 @@
 package object
 
+import (
+	"sort"
+	"sync"
+)
+
 // Crea una tabla de simbolos
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	env := newEnvironment(0)
+	env.store = make(map[string]Object)
+	return env
+}
+
+// newEnvironment crea el Environment, su frame y, si son pocas, las
+// posiciones de sus variables locales con una sola reserva de memoria, ya
+// que se crea uno en cada llamada a función.
+func newEnvironment(slots int) *Environment {
+	block := &struct {
+		env    Environment
+		fr     frame
+		inline [4]Object
+	}{}
+	block.env.frame = &block.fr
+	if slots <= len(block.inline) {
+		block.fr.slots = block.inline[:slots:slots]
+	} else {
+		block.fr.slots = make([]Object, slots)
+	}
+	return &block.env
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	if outer != nil {
+		env.exec = outer.exec
+		env.depth = outer.depth + 1
+	}
+	return env
+}
+
+// NewFunctionEnvironment crea el entorno de una llamada a función cuyas
+// variables locales ya fueron resueltas a posiciones: names[i] es el
+// nombre de la variable que vive en la posición i.
+func NewFunctionEnvironment(outer *Environment, names []string) *Environment {
+	env := newEnvironment(len(names))
+	env.outer = outer
+	env.slotNames = names
+	if outer != nil {
+		env.exec = outer.exec
+		env.depth = outer.depth + 1
+	}
 	return env
 }
 
 // Tabla de simbolos
 type Environment struct {
+	*frame
+	// exec es el estado de la ejecución a la que pertenece el entorno
+	// (límites, cancelación, etc.). Lo define y lo usa el evaluador; los
+	// entornos nuevos lo heredan de su entorno externo.
+	exec interface{}
+	// depth es la cantidad de llamadas a función anidadas que hay hasta
+	// este entorno. Por defecto es la del entorno externo más uno; el
+	// evaluador la corrige con la del código que hace la llamada.
+	depth int
+}
+
+// Depth retorna la profundidad de llamadas del entorno (0 en el global).
+func (e *Environment) Depth() int {
+	return e.depth
+}
+
+// SetDepth cambia la profundidad de llamadas. Al igual que SetExec, solo
+// se debe usar en entornos recién creados.
+func (e *Environment) SetDepth(depth int) {
+	e.depth = depth
+}
+
+// Outer retorna el entorno que contiene a este, o nil si es un entorno
+// global.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Exec retorna el estado de ejecución asociado al entorno (nil si no hay).
+func (e *Environment) Exec() interface{} {
+	return e.exec
+}
+
+// SetExec asocia un estado de ejecución al entorno. Solo se debe usar en
+// entornos recién creados que todavía no se comparten con otras goroutines.
+func (e *Environment) SetExec(exec interface{}) {
+	e.exec = exec
+}
+
+// WithExec retorna otra vista de este entorno, con las mismas variables
+// pero con su propio estado de ejecución. Así una ejecución puede tener
+// límites propios sin modificar el entorno que recibe.
+func (e *Environment) WithExec(exec interface{}) *Environment {
+	return &Environment{frame: e.frame, exec: exec, depth: e.depth}
+}
+
+// frame son las variables de un entorno. Las vistas creadas con WithExec
+// comparten el mismo frame.
+type frame struct {
+	// mu protege store: el mismo entorno (en especial el global) puede
+	// usarse desde varias goroutines a la vez (ver spawn).
+	mu sync.RWMutex
+	// store es nil en los entornos de función hasta que alguien registra
+	// un identificador por nombre.
 	store map[string]Object
 	outer *Environment
+	// Variables locales indexadas por posición (ver NewFunctionEnvironment).
+	// Una posición en nil es una variable que todavía no tiene valor.
+	slots     []Object
+	slotNames []string
 }
 
 // Obtiene el valor asociado al identificador recibido.
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
+	if !ok {
+		obj, ok = e.getSlotByName(name)
+	}
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 
+func (e *Environment) getSlotByName(name string) (Object, bool) {
+	for i := len(e.slotNames) - 1; i >= 0; i-- {
+		if e.slotNames[i] == name && e.slots[i] != nil {
+			return e.slots[i], true
+		}
+	}
+	return nil, false
+}
+
 // Registra el identificador en la tabla de simbolos.
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// GetLocal obtiene la variable local ubicada depth entornos hacia afuera
+// en la posición index. Si la variable todavía no tiene valor (se lee
+// antes de su let) se busca name en los entornos externos, igual que
+// haría Get.
+func (e *Environment) GetLocal(depth, index int, name string) (Object, bool) {
+	env := e
+	for i := 0; i < depth && env.outer != nil; i++ {
+		env = env.outer
+	}
+	if index < len(env.slots) {
+		if obj := env.slots[index]; obj != nil {
+			return obj, true
+		}
+	}
+	return e.Get(name)
+}
+
+// SetLocal asigna la variable local de la posición index de este entorno.
+func (e *Environment) SetLocal(index int, val Object) Object {
+	e.slots[index] = val
+	return val
+}
+
+// Names retorna, ordenados, los identificadores definidos en este
+// entorno (sin incluir los de los entornos externos). En un entorno de
+// función incluye las variables locales que ya tienen valor.
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.store)+len(e.slotNames))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for i, name := range e.slotNames {
+		if e.slots[i] == nil {
+			continue
+		}
+		if _, ok := e.store[name]; ok || containsName(e.slotNames[:i], name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete elimina el identificador de este entorno. Retorna false si no
+// estaba definido en él.
+func (e *Environment) Delete(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.store[name]; !ok {
+		return false
+	}
+	delete(e.store, name)
+	return true
+}
+
+// Snapshot es una copia de los identificadores de un entorno en un
+// momento dado. De los valores se copia su estado mutable, como el de
+// los ATOMIC (ver CopyState); el resto se comparte con el script, que no
+// los puede modificar.
+type Snapshot struct {
+	store map[string]Object
+}
+
+// Snapshot captura el estado actual de este entorno.
+func (e *Environment) Snapshot() *Snapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return &Snapshot{store: copyStore(e.store)}
+}
+
+// Restore devuelve el entorno al estado capturado en s, descartando los
+// identificadores definidos después. Los valores se vuelven a copiar, así
+// que s se puede restaurar muchas veces.
+func (e *Environment) Restore(s *Snapshot) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store = copyStore(s.store)
+}
+
+func copyStore(store map[string]Object) map[string]Object {
+	c := make(map[string]Object, len(store))
+	copies := map[Object]Object{}
+	for name, val := range store {
+		c[name] = CopyState(val, copies)
+	}
+	return c
+}
@@ -0,0 +1,65 @@
+package object
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvironmentIntrospection(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("global", NewInteger(0))
+	env := NewEnclosedEnvironment(outer)
+	env.Set("b", NewInteger(2))
+	env.Set("a", NewInteger(1))
+
+	if names := env.Names(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("wrong names. got=%v", names)
+	}
+
+	snapshot := env.Snapshot()
+	env.Set("c", NewInteger(3))
+	env.Set("a", NewInteger(10))
+	if !env.Delete("b") {
+		t.Errorf("Delete(b) should report that b existed")
+	}
+	if env.Delete("global") {
+		t.Errorf("Delete should not remove names from the outer environment")
+	}
+	if names := env.Names(); !reflect.DeepEqual(names, []string{"a", "c"}) {
+		t.Errorf("wrong names after changes. got=%v", names)
+	}
+
+	env.Restore(snapshot)
+	if names := env.Names(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("wrong names after restore. got=%v", names)
+	}
+	if a, _ := env.Get("a"); a.Inspect() != "1" {
+		t.Errorf("wrong value for a after restore. got=%s", a.Inspect())
+	}
+	if _, ok := env.Get("global"); !ok {
+		t.Errorf("outer environment should still be reachable")
+	}
+
+	// El snapshot no debe cambiar si el entorno se sigue modificando.
+	env.Set("d", NewInteger(4))
+	env.Restore(snapshot)
+	if _, ok := env.Get("d"); ok {
+		t.Errorf("snapshot was modified by later changes")
+	}
+}
+
+func TestFunctionEnvironmentNames(t *testing.T) {
+	env := NewFunctionEnvironment(NewEnvironment(), []string{"x", "y", "x"})
+	env.SetLocal(0, NewInteger(1))
+	env.SetLocal(2, NewInteger(3))
+
+	if names := env.Names(); !reflect.DeepEqual(names, []string{"x"}) {
+		t.Errorf("wrong names. got=%v", names)
+	}
+	if x, _ := env.Get("x"); x.Inspect() != "3" {
+		t.Errorf("x should be the last slot with that name. got=%s", x.Inspect())
+	}
+	if _, ok := env.Get("y"); ok {
+		t.Errorf("y has no value yet and should not be found")
+	}
+}
@@ -0,0 +1,105 @@
+package object
+
+import "fmt"
+
+// Los programas que embeben el intérprete pueden definir sus propios
+// objetos, por ejemplo una conexión a una base de datos: basta con
+// implementar Object. Si además implementan alguna de estas interfaces, el
+// evaluador y la VM les delegan la operación correspondiente.
+
+// Indexable es un objeto que admite obj[index].
+type Indexable interface {
+	Object
+	Index(index Object) Object
+}
+
+// Callable es un objeto que se puede llamar como una función.
+type Callable interface {
+	Object
+	Call(args ...Object) Object
+}
+
+// HasMembers es un objeto que admite obj.name. ok es false si no tiene el
+// miembro name.
+type HasMembers interface {
+	Object
+	Member(name string) (member Object, ok bool)
+}
+
+// Method es un método de un HostType. self es el objeto sobre el que se
+// llamó.
+type Method func(self *Host, args ...Object) Object
+
+// HostType describe un tipo de objeto del programa que embebe el
+// intérprete, para no tener que implementar las interfaces a mano:
+//
+//	conn := &object.HostType{
+//		Name: "CONNECTION",
+//		Methods: map[string]object.Method{
+//			"close": func(self *object.Host, args ...object.Object) object.Object {
+//				self.Value.(*sql.DB).Close()
+//				return object.NULL
+//			},
+//		},
+//	}
+//	obj := conn.New(db) // conn.close() desde Monkey
+//
+// Los campos que son nil no se delegan: un Host sin Index no admite
+// obj[index].
+type HostType struct {
+	// Name es el tipo de los objetos, el que muestran los errores.
+	Name ObjectType
+	// Methods son los miembros de los objetos: obj.name retorna el método
+	// name ligado a obj.
+	Methods map[string]Method
+	// Inspect muestra un objeto; sin Inspect se muestra como <Name>.
+	Inspect func(self *Host) string
+	// Index y Call implementan obj[index] y obj(args).
+	Index func(self *Host, index Object) Object
+	Call  func(self *Host, args ...Object) Object
+}
+
+// New retorna un objeto de tipo t que envuelve value.
+func (t *HostType) New(value interface{}) *Host {
+	return &Host{HostType: t, Value: value}
+}
+
+// Host es un objeto de un HostType.
+type Host struct {
+	HostType *HostType
+	// Value es el valor de Go que representa el objeto.
+	Value interface{}
+}
+
+func (h *Host) Type() ObjectType { return h.HostType.Name }
+func (h *Host) Inspect() string {
+	if h.HostType.Inspect != nil {
+		return h.HostType.Inspect(h)
+	}
+	return fmt.Sprintf("<%s>", h.HostType.Name)
+}
+
+// Member retorna el método name ligado a h.
+func (h *Host) Member(name string) (Object, bool) {
+	method, ok := h.HostType.Methods[name]
+	if !ok {
+		return nil, false
+	}
+	return &Builtin{Fn: func(args ...Object) Object {
+		return method(h, args...)
+	}}, true
+}
+
+func (h *Host) Index(index Object) Object {
+	if h.HostType.Index == nil {
+		return &Error{Message: fmt.Sprintf("index operator not supported: %s", h.Type())}
+	}
+	return h.HostType.Index(h, index)
+}
+
+func (h *Host) Call(args ...Object) Object {
+	if h.HostType.Call == nil {
+		return &Error{Message: fmt.Sprintf("not a function: %s", h.Type())}
+	}
+	return h.HostType.Call(h, args...)
+}
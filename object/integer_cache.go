@@ -0,0 +1,27 @@
+package object
+
+// Rango de enteros preasignados. Los ciclos y contadores casi siempre
+// trabajan con números pequeños, así que reutilizar estas instancias
+// evita la mayoría de las asignaciones de memoria en la aritmética.
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 1024
+)
+
+var integerCache = func() []*Integer {
+	cache := make([]*Integer, maxCachedInteger-minCachedInteger+1)
+	for i := range cache {
+		cache[i] = &Integer{Value: int64(i + minCachedInteger)}
+	}
+	return cache
+}()
+
+// NewInteger retorna un *Integer con el valor dado, usando la instancia
+// compartida cuando el valor está en el rango cacheado. Los *Integer son
+// inmutables, así que compartirlos es seguro.
+func NewInteger(value int64) *Integer {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return integerCache[value-minCachedInteger]
+	}
+	return &Integer{Value: value}
+}
@@ -0,0 +1,97 @@
+package object
+
+// Iterator recorre los elementos de una colección. Next retorna false
+// cuando ya no quedan elementos.
+type Iterator interface {
+	Next() (Object, bool)
+}
+
+// Iterable lo implementan los objetos que se pueden recorrer. Cada
+// llamada a Iterator() retorna un recorrido nuevo desde el principio.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// sliceIterator recorre un slice de objetos ya calculado.
+type sliceIterator struct {
+	elements []Object
+	pos      int
+}
+
+func (it *sliceIterator) Next() (Object, bool) {
+	if it.pos >= len(it.elements) {
+		return nil, false
+	}
+	obj := it.elements[it.pos]
+	it.pos++
+	return obj, true
+}
+
+// Iterator recorre los elementos del array.
+func (ao *Array) Iterator() Iterator {
+	return &sliceIterator{elements: ao.Elements}
+}
+
+// Iterator recorre las llaves del hash en orden de inserción.
+func (h *Hash) Iterator() Iterator {
+	keys := make([]Object, 0, len(h.Keys))
+	for _, pair := range h.OrderedPairs() {
+		keys = append(keys, pair.Key)
+	}
+	return &sliceIterator{elements: keys}
+}
+
+// Iterator recorre los elementos del set en el orden en que los muestra
+// Inspect, así dos recorridos del mismo set coinciden.
+func (s *Set) Iterator() Iterator {
+	return &sliceIterator{elements: s.sorted()}
+}
+
+// stringIterator recorre un string caracter por caracter (runas UTF-8).
+type stringIterator struct {
+	runes []rune
+	pos   int
+}
+
+func (it *stringIterator) Next() (Object, bool) {
+	if it.pos >= len(it.runes) {
+		return nil, false
+	}
+	r := it.runes[it.pos]
+	it.pos++
+	return &String{Value: string(r)}, true
+}
+
+// Iterator recorre los caracteres del string.
+func (s *String) Iterator() Iterator {
+	return &stringIterator{runes: []rune(s.Value)}
+}
+
+// Collect consume el iterador y retorna sus elementos.
+func Collect(it Iterator) []Object {
+	elements := []Object{}
+	for {
+		obj, ok := it.Next()
+		if !ok {
+			return elements
+		}
+		elements = append(elements, obj)
+	}
+}
+
+// Stream es una secuencia perezosa, posiblemente infinita. Sus elementos
+// se calculan a medida que se consumen; Generate crea un recorrido nuevo.
+// Si el cálculo de un elemento falla, el iterador entrega el *Error como
+// elemento para que quien lo consuma lo propague.
+type Stream struct {
+	Generate func() Iterator
+}
+
+func (s *Stream) Type() ObjectType   { return STREAM_OBJ }
+func (s *Stream) Inspect() string    { return "stream" }
+func (s *Stream) Iterator() Iterator { return s.Generate() }
+
+// IteratorFunc adapta una función al tipo Iterator.
+type IteratorFunc func() (Object, bool)
+
+func (f IteratorFunc) Next() (Object, bool) { return f() }
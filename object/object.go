@@ -6,7 +6,10 @@ import (
 	"hash/fnv"
 	"monkey/ast"
 	"monkey/code"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // ObjectType es el tipo de dato base para todos los objetos.
@@ -28,12 +31,21 @@ const (
 	NULL_OBJ              = "NULL"
 	RETURN_VALUE_OBJ      = "RETURN_VALUE"
 	ERROR_OBJ             = "ERROR"
+	EXIT_OBJ              = "EXIT"
 	FUNCTION_OBJ          = "FUNCTION"
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
 	STRING_OBJ            = "STRING"
 	BUILTIN_OBJ           = "BUILTIN"
 	ARRAY_OBJ             = "ARRAY"
 	HASH_OBJ              = "HASH"
+	SET_OBJ               = "SET"
+	STREAM_OBJ            = "STREAM"
+	CHANNEL_OBJ           = "CHANNEL"
+	MUTEX_OBJ             = "MUTEX"
+	ATOMIC_OBJ            = "ATOMIC"
+	FUTURE_OBJ            = "FUTURE"
+	OPTION_OBJ            = "OPTION"
+	FIBER_OBJ             = "FIBER"
 )
 
 // Object es una interface que comprende todos los valores
@@ -51,6 +63,23 @@ type Object interface {
 	Inspect() string
 }
 
+// Instancias únicas de true, false y null. El evaluador, la VM y los
+// builtins las comparan por identidad de puntero, así que nadie debe
+// crear otros *Boolean o *Null.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+// NativeBoolToBooleanObject retorna TRUE o FALSE según input.
+func NativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
 type Integer struct {
 	Value int64
 }
@@ -107,16 +136,60 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+	// Línea y columna del código donde ocurrió el error (0 si se desconoce).
+	Line   int
+	Column int
+	// Pila de llamadas al momento del error, de la más interna a la más externa.
+	Stack []StackFrame
+}
+
+// StackFrame es una llamada a función activa cuando ocurrió un error.
+type StackFrame struct {
+	Function string
+	Line     int
+	Column   int
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("ERROR: " + e.Message)
+	if e.Line > 0 {
+		out.WriteString(" (" + position(e.Line, e.Column) + ")")
+	}
+	for _, frame := range e.Stack {
+		fmt.Fprintf(&out, "\n\tat %s (%s)", frame.Function, position(frame.Line, frame.Column))
+	}
+	return out.String()
+}
+
+// position describe una posición del código. La VM solo conoce las
+// líneas, así que la columna se omite cuando es 0.
+func position(line, column int) string {
+	if column > 0 {
+		return fmt.Sprintf("line %d, column %d", line, column)
+	}
+	return fmt.Sprintf("line %d", line)
+}
+
+// Objeto Exit: lo produce el builtin exit(code) y se propaga hasta el
+// programa principal igual que un Error, deteniendo la evaluación.
+// Es el REPL (o quien embeba el intérprete) quien decide qué hacer con Code.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
 
 // Objeto función.
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	// Locals son los nombres de las posiciones del entorno de cada llamada
+	// (ver ast.FunctionLiteral). Si es nil la función usa un entorno por nombre.
+	Locals []string
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -138,6 +211,12 @@ func (f *Function) Inspect() string {
 // Objeto String
 type String struct {
 	Value string
+
+	// Hash FNV de Value, calculado la primera vez que se pide el HashKey.
+	// Se accede de forma atómica porque un mismo String puede usarse como
+	// llave desde varias goroutines.
+	hash   uint64
+	hashed uint32
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
@@ -147,15 +226,28 @@ func (s *String) Inspect() string  { return s.Value }
 // del tipo especificado a la derecha.
 type BuiltinFunction func(args ...Object) Object
 
+// HashKey memoriza el hash, así que un String no debe modificarse
+// después de usarse como llave.
 func (s *String) HashKey() HashKey {
+	if atomic.LoadUint32(&s.hashed) == 1 {
+		return HashKey{Type: s.Type(), Value: atomic.LoadUint64(&s.hash)}
+	}
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+	sum := h.Sum64()
+	atomic.StoreUint64(&s.hash, sum)
+	atomic.StoreUint32(&s.hashed, 1)
+	return HashKey{Type: s.Type(), Value: sum}
 }
 
 // Objeto Builtin
 type Builtin struct {
 	Fn BuiltinFunction
+	// FromCaller, si no es nil, es lo que usa el evaluador en lugar de Fn
+	// cuando llama al builtin desde código Monkey: recibe el entorno de la
+	// llamada, para que las funciones que el builtin llame sigan contando
+	// la profundidad de ese código.
+	FromCaller func(caller *Environment, args ...Object) Object
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -164,6 +256,8 @@ func (b *Builtin) Inspect() string  { return "builtin function" }
 // Objeo Array
 type Array struct {
 	Elements []Object
+	// Frozen indica que el array no admite modificaciones (ver freeze).
+	Frozen bool
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -184,15 +278,42 @@ type HashPair struct {
 	Value Object
 }
 
+// Objeto Hash. Pairs permite buscar por llave y Keys guarda el orden de
+// inserción, que es el orden en que se recorre e imprime el hash.
+// Los pares se deben agregar con Set para mantener ambos sincronizados.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+	// Frozen indica que el hash no admite modificaciones (ver freeze).
+	Frozen bool
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set agrega o reemplaza un par. Reemplazar no cambia la posición de la llave.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+// OrderedPairs retorna los pares en orden de inserción.
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, len(h.Keys))
+	for i, key := range h.Keys {
+		pairs[i] = h.Pairs[key]
+	}
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.OrderedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
@@ -201,11 +322,258 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Objeto Set: colección sin duplicados. La pertenencia se resuelve con
+// el HashKey de cada elemento, igual que las llaves de un Hash.
+type Set struct {
+	Elements map[HashKey]Object
+}
+
+func NewSet() *Set {
+	return &Set{Elements: make(map[HashKey]Object)}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+
+// sorted retorna los elementos ordenados por cómo se muestran y, si dos se
+// muestran igual (1 y "1"), por tipo.
+func (s *Set) sorted() []Object {
+	type element struct {
+		obj     Object
+		inspect string
+	}
+	elements := make([]element, 0, len(s.Elements))
+	for _, e := range s.Elements {
+		elements = append(elements, element{e, e.Inspect()})
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].inspect != elements[j].inspect {
+			return elements[i].inspect < elements[j].inspect
+		}
+		return elements[i].obj.Type() < elements[j].obj.Type()
+	})
+	sorted := make([]Object, len(elements))
+	for i, e := range elements {
+		sorted[i] = e.obj
+	}
+	return sorted
+}
+
+// Inspect ordena los elementos para que la salida sea determinista.
+func (s *Set) Inspect() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, e := range s.sorted() {
+		elements = append(elements, e.Inspect())
+	}
+	out.WriteString("set(")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+// Objeto Channel: envuelve un canal de Go para comunicar funciones que
+// corren en paralelo (ver spawn).
+type Channel struct {
+	Value chan Object
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return fmt.Sprintf("channel(%d)", cap(c.Value)) }
+
+// Objeto Future: resultado de una función que corre en paralelo (ver
+// async). Se resuelve una sola vez; Wait bloquea hasta entonces.
+type Future struct {
+	done   chan struct{}
+	result Object
+}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Resolve guarda el resultado y despierta a quienes esperan en Wait.
+// Solo se debe llamar una vez. Un resultado nil se guarda como NULL.
+func (f *Future) Resolve(result Object) {
+	if result == nil {
+		result = NULL
+	}
+	f.result = result
+	close(f.done)
+}
+
+func (f *Future) Wait() Object {
+	<-f.done
+	return f.result
+}
+
+// Done retorna un canal que se cierra cuando el future se resuelve, para
+// esperarlo junto con otra cosa.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *Future) Type() ObjectType { return FUTURE_OBJ }
+func (f *Future) Inspect() string {
+	select {
+	case <-f.done:
+		if f.result == nil {
+			return "future(null)"
+		}
+		return fmt.Sprintf("future(%s)", f.result.Inspect())
+	default:
+		return "future(pending)"
+	}
+}
+
+// Objeto Mutex: candado para las secciones críticas de funciones que
+// corren en paralelo (ver lock). A diferencia de un sync.Mutex, se puede
+// dejar de esperar (ver Lock). El valor cero es un candado libre.
+type Mutex struct {
+	once sync.Once
+	// sem tiene un elemento mientras el candado está tomado.
+	sem chan struct{}
+}
+
+func (m *Mutex) semaphore() chan struct{} {
+	m.once.Do(func() { m.sem = make(chan struct{}, 1) })
+	return m.sem
+}
+
+// Lock toma el candado, esperando a que se libere. Si done se cierra
+// antes, retorna false sin tomarlo. done puede ser nil.
+func (m *Mutex) Lock(done <-chan struct{}) bool {
+	select {
+	case m.semaphore() <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// TryLock toma el candado si está libre, sin esperar.
+func (m *Mutex) TryLock() bool {
+	select {
+	case m.semaphore() <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Mutex) Unlock() {
+	<-m.semaphore()
+}
+
+func (m *Mutex) Type() ObjectType { return MUTEX_OBJ }
+func (m *Mutex) Inspect() string  { return "mutex" }
+
+// Objeto Atomic: entero que se puede leer y modificar desde varias
+// goroutines sin condiciones de carrera.
+type Atomic struct {
+	value int64
+}
+
+func NewAtomic(v int64) *Atomic { return &Atomic{value: v} }
+
+// Add suma delta y retorna el nuevo valor.
+func (a *Atomic) Add(delta int64) int64 { return atomic.AddInt64(&a.value, delta) }
+func (a *Atomic) Load() int64           { return atomic.LoadInt64(&a.value) }
+
+func (a *Atomic) Type() ObjectType { return ATOMIC_OBJ }
+func (a *Atomic) Inspect() string  { return fmt.Sprintf("atomic(%d)", a.Load()) }
+
+// Objeto Fiber: una función que se puede pausar cediendo un valor con
+// yield y continuar con resume (ver el builtin fiber). Cada motor de
+// ejecución provee su propio Resume; el estado lo lleva el builtin resume.
+type Fiber struct {
+	// Resume continúa la función hasta que ceda un valor o termine y
+	// retorna ese valor, y si terminó. value es el resultado del yield en
+	// el que estaba pausada; en la primera llamada es el segundo argumento
+	// de la función.
+	Resume func(value Object) (result Object, done bool)
+	// Copy crea otro fiber con la misma función. Los motores lo ponen en
+	// nil cuando el fiber empieza, porque desde ahí su estado no se puede
+	// copiar (ver CopyState).
+	Copy    func() *Fiber
+	Running bool
+	Done    bool
+}
+
+func (f *Fiber) Type() ObjectType { return FIBER_OBJ }
+func (f *Fiber) Inspect() string {
+	switch {
+	case f.Done:
+		return "fiber(done)"
+	case f.Running:
+		return "fiber(running)"
+	default:
+		return "fiber(suspended)"
+	}
+}
+
 type CompiledFunction struct {
-	Instructions code.Instructions
+	Instructions  code.Instructions
+	Lines         code.LineTable // línea del código fuente de cada instrucción
+	NumLocals     int
+	NumParameters int
+	// Parameters son los nombres de los parámetros, para los argumentos
+	// con nombre.
+	Parameters []string
+	// Locals son los nombres de las variables locales por posición, para
+	// el error de leer una antes de que corra su let.
+	Locals []string
+	// Name es el nombre con el que se definió la función con let, o "" si
+	// es anónima. Se usa en la pila de llamadas de los errores.
+	Name string
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
 func (cf *CompiledFunction) Inspect() string {
 	return fmt.Sprintf("CompiledFunction[%p]", cf)
 }
+
+// Closure es una función de la VM junto con los valores de las variables
+// libres que capturó al crearse.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+	// Runner ejecuta el closure cuando lo llama un builtin o el código que
+	// embebe la VM. Lo pone la VM que crea el closure.
+	Runner ClosureRunner
+}
+
+// ClosureRunner ejecuta closures fuera del ciclo de la VM que los creó.
+type ClosureRunner interface {
+	RunClosure(cl *Closure, args ...Object) Object
+}
+
+// Para el código Monkey un closure es una función más, igual que las del
+// evaluador.
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Call ejecuta el closure con su Runner, así que un closure es un
+// Callable que los builtins pueden llamar sin envolverlo.
+func (c *Closure) Call(args ...Object) Object {
+	if c.Runner == nil {
+		return &Error{Message: "closure cannot be called outside its VM"}
+	}
+	return c.Runner.RunClosure(c, args...)
+}
+
+// Objeto Option: un valor que puede faltar, some(x) o none(). Es la
+// alternativa a un if sin else, que da NULL cuando la condición es falsa.
+type Option struct {
+	Value Object // nil en none()
+}
+
+func (o *Option) Type() ObjectType { return OPTION_OBJ }
+func (o *Option) IsSome() bool     { return o.Value != nil }
+func (o *Option) Inspect() string {
+	if o.Value == nil {
+		return "none"
+	}
+	return "some(" + o.Value.Inspect() + ")"
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello world"}
@@ -18,3 +22,233 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		iterable Iterable
+		expected []string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, []string{"1", "a"}},
+		{&String{Value: "añb"}, []string{"a", "ñ", "b"}},
+		{&Array{}, []string{}},
+	}
+	for _, tt := range tests {
+		elements := Collect(tt.iterable.Iterator())
+		if len(elements) != len(tt.expected) {
+			t.Fatalf("wrong number of elements. want=%d, got=%d", len(tt.expected), len(elements))
+		}
+		for i, el := range elements {
+			if el.Inspect() != tt.expected[i] {
+				t.Errorf("wrong element %d. want=%q, got=%q", i, tt.expected[i], el.Inspect())
+			}
+		}
+	}
+
+	key := &String{Value: "k"}
+	hash := NewHash()
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	keys := Collect(hash.Iterator())
+	if len(keys) != 1 || keys[0] != key {
+		t.Errorf("hash iterator should yield its keys. got=%v", keys)
+	}
+
+	set := NewSet()
+	for _, el := range []Hashable{&String{Value: "b"}, NewInteger(10), &String{Value: "1"}, NewInteger(2), NewInteger(1), TRUE} {
+		set.Elements[el.HashKey()] = el.(Object)
+	}
+	first := Collect(set.Iterator())
+	for i := 0; i < 10; i++ {
+		again := Collect(set.Iterator())
+		for n := range first {
+			if again[n] != first[n] {
+				t.Fatalf("set iterated in a different order: %v, then %v", first, again)
+			}
+		}
+	}
+	var inspected []string
+	for _, el := range first {
+		inspected = append(inspected, el.Inspect())
+	}
+	if got := "set(" + strings.Join(inspected, ", ") + ")"; got != set.Inspect() {
+		t.Errorf("set iterated in a different order than Inspect. want=%s, got=%s", set.Inspect(), got)
+	}
+}
+
+func TestBooleanSingletons(t *testing.T) {
+	if NativeBoolToBooleanObject(true) != TRUE {
+		t.Errorf("NativeBoolToBooleanObject(true) is not TRUE")
+	}
+	if NativeBoolToBooleanObject(false) != FALSE {
+		t.Errorf("NativeBoolToBooleanObject(false) is not FALSE")
+	}
+}
+
+func TestIntegerCache(t *testing.T) {
+	if NewInteger(5) != NewInteger(5) {
+		t.Errorf("small integers should share the same instance")
+	}
+	if NewInteger(-128) != NewInteger(-128) || NewInteger(1024) != NewInteger(1024) {
+		t.Errorf("cache bounds should share the same instance")
+	}
+	if NewInteger(1025) == NewInteger(1025) {
+		t.Errorf("integers outside the cache should be fresh instances")
+	}
+	if NewInteger(-129).Value != -129 || NewInteger(7).Value != 7 {
+		t.Errorf("wrong integer value")
+	}
+}
+
+func TestStringHashKeyIsCached(t *testing.T) {
+	s := &String{Value: "monkey"}
+	first := s.HashKey()
+	if s.hashed != 1 {
+		t.Fatalf("hash was not memoized")
+	}
+	if second := s.HashKey(); first != second {
+		t.Errorf("cached hash key differs. first=%v, second=%v", first, second)
+	}
+	if fresh := (&String{Value: "monkey"}).HashKey(); first != fresh {
+		t.Errorf("cached hash key differs from a fresh one. cached=%v, fresh=%v", first, fresh)
+	}
+}
+
+func BenchmarkStringHashKey(b *testing.B) {
+	s := &String{Value: strings.Repeat("monkey", 100)}
+	for i := 0; i < b.N; i++ {
+		s.HashKey()
+	}
+}
+
+func TestFromGo(t *testing.T) {
+	shared := []int{1}
+	cyclicMap := map[string]interface{}{}
+	cyclicMap["self"] = cyclicMap
+	cyclicSlice := []interface{}{1, nil}
+	cyclicSlice[1] = cyclicSlice
+	var cyclicPtr interface{}
+	cyclicPtr = &cyclicPtr
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{42, "42"},
+		{uint8(7), "7"},
+		{3.0, "3"},
+		{"hola", "hola"},
+		{true, "true"},
+		{nil, "null"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]interface{}{"b": []interface{}{1, "x"}, "a": nil}, "{a: null, b: [1, x]}"},
+		{map[int]bool{10: true, 2: false}, "{2: false, 10: true}"},
+		{&Integer{Value: 5}, "5"},
+		{[][]int{shared, shared}, "[[1], [1]]"},
+	}
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) failed: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. want=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	errors := []struct {
+		input    interface{}
+		expected string
+	}{
+		{1.5, "1.5 is not an INTEGER"},
+		{uint64(1 << 63), "9223372036854775808 does not fit in an INTEGER"},
+		{make(chan int), "cannot convert chan int to a Monkey value"},
+		{map[[1]int]int{{1}: 1}, "unusable as hash key: ARRAY"},
+		{cyclicMap, "cannot convert a cyclic map[string]interface {} to a Monkey value"},
+		{cyclicSlice, "cannot convert a cyclic []interface {} to a Monkey value"},
+		{[]interface{}{cyclicMap}, "cannot convert a cyclic map[string]interface {} to a Monkey value"},
+		{cyclicPtr, "cannot convert a cyclic *interface {} to a Monkey value"},
+	}
+	for _, tt := range errors {
+		_, err := FromGo(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			// %T: los valores cíclicos no se pueden imprimir con %#v.
+			t.Errorf("FromGo(%T) wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestToGo(t *testing.T) {
+	hash := NewHash()
+	key := &String{Value: "k"}
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: &Array{Elements: []Object{NewInteger(1), TRUE, NULL}}})
+
+	value, err := ToGo(hash)
+	if err != nil {
+		t.Fatalf("ToGo failed: %s", err)
+	}
+	if got := fmt.Sprintf("%#v", value); got != `map[string]interface {}{"k":[]interface {}{1, true, interface {}(nil)}}` {
+		t.Errorf("ToGo wrong. got=%s", got)
+	}
+
+	intKeys := NewHash()
+	intKeys.Set(NewInteger(1).HashKey(), HashPair{Key: NewInteger(1), Value: NULL})
+	tests := []struct {
+		input    Object
+		expected string
+	}{
+		{intKeys, "cannot use HASH with INTEGER keys as a Go value"},
+		{&Builtin{}, "cannot use BUILTIN as a Go value"},
+	}
+	for _, tt := range tests {
+		_, err := ToGo(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("ToGo(%s) wrong error. want=%q, got=%v", tt.input.Type(), tt.expected, err)
+		}
+	}
+}
+
+func TestCopyState(t *testing.T) {
+	counter := NewAtomic(1)
+	arr := &Array{Elements: []Object{NewInteger(1), counter, &Option{Value: counter}}}
+	plain := &Array{Elements: []Object{NewInteger(1)}}
+	ch := &Channel{}
+
+	copies := map[Object]Object{}
+	copied := CopyState(arr, copies).(*Array)
+	counter.Add(5)
+
+	if copied == arr {
+		t.Fatalf("array with an atomic not copied")
+	}
+	if got := copied.Inspect(); got != "[1, atomic(1), some(atomic(1))]" {
+		t.Errorf("wrong copy. got=%s", got)
+	}
+	if copied.Elements[1] != copied.Elements[2].(*Option).Value {
+		t.Errorf("the same atomic was copied twice")
+	}
+	if CopyState(counter, copies) != copied.Elements[1] {
+		t.Errorf("copies not reused")
+	}
+	if CopyState(plain, copies) != plain || CopyState(ch, copies) != ch {
+		t.Errorf("objects without state to copy should be shared")
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	alloc := NewAllocations(100)
+	s := &String{Value: strings.Repeat("x", 60)}
+	if !alloc.Add(Size(s)) {
+		t.Fatalf("60 bytes out of 100 rejected")
+	}
+	// Un builtin que retorna uno de sus argumentos no reserva nada.
+	if !alloc.AddResult(s, []Object{s}) {
+		t.Fatalf("argument returned by a builtin was counted")
+	}
+	if alloc.AddResult(&String{Value: s.Value}, []Object{s}) {
+		t.Fatalf("120 bytes out of 100 accepted")
+	}
+	if alloc.Add(1 << 62) {
+		t.Fatalf("size over the maximum accepted")
+	}
+}
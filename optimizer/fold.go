@@ -0,0 +1,167 @@
+// Package optimizer contiene pasadas que simplifican el AST antes de
+// evaluarlo o compilarlo.
+package optimizer
+
+import (
+	"math"
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+)
+
+// FoldConstants reemplaza las expresiones cuyos operandos son literales
+// por su resultado, por ejemplo `2 * 3 + 1` por `7` o `"a" + "b"` por
+// `"ab"`. Modifica node en su lugar y lo retorna; si node es en sí una
+// expresión constante, retorna el literal que la reemplaza.
+//
+// Las operaciones que fallarían o se desbordarían al ejecutarse (división
+// por cero, resultados fuera de int64) no se pliegan, para que el error
+// ocurra en tiempo de ejecución como siempre.
+func FoldConstants(node ast.Node) ast.Node {
+	return ast.Transform(node, foldNode)
+}
+
+// Constant retorna el literal al que se pliega exp, o nil si exp no es una
+// expresión constante. A diferencia de FoldConstants no modifica exp, así
+// que sirve para herramientas que solo leen el árbol, como el linter.
+func Constant(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return exp
+	case *ast.PrefixExpression:
+		right := Constant(exp.Right)
+		if right == nil {
+			return nil
+		}
+		prefix := *exp
+		prefix.Right = right
+		return foldPrefix(&prefix)
+	case *ast.InfixExpression:
+		left, right := Constant(exp.Left), Constant(exp.Right)
+		if left == nil || right == nil {
+			return nil
+		}
+		infix := *exp
+		infix.Left, infix.Right = left, right
+		return foldInfix(&infix)
+	}
+	return nil
+}
+
+// foldNode pliega un nodo cuyos hijos ya fueron plegados.
+func foldNode(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		if folded := foldPrefix(node); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		if folded := foldInfix(node); folded != nil {
+			return folded
+		}
+	}
+	return node
+}
+
+func foldPrefix(exp *ast.PrefixExpression) ast.Expression {
+	switch right := exp.Right.(type) {
+	case *ast.IntegerLiteral:
+		if exp.Operator == "-" && right.Value != math.MinInt64 {
+			return integerLiteral(exp.Token, -right.Value)
+		}
+	case *ast.Boolean:
+		if exp.Operator == "!" {
+			return booleanLiteral(exp.Token, !right.Value)
+		}
+	}
+	return nil
+}
+
+func foldInfix(exp *ast.InfixExpression) ast.Expression {
+	tok := startToken(exp.Left)
+	switch left := exp.Left.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := exp.Right.(*ast.IntegerLiteral); ok {
+			return foldIntegers(tok, exp.Operator, left.Value, right.Value)
+		}
+	case *ast.StringLiteral:
+		if right, ok := exp.Right.(*ast.StringLiteral); ok && exp.Operator == "+" {
+			return &ast.StringLiteral{Token: withLiteral(tok, token.STRING, left.Value+right.Value), Value: left.Value + right.Value}
+		}
+	case *ast.Boolean:
+		if right, ok := exp.Right.(*ast.Boolean); ok {
+			switch exp.Operator {
+			case "==":
+				return booleanLiteral(tok, left.Value == right.Value)
+			case "!=":
+				return booleanLiteral(tok, left.Value != right.Value)
+			}
+		}
+	}
+	return nil
+}
+
+func foldIntegers(tok token.Token, operator string, a, b int64) ast.Expression {
+	switch operator {
+	case "+":
+		if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+			return nil
+		}
+		return integerLiteral(tok, a+b)
+	case "-":
+		if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+			return nil
+		}
+		return integerLiteral(tok, a-b)
+	case "*":
+		if a != 0 && ((a*b)/a != b || (a == -1 && b == math.MinInt64)) {
+			return nil
+		}
+		return integerLiteral(tok, a*b)
+	case "/":
+		if b == 0 || (a == math.MinInt64 && b == -1) {
+			return nil
+		}
+		return integerLiteral(tok, a/b)
+	case "<":
+		return booleanLiteral(tok, a < b)
+	case ">":
+		return booleanLiteral(tok, a > b)
+	case "==":
+		return booleanLiteral(tok, a == b)
+	case "!=":
+		return booleanLiteral(tok, a != b)
+	}
+	return nil
+}
+
+func integerLiteral(tok token.Token, value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{Token: withLiteral(tok, token.INT, strconv.FormatInt(value, 10)), Value: value}
+}
+
+func booleanLiteral(tok token.Token, value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: withLiteral(tok, token.TRUE, "true"), Value: true}
+	}
+	return &ast.Boolean{Token: withLiteral(tok, token.FALSE, "false"), Value: false}
+}
+
+// withLiteral conserva la posición de tok con otro tipo y literal, para
+// que los errores sigan apuntando al lugar de la expresión original.
+func withLiteral(tok token.Token, typ token.TokenType, literal string) token.Token {
+	tok.Type, tok.Literal = typ, literal
+	return tok
+}
+
+// startToken retorna el token donde empieza la expresión literal.
+func startToken(exp ast.Expression) token.Token {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return exp.Token
+	case *ast.StringLiteral:
+		return exp.Token
+	case *ast.Boolean:
+		return exp.Token
+	}
+	return token.Token{}
+}
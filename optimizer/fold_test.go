@@ -0,0 +1,88 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 3 + 1", "7"},
+		{"-(4 - 10)", "6"},
+		{`"foo" + "bar" + "baz"`, `foobarbaz`},
+		{"1 < 2 == true", "true"},
+		{"!(3 != 3)", "true"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"let f = fn(a) { return a * (60 * 60); };", "let f = fn(a) return (a * 3600);;"},
+		{"if (1 > 2) { 10 / 2 } else { [1 + 1, f(2 * 2)] }", "iffalse 5else [2, f(4)]"},
+		{`{"a" + "b": 2 * 2}["ab"]`, "({ab:4}[ab])"},
+		// Lo que fallaría al ejecutarse queda para el evaluador.
+		{"1 / 0", "(1 / 0)"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+		}
+		folded := FoldConstants(program)
+		if got := folded.String(); got != tt.expected {
+			t.Errorf("wrong folding for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFoldConstantsKeepsPosition(t *testing.T) {
+	program := parser.New(lexer.New("let x = 1;\n  2 * 3 + 4")).ParseProgram()
+	FoldConstants(program)
+	lit, ok := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression not folded. got=%T", program.Statements[1].(*ast.ExpressionStatement).Expression)
+	}
+	if lit.Value != 10 || lit.Token.Line != 2 || lit.Token.Column != 3 {
+		t.Errorf("wrong literal. got=%d at %d:%d", lit.Value, lit.Token.Line, lit.Token.Column)
+	}
+}
+
+func TestFoldExpression(t *testing.T) {
+	exp := parser.New(lexer.New("(1 + 2) * 3")).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression
+	folded := FoldConstants(exp)
+	if lit, ok := folded.(*ast.IntegerLiteral); !ok || lit.Value != 9 {
+		t.Errorf("expression not folded. got=%s", folded.String())
+	}
+}
+
+func TestConstant(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // "" si no es constante
+	}{
+		{"(1 + 2) * 3", "9"},
+		{`"a" + "b" == "ab"`, ""},
+		{"!(1 < 2)", "false"},
+		{"true != false", "true"},
+		{"x + 1", ""},
+		{"1 / 0", ""},
+		{"f(1)", ""},
+	}
+	for _, tt := range tests {
+		stmt := parser.New(lexer.New(tt.input)).ParseProgram().Statements[0].(*ast.ExpressionStatement)
+		before := stmt.Expression.String()
+		got := ""
+		if folded := Constant(stmt.Expression); folded != nil {
+			got = folded.String()
+		}
+		if got != tt.expected {
+			t.Errorf("wrong constant for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+		if after := stmt.Expression.String(); after != before {
+			t.Errorf("Constant modified %q: %q", before, after)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// ParseError es un error encontrado durante el análisis. Además del
+// mensaje guarda dónde ocurrió y qué tokens se esperaban, para que las
+// herramientas (editores, linters) puedan mostrar diagnósticos precisos.
+type ParseError struct {
+	// Pos es la posición del token que provocó el error.
+	Pos token.Position
+	// Message describe el error, sin la posición.
+	Message string
+	// Expected son los tipos de token válidos en Pos. Está vacío cuando
+	// el error no se debe a un token inesperado.
+	Expected []token.TokenType
+	// Got es el token encontrado en Pos.
+	Got token.Token
+}
+
+// Error retorna el mensaje con la posición, con el mismo formato que los
+// errores del evaluador.
+func (e ParseError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// ErrorList son todos los errores de un análisis. Implementa error para
+// las funciones que retornan un error en vez de usar Errors().
+type ErrorList []ParseError
+
+// Error retorna el primer error, indicando cuántos más hay.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
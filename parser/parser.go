@@ -2,9 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
+	"os"
 	"strconv"
 )
 
@@ -15,6 +17,7 @@ const ( // Listado de constantes que definen el orden de precedencia de los oper
 	LESSGREATER // < o >
 	SUM         // +
 	PRODUCT     // *
+	POWER       // **
 	PREFIX      // -x o !x
 	CALL        // myFunction(X)
 	INDEX       // indice para arrays.
@@ -28,11 +31,28 @@ type Parser struct {
 	// Es el siguiente token.
 	peekToken token.Token
 	// Lista de todos los errores encontrados por el Parser.
-	errors []string
-	// Listado de Tokens de tipo PREFIJO asociados a la función prefixParseFn.
-	prefixParseFns map[token.TokenType]prefixParseFn
-	// Listado de Tokens de tipo INFIJO asociados a la función infixParseFn.
-	infixParseFns map[token.TokenType]infixParseFn
+	errors []ParseError
+	// Listado de Tokens de tipo PREFIJO asociados a la función PrefixParseFn.
+	prefixParseFns map[token.TokenType]PrefixParseFn
+	// Listado de Tokens de tipo INFIJO asociados a la función InfixParseFn.
+	infixParseFns map[token.TokenType]InfixParseFn
+	// Todos los comentarios leídos, y los que todavía no se asociaron a
+	// una sentencia.
+	comments []*ast.Comment
+	pending  []*ast.Comment
+	// Tabla de precedencias propia del parser. Es nil hasta que se llama
+	// a RegisterPrecedence; mientras tanto se usa la tabla precedences.
+	precedences map[token.TokenType]int
+	// Igual que precedences, para RegisterAssociativity.
+	associativities map[token.TokenType]Associativity
+	// Cuántos errores del lexer ya se pasaron a errors.
+	lexerErrors int
+	// Modo estricto (ver SetStrict).
+	strict bool
+	// Cuántos paréntesis, corchetes o hashes abiertos rodean al token
+	// actual. Mientras sea mayor que cero los saltos de línea no terminan
+	// una expresión.
+	nesting int
 }
 
 type (
@@ -40,9 +60,9 @@ type (
 	// Este es un tipo de dato que en lugar de tener un tipo nativo
 	// como: bool, int, string, tiene una función.
 	// esto se conoce como function first class citizen.
-	prefixParseFn func() ast.Expression
+	PrefixParseFn func() ast.Expression
 	// Se llama cuando el token se encuentre en la posición INFIJO.
-	infixParseFn func(ast.Expression) ast.Expression
+	InfixParseFn func(ast.Expression) ast.Expression
 )
 
 // precedences es una tabla de precedencias que asocia los tipos de token con su orden
@@ -56,88 +76,166 @@ var precedences = map[token.TokenType]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
-// registerPrefix es una función helper para registrar el tipo de token PREFIJO
-// junto con su respectiva función de análisis prefixParseFn.
-func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+// Associativity indica cómo se agrupan varios operadores INFIJO de la
+// misma precedencia: a - b - c es (a - b) - c, pero 2 ** 3 ** 2 es
+// 2 ** (3 ** 2).
+type Associativity int
+
+const (
+	LeftAssoc Associativity = iota
+	RightAssoc
+)
+
+// Operadores asociativos por la derecha; los demás lo son por la izquierda.
+var associativities = map[token.TokenType]Associativity{
+	token.POWER: RightAssoc,
+}
+
+// AssociativityOf retorna la asociatividad por omisión del operador t.
+func AssociativityOf(t token.TokenType) Associativity {
+	return associativities[t]
+}
+
+// RegisterPrefix registra el tipo de token PREFIJO junto con su respectiva
+// función de análisis. Reemplaza a la función registrada antes, lo que
+// permite a quien embebe el intérprete extender la gramática. La función
+// se llama con el token en CurToken y debe dejar en CurToken el último
+// token de la expresión.
+func (p *Parser) RegisterPrefix(tokenType token.TokenType, fn PrefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
 
-// registerInfix es una función helper para registrar el tipo de token INFIJO
-// junto con su respectiva función de análisis infixParseFn.
-func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+// RegisterInfix registra el tipo de token INFIJO junto con su respectiva
+// función de análisis, que recibe la expresión de la izquierda. Para que
+// se use, el token debe tener una precedencia mayor que LOWEST (ver
+// RegisterPrecedence).
+func (p *Parser) RegisterInfix(tokenType token.TokenType, fn InfixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+// RegisterPrecedence asigna la precedencia de un operador INFIJO en este
+// parser, por ejemplo SUM o PRODUCT. No afecta a otros parsers.
+func (p *Parser) RegisterPrecedence(tokenType token.TokenType, precedence int) {
+	if p.precedences == nil {
+		p.precedences = make(map[token.TokenType]int, len(precedences)+1)
+		for t, prec := range precedences {
+			p.precedences[t] = prec
+		}
+	}
+	p.precedences[tokenType] = precedence
+}
+
+// Precedence retorna la precedencia por omisión del operador INFIJO t, o
+// LOWEST si t no es un operador. Sirve, por ejemplo, para decidir dónde
+// hacen falta paréntesis al imprimir un AST.
+func Precedence(t token.TokenType) int {
+	if prec, ok := precedences[t]; ok {
+		return prec
+	}
+	return LOWEST
+}
+
+// RegisterAssociativity cambia la asociatividad del operador INFIJO t en
+// este parser. No afecta a otros parsers.
+func (p *Parser) RegisterAssociativity(t token.TokenType, assoc Associativity) {
+	if p.associativities == nil {
+		p.associativities = make(map[token.TokenType]Associativity, len(associativities)+1)
+		for op, a := range associativities {
+			p.associativities[op] = a
+		}
+	}
+	p.associativities[t] = assoc
+}
+
+func (p *Parser) associativity(t token.TokenType) Associativity {
+	if p.associativities == nil {
+		return associativities[t]
+	}
+	return p.associativities[t]
+}
+
+// Retorna la precedencia del token según la tabla del parser, o LOWEST
+// si no tiene una.
+func (p *Parser) precedence(t token.TokenType) int {
+	table := p.precedences
+	if table == nil {
+		table = precedences
+	}
+	if prec, ok := table[t]; ok {
+		return prec
+	}
+	return LOWEST
+}
+
 // Este método revisa si el siguiente token tiene algún registro asociado
 // en la tabla de precedencias. De lo contrario devuelve LOWEST.
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return p.precedence(p.peekToken.Type)
 }
 
 // Y este método consulta si la tabla tiene un orden asociado para el token actual
 // de lo contrario devuelve la precedencia más baja que es LOWEST.
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return p.precedence(p.curToken.Type)
 }
 
 // Crea una instancia de Parser
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []ParseError{}}
 	// Registramos los tokens de tipo PREFIJO.
 
 	// Primero inicializamos el map prefixParseFns
-	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.prefixParseFns = make(map[token.TokenType]PrefixParseFn)
 	// Inicializamos el map para las operaciones INFIJO o BINARIAS.
-	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.infixParseFns = make(map[token.TokenType]InfixParseFn)
 
 	// Registramos el token IDENT para identificadores.
-	p.registerPrefix(token.IDENT, p.parseIdentifier)
+	p.RegisterPrefix(token.IDENT, p.parseIdentifier)
 	// Registramos el token INT para enteros literales.
-	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.RegisterPrefix(token.INT, p.parseIntegerLiteral)
 	// Registramos el token BANG para las negaciones booleanas.
-	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.RegisterPrefix(token.BANG, p.parsePrefixExpression)
 	// Registramos el token MINUS para el operador PREFIJO '-'.
-	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.RegisterPrefix(token.MINUS, p.parsePrefixExpression)
 	// Registramos el token TRUE
-	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.RegisterPrefix(token.TRUE, p.parseBoolean)
 	// Registramos el token FALSE
-	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.RegisterPrefix(token.FALSE, p.parseBoolean)
 	// Registramos el token LPAREN
-	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.RegisterPrefix(token.LPAREN, p.parseGroupedExpression)
 	// Registramos el token IF
-	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.RegisterPrefix(token.IF, p.parseIfExpression)
 	// Registramos el token FUNCTION
-	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.RegisterPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	// Registramos el token STRING
-	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.RegisterPrefix(token.STRING, p.parseStringLiteral)
 	// Registramos el token LBRACKET para los arrays.
-	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.RegisterPrefix(token.LBRACKET, p.parseArrayLiteral)
 	// Registramos el token LBRACE para los hashes.
-	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.RegisterPrefix(token.LBRACE, p.parseHashLiteral)
 
 	// Procedemos a registrar las operaciones INFIJO o BINARIAS.
-	p.registerInfix(token.PLUS, p.parseInfixExpression)
-	p.registerInfix(token.MINUS, p.parseInfixExpression)
-	p.registerInfix(token.SLASH, p.parseInfixExpression)
-	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
-	p.registerInfix(token.EQ, p.parseInfixExpression)
-	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
-	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.RegisterInfix(token.PLUS, p.parseInfixExpression)
+	p.RegisterInfix(token.MINUS, p.parseInfixExpression)
+	p.RegisterInfix(token.SLASH, p.parseInfixExpression)
+	p.RegisterInfix(token.ASTERISK, p.parseInfixExpression)
+	p.RegisterInfix(token.POWER, p.parseInfixExpression)
+	p.RegisterInfix(token.EQ, p.parseInfixExpression)
+	p.RegisterInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.RegisterInfix(token.LT, p.parseInfixExpression)
+	p.RegisterInfix(token.GT, p.parseInfixExpression)
 	// Registramos las llamadas a las funciones.
-	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.RegisterInfix(token.LPAREN, p.parseCallExpression)
 	// Registramos el operador índice para los arrays.
-	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.RegisterInfix(token.LBRACKET, p.parseIndexExpression)
+	// Registramos el acceso a miembros.
+	p.RegisterInfix(token.DOT, p.parseMemberExpression)
 	// leemos 2 tokens, uno para el actual y el otro para el siguiente.
 	p.nextToken()
 	p.nextToken()
@@ -146,6 +244,7 @@ func New(l *lexer.Lexer) *Parser {
 
 // Analiza un diccionario (hash)
 func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.nest()()
 	hash := &ast.HashLiteral{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
@@ -153,29 +252,43 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
 		if !p.expectPeek(token.COLON) {
-			return nil
+			return p.badExpression(hash.Token)
 		}
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
+			return p.badExpression(hash.Token)
 		}
 	}
 	if !p.expectPeek(token.RBRACE) {
-		return nil
+		return p.badExpression(hash.Token)
 	}
+	hash.Rbrace = p.curToken
 	return hash
 }
 
 // Analiza el operador de índice
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.nest()()
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RBRACKET) {
-		return nil
+		return p.badExpression(exp.Token)
+	}
+	exp.Rbracket = p.curToken
+	return exp
+}
+
+// Analiza el acceso a un miembro: objeto.nombre
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+	if !p.expectPeek(token.IDENT) {
+		return p.badExpression(exp.Token)
 	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	return exp
 }
 
@@ -183,6 +296,9 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.curTokenIs(token.RBRACKET) {
+		array.Rbracket = p.curToken
+	}
 	return array
 }
 
@@ -194,12 +310,74 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 // Analiza las llamadas a las funciones.
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	p.parseCallArguments(exp)
+	if p.curTokenIs(token.RPAREN) {
+		exp.Rparen = p.curToken
+	}
 	return exp
 }
 
+// Analiza los argumentos de una llamada: es como parseExpressionList pero
+// además acepta argumentos con nombre, draw(x: 1, color: "red"), que van
+// a exp.Keywords. Los posicionales tienen que ir antes que los nombrados.
+func (p *Parser) parseCallArguments(exp *ast.CallExpression) {
+	defer p.nest()()
+	exp.Arguments = []ast.Expression{}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return
+	}
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			exp.Keywords = append(exp.Keywords, p.parseKeywordArgument(exp.Keywords))
+		} else {
+			if len(exp.Keywords) > 0 {
+				p.addError(ParseError{
+					Pos:     p.curToken.Pos(),
+					Message: "positional argument after keyword argument",
+					Got:     p.curToken,
+				})
+			}
+			exp.Arguments = append(exp.Arguments, p.parseExpression(LOWEST))
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+		// Se admite una coma final: f(x,)
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+	}
+	// Si falta el cierre se conservan los argumentos ya analizados.
+	p.expectPeek(token.RPAREN)
+}
+
+// Analiza "nombre: valor" con el nombre en el token actual. previous son
+// los argumentos con nombre anteriores de la misma llamada.
+func (p *Parser) parseKeywordArgument(previous []*ast.KeywordArgument) *ast.KeywordArgument {
+	arg := &ast.KeywordArgument{Token: p.curToken}
+	arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	for _, prev := range previous {
+		if prev.Name.Value == arg.Name.Value {
+			p.addError(ParseError{
+				Pos:     p.curToken.Pos(),
+				Message: fmt.Sprintf("duplicate keyword argument: %s", arg.Name.Value),
+				Got:     p.curToken,
+			})
+			break
+		}
+	}
+	p.nextToken() // ':'
+	p.nextToken()
+	arg.Value = p.parseExpression(LOWEST)
+	return arg
+}
+
 // Alaliza una lista de expresiones separadas por comas.
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	defer p.nest()()
 	list := []ast.Expression{}
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -209,12 +387,15 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list = append(list, p.parseExpression(LOWEST))
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		// Se admite una coma final: [1, 2, 3,]
+		if p.peekTokenIs(end) {
+			break
+		}
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectPeek(end) {
-		return nil
-	}
+	// Si falta el cierre se conservan las expresiones ya analizadas.
+	p.expectPeek(end)
 	return list
 }
 
@@ -223,11 +404,11 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 	lit.Parameters = p.parseFunctionParameters()
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 	lit.Body = p.parseBlockStatement()
 	return lit
@@ -246,6 +427,10 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		// Se admite una coma final: fn(a, b,)
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
 		p.nextToken()
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
@@ -262,24 +447,25 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 // el AST para ast.IfExpression.
 // if <condition> <consequence> else <alternative>
 func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.nest()()
 	expression := &ast.IfExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 	expression.Consequence = p.parseBlockStatement()
 	// else support.
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 		if !p.expectPeek(token.LBRACE) {
-			return nil
+			return p.badExpression(expression.Token)
 		}
 		expression.Alternative = p.parseBlockStatement()
 	}
@@ -288,16 +474,25 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 // Analiza uno o varios bloques de código.
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	// Dentro de un bloque los saltos de línea vuelven a separar
+	// sentencias, aunque el bloque esté dentro de una llamada: f(fn() { ... }).
+	nesting := p.nesting
+	p.nesting = 0
+	defer func() { p.nesting = nesting }()
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{} // Inicializa el slice de Statement.
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseCommentedStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+		block.EndComments = p.takeCommentsBefore(p.curToken.Offset)
+	}
 	return block
 }
 
@@ -316,10 +511,12 @@ func (p *Parser) parseBoolean() ast.Expression {
 
 // Analiza una expresión agrupada '(' expression ')'
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	defer p.nest()()
+	lparen := p.curToken
 	p.nextToken() // Se salta el LPAREN '('
 	exp := p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return p.badExpression(lparen)
 	}
 	return exp
 }
@@ -333,8 +530,11 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(ParseError{
+			Pos:     p.curToken.Pos(),
+			Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Got:     p.curToken,
+		})
 	}
 	lit.Value = value
 	return lit
@@ -343,17 +543,28 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 // Crea un AST de tipo Expression
 // llena sus datos con p.curToken
 // y llama a parseExpression() para que analice el operador
-// de su derecha. Le pasa la precedencia de PREFIJO.
+// de su derecha. Le pasa la precedencia de PrefixPrecedence.
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
 	}
 	p.nextToken()
-	expression.Right = p.parseExpression(PREFIX)
+	expression.Right = p.parseExpression(PrefixPrecedence(expression.Operator))
 	return expression
 }
 
+// PrefixPrecedence retorna la precedencia con la que se analiza el
+// operando del operador PREFIJO operator: el operando incluye los
+// operadores de mayor precedencia. El - unario deja dentro a **, como en
+// matemática: -2 ** 2 es -(2 ** 2), o sea -4.
+func PrefixPrecedence(operator string) int {
+	if operator == "-" {
+		return PRODUCT
+	}
+	return PREFIX
+}
+
 // Este es el famoso analisis para las expresiones INFIJO o BINARIAS.
 // veamos de qué se trata :P
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -363,6 +574,11 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 	precedence := p.curPrecedence()
+	// Con una precedencia un punto menor, el operando derecho absorbe a
+	// los operadores siguientes de igual precedencia.
+	if p.associativity(p.curToken.Type) == RightAssoc {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -370,9 +586,57 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 // El curToken lo iguala a peekToken y avanza peekToken
+// Los comentarios no llegan al análisis: se guardan para asociarlos a
+// las sentencias (ver parseCommentedStatement).
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.COMMENT || p.peekToken.Type == token.WHITESPACE {
+		if p.peekToken.Type == token.COMMENT {
+			comment := &ast.Comment{Token: p.peekToken, Text: p.peekToken.Literal}
+			p.comments = append(p.comments, comment)
+			p.pending = append(p.pending, comment)
+		}
+		p.peekToken = p.l.NextToken()
+	}
+	// Los errores del lexer (caracteres inválidos, strings sin cerrar) son
+	// también errores del análisis.
+	for _, err := range p.l.Errors()[p.lexerErrors:] {
+		p.addError(ParseError{Pos: err.Pos, Message: err.Message, Got: p.peekToken})
+	}
+	p.lexerErrors = len(p.l.Errors())
+}
+
+// Analiza una sentencia y le asocia los comentarios anteriores y el que
+// la sigue en la misma línea. Los comentarios que quedan dentro de una
+// expresión se agregan a los anteriores.
+func (p *Parser) parseCommentedStatement() ast.Statement {
+	leading := p.takeCommentsBefore(p.curToken.Offset)
+	stmt := p.parseStatement()
+	leading = append(leading, p.takeCommentsBefore(p.curToken.Offset)...)
+	if commented, ok := stmt.(ast.Commented); ok {
+		comments := commented.StatementComments()
+		comments.Leading = leading
+		if len(p.pending) > 0 && p.pending[0].Token.Line == p.curToken.Line {
+			comments.Trailing = p.pending[0]
+			p.pending = p.pending[1:]
+		}
+	}
+	return stmt
+}
+
+// Retira y retorna los comentarios pendientes anteriores a offset.
+func (p *Parser) takeCommentsBefore(offset int) []*ast.Comment {
+	n := 0
+	for n < len(p.pending) && p.pending[n].Token.Offset < offset {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	taken := p.pending[:n:n]
+	p.pending = p.pending[n:]
+	return taken
 }
 
 // ParseProgram crea el AST para la siguiente gramática:
@@ -381,15 +645,81 @@ func (p *Parser) ParseProgram() *ast.Program {
 	programNode := &ast.Program{}
 	programNode.Statements = []ast.Statement{}
 	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseCommentedStatement()
 		if stmt != nil {
 			programNode.Statements = append(programNode.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	programNode.Comments = p.comments
+	programNode.EndComments = p.pending
+	p.pending = nil
+	if p.strict {
+		p.checkStrict(programNode, false)
+	}
 	return programNode
 }
 
+// ParseSingleExpression analiza exactamente una expresión, seguida
+// opcionalmente de ';'. Si después queda algún token registra un error.
+func (p *Parser) ParseSingleExpression() ast.Expression {
+	exp := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.peekTokenIs(token.EOF) {
+		p.addError(ParseError{
+			Pos:      p.peekToken.Pos(),
+			Message:  fmt.Sprintf("unexpected %q after expression", p.peekToken.Literal),
+			Expected: []token.TokenType{token.EOF},
+			Got:      p.peekToken,
+		})
+	}
+	if p.strict {
+		p.checkStrict(exp, true)
+	}
+	return exp
+}
+
+// ParseExpressionString analiza src como una única expresión, por ejemplo
+// la fórmula de una celda o un valor de configuración. El error, si lo
+// hay, es un ErrorList.
+func ParseExpressionString(src string) (ast.Expression, error) {
+	p := New(lexer.New(src))
+	exp := p.ParseSingleExpression()
+	if len(p.errors) != 0 {
+		return exp, ErrorList(p.errors)
+	}
+	return exp, nil
+}
+
+// ParseReader analiza el programa que se lee de r, sin cargarlo entero en
+// memoria. El error es el de r si la lectura falló, o un ErrorList si el
+// programa tiene errores de sintaxis; en los dos casos también se retorna
+// lo que se pudo analizar.
+func ParseReader(r io.Reader) (*ast.Program, error) {
+	l := lexer.NewFromReader(r)
+	p := New(l)
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		return program, err
+	}
+	if len(p.errors) != 0 {
+		return program, ErrorList(p.errors)
+	}
+	return program, nil
+}
+
+// ParseFile es ParseReader para el archivo en path.
+func ParseFile(path string) (*ast.Program, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseReader(f)
+}
+
 // Analiza el Statement actual. Primero verifica de qué tipo es
 // y luego llama a su respectivo analizador.
 func (p *Parser) parseStatement() ast.Statement {
@@ -412,16 +742,16 @@ func (p *Parser) parseStatement() ast.Statement {
 // Analiza y crea un AST de tipo ast.LetStatement
 // usando la siguiente gramática:
 // letStatement = 'let' identifier '=' expression
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
 	if !p.expectPeek(token.IDENT) {
-		return nil
+		return p.badStatement(stmt.Token)
 	}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	if !p.expectPeek(token.ASSIGN) {
-		return nil
+		return p.badStatement(stmt.Token)
 	}
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
@@ -465,10 +795,10 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefixFn := p.prefixParseFns[p.curToken.Type]
 	if prefixFn == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return p.badExpression(p.curToken)
 	}
 	leftExp := prefixFn()
-	for !p.curTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	for !p.curTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() && !p.atStatementBoundary() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -479,6 +809,65 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
+// atStatementBoundary indica si un salto de línea antes del siguiente token
+// termina la expresión actual. Eso pasa cuando el token podría empezar una
+// sentencia nueva; sin esta regla
+//
+//	let f = g
+//	(1 + 2)
+//
+// se leería como la llamada g(1 + 2). Los operadores que solo son infijos,
+// como + o ==, continúan la expresión en la línea siguiente, y dentro de
+// paréntesis, corchetes o un hash los saltos de línea no cuentan.
+func (p *Parser) atStatementBoundary() bool {
+	if p.nesting > 0 || p.peekToken.Line <= p.curToken.End().Line {
+		return false
+	}
+	_, ok := p.prefixParseFns[p.peekToken.Type]
+	return ok
+}
+
+// nest marca que el parser entró en un par de delimitadores. La función
+// que retorna deshace la marca: defer p.nest()().
+func (p *Parser) nest() func() {
+	p.nesting++
+	return func() { p.nesting-- }
+}
+
+// badExpression crea el nodo que reemplaza a una expresión que no se pudo
+// analizar: va desde from hasta el token actual. Así el AST nunca tiene
+// expresiones en nil, aunque el programa tenga errores.
+func (p *Parser) badExpression(from token.Token) *ast.BadExpression {
+	return &ast.BadExpression{Token: from, To: p.curToken}
+}
+
+// badStatement es como badExpression pero para una sentencia.
+func (p *Parser) badStatement(from token.Token) *ast.BadStatement {
+	return &ast.BadStatement{Token: from, To: p.curToken}
+}
+
+// Las siguientes funciones exponen el estado del parser a las funciones
+// registradas con RegisterPrefix y RegisterInfix.
+
+// CurToken retorna el token actual.
+func (p *Parser) CurToken() token.Token { return p.curToken }
+
+// PeekToken retorna el token siguiente al actual.
+func (p *Parser) PeekToken() token.Token { return p.peekToken }
+
+// NextToken avanza al siguiente token.
+func (p *Parser) NextToken() { p.nextToken() }
+
+// ParseExpression analiza una expresión a partir del token actual, hasta
+// encontrar un operador con precedencia menor o igual a precedence.
+func (p *Parser) ParseExpression(precedence int) ast.Expression {
+	return p.parseExpression(precedence)
+}
+
+// ExpectPeek avanza si el siguiente token es t; si no, registra un error
+// de análisis y retorna false.
+func (p *Parser) ExpectPeek(t token.TokenType) bool { return p.expectPeek(t) }
+
 // Compara el token recibido con el siguiente token (peekToken).
 // Si son iguales avanza el Token, sino entonces registra el error.
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -503,17 +892,43 @@ func (p *Parser) curTokenIs(t token.TokenType) bool {
 
 // Registra un error cuando no existan funciones asociadas al token recibido.
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	// El lexer ya informó por qué el token es ILLEGAL.
+	if t == token.ILLEGAL {
+		return
+	}
+	p.addError(ParseError{
+		Pos:     p.curToken.Pos(),
+		Message: fmt.Sprintf("no prefix parse function for %s found", t),
+		Got:     p.curToken,
+	})
 }
 
-// Retorna la lista de los posibles errores encontrados durante el análisis.
+// Retorna la lista de los posibles errores encontrados durante el análisis,
+// como texto. ParseErrors retorna los mismos errores con todos sus datos.
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
+
+// ParseErrors retorna los errores encontrados durante el análisis, en el
+// orden en que aparecen en el código fuente.
+func (p *Parser) ParseErrors() []ParseError {
 	return p.errors
 }
 
 // Registra el error en la lista de errores.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead.", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(ParseError{
+		Pos:      p.peekToken.Pos(),
+		Message:  fmt.Sprintf("expected next token to be %s, got %s instead.", t, p.peekToken.Type),
+		Expected: []token.TokenType{t},
+		Got:      p.peekToken,
+	})
+}
+
+func (p *Parser) addError(err ParseError) {
+	p.errors = append(p.errors, err)
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -247,6 +250,19 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"a * b ** c ** d * e", "((a * (b ** (c ** d))) * e)"},
+		{"(2 ** 3) ** 2", "((2 ** 3) ** 2)"},
+		{"-2 ** 2", "(-(2 ** 2))"},
+		{"(-2) ** 2", "((-2) ** 2)"},
+		{"-2 ** 3 ** 2 * 4", "((-(2 ** (3 ** 2))) * 4)"},
+		{"2 ** -3 ** 2", "(2 ** (-(3 ** 2)))"},
+		{"-a * b", "((-a) * b)"},
+		{"-a[0] ** 2", "(-((a[0]) ** 2))"},
+		{"!a ** 2", "((!a) ** 2)"},
+		{"-a.b * c", "((-(a.b)) * c)"},
+		{"db.query(q)[0].name", "(((db.query)(q)[0]).name)"},
+		{"f(x).y", "(f(x).y)"},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -647,3 +663,503 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	}
 	return true
 }
+
+func TestParsingHashLiteralKeepsKeyOrder(t *testing.T) {
+	input := `{"two": 2, "one": 1, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	expected := []string{"two", "one", "three"}
+	if len(hash.Keys) != len(expected) {
+		t.Fatalf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+	}
+	for i, key := range hash.Keys {
+		if key.String() != expected[i] {
+			t.Errorf("hash.Keys[%d] wrong. want=%q, got=%q", i, expected[i], key.String())
+		}
+	}
+	if hash.String() != "{two:2, one:1, three:3}" {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};\nadd(1, [2, 3][0]) * -x;\n{\"k\": \"v\"}"
+	program := New(lexer.New(input)).ParseProgram()
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	infix := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	call := infix.Left.(*ast.CallExpression)
+	index := call.Arguments[1].(*ast.IndexExpression)
+	hash := program.Statements[2].(*ast.ExpressionStatement).Expression
+
+	tests := []struct {
+		node          ast.Node
+		expectedPos   string
+		expectedEnd   string
+		expectedRange string
+	}{
+		{let, "1:1", "3:2", "let add = fn(a, b) {\n  a + b\n}"},
+		{fn.Body.Statements[0], "2:3", "2:8", "a + b"},
+		{infix, "4:1", "4:23", "add(1, [2, 3][0]) * -x"},
+		{call, "4:1", "4:18", "add(1, [2, 3][0])"},
+		{index, "4:8", "4:17", "[2, 3][0]"},
+		{infix.Right, "4:21", "4:23", "-x"},
+		{hash, "5:1", "5:11", `{"k": "v"}`},
+		{program, "1:1", "5:11", input},
+	}
+	for i, tt := range tests {
+		pos, end := tt.node.Pos(), tt.node.End()
+		if pos.String() != tt.expectedPos || end.String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - wrong position. expected=%s-%s, got=%s-%s",
+				i, tt.expectedPos, tt.expectedEnd, pos, end)
+			continue
+		}
+		if got := input[pos.Offset:end.Offset]; got != tt.expectedRange {
+			t.Errorf("tests[%d] - wrong range. expected=%q, got=%q", i, tt.expectedRange, got)
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "expected next token to be =, got INT instead. (line 1, column 7)"},
+		{"let x = 1;\n  )", "no prefix parse function for ) found (line 2, column 3)"},
+		{"99999999999999999999", "could not parse \"99999999999999999999\" as integer (line 1, column 1)"},
+		{"h.1", "expected next token to be IDENT, got INT instead. (line 1, column 3)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestStructuredParseErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedPos      string
+		expectedExpected []token.TokenType
+		expectedGot      token.TokenType
+	}{
+		{"let = 5;", "1:5", []token.TokenType{token.IDENT}, token.ASSIGN},
+		{"let f = 1;\nfn(x { x }", "2:6", []token.TokenType{token.RPAREN}, token.LBRACE},
+		{"1 + ;", "1:5", nil, token.SEMICOLON},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.ParseErrors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
+		}
+		err := errors[0]
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("%q: Pos wrong. expected=%s, got=%s", tt.input, tt.expectedPos, err.Pos)
+		}
+		if fmt.Sprint(err.Expected) != fmt.Sprint(tt.expectedExpected) {
+			t.Errorf("%q: Expected wrong. expected=%v, got=%v", tt.input, tt.expectedExpected, err.Expected)
+		}
+		if err.Got.Type != tt.expectedGot {
+			t.Errorf("%q: Got wrong. expected=%s, got=%s", tt.input, tt.expectedGot, err.Got.Type)
+		}
+		if p.Errors()[0] != err.Error() {
+			t.Errorf("%q: Errors()[0] = %q does not match %q", tt.input, p.Errors()[0], err.Error())
+		}
+	}
+}
+
+func TestBadNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "<bad statement>"},
+		{"-;", "(-<bad expression>)"},
+		{"1 + (2 * 3;", "(1 + <bad expression>)"},
+		{"if (x { 1 }", "<bad expression>"},
+		{"fn(x) 1", "<bad expression>"},
+		{"add(1, {2 3})", "add(1, <bad expression>)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected errors for %q", tt.input)
+		}
+		if len(program.Statements) == 0 {
+			t.Fatalf("no statements for %q", tt.input)
+		}
+		// String() y Pos() no deben fallar con nodos en nil.
+		program.Pos()
+		program.End()
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("wrong String() for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRegisterOperators(t *testing.T) {
+	p := New(lexer.New("x * :name + 5!"))
+	// :name es un string literal.
+	p.RegisterPrefix(token.COLON, func() ast.Expression {
+		if !p.ExpectPeek(token.IDENT) {
+			return nil
+		}
+		tok := p.CurToken()
+		tok.Type = token.STRING
+		return &ast.StringLiteral{Token: tok, Value: tok.Literal}
+	})
+	// n! es una llamada a fact(n).
+	p.RegisterInfix(token.BANG, func(left ast.Expression) ast.Expression {
+		bang := p.CurToken()
+		fact := &ast.Identifier{Token: bang, Value: "fact"}
+		return &ast.CallExpression{Token: bang, Function: fact, Arguments: []ast.Expression{left}, Rparen: bang}
+	})
+	p.RegisterPrecedence(token.BANG, INDEX)
+	// + se agrupa antes que *.
+	p.RegisterPrecedence(token.PLUS, PREFIX)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if got := program.String(); got != "(x * (name + fact(5)))" {
+		t.Errorf("wrong program. got=%q", got)
+	}
+
+	// Los demás parsers no se ven afectados.
+	other := New(lexer.New("x * y + 5"))
+	if got := other.ParseProgram().String(); got != "((x * y) + 5)" {
+		t.Errorf("precedence leaked to another parser. got=%q", got)
+	}
+
+	right := New(lexer.New("a - b - c"))
+	right.RegisterAssociativity(token.MINUS, RightAssoc)
+	if got := right.ParseProgram().String(); got != "(a - (b - c))" {
+		t.Errorf("wrong right-associative parse. got=%q", got)
+	}
+	if AssociativityOf(token.MINUS) != LeftAssoc {
+		t.Errorf("associativity leaked to the default table")
+	}
+}
+
+func TestParseExpressionString(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{"1 + 2 * x", "(1 + (2 * x))", ""},
+		{"len(items);", "len(items)", ""},
+		{"  {\"a\": [1, 2]}  ", "{a:[1, 2]}", ""},
+		{"1 + 2 3", "(1 + 2)", "unexpected \"3\" after expression (line 1, column 7)"},
+		{"let x = 1", "<bad expression>", "no prefix parse function for LET found (line 1, column 1) (and 1 more errors)"},
+		{"", "<bad expression>", "no prefix parse function for EOF found (line 1, column 1)"},
+	}
+	for _, tt := range tests {
+		exp, err := ParseExpressionString(tt.input)
+		if exp == nil {
+			t.Fatalf("%q: nil expression", tt.input)
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("%q: wrong expression. expected=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != tt.expectedError {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errMsg)
+		}
+	}
+}
+
+func TestCommentAttachment(t *testing.T) {
+	input := `// suma dos números
+// (enteros)
+let add = fn(a, b) {
+	// el resultado
+	a + b // sin overflow
+	// fin del cuerpo
+}; // add
+
+add(1, // uno
+	2);
+// fin`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	texts := func(comments []*ast.Comment) []string {
+		out := []string{}
+		for _, c := range comments {
+			out = append(out, c.Text)
+		}
+		return out
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	if got := fmt.Sprint(texts(let.Leading)); got != "[// suma dos números // (enteros)]" {
+		t.Errorf("wrong leading comments for let. got=%s", got)
+	}
+	if let.Trailing == nil || let.Trailing.Text != "// add" {
+		t.Errorf("wrong trailing comment for let. got=%v", let.Trailing)
+	}
+
+	body := let.Value.(*ast.FunctionLiteral).Body
+	inner := body.Statements[0].(*ast.ExpressionStatement)
+	if got := fmt.Sprint(texts(inner.Leading)); got != "[// el resultado]" {
+		t.Errorf("wrong leading comments in body. got=%s", got)
+	}
+	if inner.Trailing == nil || inner.Trailing.Text != "// sin overflow" {
+		t.Errorf("wrong trailing comment in body. got=%v", inner.Trailing)
+	}
+	if got := fmt.Sprint(texts(body.EndComments)); got != "[// fin del cuerpo]" {
+		t.Errorf("wrong end comments in body. got=%s", got)
+	}
+
+	// Un comentario dentro de una expresión queda antes de su sentencia.
+	call := program.Statements[1].(*ast.ExpressionStatement)
+	if got := fmt.Sprint(texts(call.Leading)); got != "[// uno]" {
+		t.Errorf("wrong leading comments for call. got=%s", got)
+	}
+	if got := fmt.Sprint(texts(program.EndComments)); got != "[// fin]" {
+		t.Errorf("wrong end comments. got=%s", got)
+	}
+	if len(program.Comments) != 8 {
+		t.Errorf("program.Comments has wrong length. got=%d", len(program.Comments))
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{"[1, 2, 3,]", "[1, 2, 3]", ""},
+		{"[\n\t1,\n\t2,\n]", "[1, 2]", ""},
+		{"{\"a\": 1, \"b\": 2,}", "{a:1, b:2}", ""},
+		{"fn(a, b,) { a }", "fn(a, b) a", ""},
+		{"f(x,)", "f(x)", ""},
+		{"[,]", "[<bad expression>]", "no prefix parse function for , found (line 1, column 2)"},
+		{"f(x,,)", "f(x, <bad expression>)", "no prefix parse function for , found (line 1, column 5)"},
+	}
+	for _, tt := range tests {
+		exp, err := ParseExpressionString(tt.input)
+		if exp == nil {
+			t.Fatalf("%q: nil expression", tt.input)
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("%q: wrong expression. expected=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != tt.expectedError {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errMsg)
+		}
+	}
+}
+
+func TestNewlineTerminatesStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5\nlet y = x", []string{"let x = 5;", "let y = x;"}},
+		{"let f = g\n(1 + 2)", []string{"let f = g;", "(1 + 2)"}},
+		{"x\n[1]", []string{"x", "[1]"}},
+		{"x\n-1", []string{"x", "(-1)"}},
+		{"if (x) { 1 }\n(y)", []string{"ifx 1", "y"}},
+		// Los operadores que solo son infijos continúan en la línea siguiente.
+		{"let x = 1\n+ 2", []string{"let x = (1 + 2);"}},
+		{"a ==\nb", []string{"(a == b)"}},
+		// Dentro de delimitadores los saltos de línea no cuentan.
+		{"f(a\n-1)", []string{"f((a - 1))"}},
+		{"(a\n(b))", []string{"a(b)"}},
+		{"[a\n[0]]", []string{"[(a[0])]"}},
+		{"{\"k\": a\n-1}", []string{"{k:(a - 1)}"}},
+		// Pero sí dentro de un bloque, aunque esté dentro de una llamada.
+		{"f(fn() { a\n(b) })", []string{"f(fn() ab)"}},
+		{"let x = 5; f\n(x)", []string{"let x = 5;", "f", "x"}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("%q: wrong number of statements. expected=%d, got=%d (%s)",
+				tt.input, len(tt.expected), len(program.Statements), program.String())
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("%q: statement %d wrong. expected=%q, got=%q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.mk")
+	bad := filepath.Join(dir, "bad.mk")
+	os.WriteFile(good, []byte("let x = 1;\nputs(x + 2);\n"), 0644)
+	os.WriteFile(bad, []byte("let = 1;\n"), 0644)
+
+	program, err := ParseFile(good)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if program.String() != "let x = 1;puts((x + 2))" {
+		t.Errorf("wrong program. got=%q", program.String())
+	}
+
+	program, err = ParseFile(bad)
+	if _, ok := err.(ErrorList); !ok || program == nil {
+		t.Errorf("expected ErrorList and partial program. got=%T (%v), %v", err, err, program)
+	}
+
+	if _, err := ParseFile(filepath.Join(dir, "missing.mk")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error. got=%v", err)
+	}
+}
+
+func TestKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input            string
+		expected         string
+		expectedKeywords []string
+		expectedError    string
+	}{
+		{`draw(x: 1, y: 2, color: "red")`, "draw(x: 1, y: 2, color: red)", []string{"x", "y", "color"}, ""},
+		{"f(a, b + 1, c: d)", "f(a, (b + 1), c: d)", []string{"c"}, ""},
+		{"f(a: 1,)", "f(a: 1)", []string{"a"}, ""},
+		{"f(\n\ta: 1,\n\tb: 2,\n)", "f(a: 1, b: 2)", []string{"a", "b"}, ""},
+		// Un hash como argumento no es un argumento con nombre.
+		{`f({a: 1})`, "f({a:1})", nil, ""},
+		{"f(a: 1, 2)", "f(2, a: 1)", []string{"a"}, "positional argument after keyword argument (line 1, column 9)"},
+		{"f(a: 1, a: 2)", "f(a: 1, a: 2)", []string{"a", "a"}, "duplicate keyword argument: a (line 1, column 9)"},
+	}
+	for _, tt := range tests {
+		exp, err := ParseExpressionString(tt.input)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != tt.expectedError {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errMsg)
+		}
+		call, ok := exp.(*ast.CallExpression)
+		if !ok {
+			t.Errorf("%q: exp not *ast.CallExpression. got=%T", tt.input, exp)
+			continue
+		}
+		if call.String() != tt.expected {
+			t.Errorf("%q: wrong expression. expected=%q, got=%q", tt.input, tt.expected, call.String())
+		}
+		var names []string
+		for _, arg := range call.Keywords {
+			names = append(names, arg.Name.Value)
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tt.expectedKeywords) {
+			t.Errorf("%q: wrong keywords. expected=%v, got=%v", tt.input, tt.expectedKeywords, names)
+		}
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"if (x) { 1 }", nil},
+		{"if (x) { 1 }; 2", nil},
+		{"let y = if (x) { 1 } else { 2 };", nil},
+		{"let y = if (x) { 1 };", []string{"if without else used as a value (strict mode) (line 1, column 9)"}},
+		{"puts(if (x) { 1 })", []string{"if without else used as a value (strict mode) (line 1, column 6)"}},
+		{"1 + if (x) { 1 }", []string{"if without else used as a value (strict mode) (line 1, column 5)"}},
+		{"let f = fn(x) { if (x) { 1 } };", []string{"if without else used as a value (strict mode) (line 1, column 17)"}},
+		{"let f = fn(x) { if (x) { puts(x) }; x };", nil},
+		{"let f = fn(x) { return if (x) { 1 }; };", []string{"if without else used as a value (strict mode) (line 1, column 24)"}},
+		// El valor de un bloque es el de su última sentencia.
+		{"let y = if (a) { if (b) { 1 } } else { 2 };", []string{"if without else used as a value (strict mode) (line 1, column 18)"}},
+		{"let y = if (a) { if (b) { 1 }; 2 } else { 2 };", nil},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.SetStrict(true)
+		p.ParseProgram()
+		if fmt.Sprint(p.Errors()) != fmt.Sprint(tt.errors) {
+			t.Errorf("%q: wrong errors. expected=%q, got=%q", tt.input, tt.errors, p.Errors())
+		}
+
+		// Fuera del modo estricto ninguno es un error.
+		p = New(lexer.New(tt.input))
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+
+	p := New(lexer.New("if (x) { 1 }"))
+	p.SetStrict(true)
+	p.ParseSingleExpression()
+	if len(p.Errors()) != 1 {
+		t.Errorf("expected an error for a single if expression. got=%q", p.Errors())
+	}
+}
+
+func TestLexerErrorsInParser(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5; @", []string{"illegal character U+0040 '@' (line 1, column 12)"}},
+		{"let x = \"abc", []string{"unterminated string literal (line 1, column 9)"}},
+		{"let = #;", []string{
+			"expected next token to be IDENT, got = instead. (line 1, column 5)",
+			"illegal character U+0023 '#' (line 1, column 7)",
+			"no prefix parse function for = found (line 1, column 5)",
+		}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if fmt.Sprint(p.Errors()) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q: wrong errors.\nexpected=%q\ngot=     %q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestParseWithWhitespaceTokens(t *testing.T) {
+	input := "let x = 1; // uno\nif (x) {\n\tputs(x)\n}\n"
+	l := lexer.New(input)
+	l.SetKeepWhitespace(true)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	expected := New(lexer.New(input)).ParseProgram()
+	if program.String() != expected.String() {
+		t.Errorf("wrong program. expected=%q, got=%q", expected.String(), program.String())
+	}
+	if len(program.Comments) != 1 {
+		t.Errorf("wrong number of comments. got=%d", len(program.Comments))
+	}
+}
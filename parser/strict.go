@@ -0,0 +1,85 @@
+package parser
+
+import "monkey/ast"
+
+// SetStrict activa o apaga el modo estricto, que está apagado por defecto.
+// En modo estricto es un error usar como valor un if sin else, porque
+// cuando la condición es falsa no hay valor (el evaluador da NULL):
+//
+//	let x = if (n > 0) { n };
+//
+// Un if se usa como valor cuando está en un let, un return, un argumento
+// o un operando, o cuando es la última sentencia del cuerpo de una función
+// o de un bloque que a su vez se usa como valor. Para un valor que puede
+// faltar están some(x) y none().
+func (p *Parser) SetStrict(strict bool) {
+	p.strict = strict
+}
+
+// checkStrict registra un error por cada if sin else de node que se usa
+// como valor. used indica si se usa el valor de node.
+func (p *Parser) checkStrict(node ast.Node, used bool) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			p.checkStrict(stmt, false)
+		}
+	case *ast.BlockStatement:
+		for i, stmt := range node.Statements {
+			p.checkStrict(stmt, used && i == len(node.Statements)-1)
+		}
+	case *ast.ExpressionStatement:
+		p.checkStrict(node.Expression, used)
+	case *ast.LetStatement:
+		p.checkStrict(node.Value, true)
+	case *ast.ReturnStatement:
+		p.checkStrict(node.ReturnValue, true)
+	case *ast.IfExpression:
+		if used && node.Alternative == nil {
+			p.addError(ParseError{
+				Pos:     node.Pos(),
+				Message: "if without else used as a value (strict mode)",
+				Got:     node.Token,
+			})
+		}
+		p.checkStrict(node.Condition, true)
+		if node.Consequence != nil {
+			p.checkStrict(node.Consequence, used)
+		}
+		if node.Alternative != nil {
+			p.checkStrict(node.Alternative, used)
+		}
+	case *ast.FunctionLiteral:
+		// La última sentencia es el valor de retorno.
+		if node.Body != nil {
+			p.checkStrict(node.Body, true)
+		}
+	case *ast.PrefixExpression:
+		p.checkStrict(node.Right, true)
+	case *ast.InfixExpression:
+		p.checkStrict(node.Left, true)
+		p.checkStrict(node.Right, true)
+	case *ast.CallExpression:
+		p.checkStrict(node.Function, true)
+		for _, arg := range node.Arguments {
+			p.checkStrict(arg, true)
+		}
+		for _, arg := range node.Keywords {
+			p.checkStrict(arg.Value, true)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			p.checkStrict(el, true)
+		}
+	case *ast.IndexExpression:
+		p.checkStrict(node.Left, true)
+		p.checkStrict(node.Index, true)
+	case *ast.MemberExpression:
+		p.checkStrict(node.Object, true)
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			p.checkStrict(key, true)
+			p.checkStrict(node.Pairs[key], true)
+		}
+	}
+}
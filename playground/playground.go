@@ -0,0 +1,123 @@
+// Package playground ejecuta programas Monkey enviados por usuarios para
+// mostrarlos en una página web: junta la salida de puts con el resultado
+// o el error, y limita lo que el programa puede hacer.
+package playground
+
+import (
+	"context"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"sync"
+)
+
+// MaxSteps es la cantidad de pasos de evaluación que puede dar un programa
+// antes de abortar con "fuel exhausted".
+const MaxSteps = 10000000
+
+// MaxMemory es cuántos bytes pueden reservar los strings, arrays y hashes
+// que crea un programa.
+const MaxMemory = 64 << 20
+
+// MaxOutput es la cantidad máxima de bytes de salida que se guardan. El
+// resto se descarta.
+const MaxOutput = 1 << 20
+
+// Request es un programa a ejecutar.
+type Request struct {
+	Source string `json:"source"`
+	// Stdin es lo que lee el builtin input.
+	Stdin string `json:"stdin,omitempty"`
+}
+
+// Result es lo que produjo un programa.
+type Result struct {
+	// Output es lo que escribió puts.
+	Output string `json:"output"`
+	// Value es el valor de la última expresión, como lo muestra el REPL.
+	Value string `json:"value,omitempty"`
+	// Error es el error de sintaxis o de ejecución, si lo hubo.
+	Error string `json:"error,omitempty"`
+}
+
+// Run evalúa source sin acceso al anfitrión (sin archivos, red ni
+// procesos), con un máximo de MaxSteps pasos y MaxMemory bytes.
+func Run(source string) Result {
+	return RunRequest(context.Background(), Request{Source: source})
+}
+
+// RunRequest evalúa req con los mismos límites que Run. Si ctx se cancela
+// la evaluación termina con un error, también si está esperando en un
+// builtin como sleep o recv. Al retornar se cancelan las funciones
+// lanzadas con spawn o async que sigan ejecutándose. Un panic de Go
+// durante la evaluación es el error del resultado.
+func RunRequest(ctx context.Context, req Request) (result Result) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := parser.New(lexer.New(req.Source))
+	program := p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		messages := make([]string, len(errors))
+		for i, err := range errors {
+			messages[i] = err.Error()
+		}
+		return Result{Error: strings.Join(messages, "\n")}
+	}
+
+	out := &limitedWriter{max: MaxOutput}
+	defer func() {
+		if r := recover(); r != nil {
+			result = Result{Output: out.String(), Error: fmt.Sprintf("ERROR: %v", r)}
+		}
+	}()
+	opts := evaluator.Options{
+		MaxSteps:     MaxSteps,
+		MaxMemory:    MaxMemory,
+		Context:      ctx,
+		Sandboxed:    true,
+		Capabilities: evaluator.CapNone,
+		Stdout:       out,
+		Stdin:        strings.NewReader(req.Stdin),
+	}
+	evaluated := evaluator.EvalWithOptions(program, object.NewEnvironment(), opts)
+
+	result = Result{Output: out.String()}
+	switch evaluated := evaluated.(type) {
+	case nil:
+	case *object.Error:
+		result.Error = evaluated.Inspect()
+	default:
+		result.Value = evaluated.Inspect()
+	}
+	return result
+}
+
+// limitedWriter guarda hasta max bytes y descarta el resto sin fallar.
+// Las funciones lanzadas con spawn pueden seguir escribiendo después de
+// que terminó la evaluación, así que mu protege a buf.
+type limitedWriter struct {
+	mu  sync.Mutex
+	buf strings.Builder
+	max int
+}
+
+func (w *limitedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if room := w.max - w.buf.Len(); len(p) > room {
+		w.buf.Write(p[:room])
+	} else {
+		w.buf.Write(p)
+	}
+	return len(p), nil
+}
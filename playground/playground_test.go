@@ -0,0 +1,63 @@
+package playground
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Result
+	}{
+		{`puts("hola"); 1 + 2`, Result{Output: "hola\n", Value: "3"}},
+		{`let x = 1;`, Result{}},
+		{`puts(1); 1 + true`, Result{Output: "1\n", Error: "ERROR: type mismatch: INTEGER + BOOLEAN (line 1, column 10)"}},
+		{`read_file("/etc/passwd")`, Result{Error: "ERROR: `read_file` requires the fs capability (line 1, column 1)"}},
+		{`exit(2)`, Result{Value: "exit(2)"}},
+	}
+	for _, tt := range tests {
+		if got := Run(tt.input); got != tt.expected {
+			t.Errorf("%q: wrong result.\nwant=%+v\ngot= %+v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	result := Run("let = 1;\nlet y 2;")
+	if lines := strings.Split(result.Error, "\n"); len(lines) < 2 {
+		t.Errorf("expected every syntax error. got=%q", result.Error)
+	}
+
+	result = Run("let loop = fn() { loop() }; loop()")
+	if !strings.HasPrefix(result.Error, "ERROR: ") || result.Value != "" {
+		t.Errorf("an endless program must fail. got=%+v", result)
+	}
+
+	// La recursión que pasa por un builtin también tiene que fallar sin
+	// terminar el proceso.
+	result = Run("let f = fn(n) { take(lazy_map([n], f), 1) }; f(1)")
+	if !strings.HasPrefix(result.Error, "ERROR: maximum recursion depth exceeded") {
+		t.Errorf("recursion through a builtin must fail. got=%+v", result)
+	}
+}
+
+// Las funciones lanzadas con spawn no siguen ejecutándose cuando Run
+// retorna.
+func TestRunStopsSpawned(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		if got := Run("let c = channel(); spawn(fn() { recv(c) }); 1"); got.Value != "1" {
+			t.Fatalf("wrong result. got=%+v", got)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("spawned functions kept running: %d goroutines, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
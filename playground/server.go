@@ -0,0 +1,82 @@
+package playground
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MaxSourceSize es el tamaño máximo, en bytes, de un pedido a /run.
+const MaxSourceSize = 64 << 10
+
+// MaxConcurrentRuns es cuántos programas ejecuta el servidor a la vez.
+// Como cada uno puede reservar MaxMemory bytes, también limita la memoria
+// que usan entre todos.
+const MaxConcurrentRuns = 8
+
+//go:embed index.html
+var indexPage []byte
+
+// NewHandler retorna el servidor del playground:
+//
+//	GET /      una página para escribir y ejecutar programas
+//	POST /run  ejecuta un Request en JSON y responde un Result en JSON
+//
+// Cada programa se ejecuta con RunRequest y se cancela si tarda más que
+// timeout. Se ejecutan hasta MaxConcurrentRuns a la vez; un pedido que no
+// consigue lugar antes del timeout recibe 503 Service Unavailable.
+func NewHandler(timeout time.Duration) http.Handler {
+	slots := make(chan struct{}, MaxConcurrentRuns)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexPage)
+	})
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req Request
+		body := http.MaxBytesReader(w, r.Body, MaxSourceSize)
+		if err := json.NewDecoder(body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		if !acquire(ctx, slots) {
+			http.Error(w, "too many programs running", http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-slots }()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RunRequest(ctx, req))
+	})
+	return mux
+}
+
+// acquire ocupa un lugar de slots, esperando hasta que se cancele ctx.
+// Retorna false si no lo consiguió. Si hay un lugar libre lo ocupa aunque
+// ctx ya esté cancelado: RunRequest se encarga de reportarlo.
+func acquire(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,147 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerRun(t *testing.T) {
+	tests := []struct {
+		body     string
+		timeout  time.Duration
+		expected Result
+	}{
+		{`{"source": "puts(\"hola\"); 1 + 2"}`, time.Second, Result{Output: "hola\n", Value: "3"}},
+		{`{"source": "puts(input(\"? \"))", "stdin": "Ana\n"}`, time.Second, Result{Output: "? Ana\n", Value: "null"}},
+		{`{"source": "input()"}`, time.Second, Result{Value: "null"}},
+		{`{"source": "` + strings.Repeat("1 + ", 1000) + `1"}`, time.Nanosecond,
+			Result{Error: "ERROR: evaluation cancelled: context deadline exceeded (line 1, column 1)"}},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(NewHandler(tt.timeout))
+		resp, err := http.Post(server.URL+"/run", "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Errorf("%s: invalid response: %s", tt.body, err)
+		}
+		resp.Body.Close()
+		server.Close()
+		if resp.StatusCode != http.StatusOK || result != tt.expected {
+			t.Errorf("%s: wrong response %d.\nwant=%+v\ngot= %+v", tt.body, resp.StatusCode, tt.expected, result)
+		}
+	}
+}
+
+// Ningún programa puede terminar el servidor ni dejar a su pedido
+// esperando más que el timeout.
+func TestServerSurvivesHostileScripts(t *testing.T) {
+	server := httptest.NewServer(NewHandler(200 * time.Millisecond))
+	defer server.Close()
+	cancelled := "ERROR: evaluation cancelled: context deadline exceeded"
+	tests := []struct {
+		source   string
+		expected Result
+	}{
+		{"let t = spawn(fn() { 1 / 0 }); 5", Result{Value: "5"}},
+		{"recv(spawn(fn() { 1 / 0 }))", Result{Error: "ERROR: division by zero"}},
+		{"1 / 0", Result{Error: "ERROR: division by zero"}},
+		{"puts(fn() {}())", Result{Output: "null\n", Value: "null"}},
+		{`let f = fn(s, n) { if (n == 0) { len(s) } else { f(s + s, n - 1) } }; f("a", 34)`,
+			Result{Error: "ERROR: memory limit exceeded: more than 67108864 bytes"}},
+		{"sleep(100000)", Result{Error: cancelled}},
+		{"recv(channel())", Result{Error: cancelled}},
+		{"let m = mutex(); lock(m, fn() { lock(m, fn() { 1 }) })", Result{Error: cancelled}},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(Request{Source: tt.source})
+		start := time.Now()
+		resp, err := http.Post(server.URL+"/run", "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.source, err)
+		}
+		var result Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Errorf("%s: invalid response: %s", tt.source, err)
+		}
+		resp.Body.Close()
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %s with a 200ms timeout", tt.source, elapsed)
+		}
+		// Los errores de ejecución terminan con su posición.
+		if i := strings.Index(result.Error, " (line"); i >= 0 {
+			result.Error = result.Error[:i]
+		}
+		if result != tt.expected {
+			t.Errorf("%s: wrong response.\nwant=%+v\ngot= %+v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	handler := NewHandler(time.Second)
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/", "", http.StatusOK},
+		{"GET", "/missing", "", http.StatusNotFound},
+		{"GET", "/run", "", http.StatusMethodNotAllowed},
+		{"POST", "/run", "{", http.StatusBadRequest},
+		{"POST", "/run", `{"source": "` + strings.Repeat("1", MaxSourceSize) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: wrong status. want=%d, got=%d", tt.method, tt.path, tt.status, rec.Code)
+		}
+	}
+}
+
+// Los pedidos que no consiguen lugar antes de cancelarse no se ejecutan.
+func TestServerConcurrentRuns(t *testing.T) {
+	handler := NewHandler(time.Second)
+	run := func(ctx context.Context) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/run", strings.NewReader(`{"source": "sleep(100000)"}`))
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		return rec.Code
+	}
+	statuses := make(chan int, MaxConcurrentRuns)
+	for i := 0; i < MaxConcurrentRuns; i++ {
+		go func() { statuses <- run(context.Background()) }()
+	}
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if status := run(ctx); status != http.StatusServiceUnavailable {
+		t.Errorf("wrong status with every slot taken. want=%d, got=%d", http.StatusServiceUnavailable, status)
+	}
+	for i := 0; i < MaxConcurrentRuns; i++ {
+		if status := <-statuses; status != http.StatusOK {
+			t.Errorf("wrong status. want=%d, got=%d", http.StatusOK, status)
+		}
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	w := &limitedWriter{max: 5}
+	for _, s := range []string{"abc", "def", "ghi"} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Errorf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if got := w.buf.String(); got != "abcde" {
+		t.Errorf("wrong output. want=%q, got=%q", "abcde", got)
+	}
+}
@@ -0,0 +1,114 @@
+package monkey
+
+import "sync"
+
+// Pool ejecuta programas en paralelo, cada uno en un Interpreter nuevo,
+// de modo que no comparten variables globales. Sirve, por ejemplo, para
+// un servidor que ejecuta un script por pedido:
+//
+//	pool := monkey.NewPool(runtime.NumCPU(), func() *monkey.Interpreter {
+//		return monkey.New(monkey.WithOptions(monkey.Options{Timeout: time.Second}))
+//	})
+//	defer pool.Shutdown()
+//	// en cada pedido:
+//	result, err := pool.Run(program)
+//
+// Sus métodos se pueden llamar desde varias goroutines a la vez.
+type Pool struct {
+	// newInterp crea el Interpreter de cada ejecución.
+	newInterp func() *Interpreter
+	// slots limita cuántos programas se ejecutan a la vez.
+	slots chan struct{}
+	// done se cierra con Shutdown, para que los Run que esperan un lugar
+	// dejen de esperar.
+	done chan struct{}
+
+	mu sync.Mutex
+	// running son los Interpreter que están ejecutando un programa.
+	running map[*Interpreter]bool
+	closed  bool
+}
+
+// NewPool crea un Pool que ejecuta hasta size programas a la vez (uno si
+// size es menor que 1). newInterp crea el Interpreter de cada ejecución,
+// con las opciones y las funciones que necesite el programa; si es nil se
+// usa New().
+func NewPool(size int, newInterp func() *Interpreter) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if newInterp == nil {
+		newInterp = func() *Interpreter { return New() }
+	}
+	return &Pool{
+		newInterp: newInterp,
+		slots:     make(chan struct{}, size),
+		done:      make(chan struct{}),
+		running:   make(map[*Interpreter]bool),
+	}
+}
+
+// Run ejecuta program en un Interpreter nuevo, igual que RunProgram. Si
+// ya hay size programas ejecutándose, espera a que termine alguno.
+func (p *Pool) Run(program *Program) (Result, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return Result{}, ErrShutdown
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.done:
+		return Result{}, ErrShutdown
+	}
+	defer func() { <-p.slots }()
+
+	interp := p.newInterp()
+	defer interp.Shutdown()
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return Result{}, ErrShutdown
+	}
+	p.running[interp] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, interp)
+		p.mu.Unlock()
+	}()
+	return interp.RunProgram(program)
+}
+
+// RunAll ejecuta todos los programas de programs con Run y retorna los
+// resultados y los errores en el mismo orden.
+func (p *Pool) RunAll(programs []*Program) ([]Result, []error) {
+	results := make([]Result, len(programs))
+	errs := make([]error, len(programs))
+	var wg sync.WaitGroup
+	for n, program := range programs {
+		wg.Add(1)
+		go func(n int, program *Program) {
+			defer wg.Done()
+			results[n], errs[n] = p.Run(program)
+		}(n, program)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// Shutdown detiene los programas que se están ejecutando, como
+// Interpreter.Shutdown, y hace que los Run siguientes retornen
+// ErrShutdown.
+func (p *Pool) Shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		close(p.done)
+	}
+	p.closed = true
+	for interp := range p.running {
+		interp.Shutdown()
+	}
+}
@@ -0,0 +1,124 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	program, err := Compile(`
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let results = channel(4);
+let worker = fn(n) { puts(n); send(results, fib(n)) };
+spawn(worker, 10); spawn(worker, 11);
+recv(results) + recv(results) + fib(n)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, engine := range engines {
+		var mu sync.Mutex
+		var outputs []*strings.Builder
+		created := 0
+		pool := NewPool(4, func() *Interpreter {
+			mu.Lock()
+			defer mu.Unlock()
+			out := new(strings.Builder)
+			outputs = append(outputs, out)
+			interp := New(WithEngine(engine), WithOptions(Options{Stdout: out}))
+			// Cada Interpreter recibe un n distinto; si compartieran las
+			// variables globales los resultados se mezclarían.
+			if _, err := interp.Run(fmt.Sprintf("let n = %d;", created%5*2)); err != nil {
+				t.Error(err)
+			}
+			created++
+			return interp
+		})
+		programs := make([]*Program, 20)
+		for n := range programs {
+			programs[n] = program
+		}
+		results, errs := pool.RunAll(programs)
+		sum := map[int64]int{}
+		for n, result := range results {
+			if errs[n] != nil {
+				t.Fatalf("%s: %s", name, errs[n])
+			}
+			v, err := result.Int64()
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			sum[v]++
+		}
+		for n := 0; n < 5; n++ {
+			if got := sum[int64(55+89+fib(n*2))]; got != 4 {
+				t.Errorf("%s: fib(%d): want 4 results, got=%d (%v)", name, n*2, got, sum)
+			}
+		}
+		for _, out := range outputs {
+			if got := out.String(); got != "10\n11\n" && got != "11\n10\n" {
+				t.Errorf("%s: wrong output. got=%q", name, got)
+			}
+		}
+	}
+}
+
+func TestPoolShutdown(t *testing.T) {
+	loop, err := Compile("let loop = fn(n) { loop(n + 1) }; loop(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewPool(2, nil)
+	done := make(chan error)
+	go func() {
+		_, err := pool.Run(loop)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	pool.Shutdown()
+	select {
+	case err := <-done:
+		if err == nil || !strings.HasPrefix(err.Error(), "evaluation cancelled") {
+			t.Errorf("expected a cancellation error. got=%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not stop the program")
+	}
+	if _, err := pool.Run(loop); err != ErrShutdown {
+		t.Errorf("expected ErrShutdown. got=%v", err)
+	}
+}
+
+func TestPoolShutdownWhileWaiting(t *testing.T) {
+	program, err := Compile("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewPool(1, nil)
+	// Un programa que no termina ocupa el único lugar.
+	pool.slots <- struct{}{}
+	run := func() <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			_, err := pool.Run(program)
+			done <- err
+		}()
+		return done
+	}
+	waiting := run()
+	time.Sleep(20 * time.Millisecond)
+	pool.Shutdown()
+	// Ni el Run que esperaba ni uno nuevo esperan el lugar.
+	for _, done := range []<-chan error{waiting, run()} {
+		select {
+		case err := <-done:
+			if err != ErrShutdown {
+				t.Errorf("expected ErrShutdown. got=%v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run waited for a slot after Shutdown")
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package printer convierte un AST de vuelta en código Monkey con un
+// formato canónico: sangría con tabuladores, un espacio alrededor de los
+// operadores binarios, solo los paréntesis necesarios y los comentarios
+// en su lugar. A diferencia de String(), el resultado es código que se
+// puede volver a leer y que conserva la estructura del original.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strconv"
+)
+
+// FormatSource reformatea el código src. Si src tiene errores de sintaxis
+// retorna un parser.ErrorList y no lo modifica.
+func FormatSource(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		return "", parser.ErrorList(errors)
+	}
+	var out bytes.Buffer
+	if err := Fprint(&out, program); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Fprint escribe node en w con el formato canónico. Un programa termina
+// con un salto de línea; una sentencia o expresión suelta, no.
+func Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{lineStart: true}
+	switch node := node.(type) {
+	case *ast.Program:
+		p.statements(node.Statements, node.EndComments)
+		if p.out.Len() > 0 {
+			p.newline()
+		}
+	case ast.Statement:
+		p.statement(node)
+	case ast.Expression:
+		p.expression(node)
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+	if p.err != nil {
+		return p.err
+	}
+	_, err := w.Write(p.out.Bytes())
+	return err
+}
+
+type printer struct {
+	out       bytes.Buffer
+	indent    int
+	lineStart bool // no se escribió nada en la línea actual
+	err       error
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart {
+		for i := 0; i < p.indent; i++ {
+			p.out.WriteByte('\t')
+		}
+		p.lineStart = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.lineStart = true
+}
+
+func (p *printer) fail(node ast.Node) {
+	if p.err == nil {
+		p.err = fmt.Errorf("printer: cannot format invalid syntax at %s", node.Pos())
+	}
+}
+
+// statements escribe una sentencia por línea seguida de los comentarios
+// finales. Se conserva una línea en blanco donde el original tenía una o
+// más. Los nodos creados en código, sin posiciones, van uno por línea.
+func (p *printer) statements(stmts []ast.Statement, endComments []*ast.Comment) {
+	first, lastLine := true, 0
+	separate := func(line int) {
+		if !first {
+			p.newline()
+			if lastLine > 0 && line > lastLine+1 {
+				p.newline()
+			}
+		}
+		first = false
+	}
+	for _, stmt := range stmts {
+		var comments *ast.Comments
+		if commented, ok := stmt.(ast.Commented); ok {
+			comments = commented.StatementComments()
+		}
+		if comments != nil {
+			for _, c := range comments.Leading {
+				separate(c.Pos().Line)
+				p.write(c.Text)
+				lastLine = c.Pos().Line
+			}
+		}
+		separate(stmt.Pos().Line)
+		p.statement(stmt)
+		lastLine = stmt.End().Line
+		if comments != nil && comments.Trailing != nil {
+			p.write(" " + comments.Trailing.Text)
+			lastLine = comments.Trailing.Pos().Line
+		}
+	}
+	for _, c := range endComments {
+		separate(c.Pos().Line)
+		p.write(c.Text)
+		lastLine = c.Pos().Line
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value + " = ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expression(stmt.ReturnValue)
+		}
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		// Un if termina en '}' y no lleva ';', igual que un bloque.
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	default:
+		p.fail(stmt)
+	}
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 && len(block.EndComments) == 0 {
+		p.write("{}")
+		return
+	}
+	p.write("{")
+	p.newline()
+	p.indent++
+	p.statements(block.Statements, block.EndComments)
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		p.write(strconv.FormatInt(exp.Value, 10))
+	case *ast.StringLiteral:
+		p.write(`"` + exp.Value + `"`)
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(exp.Value))
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		// Un prefijo dentro de otro lleva paréntesis: -(-a) y no --a, que
+		// se lee como un decremento.
+		if _, ok := exp.Right.(*ast.PrefixExpression); ok {
+			p.write("(")
+			p.expression(exp.Right)
+			p.write(")")
+			break
+		}
+		p.operand(exp.Right, parser.PrefixPrecedence(exp.Operator), true)
+	case *ast.InfixExpression:
+		op := token.LookupOperator(exp.Operator)
+		prec, rightAssoc := parser.Precedence(op), parser.AssociativityOf(op) == parser.RightAssoc
+		p.operand(exp.Left, prec, rightAssoc)
+		p.write(" " + exp.Operator + " ")
+		p.operand(exp.Right, prec, !rightAssoc)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(exp.Condition)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.write("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.write(param.Value)
+		}
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.operand(exp.Function, parser.CALL, false)
+		p.write("(")
+		p.list(exp.Arguments)
+		for i, arg := range exp.Keywords {
+			if i > 0 || len(exp.Arguments) > 0 {
+				p.write(", ")
+			}
+			p.write(arg.Name.Value + ": ")
+			p.expression(arg.Value)
+		}
+		p.write(")")
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.list(exp.Elements)
+		p.write("]")
+	case *ast.IndexExpression:
+		p.operand(exp.Left, parser.INDEX, false)
+		p.write("[")
+		p.expression(exp.Index)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.operand(exp.Object, parser.INDEX, false)
+		p.write("." + exp.Property.Value)
+	case *ast.HashLiteral:
+		p.write("{")
+		for i, key := range exp.Keys {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(key)
+			p.write(": ")
+			p.expression(exp.Pairs[key])
+		}
+		p.write("}")
+	default:
+		p.fail(exp)
+	}
+}
+
+func (p *printer) list(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(exp)
+	}
+}
+
+// operand escribe exp como operando de un operador con precedencia prec,
+// entre paréntesis si sin ellos se leería de otra forma. Con sameLevel
+// también hacen falta si exp tiene la misma precedencia: es el caso del
+// operando derecho de un operador asociativo por la izquierda, a - (b - c),
+// y del izquierdo de uno asociativo por la derecha, (a ** b) ** c.
+func (p *printer) operand(exp ast.Expression, prec int, sameLevel bool) {
+	var inner int
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		inner = parser.Precedence(token.LookupOperator(exp.Operator))
+	case *ast.PrefixExpression:
+		// -a a la izquierda de ** lleva paréntesis, (-a) ** b, porque
+		// -a ** b es -(a ** b).
+		inner = parser.PREFIX
+		if exp.Operator == "-" {
+			inner = parser.POWER
+		}
+	default:
+		p.expression(exp)
+		return
+	}
+	if inner < prec || (sameLevel && inner == prec) {
+		p.write("(")
+		p.expression(exp)
+		p.write(")")
+		return
+	}
+	p.expression(exp)
+}
@@ -0,0 +1,131 @@
+package printer
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/token"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"return  x", "return x;\n"},
+		{"1+2*3;(1+2)*3; a-(b-c); (a-b)-c", "1 + 2 * 3;\n(1 + 2) * 3;\na - (b - c);\na - b - c;\n"},
+		{"-(a+b); !-x; (-a)[0]; -a[0]; (a+b)(c)", "-(a + b);\n!(-x);\n(-a)[0];\n-a[0];\n(a + b)(c);\n"},
+		{"-(-a); --a; !!b; -(-(-1))", "-(-a);\n-(-a);\n!(!b);\n-(-(-1));\n"},
+		{`let h={"a":1,"b":[1,2,3]}; h [ "a" ]`, "let h = {\"a\": 1, \"b\": [1, 2, 3]};\nh[\"a\"];\n"},
+		{"if(x<y){x}else{y}", "if (x < y) {\n\tx;\n} else {\n\ty;\n}\n"},
+		{"let f=fn(a,b){if(a){return b;}}; f(1,fn(){})",
+			"let f = fn(a, b) {\n\tif (a) {\n\t\treturn b;\n\t}\n};\nf(1, fn() {});\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"2**3**2; (2**3)**2; -2**2", "2 ** 3 ** 2;\n(2 ** 3) ** 2;\n-2 ** 2;\n"},
+		{"(-2)**2; -(2*3); -(-a); !(-a); -a*b; 2 ** -3; (-a)(1); -(a**b)*c", "(-2) ** 2;\n-(2 * 3);\n-(-a);\n!(-a);\n-a * b;\n2 ** -3;\n(-a)(1);\n-a ** b * c;\n"},
+		{`draw(x:1,y: 2+3,); draw(0, color:"red")`, "draw(x: 1, y: 2 + 3);\ndraw(0, color: \"red\");\n"},
+		{"db . query(1) [0] .name; (-a).b; -a.b", "db.query(1)[0].name;\n(-a).b;\n-a.b;\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := FormatSource(tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong format.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+		// El resultado ya está en su forma canónica.
+		again, err := FormatSource(got)
+		if err != nil || again != got {
+			t.Errorf("%q: formatting is not idempotent. got=%q (%v)", tt.input, again, err)
+		}
+	}
+}
+
+func TestFormatSourceComments(t *testing.T) {
+	input := `// cabecera
+let add = fn(a, b) {   // suma
+  // cuerpo
+      a + b // resultado
+  // fin
+};
+
+add(1, 2) // llamada
+// final`
+	expected := `// cabecera
+let add = fn(a, b) {
+	// suma
+	// cuerpo
+	a + b; // resultado
+	// fin
+};
+
+add(1, 2); // llamada
+// final
+`
+	got, err := FormatSource(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("wrong format.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestFormatSourceErrors(t *testing.T) {
+	_, err := FormatSource("let = 1;")
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	expected := "expected next token to be IDENT, got = instead. (line 1, column 5) (and 1 more errors)"
+	if err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestFprintExpression(t *testing.T) {
+	exp := &ast.InfixExpression{
+		Operator: "*",
+		Left: &ast.InfixExpression{
+			Operator: "+",
+			Left:     &ast.Identifier{Value: "a"},
+			Right:    &ast.IntegerLiteral{Token: token.Token{Literal: "1"}, Value: 1},
+		},
+		Right: &ast.Identifier{Value: "b"},
+	}
+	var out bytes.Buffer
+	if err := Fprint(&out, exp); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "(a + 1) * b" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+	if err := Fprint(&out, &ast.BadExpression{}); err == nil {
+		t.Errorf("expected an error for BadExpression")
+	}
+}
+
+func TestFprintBuiltProgram(t *testing.T) {
+	let := func(name string, value ast.Expression) ast.Statement {
+		return &ast.LetStatement{Name: &ast.Identifier{Value: name}, Value: value}
+	}
+	program := &ast.Program{Statements: []ast.Statement{
+		let("a", &ast.IntegerLiteral{Value: -5}),
+		let("b", &ast.Boolean{Value: true}),
+		let("f", &ast.FunctionLiteral{Body: &ast.BlockStatement{Statements: []ast.Statement{
+			let("x", &ast.Identifier{Value: "a"}),
+			&ast.ExpressionStatement{Expression: &ast.Identifier{Value: "x"}},
+		}}}),
+	}}
+	var out bytes.Buffer
+	if err := Fprint(&out, program); err != nil {
+		t.Fatal(err)
+	}
+	expected := "let a = -5;\nlet b = true;\nlet f = fn() {\n\tlet x = a;\n\tx;\n};\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, out.String())
+	}
+}
@@ -0,0 +1,204 @@
+package monkey
+
+import (
+	"fmt"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/token"
+	"reflect"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterFunc define en el Interpreter la variable global name con un
+// builtin que llama a fn, que tiene que ser una función de Go. Los
+// argumentos se convierten a los tipos de los parámetros de fn: enteros,
+// floats, strings, bools, slices, maps, object.Object o interface{}. El
+// resultado se convierte a un valor de Monkey; si fn retorna un error
+// distinto de nil, el builtin retorna ese error. Un panic de fn también
+// se convierte en un error.
+//
+//	interp.RegisterFunc("upper", strings.ToUpper)
+//	interp.Run(`upper("hola")`) // HOLA
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	tok := lexer.New(name).NextToken()
+	if tok.Type != token.IDENT || tok.Literal != name {
+		return fmt.Errorf("monkey: invalid function name %q", name)
+	}
+	builtin, err := wrapFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.define(name, builtin)
+	return nil
+}
+
+// define asigna la variable global name.
+func (i *Interpreter) define(name string, val object.Object) {
+	if i.engine == VM {
+		symbol := i.symbolTable.Define(name)
+		i.globals[symbol.Index] = val
+		return
+	}
+	i.env.Set(name, val)
+}
+
+// wrapFunc retorna el builtin name que llama a fn (ver RegisterFunc).
+func wrapFunc(name string, fn interface{}) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("monkey: %s is %T, not a function", name, fn)
+	}
+	t := v.Type()
+	// Se admiten f(...), f(...) T, f(...) error y f(...) (T, error).
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("monkey: %s must return a value, an error or both, got %s", name, t)
+	}
+	return &object.Builtin{Fn: func(args ...object.Object) (result object.Object) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = &object.Error{Message: fmt.Sprintf("panic in `%s`: %v", name, r)}
+			}
+		}()
+		in, err := funcArgs(t, args)
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("%s in call to `%s`", err, name)}
+		}
+		out := v.Call(in)
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if err, _ := out[n-1].Interface().(error); err != nil {
+				return &object.Error{Message: err.Error()}
+			}
+			out = out[:n-1]
+		}
+		if len(out) == 0 {
+			return object.NULL
+		}
+		obj, err := object.FromGo(out[0].Interface())
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("result of `%s`: %s", name, err)}
+		}
+		return obj
+	}}, nil
+}
+
+// funcArgs convierte args en los argumentos de una función de tipo t.
+func funcArgs(t reflect.Type, args []object.Object) ([]reflect.Value, error) {
+	n := t.NumIn()
+	if t.IsVariadic() {
+		if len(args) < n-1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least %d", len(args), n-1)
+		}
+	} else if len(args) != n {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var pt reflect.Type
+		if t.IsVariadic() && i >= n-1 {
+			pt = t.In(n - 1).Elem()
+		} else {
+			pt = t.In(i)
+		}
+		v, err := toGo(arg, pt)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+// toGo convierte obj en un valor de Go de tipo t.
+func toGo(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := obj.(*object.Integer)
+		if !ok || v.OverflowInt(n.Value) {
+			return fail()
+		}
+		v.SetInt(n.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := obj.(*object.Integer)
+		if !ok || n.Value < 0 || v.OverflowUint(uint64(n.Value)) {
+			return fail()
+		}
+		v.SetUint(uint64(n.Value))
+	case reflect.Float32, reflect.Float64:
+		n, ok := obj.(*object.Integer)
+		if !ok {
+			return fail()
+		}
+		v.SetFloat(float64(n.Value))
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return fail()
+		}
+		v.SetString(s.Value)
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return fail()
+		}
+		v.SetBool(b.Value)
+	case reflect.Slice:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements)))
+		for i, el := range arr.Elements {
+			ev, err := toGo(el, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(hash.Keys)))
+		for _, pair := range hash.OrderedPairs() {
+			kv, err := toGo(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			vv, err := toGo(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.SetMapIndex(kv, vv)
+		}
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return fail()
+		}
+		native, err := object.ToGo(obj)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if native != nil {
+			v.Set(reflect.ValueOf(native))
+		}
+	default:
+		return fail()
+	}
+	return v, nil
+}
@@ -0,0 +1,93 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFunc(t *testing.T) {
+	funcs := map[string]interface{}{
+		"upper": strings.ToUpper,
+		"add":   func(a, b int) int { return a + b },
+		"sum": func(nums ...int64) int64 {
+			var total int64
+			for _, n := range nums {
+				total += n
+			}
+			return total
+		},
+		"half": func(n float64) float64 { return n / 2 },
+		"div": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"keys": func(m map[string]int) []string {
+			var keys []string
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return keys
+		},
+		"counts": func(words []string) map[string]int {
+			counts := map[string]int{}
+			for _, w := range words {
+				counts[w]++
+			}
+			return counts
+		},
+		"kind":  func(v interface{}) string { return fmt.Sprintf("%T", v) },
+		"same":  func(obj object.Object) object.Object { return obj },
+		"noop":  func() {},
+		"boom":  func() int { panic("kaboom") },
+		"small": func(n int8) int8 { return n },
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper("hola")`, "HOLA"},
+		{`add(2, 3)`, "5"},
+		{`sum()`, "0"},
+		{`sum(1, 2, 3)`, "6"},
+		{`half(10)`, "5"},
+		{`half(3)`, "ERROR: result of `half`: 1.5 is not an INTEGER"},
+		{`div(7, 2)`, "3"},
+		{`div(1, 0)`, "ERROR: division by zero"},
+		{`keys({"a": 1})`, "[a]"},
+		{`counts(["a", "b", "a"])`, "{a: 2, b: 1}"},
+		{`kind([1, "a"])`, "[]interface {}"},
+		{`kind({"a": true})`, "map[string]interface {}"},
+		{`same(fn(x) { x })(7)`, "7"},
+		{`noop()`, "null"},
+		{`boom()`, "ERROR: panic in `boom`: kaboom"},
+		{`add(1)`, "ERROR: wrong number of arguments. got=1, want=2 in call to `add`"},
+		{`add(1, "2")`, "ERROR: argument 2: cannot use STRING as int in call to `add`"},
+		{`small(300)`, "ERROR: argument 1: cannot use INTEGER as int8 in call to `small`"},
+		{`upper(1)`, "ERROR: argument 1: cannot use INTEGER as string in call to `upper`"},
+	}
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		for fname, fn := range funcs {
+			if err := interp.RegisterFunc(fname, fn); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+		for _, tt := range tests {
+			result, err := interp.Run(tt.input)
+			got := ""
+			if err != nil {
+				got = "ERROR: " + err.(*RuntimeError).Message
+			} else if result.Value != nil {
+				got = result.Value.Inspect()
+			}
+			if got != tt.expected {
+				t.Errorf("%s: %s: want=%q, got=%q", name, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
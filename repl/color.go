@@ -0,0 +1,88 @@
+package repl
+
+import (
+	"io"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/token"
+	"os"
+	"strings"
+)
+
+// Códigos ANSI de los colores que usa el REPL.
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+// useColor indica si el REPL que escribe en out usa colores: solo si out
+// es una terminal, cfg.NoColor es false y no está definida NO_COLOR (ver
+// https://no-color.org).
+func useColor(out io.Writer, cfg Config) bool {
+	if cfg.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// paint retorna s con el color code, o s tal cual si code es "".
+func paint(code, s string) string {
+	if code == "" {
+		return s
+	}
+	return code + s + colorReset
+}
+
+// objectColor es el color con que se muestra un valor: los errores en
+// rojo, los strings en verde, los números en cian y los booleanos y null
+// en amarillo.
+func objectColor(obj object.Object) string {
+	switch obj.(type) {
+	case *object.Error:
+		return colorRed
+	case *object.String:
+		return colorGreen
+	case *object.Integer:
+		return colorCyan
+	case *object.Boolean, *object.Null:
+		return colorYellow
+	}
+	return ""
+}
+
+// highlight retorna line con las palabras clave, los strings, los
+// números y los comentarios coloreados.
+func highlight(line string) string {
+	var out strings.Builder
+	keywords := token.NewKeywords()
+	l := lexer.New(line)
+	last := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		end := tok.End().Offset
+		if tok.Offset < last || end > len(line) {
+			break
+		}
+		color := ""
+		switch {
+		case tok.Type == token.STRING:
+			color = colorGreen
+		case tok.Type == token.INT:
+			color = colorCyan
+		case tok.Type == token.COMMENT:
+			color = colorGray
+		case tok.Type != token.IDENT && keywords.Lookup(tok.Literal) == tok.Type:
+			color = colorMagenta
+		}
+		out.WriteString(line[last:tok.Offset])
+		out.WriteString(paint(color, line[tok.Offset:end]))
+		last = end
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
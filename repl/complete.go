@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"monkey/evaluator"
+	"monkey/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commands son los comandos del REPL, que se completan al principio de la
+// línea.
+var commands = []string{":debug", ":engine", ":env", ":load-session", ":mem", ":save", ":strict", ":time"}
+
+// completer retorna la función que completa la palabra que está antes del
+// cursor con los identificadores de la sesión, los builtins y las
+// palabras clave. names retorna los identificadores de la sesión.
+func completer(names func() []string) func(word string) []string {
+	return func(word string) []string {
+		candidates := commands
+		if !strings.HasPrefix(word, ":") {
+			candidates = append(names(), evaluator.BuiltinNames()...)
+			candidates = append(candidates, token.NewKeywords().Words()...)
+		}
+		seen := map[string]bool{}
+		var matches []string
+		for _, name := range candidates {
+			if strings.HasPrefix(name, word) && !seen[name] {
+				seen[name] = true
+				matches = append(matches, name)
+			}
+		}
+		sort.Strings(matches)
+		return matches
+	}
+}
+
+// wordStart retorna dónde empieza la palabra que termina en la posición
+// pos de line: un identificador o, al principio de la línea, un comando.
+func wordStart(line []rune, pos int) int {
+	start := pos
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+	if start == 1 && line[0] == ':' {
+		start = 0
+	}
+	return start
+}
+
+// isIdentRune indica si r puede formar parte de un identificador; igual
+// que en el lexer, los identificadores pueden tener letras no ASCII.
+func isIdentRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' ||
+		'0' <= r && r <= '9' || r >= utf8.RuneSelf && unicode.IsLetter(r)
+}
+
+// commonPrefix retorna el prefijo más largo que comparten words.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
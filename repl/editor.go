@@ -0,0 +1,333 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// lineReader lee las líneas que escribe el usuario. ok es false al
+// terminarse la entrada.
+type lineReader interface {
+	readLine(prompt string) (line string, ok bool)
+}
+
+// scannerReader lee líneas sin editarlas, para cuando la entrada no es
+// una terminal.
+type scannerReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r scannerReader) readLine(prompt string) (string, bool) {
+	fmt.Print(prompt)
+	if !r.scanner.Scan() {
+		return "", false
+	}
+	return r.scanner.Text(), true
+}
+
+// Cantidad máxima de líneas que se guardan en el historial.
+const maxHistory = 1000
+
+// editor lee líneas de una terminal y permite editarlas como en una
+// shell: flechas, Inicio y Fin, Ctrl-A, Ctrl-E, Ctrl-K, Ctrl-U, Ctrl-W y
+// el historial con las flechas arriba y abajo. Tab completa la palabra
+// que está antes del cursor.
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// raw pone la terminal en modo crudo y retorna la función que la
+	// restaura.
+	raw     func() (func(), error)
+	history []string
+	// historyFile es el archivo donde se guarda el historial; "" para no
+	// guardarlo.
+	historyFile string
+	// complete retorna las palabras que empiezan con word; nil para no
+	// completar.
+	complete func(word string) []string
+	// highlight, si no es nil, colorea la línea al mostrarla.
+	highlight func(line string) string
+}
+
+// newEditor crea un editor para la terminal term que escribe en out. El
+// historial se carga de historyFile y se guarda en él.
+func newEditor(term *os.File, out io.Writer, historyFile string) *editor {
+	e := &editor{
+		in:          bufio.NewReader(term),
+		out:         out,
+		raw:         func() (func(), error) { return makeRaw(term.Fd()) },
+		historyFile: historyFile,
+	}
+	e.loadHistory()
+	return e
+}
+
+// loadHistory lee el historial guardado. Si el archivo tiene más de
+// maxHistory líneas, lo recorta.
+func (e *editor) loadHistory() {
+	if e.historyFile == "" {
+		return
+	}
+	data, err := os.ReadFile(e.historyFile)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+}
+
+// addHistory agrega line al historial, salvo que esté en blanco o sea
+// igual a la anterior.
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+}
+
+// lineState es la línea que se está editando.
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func (e *editor) refresh(s *lineState) {
+	line := string(s.buf)
+	if e.highlight != nil {
+		line = e.highlight(line)
+	}
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, line)
+	if back := len(s.buf) - s.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (s *lineState) set(line string) {
+	s.buf = []rune(line)
+	s.pos = len(s.buf)
+}
+
+func (e *editor) readLine(prompt string) (string, bool) {
+	restore, err := e.raw()
+	if err != nil {
+		// Sin modo crudo se lee la línea tal cual.
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimRight(line, "\r\n"), true
+	}
+	defer restore()
+
+	s := &lineState{prompt: prompt}
+	// index es la posición en el historial; len(history) es la línea
+	// nueva, que se guarda en current mientras se recorre el historial.
+	index := len(e.history)
+	current := ""
+	showHistory := func(i int) {
+		if i < 0 || i > len(e.history) {
+			return
+		}
+		if index == len(e.history) {
+			current = string(s.buf)
+		}
+		index = i
+		if i == len(e.history) {
+			s.set(current)
+		} else {
+			s.set(e.history[i])
+		}
+	}
+
+	e.refresh(s)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			if len(s.buf) > 0 {
+				return string(s.buf), true
+			}
+			return "", false
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			line := string(s.buf)
+			e.addHistory(line)
+			return line, true
+		case ctrl('C'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", true
+		case ctrl('D'):
+			if len(s.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", false
+			}
+			s.deleteAt(s.pos)
+		case ctrl('A'):
+			s.pos = 0
+		case ctrl('E'):
+			s.pos = len(s.buf)
+		case ctrl('B'):
+			s.left()
+		case ctrl('F'):
+			s.right()
+		case ctrl('H'), 127:
+			if s.pos > 0 {
+				s.pos--
+				s.deleteAt(s.pos)
+			}
+		case ctrl('K'):
+			s.buf = s.buf[:s.pos]
+		case ctrl('U'):
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+		case ctrl('W'):
+			start := s.pos
+			for start > 0 && unicode.IsSpace(s.buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+				start--
+			}
+			s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+			s.pos = start
+		case '\t':
+			e.completeWord(s)
+		case ctrl('L'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case ctrl('P'):
+			showHistory(index - 1)
+		case ctrl('N'):
+			showHistory(index + 1)
+		case 27:
+			switch e.escape() {
+			case "A":
+				showHistory(index - 1)
+			case "B":
+				showHistory(index + 1)
+			case "C":
+				s.right()
+			case "D":
+				s.left()
+			case "H", "1~", "7~":
+				s.pos = 0
+			case "F", "4~", "8~":
+				s.pos = len(s.buf)
+			case "3~":
+				s.deleteAt(s.pos)
+			}
+		default:
+			if unicode.IsPrint(r) {
+				s.buf = append(s.buf[:s.pos], append([]rune{r}, s.buf[s.pos:]...)...)
+				s.pos++
+			}
+		}
+		e.refresh(s)
+	}
+}
+
+// completeWord completa la palabra que está antes del cursor. Si hay una
+// sola opción se escribe entera; si hay varias se escribe lo que tienen en
+// común y, si no tienen nada más en común, se muestran debajo de la línea.
+func (e *editor) completeWord(s *lineState) {
+	if e.complete == nil {
+		return
+	}
+	start := wordStart(s.buf, s.pos)
+	word := string(s.buf[start:s.pos])
+	var matches []string
+	if word != "" {
+		matches = e.complete(word)
+	}
+	if len(matches) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+	prefix := commonPrefix(matches)
+	if len(matches) == 1 {
+		prefix += " "
+	}
+	if prefix != word {
+		s.insert([]rune(prefix[len(word):]))
+		return
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(matches, "  "))
+}
+
+// escape lee el resto de una secuencia de escape, como "\x1b[A" (flecha
+// arriba) o "\x1b[3~" (Suprimir), y retorna lo que sigue a "\x1b[" u
+// "\x1bO".
+func (e *editor) escape() string {
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+	var seq []byte
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			return string(seq)
+		}
+	}
+}
+
+func (s *lineState) left() {
+	if s.pos > 0 {
+		s.pos--
+	}
+}
+
+func (s *lineState) right() {
+	if s.pos < len(s.buf) {
+		s.pos++
+	}
+}
+
+func (s *lineState) insert(text []rune) {
+	s.buf = append(s.buf[:s.pos], append(text, s.buf[s.pos:]...)...)
+	s.pos += len(text)
+}
+
+func (s *lineState) deleteAt(i int) {
+	if i < len(s.buf) {
+		s.buf = append(s.buf[:i], s.buf[i+1:]...)
+	}
+}
+
+// ctrl retorna el código de la tecla Ctrl-c.
+func ctrl(c rune) rune {
+	return c & 0x1f
+}
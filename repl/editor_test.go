@@ -0,0 +1,127 @@
+package repl
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testEditor(keys string, history ...string) *editor {
+	return &editor{
+		in:      bufio.NewReader(strings.NewReader(keys)),
+		out:     io.Discard,
+		raw:     func() (func(), error) { return func() {}, nil },
+		history: history,
+	}
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"let x = 1;\r", "let x = 1;"},
+		{"ab\x1b[Dc\r", "acb"},
+		{"abc\x01x\x05y\r", "xabcy"},
+		{"abc\x7f\x7f\r", "a"},
+		{"abc\x1b[D\x1b[D\x0b\r", "a"},
+		{"abc def\x17\r", "abc "},
+		{"abc\x1b[D\x15\r", "c"},
+		{"abc\x1b[H\x1b[3~\r", "bc"},
+		{"abc\x02\x02\x04\r", "ac"},
+		{"\x1b[Ax\r", "secondx"},
+		{"\x1b[A\x1b[A\r", "first"},
+		{"new\x1b[A\x1b[B\r", "new"},
+		{"añ\x1b[Do\r", "aoñ"},
+		{"abc\x03", ""},
+	}
+	for _, tt := range tests {
+		e := testEditor(tt.keys, "first", "second")
+		line, ok := e.readLine(PROMPT)
+		if !ok || line != tt.expected {
+			t.Errorf("keys %q: got %q (%t), want %q", tt.keys, line, ok, tt.expected)
+		}
+	}
+}
+
+func TestEditorEOF(t *testing.T) {
+	if _, ok := testEditor("\x04").readLine(PROMPT); ok {
+		t.Errorf("Ctrl-D on an empty line must end the input")
+	}
+	if line, ok := testEditor("abc").readLine(PROMPT); !ok || line != "abc" {
+		t.Errorf("wrong last line. got %q (%t)", line, ok)
+	}
+}
+
+func TestEditorHistoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(file, []byte("old\n"), 0o600)
+
+	e := testEditor("1 + 1\r1 + 1\r\r2 + 2\r")
+	e.historyFile = file
+	e.loadHistory()
+	for i := 0; i < 4; i++ {
+		e.readLine(PROMPT)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "old\n1 + 1\n2 + 2\n"; got != want {
+		t.Errorf("wrong history file. want=%q, got=%q", want, got)
+	}
+
+	e = testEditor("\x1b[A\x1b[A\x1b[A\r")
+	e.historyFile = file
+	e.loadHistory()
+	if line, _ := e.readLine(PROMPT); line != "old" {
+		t.Errorf("history not loaded. got %q", line)
+	}
+}
+
+func TestEditorComplete(t *testing.T) {
+	complete := completer(func() []string { return []string{"counter", "count", "añadir"} })
+	tests := []struct {
+		keys     string
+		expected string
+		bell     bool
+	}{
+		{"retu\t\r", "return ", false},
+		{"let x = coun\t\r", "let x = count", false},
+		{"let x = counte\t\r", "let x = counter ", false},
+		{"añ\t\r", "añadir ", false},
+		{"first(pus\t\r", "first(push ", false},
+		{"zz\t\r", "zz", true},
+		{"x\x01\t\r", "x", true},
+		{":st\t\r", ":strict ", false},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		e := testEditor(tt.keys)
+		e.out = &out
+		e.complete = complete
+		line, _ := e.readLine(PROMPT)
+		if line != tt.expected {
+			t.Errorf("keys %q: got %q, want %q", tt.keys, line, tt.expected)
+		}
+		if bell := strings.Contains(out.String(), "\a"); bell != tt.bell {
+			t.Errorf("keys %q: bell=%t, want %t", tt.keys, bell, tt.bell)
+		}
+	}
+}
+
+func TestEditorCompleteList(t *testing.T) {
+	var out strings.Builder
+	e := testEditor("count\t\r")
+	e.out = &out
+	e.complete = completer(func() []string { return []string{"counter", "count"} })
+	if line, _ := e.readLine(PROMPT); line != "count" {
+		t.Errorf("wrong line. got %q", line)
+	}
+	if !strings.Contains(out.String(), "\r\ncount  counter\r\n") {
+		t.Errorf("candidates not listed. got %q", out.String())
+	}
+}
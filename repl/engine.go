@@ -0,0 +1,193 @@
+package repl
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/vm"
+)
+
+// Engine es el motor que ejecuta el código ingresado en el REPL.
+type Engine string
+
+const (
+	EngineEval Engine = "eval" // evaluador que recorre el AST
+	EngineVM   Engine = "vm"   // compilador a bytecode y máquina virtual
+)
+
+// ParseEngine retorna el motor de nombre name ("eval" o "vm").
+func ParseEngine(name string) (Engine, error) {
+	switch engine := Engine(name); engine {
+	case EngineEval, EngineVM:
+		return engine, nil
+	}
+	return "", fmt.Errorf("unknown engine %q (want eval or vm)", name)
+}
+
+// vmSession guarda entre línea y línea lo que definió el código compilado:
+// los símbolos, las constantes y los valores de las variables globales.
+type vmSession struct {
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	// opts.Globals guarda los valores de las variables globales.
+	opts vm.Options
+	// steps es la cantidad de instrucciones de la última ejecución.
+	steps int64
+	// sources son los literales de las funciones definidas en el nivel
+	// superior, para volver a evaluarlas al pasar al evaluador (ver toEval).
+	sources map[*object.CompiledFunction]*ast.FunctionLiteral
+}
+
+// newVMSession crea una sesión que ejecuta el código con opts. Las
+// variables globales de opts se reemplazan por las de la sesión.
+func newVMSession(opts vm.Options) *vmSession {
+	symbolTable := compiler.NewSymbolTable()
+	compiler.DefineBuiltins(symbolTable)
+	opts.Globals = make([]object.Object, vm.GlobalsSize)
+	return &vmSession{
+		symbolTable: symbolTable,
+		constants:   []object.Object{},
+		opts:        opts,
+		sources:     map[*object.CompiledFunction]*ast.FunctionLiteral{},
+	}
+}
+
+// run compila y ejecuta program. Retorna el valor de la última expresión.
+func (s *vmSession) run(program *ast.Program) (object.Object, error) {
+	// Los símbolos de un programa que no compila no se conservan: sus
+	// variables globales nunca tendrían valor.
+	symbolTable := s.symbolTable.Copy()
+	comp := compiler.NewWithState(symbolTable, s.constants)
+	comp.SetFoldConstants(true)
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("Compilation failed:\n %s", err)
+	}
+	bytecode := comp.Bytecode().Optimize().Fuse()
+	s.symbolTable = symbolTable
+	s.constants = bytecode.Constants
+
+	machine := vm.NewWithOptions(bytecode, s.opts)
+	err := machine.Run()
+	s.steps = machine.Steps()
+	s.addSources(program)
+	if err != nil {
+		s.symbolTable.DeleteUnset(s.opts.Globals)
+		// Los errores de ejecución se muestran igual que los del evaluador,
+		// con la línea y la pila de llamadas.
+		if rt, ok := err.(*vm.RuntimeError); ok {
+			return rt.Object(), nil
+		}
+		return nil, fmt.Errorf("Executing bytecode failed:\n %s", err)
+	}
+	// Igual que con el evaluador, un let no muestra nada.
+	if n := len(program.Statements); n == 0 {
+		return nil, nil
+	} else if _, ok := program.Statements[n-1].(*ast.LetStatement); ok {
+		return nil, nil
+	}
+	return machine.LastPoppedStackElem(), nil
+}
+
+// addSources guarda el literal de cada función que program asignó a una
+// variable global. Las que usan variables de otra función no se guardan:
+// sus valores solo los tiene el closure.
+func (s *vmSession) addSources(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+		lit, ok := let.Value.(*ast.FunctionLiteral)
+		if !ok {
+			continue
+		}
+		val, _ := s.get(let.Name.Value)
+		if cl, ok := val.(*object.Closure); ok && len(cl.Free) == 0 {
+			s.sources[cl.Fn] = lit
+		}
+	}
+}
+
+// get retorna el valor de la variable global name, si ya tiene uno.
+func (s *vmSession) get(name string) (object.Object, bool) {
+	symbol, ok := s.symbolTable.Resolve(name)
+	if !ok || symbol.Scope != compiler.GlobalScope || s.opts.Globals[symbol.Index] == nil {
+		return nil, false
+	}
+	return s.opts.Globals[symbol.Index], true
+}
+
+// set asigna la variable global name, definiéndola si hace falta.
+func (s *vmSession) set(name string, val object.Object) {
+	symbol := s.symbolTable.Define(name)
+	s.opts.Globals[symbol.Index] = val
+}
+
+// toVM retorna una sesión que ejecuta el código con opts y empieza con
+// las variables de env. Las funciones definidas en el nivel superior se
+// vuelven a compilar; los closures, y las funciones que no compilan,
+// siguen ejecutándose en el evaluador.
+func toVM(env *object.Environment, opts vm.Options) *vmSession {
+	s := newVMSession(opts)
+	var functions []string
+	for _, name := range env.Names() {
+		val, _ := env.Get(name)
+		if fn, ok := val.(*object.Function); ok {
+			if fn.Env.Outer() == nil {
+				functions = append(functions, name)
+			}
+			val = evalFunction(fn)
+		}
+		s.set(name, val)
+	}
+	// Todas las variables ya están definidas, así que una función puede
+	// usar las que se definieron después de ella.
+	for _, name := range functions {
+		val, _ := env.Get(name)
+		fn := val.(*object.Function)
+		program := &ast.Program{Statements: []ast.Statement{&ast.LetStatement{
+			Name:  &ast.Identifier{Value: name},
+			Value: &ast.FunctionLiteral{Parameters: fn.Parameters, Body: fn.Body},
+		}}}
+		// Si no compila queda el builtin que la ejecuta en el evaluador.
+		s.run(program)
+	}
+	return s
+}
+
+// evalFunction retorna un builtin que ejecuta fn en el evaluador.
+func evalFunction(fn *object.Function) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return evaluator.Apply(fn, args...)
+	}}
+}
+
+// toEval retorna un entorno con las variables globales de s. Las
+// funciones definidas en el nivel superior se vuelven a evaluar desde su
+// literal, así se pueden guardar con :save; los demás closures se siguen
+// ejecutando en la VM.
+func (s *vmSession) toEval() *object.Environment {
+	env := object.NewEnvironment()
+	for _, name := range s.symbolTable.Names() {
+		val, ok := s.get(name)
+		if !ok {
+			continue
+		}
+		if cl, ok := val.(*object.Closure); ok {
+			if lit, ok := s.sources[cl.Fn]; ok {
+				// El evaluador busca las variables globales al llamar a la
+				// función, así que no importa si todavía no están en env.
+				evaluator.Eval(&ast.Program{Statements: []ast.Statement{&ast.LetStatement{
+					Name:  &ast.Identifier{Value: name},
+					Value: lit,
+				}}}, env)
+				continue
+			}
+			val = vm.Callable(cl, s.constants, s.opts)
+		}
+		env.Set(name, val)
+	}
+	return env
+}
@@ -0,0 +1,80 @@
+package repl
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// measureCommand reconoce ":time código" y ":mem código". Retorna el
+// comando y el código a ejecutar.
+func measureCommand(line string) (command, rest string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, command := range []string{":time", ":mem"} {
+		if rest, found := strings.CutPrefix(trimmed, command); found && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return command, strings.TrimSpace(rest), true
+		}
+	}
+	return "", line, false
+}
+
+// measurement mide una ejecución: cuánto tardó, cuántos pasos dio y
+// cuánta memoria reservó. Con el evaluador cada paso es un nodo del AST;
+// con la VM, una instrucción. Como Hooks, cuenta los pasos del evaluador.
+type measurement struct {
+	evaluator.NoopHooks
+	command string
+	start   time.Time
+	before  runtime.MemStats
+
+	steps   int64
+	elapsed time.Duration
+	bytes   uint64
+	allocs  uint64
+}
+
+func startMeasurement(command string) *measurement {
+	m := &measurement{command: command}
+	runtime.ReadMemStats(&m.before)
+	m.start = time.Now()
+	return m
+}
+
+func (m *measurement) OnEnterNode(ast.Node, *object.Environment) {
+	atomic.AddInt64(&m.steps, 1)
+}
+
+// stop termina la medición.
+func (m *measurement) stop() {
+	m.elapsed = time.Since(m.start)
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	m.bytes = after.TotalAlloc - m.before.TotalAlloc
+	m.allocs = after.Mallocs - m.before.Mallocs
+}
+
+func (m *measurement) String() string {
+	if m.command == ":mem" {
+		return fmt.Sprintf("allocated %s in %d allocations", formatBytes(m.bytes), m.allocs)
+	}
+	return fmt.Sprintf("time: %s, steps: %d", m.elapsed, m.steps)
+}
+
+// formatBytes muestra n con la unidad más adecuada (B, KiB, MiB o GiB).
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 2 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMG"[exp])
+}
@@ -0,0 +1,55 @@
+package repl
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+)
+
+// CONTINUATION_PROMPT se muestra mientras la entrada está incompleta.
+const CONTINUATION_PROMPT = "... "
+
+// readInput lee una entrada que empieza con line. Mientras queden
+// paréntesis, llaves o corchetes sin cerrar, o el parser llegue al final
+// del texto esperando algo más, sigue leyendo líneas. Una línea en blanco
+// o el fin de la entrada terminan la lectura aunque falte algo.
+func readInput(reader lineReader, line string) string {
+	input := line
+	for incomplete(input) {
+		line, ok := reader.readLine(CONTINUATION_PROMPT)
+		if !ok || strings.TrimSpace(line) == "" {
+			break
+		}
+		input += "\n" + line
+	}
+	return input
+}
+
+// incomplete indica si a input le falta texto para ser un programa.
+func incomplete(input string) bool {
+	if strings.TrimSpace(input) == "" {
+		return false
+	}
+	depth := 0
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		}
+	}
+	if depth != 0 {
+		return depth > 0
+	}
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+	for _, err := range p.ParseErrors() {
+		if err.Got.Type == token.EOF {
+			return true
+		}
+	}
+	return false
+}
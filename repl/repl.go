@@ -1,80 +1,342 @@
 package repl
 
 import (
-	"Monkey/evaluator"
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"monkey/ast"
+	"monkey/debugger"
+	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/vm"
+	"os"
+	"strings"
 )
 
 // PROMPT es una constante que imprime las comillas en la consola.
 const PROMPT = ">> "
 
-// Start inicio de la consola REPL
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// VERSION es la versión del lenguaje que muestra el banner.
+const VERSION = "0.1.0"
 
-	// constants := []object.Object{}
-	// globals := make([]object.Object, vm.GlobalsSize)
-	// symbolTable := compiler.NewSymbolTable()
+// Config configura una sesión del REPL. El valor cero usa el evaluador.
+type Config struct {
+	Engine Engine
+	// Trace, si no es nil, recibe la traza de cada instrucción que ejecuta
+	// la VM (ver vm.Options). El evaluador lo ignora.
+	Trace io.Writer
+	// HistoryFile es el archivo donde se guardan las líneas escritas
+	// cuando la entrada es una terminal. "" para no guardarlas.
+	HistoryFile string
+	// RCFile es un script que se ejecuta al empezar la sesión, por
+	// ejemplo para definir funciones auxiliares. Si define la variable
+	// PROMPT con un string, ese es el prompt. Si el archivo no existe se
+	// ignora.
+	RCFile string
+	// Prompt reemplaza a PROMPT si no es "". La variable PROMPT de la
+	// sesión tiene prioridad.
+	Prompt string
+	// Banner se muestra al empezar la sesión (ver DefaultBanner).
+	Banner string
+	// NoMonkeyFace omite la cara de mono que acompaña a los errores de
+	// sintaxis.
+	NoMonkeyFace bool
+	// NoColor desactiva los colores, que se usan cuando out es una
+	// terminal y no está definida la variable de entorno NO_COLOR.
+	NoColor bool
+}
+
+// DefaultBanner es el saludo que muestra el REPL de monkey al usuario
+// username, con la versión del lenguaje y el motor engine.
+func DefaultBanner(username string, engine Engine) string {
+	return fmt.Sprintf("Hello %s! This is the Monkey programming language %s (%s engine)!\nFeel free to type in commands\n",
+		username, VERSION, engine)
+}
+
+// Start inicio de la consola REPL. Retorna el código de salida pedido
+// con exit(code), o 0 cuando se termina la entrada.
+func Start(in io.Reader, out io.Writer) int {
+	return StartWithConfig(in, out, Config{})
+}
+
+// StartWithConfig es Start con la configuración cfg.
+func StartWithConfig(in io.Reader, out io.Writer, cfg Config) int {
+	var session *vmSession
+	// input() lee de stdin, la misma entrada que el REPL.
+	var stdin io.Reader
+	vmOptions := func() vm.Options {
+		return vm.Options{Trace: cfg.Trace, Stdin: stdin}
+	}
 
 	env := object.NewEnvironment()
+	color := useColor(out, cfg)
+	// run ejecuta un programa entero en la sesión, como un archivo.
+	run := func(program *ast.Program) (object.Object, error) {
+		if session != nil {
+			return session.run(program)
+		}
+		return evaluator.EvalWithOptions(program, env, evaluator.Options{Stdin: stdin}), nil
+	}
+	// prompt es el string de la variable PROMPT de la sesión, cfg.Prompt
+	// o PROMPT.
+	prompt := func() string {
+		var val object.Object
+		if session != nil {
+			val, _ = session.get("PROMPT")
+		} else {
+			val, _ = env.Get("PROMPT")
+		}
+		if str, ok := val.(*object.String); ok {
+			return str.Value
+		}
+		if cfg.Prompt != "" {
+			return cfg.Prompt
+		}
+		return PROMPT
+	}
 
-	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+	io.WriteString(out, cfg.Banner)
+
+	// Si la entrada es una terminal las líneas se pueden editar.
+	var reader lineReader
+	var scanner *bufio.Scanner
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		ed := newEditor(f, os.Stdout, cfg.HistoryFile)
+		ed.complete = completer(func() []string {
+			if session != nil {
+				return session.symbolTable.Names()
+			}
+			return env.Names()
+		})
+		if color {
+			ed.highlight = highlight
+		}
+		reader = ed
+		scanner = bufio.NewScanner(ed.in)
+	} else {
+		scanner = bufio.NewScanner(in)
+		reader = scannerReader{scanner}
+	}
+	stdin = evaluator.LineReader(scanner)
+	if cfg.Engine == EngineVM {
+		session = newVMSession(vmOptions())
+	}
+
+	if cfg.RCFile != "" {
+		if err := loadSession(cfg.RCFile, run); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			printError(out, err, color)
 		}
+	}
 
-		line := scanner.Text()
-		l := lexer.New(line)
+	// :strict activa el modo estricto del parser (ver parser.SetStrict).
+	strict := false
+
+	for {
+		line, ok := reader.readLine(prompt())
+		if !ok {
+			return 0
+		}
+		if strings.TrimSpace(line) == ":env" {
+			if session != nil {
+				printEnvironment(out, session.symbolTable.Names(), session.get, color)
+			} else {
+				printEnvironment(out, env.Names(), env.Get, color)
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == ":strict" {
+			strict = !strict
+			if strict {
+				io.WriteString(out, "strict mode on\n")
+			} else {
+				io.WriteString(out, "strict mode off\n")
+			}
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == ":engine" {
+			// :engine muestra el motor; :engine vm|eval lo cambia y pasa
+			// las variables globales al nuevo motor.
+			engine := EngineEval
+			if session != nil {
+				engine = EngineVM
+			}
+			if len(fields) == 1 {
+				fmt.Fprintln(out, engine)
+				continue
+			}
+			next, err := ParseEngine(fields[1])
+			if err != nil || len(fields) > 2 {
+				io.WriteString(out, "usage: :engine [eval|vm]\n")
+				continue
+			}
+			switch {
+			case next == engine:
+			case next == EngineVM:
+				session = toVM(env, vmOptions())
+			default:
+				env = session.toEval()
+				session = nil
+			}
+			continue
+		}
+		if command, path, ok := sessionCommand(line); ok {
+			runSessionCommand(out, command, path, env, session != nil, run, color)
+			continue
+		}
+		if path, ok := debugCommand(line); ok {
+			if code, exited := debugScript(scanner, out, path, color); exited {
+				return code
+			}
+			continue
+		}
+		// :time y :mem ejecutan el resto de la línea y muestran lo que
+		// midieron.
+		command, line, measure := measureCommand(line)
+		l := lexer.New(readInput(reader, line))
 		p := parser.New(l)
+		p.SetStrict(strict)
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+			printParseErrors(out, p.Errors(), !cfg.NoMonkeyFace, color)
 			continue
 		}
-		// inicio virtual machine
-		// comp := compiler.NewWithState(symbolTable, constants)
-		// err := comp.Compile(program)
-		// if err != nil {
-		// 	fmt.Fprintf(out, "Woops! Compilation failer:\n %s\n", err)
-		// 	continue
-		// }
-
-		// code := comp.Bytecode()
-		// constants = code.Constants
-
-		// machine := vm.NewWithGlobalsStore(code, globals)
-		// err = machine.Run()
-		// if err != nil {
-		// 	fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", err)
-		// 	continue
-		// }
-
-		// lastPopped := machine.LastPoppedStackElem()
-		// io.WriteString(out, lastPopped.Inspect())
-		// io.WriteString(out, "\n")
-		// fin virtual machine
-
-		evaluated := evaluator.Eval(program, env)
+		var m *measurement
+		if measure {
+			m = startMeasurement(command)
+		}
+		var evaluated object.Object
+		if session != nil {
+			var err error
+			evaluated, err = session.run(program)
+			if err != nil {
+				printError(out, err, color)
+				continue
+			}
+		} else if m != nil {
+			evaluated = evaluator.EvalWithOptions(program, env, evaluator.Options{Hooks: m, Stdin: stdin})
+		} else {
+			evaluated = evaluator.EvalWithOptions(program, env, evaluator.Options{Stdin: stdin})
+		}
+		if m != nil {
+			m.stop()
+			if session != nil {
+				m.steps = session.steps
+			}
+		}
+		if exit, ok := evaluated.(*object.Exit); ok {
+			return exit.Code
+		}
 		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+			printObject(out, evaluated, color)
+		}
+		if m != nil {
+			fmt.Fprintln(out, m)
+		}
+	}
+}
+
+// runSessionCommand ejecuta :save o :load-session con el archivo path.
+func runSessionCommand(out io.Writer, command, path string, env *object.Environment, vmEngine bool, run func(*ast.Program) (object.Object, error), color bool) {
+	if command == ":save" {
+		if vmEngine {
+			io.WriteString(out, ":save is not available with the vm engine\n")
+			return
+		}
+		skipped, err := saveSession(path, env)
+		if err != nil {
+			printError(out, err, color)
+			return
+		}
+		if len(skipped) > 0 {
+			fmt.Fprintf(out, "not saved: %s\n", strings.Join(skipped, ", "))
 		}
+		return
+	}
+	if err := loadSession(path, run); err != nil {
+		printError(out, err, color)
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	io.WriteString(out, MONKEY_FACE)
+// debugCommand reconoce ":debug script.mk" y retorna la ruta del script.
+func debugCommand(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != ":debug" {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// debugScript ejecuta el script en el debugger, leyendo los comandos de
+// la misma entrada que el REPL. Retorna true si el script llamó a exit.
+func debugScript(scanner *bufio.Scanner, out io.Writer, path string, color bool) (int, bool) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		printError(out, err, color)
+		return 0, false
+	}
+	io.WriteString(out, "debugging "+path+" (type help for commands)\n")
+	result, err := debugger.New(scanner, out).Run(string(source), object.NewEnvironment())
+	switch {
+	case err == debugger.ErrQuit:
+		return 0, false
+	case err != nil:
+		printError(out, err, color)
+		return 0, false
+	}
+	if exit, ok := result.(*object.Exit); ok {
+		return exit.Code, true
+	}
+	if result != nil {
+		printObject(out, result, color)
+	}
+	return 0, false
+}
+
+// Muestra los identificadores definidos en la sesión, names, con el valor
+// que les da get. Se omiten los que todavía no tienen valor.
+func printEnvironment(out io.Writer, names []string, get func(string) (object.Object, bool), color bool) {
+	for _, name := range names {
+		if val, ok := get(name); ok {
+			fmt.Fprintf(out, "%s = %s\n", name, inspect(val, color))
+		}
+	}
+}
+
+// printObject muestra el valor obj, con su color si color es true.
+func printObject(out io.Writer, obj object.Object, color bool) {
+	io.WriteString(out, inspect(obj, color)+"\n")
+}
+
+func inspect(obj object.Object, color bool) string {
+	if !color {
+		return obj.Inspect()
+	}
+	return paint(objectColor(obj), obj.Inspect())
+}
+
+func printError(out io.Writer, err error, color bool) {
+	msg := "Woops! " + err.Error()
+	if color {
+		msg = paint(colorRed, msg)
+	}
+	io.WriteString(out, msg+"\n")
+}
+
+func printParseErrors(out io.Writer, errors []string, face, color bool) {
+	if face {
+		io.WriteString(out, MONKEY_FACE)
+	}
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parse errors:\n")
 	for _, msg := range errors {
+		if color {
+			msg = paint(colorRed, msg)
+		}
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
@@ -0,0 +1,301 @@
+package repl
+
+import (
+	"bufio"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"1 + 2", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n x * 2\n}", false},
+		{"[1, 2,", true},
+		{`puts("{")`, false},
+		{"let f = fn(x)", true},
+		{"1 +", true},
+		{"let x = 1; }", false},
+		{"let = 1", false},
+	}
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) = %t, want %t", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		first    string
+		rest     string
+		expected string
+	}{
+		{"1 + 2", "3", "1 + 2"},
+		{"let f = fn(x) {", "x * 2\n}\n3", "let f = fn(x) {\nx * 2\n}"},
+		{"let f = fn(x) {", "x * 2\n\n3", "let f = fn(x) {\nx * 2"},
+		{"[1,", "2", "[1,\n2"},
+	}
+	for _, tt := range tests {
+		scanner := bufio.NewScanner(strings.NewReader(tt.rest))
+		if got := readInput(scannerReader{scanner}, tt.first); got != tt.expected {
+			t.Errorf("readInput(%q) = %q, want %q", tt.first, got, tt.expected)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x + y", "x + y"},
+		{`let s = "hi"; // saludo`,
+			colorMagenta + "let" + colorReset + ` s = ` + colorGreen + `"hi"` + colorReset + "; " + colorGray + "// saludo" + colorReset},
+		{"if (true) { 10 }",
+			colorMagenta + "if" + colorReset + " (" + colorMagenta + "true" + colorReset + ") { " + colorCyan + "10" + colorReset + " }"},
+		{`puts("abc`, `puts("abc`},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.input); got != tt.expected {
+			t.Errorf("highlight(%q) wrong. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	var out strings.Builder
+	if useColor(&out, Config{}) {
+		t.Errorf("colors must not be used when out is not a terminal")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor(os.Stdout, Config{}) {
+		t.Errorf("colors must not be used when NO_COLOR is set")
+	}
+}
+
+func TestMeasureCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		command string
+		rest    string
+		ok      bool
+	}{
+		{":time fib(20)", ":time", "fib(20)", true},
+		{"  :mem\tlet x = [1, 2]", ":mem", "let x = [1, 2]", true},
+		{":time", ":time", "", true},
+		{":timex", "", ":timex", false},
+		{"1 + 1", "", "1 + 1", false},
+	}
+	for _, tt := range tests {
+		command, rest, ok := measureCommand(tt.line)
+		if command != tt.command || rest != tt.rest || ok != tt.ok {
+			t.Errorf("measureCommand(%q) = %q, %q, %t; want %q, %q, %t",
+				tt.line, command, rest, ok, tt.command, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:       "0 B",
+		1023:    "1023 B",
+		1536:    "1.5 KiB",
+		3 << 20: "3.0 MiB",
+		5 << 40: "5120.0 GiB",
+	}
+	for n, expected := range tests {
+		if got := formatBytes(n); got != expected {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, expected)
+		}
+	}
+}
+
+func TestSaveSession(t *testing.T) {
+	env := object.NewEnvironment()
+	input := `
+let n = -3;
+let s = "hola";
+let data = {"a": [1, true], 2: "dos"};
+let add = fn(a, b) { a + b };
+let adder = fn(x) { fn(y) { x + y } };
+let add2 = adder(2);
+let p = puts;
+`
+	evaluator.Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+
+	path := filepath.Join(t.TempDir(), "session.mks")
+	skipped, err := saveSession(path, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(skipped, " "); got != "add2 p" {
+		t.Errorf("wrong skipped names. got=%q", got)
+	}
+	source, _ := os.ReadFile(path)
+	var lets []string
+	for _, line := range strings.Split(string(source), "\n") {
+		if strings.HasPrefix(line, "let ") {
+			lets = append(lets, strings.SplitN(line, " ", 3)[1])
+		}
+	}
+	if got := strings.Join(lets, " "); got != "add adder data n s" || strings.Count(string(source), "let ") != len(lets) {
+		t.Errorf("the saved file does not have one let per line. got=%q", source)
+	}
+
+	loaded := object.NewEnvironment()
+	err = loadSession(path, func(program *ast.Program) (object.Object, error) {
+		return evaluator.Eval(program, loaded), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"n", "s", "data", "add", "adder"} {
+		want, _ := env.Get(name)
+		got, ok := loaded.Get(name)
+		if !ok || got.Inspect() != want.Inspect() {
+			t.Errorf("wrong value for %s. want=%s, got=%v", name, want.Inspect(), got)
+		}
+	}
+	result := evaluator.Eval(parser.New(lexer.New("adder(1)(add(2, 3))")).ParseProgram(), loaded)
+	if result.Inspect() != "6" {
+		t.Errorf("loaded functions do not work. got=%s", result.Inspect())
+	}
+}
+
+func TestRCFile(t *testing.T) {
+	rc := filepath.Join(t.TempDir(), ".monkeyrc")
+	os.WriteFile(rc, []byte(`let twice = fn(f, x) { f(f(x)) }; let PROMPT = "λ> ";`), 0o644)
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		var out strings.Builder
+		StartWithConfig(strings.NewReader("twice(fn(x) { x * 3 }, 2)\nPROMPT\n"), &out, Config{Engine: engine, RCFile: rc})
+		if got, want := out.String(), "18\nλ> \n"; got != want {
+			t.Errorf("engine %s: wrong output. want=%q, got=%q", engine, want, got)
+		}
+	}
+
+	var out strings.Builder
+	StartWithConfig(strings.NewReader("1\n"), &out, Config{RCFile: rc + ".missing"})
+	if out.String() != "1\n" {
+		t.Errorf("a missing rc file must be ignored. got=%q", out.String())
+	}
+}
+
+func TestBannerAndMonkeyFace(t *testing.T) {
+	tests := []struct {
+		cfg      Config
+		expected string
+	}{
+		{Config{Banner: "Monkey " + VERSION + "\n"}, "Monkey " + VERSION + "\n" + MONKEY_FACE + "Woops!"},
+		{Config{NoMonkeyFace: true}, "Woops!"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		StartWithConfig(strings.NewReader("let = 1\n"), &out, tt.cfg)
+		if !strings.HasPrefix(out.String(), tt.expected) {
+			t.Errorf("wrong output for %+v. got=%q", tt.cfg, out.String())
+		}
+	}
+}
+
+func TestSwitchEngine(t *testing.T) {
+	input := `let base = 10
+let add = fn(x) { x + base }
+let mk = fn(n) { fn(x) { x * n } }
+let triple = mk(3)
+:engine vm
+:engine
+add(1)
+triple(2)
+let base = 20
+add(1)
+let sq = fn(x) { x * x }
+:engine eval
+:engine
+sq(add(1))
+triple(3)
+:engine lua
+`
+	var out strings.Builder
+	StartWithConfig(strings.NewReader(input), &out, Config{})
+	expected := "vm\n11\n6\n21\neval\n441\n9\nusage: :engine [eval|vm]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestVMSessionAfterErrors(t *testing.T) {
+	input := "let a = 1; let b = nope;\nlet c = 2; let d = 1 + true;\nd + 1\nb\nc\n"
+	var out strings.Builder
+	StartWithConfig(strings.NewReader(input), &out, Config{Engine: EngineVM, NoMonkeyFace: true})
+	for _, want := range []string{"identifier not found: nope", "identifier not found: d", "identifier not found: b", "\n2\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q. got=%q", want, out.String())
+		}
+	}
+}
+
+func TestSaveAfterSwitchingEngines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mks")
+	input := "let base = 10\nlet add = fn(x) { x + base }\n:engine vm\nlet sq = fn(x) { x * x }\nlet mk = fn(n) { fn(x) { x * n } }\nlet triple = mk(3)\n:engine eval\n:save " + path + "\nsq(add(1))\n"
+	var out strings.Builder
+	StartWithConfig(strings.NewReader(input), &out, Config{NoMonkeyFace: true})
+	expected := "not saved: triple\n121\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+	source, _ := os.ReadFile(path)
+	for _, name := range []string{"add", "sq", "mk"} {
+		if !strings.Contains(string(source), "let "+name+" = fn(") {
+			t.Errorf("function %s was not saved. got=%q", name, source)
+		}
+	}
+}
+
+// Las sesiones guardan las variables por orden alfabético, así que una
+// función puede quedar antes que las variables que usa.
+func TestLoadSessionInVM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.mks")
+	os.WriteFile(path, []byte("let add = fn(x) { x + base };\nlet base = 10;\n"), 0o644)
+	input := ":load-session " + path + "\nadd(1)\n"
+	var out strings.Builder
+	StartWithConfig(strings.NewReader(input), &out, Config{Engine: EngineVM, NoMonkeyFace: true})
+	if out.String() != "11\n" {
+		t.Errorf("wrong output. want=%q, got=%q", "11\n", out.String())
+	}
+}
+
+func TestInputReadsSessionLines(t *testing.T) {
+	input := "let a = input()\nhola\na\nlet b = input(); b\nchau\nlen(a + b)\n"
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		var out strings.Builder
+		StartWithConfig(strings.NewReader(input), &out, Config{Engine: engine})
+		expected := "hola\nchau\n8\n"
+		if out.String() != expected {
+			t.Errorf("wrong output with %s. want=%q, got=%q", engine, expected, out.String())
+		}
+	}
+}
+
+func TestPrintEnvironment(t *testing.T) {
+	input := "let b = [1, 2]\nlet a = fn(x) { x }\nlet c = 1 + true\n:env\n"
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		var out strings.Builder
+		StartWithConfig(strings.NewReader(input), &out, Config{Engine: engine, NoMonkeyFace: true})
+		if !strings.HasSuffix(out.String(), "\nb = [1, 2]\n") || !strings.Contains(out.String(), "\na = ") {
+			t.Errorf("wrong environment with %s. got=%q", engine, out.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/printer"
+	"os"
+	"strings"
+)
+
+// sessionCommand reconoce ":save archivo" y ":load-session archivo".
+func sessionCommand(line string) (command, path string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || (fields[0] != ":save" && fields[0] != ":load-session") {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// loadSession ejecuta en la sesión el programa guardado en path con run.
+func loadSession(path string, run func(*ast.Program) (object.Object, error)) error {
+	program, err := parser.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	result, err := run(program)
+	if err != nil {
+		return err
+	}
+	if err, ok := result.(*object.Error); ok {
+		return errors.New(err.Inspect())
+	}
+	return nil
+}
+
+// saveSession escribe en path las variables de env como un programa de
+// sentencias let que :load-session vuelve a ejecutar. Se guardan los
+// enteros, strings, booleanos, arrays y hashes, y las funciones definidas
+// en env con su código. Retorna los nombres de las variables que no se
+// pudieron guardar: builtins, null, closures que dependen de variables
+// de otra función, strings con comillas, etc.
+func saveSession(path string, env *object.Environment) (skipped []string, err error) {
+	program := &ast.Program{}
+	for _, name := range env.Names() {
+		val, _ := env.Get(name)
+		exp, ok := literal(val)
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		program.Statements = append(program.Statements, &ast.LetStatement{
+			Name:  &ast.Identifier{Value: name},
+			Value: exp,
+		})
+	}
+	var out bytes.Buffer
+	if err := printer.Fprint(&out, program); err != nil {
+		return nil, err
+	}
+	return skipped, os.WriteFile(path, out.Bytes(), 0o644)
+}
+
+// literal retorna una expresión que al evaluarse da un valor igual a obj.
+func literal(obj object.Object) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		// -9223372036854775808 no se puede escribir: el lexer lee primero
+		// el número sin el signo.
+		return &ast.IntegerLiteral{Value: obj.Value}, obj.Value != math.MinInt64
+	case *object.String:
+		// Los strings no tienen secuencias de escape.
+		return &ast.StringLiteral{Value: obj.Value}, !strings.Contains(obj.Value, `"`)
+	case *object.Boolean:
+		return &ast.Boolean{Value: obj.Value}, true
+	case *object.Array:
+		elements := make([]ast.Expression, len(obj.Elements))
+		for i, el := range obj.Elements {
+			exp, ok := literal(el)
+			if !ok {
+				return nil, false
+			}
+			elements[i] = exp
+		}
+		return &ast.ArrayLiteral{Elements: elements}, true
+	case *object.Hash:
+		hash := &ast.HashLiteral{Pairs: map[ast.Expression]ast.Expression{}}
+		for _, pair := range obj.OrderedPairs() {
+			key, ok := literal(pair.Key)
+			if !ok {
+				return nil, false
+			}
+			value, ok := literal(pair.Value)
+			if !ok {
+				return nil, false
+			}
+			hash.Keys = append(hash.Keys, key)
+			hash.Pairs[key] = value
+		}
+		return hash, true
+	case *object.Function:
+		// Una función definida dentro de otra puede usar variables que no
+		// están en la sesión.
+		if obj.Env.Outer() != nil {
+			return nil, false
+		}
+		return &ast.FunctionLiteral{Parameters: obj.Parameters, Body: obj.Body}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package repl
+
+import "errors"
+
+// En estos sistemas el REPL no edita las líneas: las lee tal cual.
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
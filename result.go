@@ -0,0 +1,139 @@
+package monkey
+
+import (
+	"fmt"
+	"monkey/object"
+)
+
+// Kind es la clase del valor de un Result.
+type Kind int
+
+const (
+	// KindNull es null, o la falta de valor de un programa que no termina
+	// en una expresión.
+	KindNull Kind = iota
+	KindInteger
+	KindString
+	KindBoolean
+	KindArray
+	KindHash
+	KindFunction
+	// KindOther son los demás valores, como los sets o los objetos del
+	// anfitrión (ver object.HostType).
+	KindOther
+)
+
+var kindNames = [...]string{
+	KindNull:     "null",
+	KindInteger:  "integer",
+	KindString:   "string",
+	KindBoolean:  "boolean",
+	KindArray:    "array",
+	KindHash:     "hash",
+	KindFunction: "function",
+	KindOther:    "other",
+}
+
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// KindError es el error de los accesores de Result cuando el valor no es
+// de la clase pedida.
+type KindError struct {
+	Got, Want Kind
+}
+
+func (e *KindError) Error() string {
+	return fmt.Sprintf("monkey: result is %s, not %s", e.Got, e.Want)
+}
+
+// Kind retorna la clase del valor.
+func (r Result) Kind() Kind {
+	switch r.Value.(type) {
+	case nil, *object.Null:
+		return KindNull
+	case *object.Integer:
+		return KindInteger
+	case *object.String:
+		return KindString
+	case *object.Boolean:
+		return KindBoolean
+	case *object.Array:
+		return KindArray
+	case *object.Hash:
+		return KindHash
+	case *object.Function, *object.Closure, *object.Builtin:
+		return KindFunction
+	}
+	return KindOther
+}
+
+// check retorna un *KindError si el valor no es de la clase want.
+func (r Result) check(want Kind) error {
+	if got := r.Kind(); got != want {
+		return &KindError{Got: got, Want: want}
+	}
+	return nil
+}
+
+// Int64 retorna el valor si es un entero.
+func (r Result) Int64() (int64, error) {
+	if err := r.check(KindInteger); err != nil {
+		return 0, err
+	}
+	return r.Value.(*object.Integer).Value, nil
+}
+
+// Float64 retorna el valor si es un entero, como float64: Monkey no tiene
+// números con decimales.
+func (r Result) Float64() (float64, error) {
+	n, err := r.Int64()
+	return float64(n), err
+}
+
+// String retorna el valor si es un string. Para mostrar cualquier valor
+// está Value.Inspect().
+func (r Result) String() (string, error) {
+	if err := r.check(KindString); err != nil {
+		return "", err
+	}
+	return r.Value.(*object.String).Value, nil
+}
+
+// Bool retorna el valor si es un booleano.
+func (r Result) Bool() (bool, error) {
+	if err := r.check(KindBoolean); err != nil {
+		return false, err
+	}
+	return r.Value.(*object.Boolean).Value, nil
+}
+
+// Slice retorna el valor si es un array, con sus elementos convertidos
+// por object.ToGo.
+func (r Result) Slice() ([]interface{}, error) {
+	if err := r.check(KindArray); err != nil {
+		return nil, err
+	}
+	value, err := object.ToGo(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]interface{}), nil
+}
+
+// Map retorna el valor si es un hash con llaves string, con sus valores
+// convertidos por object.ToGo.
+func (r Result) Map() (map[string]interface{}, error) {
+	if err := r.check(KindHash); err != nil {
+		return nil, err
+	}
+	value, err := object.ToGo(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]interface{}), nil
+}
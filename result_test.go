@@ -0,0 +1,76 @@
+package monkey
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResultAccessors(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  Kind
+		// get lee el resultado con el accesor de su clase.
+		get      func(Result) (interface{}, error)
+		expected interface{}
+	}{
+		{"40 + 2", KindInteger, func(r Result) (interface{}, error) { return r.Int64() }, int64(42)},
+		{"7", KindInteger, func(r Result) (interface{}, error) { return r.Float64() }, float64(7)},
+		{`"ho" + "la"`, KindString, func(r Result) (interface{}, error) { return r.String() }, "hola"},
+		{"1 < 2", KindBoolean, func(r Result) (interface{}, error) { return r.Bool() }, true},
+		{`[1, "a", [true]]`, KindArray, func(r Result) (interface{}, error) { return r.Slice() },
+			[]interface{}{int64(1), "a", []interface{}{true}}},
+		{`{"a": 1, "b": [2]}`, KindHash, func(r Result) (interface{}, error) { return r.Map() },
+			map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}}},
+		{"fn(x) { x }", KindFunction, nil, nil},
+		{"len", KindFunction, nil, nil},
+		{"let x = 1;", KindNull, nil, nil},
+		{`puts("")`, KindNull, nil, nil},
+	}
+	for name, engine := range engines {
+		interp := New(WithEngine(engine), WithOptions(Options{Stdout: new(strings.Builder)}))
+		for _, tt := range tests {
+			result, err := interp.Run(tt.input)
+			if err != nil {
+				t.Fatalf("%s: %q: %s", name, tt.input, err)
+			}
+			if result.Kind() != tt.kind {
+				t.Errorf("%s: %q: wrong kind. want=%s, got=%s", name, tt.input, tt.kind, result.Kind())
+			}
+			if tt.get == nil {
+				continue
+			}
+			got, err := tt.get(result)
+			if err != nil || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("%s: %q: want=%#v, got=%#v (%v)", name, tt.input, tt.expected, got, err)
+			}
+		}
+	}
+}
+
+func TestResultKindErrors(t *testing.T) {
+	result, err := New().Run(`"42"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = result.Int64()
+	var kindErr *KindError
+	if !errors.As(err, &kindErr) || kindErr.Got != KindString || kindErr.Want != KindInteger {
+		t.Fatalf("expected a KindError. got=%v", err)
+	}
+	if err.Error() != "monkey: result is string, not integer" {
+		t.Errorf("wrong message. got=%q", err.Error())
+	}
+	if _, err := result.Map(); err == nil {
+		t.Errorf("Map of a string should fail")
+	}
+
+	result, err = New().Run(`[1, fn() {}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.Slice(); err == nil || err.Error() != "cannot use FUNCTION as a Go value" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
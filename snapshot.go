@@ -0,0 +1,98 @@
+package monkey
+
+import (
+	"errors"
+	"monkey/compiler"
+	"monkey/object"
+)
+
+// Snapshot es el estado de un Interpreter en un momento dado: sus
+// variables globales y, con el motor VM, lo que ya compiló. Sirve para
+// guardar un punto al que volver con Restore, como al guardar una
+// partida:
+//
+//	checkpoint := interp.Snapshot()
+//	if _, err := interp.Run(src); err != nil {
+//		interp.Restore(checkpoint) // se descarta lo que hizo src
+//	}
+//
+// De los valores se copia su estado mutable, como el de los ATOMIC y los
+// FIBER que todavía no empezaron, así que al restaurar las variables
+// vuelven a tener los valores de ese momento. Los que no se pueden copiar
+// se comparten y pueden haber cambiado: los CHANNEL, MUTEX y FUTURE, los
+// FIBER ya empezados y los objetos del programa que embebe el intérprete
+// (ver object.CopyState).
+type Snapshot struct {
+	// interp es el Interpreter del que se tomó.
+	interp *Interpreter
+	// Variables globales del evaluador.
+	env *object.Snapshot
+	// Estado de la VM. Entre un Run y otro no hay llamadas en curso, así
+	// que basta con las variables globales (vm.VM.Snapshot guarda también
+	// las llamadas de una VM detenida).
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+}
+
+// ErrForeignSnapshot es el error de Restore con un Snapshot de otro
+// Interpreter.
+var ErrForeignSnapshot = errors.New("monkey: snapshot taken from another interpreter")
+
+// Snapshot captura el estado del Interpreter. Si se está ejecutando un
+// programa, espera a que termine.
+func (i *Interpreter) Snapshot() *Snapshot {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	s := &Snapshot{interp: i}
+	if i.engine != VM {
+		s.env = i.env.Snapshot()
+		return s
+	}
+	s.symbolTable = i.symbolTable.Copy()
+	// Con la capacidad limitada, compilar después agrega las constantes
+	// en otro arreglo en vez de pisar las del Snapshot.
+	s.constants = i.constants[:len(i.constants):len(i.constants)]
+	n := len(i.globals)
+	for n > 0 && i.globals[n-1] == nil {
+		n--
+	}
+	s.globals = copyGlobals(i.globals[:n])
+	return s
+}
+
+// Restore devuelve el Interpreter al estado capturado en s, que tiene
+// que venir de este Interpreter: las funciones del programa siguen
+// ligadas a sus variables globales. Las variables definidas después de
+// capturar s se descartan. Un mismo Snapshot se puede restaurar muchas
+// veces.
+func (i *Interpreter) Restore(s *Snapshot) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if s.interp != i {
+		return ErrForeignSnapshot
+	}
+	if i.engine != VM {
+		i.env.Restore(s.env)
+		return nil
+	}
+	i.symbolTable = s.symbolTable.Copy()
+	i.constants = s.constants
+	n := copy(i.globals, copyGlobals(s.globals))
+	for ; n < len(i.globals); n++ {
+		i.globals[n] = nil
+	}
+	return nil
+}
+
+// copyGlobals retorna una copia de globals y de su estado mutable.
+func copyGlobals(globals []object.Object) []object.Object {
+	c := make([]object.Object, len(globals))
+	copies := map[object.Object]object.Object{}
+	for i, val := range globals {
+		if val != nil {
+			c[i] = object.CopyState(val, copies)
+		}
+	}
+	return c
+}
@@ -0,0 +1,73 @@
+package monkey
+
+import "testing"
+
+func TestSnapshot(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		mustRun := func(src string) Result {
+			t.Helper()
+			result, err := interp.Run(src)
+			if err != nil {
+				t.Fatalf("%s: %q: %s", name, src, err)
+			}
+			return result
+		}
+		mustRun(`let a = 1; let f = fn(x) { x + a };`)
+		checkpoint := interp.Snapshot()
+
+		mustRun(`let a = 100; let b = [a];`)
+		if n, _ := mustRun("f(1)").Int64(); n != 101 {
+			t.Errorf("%s: f(1) before Restore: want=101, got=%d", name, n)
+		}
+		// El mismo Snapshot se puede restaurar varias veces.
+		for round := 0; round < 2; round++ {
+			if err := interp.Restore(checkpoint); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if n, _ := mustRun("f(1)").Int64(); n != 2 {
+				t.Errorf("%s: f(1) after Restore: want=2, got=%d", name, n)
+			}
+			if _, err := interp.Run("b"); err == nil {
+				t.Errorf("%s: b is still defined after Restore", name)
+			}
+			mustRun(`let c = "nueva"; let a = 5;`)
+		}
+
+		if err := New(WithEngine(engine)).Restore(checkpoint); err != ErrForeignSnapshot {
+			t.Errorf("%s: expected ErrForeignSnapshot. got=%v", name, err)
+		}
+	}
+}
+
+// Restaurar un Snapshot devuelve los ATOMIC y los FIBER sin empezar al
+// estado de ese momento, aunque el programa los haya cambiado después.
+func TestSnapshotCopiesMutableState(t *testing.T) {
+	for name, engine := range engines {
+		interp := New(WithEngine(engine))
+		if _, err := interp.Run(`let c = atomic(); let d = c; let f = fiber(fn(y) { y(1); y(2); 3 });`); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		checkpoint := interp.Snapshot()
+		for round := 0; round < 2; round++ {
+			result, err := interp.Run(`[atomic_load(c), resume(f), atomic_add(c, 1)]`)
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if got := result.Value.Inspect(); got != "[0, 1, 1]" {
+				t.Errorf("%s: round %d: want=[0, 1, 1], got=%s", name, round, got)
+			}
+			// d sigue siendo el mismo ATOMIC que c.
+			result, err = interp.Run(`atomic_load(d)`)
+			if n, _ := result.Int64(); err != nil || n != 1 {
+				t.Errorf("%s: round %d: d is not c after Restore: got=%d (%v)", name, round, n, err)
+			}
+			if _, err := interp.Run(`atomic_add(c, 5); resume(f)`); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if err := interp.Restore(checkpoint); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// Keywords es una tabla de palabras reservadas: asocia cada palabra con
+// el tipo de token que la representa. Permite armar dialectos del
+// lenguaje, por ejemplo con palabras en español:
+//
+//	k := token.NewKeywords()
+//	k.Register("si", token.IF)
+//	k.Register("sino", token.ELSE)
+//	k.Register("funcion", token.FUNCTION)
+//	l := lexer.New(src)
+//	l.SetKeywords(k)
+type Keywords struct {
+	words map[string]TokenType
+}
+
+// NewKeywords retorna una tabla con las palabras reservadas por omisión
+// (las de Monkey más las agregadas con RegisterKeyword).
+func NewKeywords() *Keywords {
+	return defaultKeywords.Copy()
+}
+
+// Copy retorna una tabla independiente con las mismas palabras.
+func (k *Keywords) Copy() *Keywords {
+	words := make(map[string]TokenType, len(k.words))
+	for word, t := range k.words {
+		words[word] = t
+	}
+	return &Keywords{words: words}
+}
+
+// Register agrega word como otra forma de escribir la palabra reservada
+// t. word tiene que poder ser un identificador y t tiene que ser el tipo
+// de una palabra reservada (FUNCTION, LET, IF, ...).
+func (k *Keywords) Register(word string, t TokenType) error {
+	if !isIdentifier(word) {
+		return fmt.Errorf("invalid keyword %q: not an identifier", word)
+	}
+	if t <= keywordsStart || t >= keywordsEnd {
+		return fmt.Errorf("cannot register %q as %s: not a keyword token type", word, t)
+	}
+	k.words[word] = t
+	return nil
+}
+
+// Remove hace que word vuelva a ser un identificador común, por ejemplo
+// para que un dialecto use "funcion" en lugar de "fn" y no ambas.
+func (k *Keywords) Remove(word string) {
+	delete(k.words, word)
+}
+
+// Lookup retorna el tipo de la palabra reservada ident, o IDENT si ident
+// no es una.
+func (k *Keywords) Lookup(ident string) TokenType {
+	if tok, ok := k.words[ident]; ok {
+		return tok
+	}
+	return IDENT
+}
+
+// Words retorna, ordenadas, las palabras reservadas de la tabla.
+func (k *Keywords) Words() []string {
+	words := make([]string, 0, len(k.words))
+	for word := range k.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+var defaultKeywords = &Keywords{words: map[string]TokenType{
+	"fn":     FUNCTION,
+	"let":    LET,
+	"true":   TRUE,
+	"false":  FALSE,
+	"if":     IF,
+	"else":   ELSE,
+	"return": RETURN,
+}}
+
+// RegisterKeyword agrega word a las palabras reservadas por omisión, las
+// que usan todos los Lexer sin una tabla propia. No es seguro llamarla
+// mientras se lee código; lo normal es hacerlo en un init.
+func RegisterKeyword(word string, t TokenType) error {
+	return defaultKeywords.Register(word, t)
+}
+
+// LookupIdent verifica si el contenido del token
+// es un identificador o una palabra reservada.
+func LookupIdent(ident string) TokenType {
+	return defaultKeywords.Lookup(ident)
+}
+
+// isIdentifier indica si s se leería como un identificador: una letra o
+// '_' seguida de letras, dígitos o '_'.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
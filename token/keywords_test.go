@@ -0,0 +1,62 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	k := NewKeywords()
+	registrations := []struct {
+		word     string
+		typ      TokenType
+		expected string
+	}{
+		{"si", IF, ""},
+		{"sino", ELSE, ""},
+		{"función", FUNCTION, ""},
+		{"retornar2", RETURN, ""},
+		{"2x", IF, `invalid keyword "2x": not an identifier`},
+		{"", IF, `invalid keyword "": not an identifier`},
+		{"mas", PLUS, `cannot register "mas" as +: not a keyword token type`},
+		{"x", IDENT, `cannot register "x" as IDENT: not a keyword token type`},
+	}
+	for _, tt := range registrations {
+		errMsg := ""
+		if err := k.Register(tt.word, tt.typ); err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != tt.expected {
+			t.Errorf("Register(%q) wrong error. expected=%q, got=%q", tt.word, tt.expected, errMsg)
+		}
+	}
+	k.Remove("fn")
+
+	lookups := []struct {
+		ident    string
+		expected TokenType
+	}{
+		{"si", IF},
+		{"función", FUNCTION},
+		{"let", LET},
+		{"fn", IDENT},
+		{"mas", IDENT},
+	}
+	for _, tt := range lookups {
+		if got := k.Lookup(tt.ident); got != tt.expected {
+			t.Errorf("Lookup(%q) wrong. expected=%s, got=%s", tt.ident, tt.expected, got)
+		}
+	}
+
+	if got, want := strings.Join(k.Words(), " "), "else false función if let retornar2 return si sino true"; got != want {
+		t.Errorf("wrong Words. expected=%q, got=%q", want, got)
+	}
+
+	// La tabla por omisión no cambia.
+	if LookupIdent("si") != IDENT || LookupIdent("fn") != FUNCTION {
+		t.Errorf("registrations leaked to the default table")
+	}
+	if k2 := NewKeywords(); k2.Lookup("si") != IDENT {
+		t.Errorf("registrations leaked to new tables")
+	}
+}
@@ -1,72 +1,193 @@
 package token
 
-// TokenType es un tipo de dato.
-type TokenType string
+import (
+	"fmt"
+	"strconv"
+)
+
+// TokenType es el tipo de un token. El valor cero es ILLEGAL.
+type TokenType int
 
 // Token es un token.
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Línea y columna (ambas desde 1) donde empieza el token.
+	Line   int
+	Column int
+	// Offset es la posición en bytes (desde 0) donde empieza el token.
+	Offset int
+}
+
+// Position es una ubicación en el código fuente. El valor cero no es
+// una posición válida: lo tienen los nodos creados fuera del parser.
+type Position struct {
+	Offset int // en bytes, desde 0
+	Line   int // desde 1
+	Column int // en bytes, desde 1
+}
+
+// IsValid indica si la posición apunta a un lugar del código fuente.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
-var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+// Pos retorna la posición del primer carácter del token.
+func (t Token) Pos() Position {
+	return Position{Offset: t.Offset, Line: t.Line, Column: t.Column}
 }
 
-// LookupIdent verifica si el contenido del token
-// es un identificador o una palabra reservada.
-func LookupIdent(ident string) TokenType {
-	if tok, ok := keywords[ident]; ok {
-		return tok
+// End retorna la posición inmediatamente después del último carácter
+// del token. Los STRING incluyen sus comillas, que no están en Literal.
+func (t Token) End() Position {
+	pos := t.Pos()
+	if !pos.IsValid() {
+		return pos
+	}
+	text := t.Literal
+	if t.Type == STRING {
+		text = `"` + text + `"`
 	}
-	return IDENT
+	for i := 0; i < len(text); i++ {
+		pos.Offset++
+		pos.Column++
+		if text[i] == '\n' {
+			pos.Line++
+			pos.Column = 1
+		}
+	}
+	return pos
 }
 
-// constants
 const (
-	ILLEGAL = "ILLEGAL"
-	EOF     = ""
+	ILLEGAL TokenType = iota
+	EOF
+	COMMENT // desde // hasta el fin de la línea
+	// Espacios, tabuladores y saltos de línea seguidos. Solo los emite un
+	// Lexer con SetKeepWhitespace.
+	WHITESPACE
+
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 123456
+	IDENT  // add, foobar, x, y, ...
+	INT    // 123456
+	STRING // "foo bar"
+
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	BANG     = "!"
-	ASTERISK = "*"
-	SLASH    = "/"
-
-	LT     = "<"
-	GT     = ">"
-	EQ     = "=="
-	NOT_EQ = "!="
+	operatorsStart
+	ASSIGN   // =
+	PLUS     // +
+	MINUS    // -
+	BANG     // !
+	ASTERISK // *
+	POWER    // **
+	SLASH    // /
+
+	LT     // <
+	GT     // >
+	EQ     // ==
+	NOT_EQ // !=
 
 	// Delimitiers
-	COMMA     = ","
-	SEMICOLON = ";"
+	COMMA     // ,
+	SEMICOLON // ;
+	COLON     // :
+	DOT       // .
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   // (
+	RPAREN   // )
+	LBRACE   // {
+	RBRACE   // }
+	LBRACKET // [
+	RBRACKET // ]
+	operatorsEnd
 
 	// keywords
-	FUNCTION = "FUNCTION"
-	LET      = "LET"
-	TRUE     = "TRUE"
-	FALSE    = "FALSE"
-	IF       = "IF"
-	ELSE     = "ELSE"
-	RETURN   = "RETURN"
-	STRING   = "STRING"
-	LBRACKET = "["
-	RBRACKET = "]"
-	COLON    = ":"
+	keywordsStart
+	FUNCTION
+	LET
+	TRUE
+	FALSE
+	IF
+	ELSE
+	RETURN
+	keywordsEnd
 )
+
+// Nombre de cada tipo de token: el símbolo en los operadores y
+// delimitadores, el nombre en mayúsculas en los demás.
+var tokenNames = [...]string{
+	ILLEGAL:    "ILLEGAL",
+	EOF:        "EOF",
+	COMMENT:    "COMMENT",
+	WHITESPACE: "WHITESPACE",
+
+	IDENT:  "IDENT",
+	INT:    "INT",
+	STRING: "STRING",
+
+	ASSIGN:   "=",
+	PLUS:     "+",
+	MINUS:    "-",
+	BANG:     "!",
+	ASTERISK: "*",
+	POWER:    "**",
+	SLASH:    "/",
+
+	LT:     "<",
+	GT:     ">",
+	EQ:     "==",
+	NOT_EQ: "!=",
+
+	COMMA:     ",",
+	SEMICOLON: ";",
+	COLON:     ":",
+	DOT:       ".",
+
+	LPAREN:   "(",
+	RPAREN:   ")",
+	LBRACE:   "{",
+	RBRACE:   "}",
+	LBRACKET: "[",
+	RBRACKET: "]",
+
+	FUNCTION: "FUNCTION",
+	LET:      "LET",
+	TRUE:     "TRUE",
+	FALSE:    "FALSE",
+	IF:       "IF",
+	ELSE:     "ELSE",
+	RETURN:   "RETURN",
+}
+
+func (t TokenType) String() string {
+	if 0 <= t && int(t) < len(tokenNames) && tokenNames[t] != "" {
+		return tokenNames[t]
+	}
+	return "TokenType(" + strconv.Itoa(int(t)) + ")"
+}
+
+var operators map[string]TokenType
+
+func init() {
+	operators = make(map[string]TokenType, operatorsEnd-operatorsStart)
+	for t := operatorsStart + 1; t < operatorsEnd; t++ {
+		operators[tokenNames[t]] = t
+	}
+}
+
+// LookupOperator retorna el tipo del operador o delimitador op, por
+// ejemplo PLUS para "+", o ILLEGAL si op no es uno. Sirve para pasar de
+// ast.InfixExpression.Operator a un tipo de token.
+func LookupOperator(op string) TokenType {
+	if t, ok := operators[op]; ok {
+		return t
+	}
+	return ILLEGAL
+}
@@ -0,0 +1,56 @@
+package token
+
+import "testing"
+
+func TestTokenTypeString(t *testing.T) {
+	tests := []struct {
+		typ      TokenType
+		expected string
+	}{
+		{ILLEGAL, "ILLEGAL"},
+		{EOF, "EOF"},
+		{IDENT, "IDENT"},
+		{POWER, "**"},
+		{NOT_EQ, "!="},
+		{RBRACKET, "]"},
+		{FUNCTION, "FUNCTION"},
+		{RETURN, "RETURN"},
+		{TokenType(1000), "TokenType(1000)"},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.expected {
+			t.Errorf("wrong String(). expected=%q, got=%q", tt.expected, got)
+		}
+	}
+	// Todos los tipos tienen nombre.
+	for typ := ILLEGAL; typ <= RETURN; typ++ {
+		switch typ {
+		case operatorsStart, operatorsEnd, keywordsStart:
+			continue
+		}
+		if tokenNames[typ] == "" {
+			t.Errorf("token type %d has no name", int(typ))
+		}
+	}
+}
+
+func TestLookupOperator(t *testing.T) {
+	tests := []struct {
+		op       string
+		expected TokenType
+	}{
+		{"+", PLUS},
+		{"**", POWER},
+		{"==", EQ},
+		{"(", LPAREN},
+		{":", COLON},
+		{"%", ILLEGAL},
+		{"LET", ILLEGAL},
+		{"", ILLEGAL},
+	}
+	for _, tt := range tests {
+		if got := LookupOperator(tt.op); got != tt.expected {
+			t.Errorf("LookupOperator(%q) wrong. expected=%s, got=%s", tt.op, tt.expected, got)
+		}
+	}
+}
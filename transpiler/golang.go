@@ -0,0 +1,354 @@
+// Package transpiler traduce un programa Monkey a código fuente de otro
+// lenguaje. El resultado es un programa independiente que se comporta
+// igual que el evaluador: mismos valores, mismos errores y mismos
+// builtins.
+package transpiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"monkey/ast"
+	"sort"
+)
+
+// ToGo traduce program a un programa Go (package main). Las operaciones
+// del lenguaje y los builtins se resuelven con llamadas a los paquetes
+// object y evaluator, así que el programa se compila junto con ellos:
+//
+//	monkey transpile -o prog/main.go script.mk
+//	go build ./prog
+//
+// Al terminar, el programa sale con el código de exit(), o muestra el
+// error en la salida de errores y sale con 1.
+func ToGo(program *ast.Program) (string, error) {
+	g := &goGen{out: &bytes.Buffer{}}
+	g.out.WriteString(goHeader)
+	g.out.WriteString("func program() object.Object {\n")
+	g.function(nil, program.Statements)
+	g.out.WriteString("}\n")
+	if g.err != nil {
+		return "", g.err
+	}
+	src, err := format.Source(g.out.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generated invalid Go code: %s", err)
+	}
+	return string(src), nil
+}
+
+const goHeader = `// Code generated by monkey transpile. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"monkey/evaluator"
+	"monkey/object"
+)
+
+func main() {
+	switch result := program().(type) {
+	case *object.Exit:
+		os.Exit(result.Code)
+	case *object.Error:
+		fmt.Fprintln(os.Stderr, result.Inspect())
+		os.Exit(1)
+	}
+}
+
+`
+
+// goScope son las variables de una función: sus parámetros y los let de
+// su cuerpo, incluidos los que están dentro de un if.
+type goScope struct {
+	outer  *goScope
+	params map[string]bool
+	lets   map[string]bool
+	read   map[string]bool
+}
+
+// resolve retorna el ámbito donde está definida name, o nil si es global
+// en el sentido de los builtins y módulos.
+func (s *goScope) resolve(name string) *goScope {
+	for ; s != nil; s = s.outer {
+		if s.params[name] || s.lets[name] {
+			return s
+		}
+	}
+	return nil
+}
+
+type goGen struct {
+	out   *bytes.Buffer
+	scope *goScope
+	temps int
+	err   error
+}
+
+// goName es el nombre de la variable Go que guarda la variable name.
+func goName(name string) string {
+	return "m_" + name
+}
+
+func (g *goGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+// temp retorna el nombre de una variable temporal nueva.
+func (g *goGen) temp() string {
+	g.temps++
+	return fmt.Sprintf("t%d", g.temps)
+}
+
+// function escribe el cuerpo de una función con los parámetros params:
+// las declaraciones de sus variables y las sentencias de body. El valor
+// de la función es el de la última sentencia.
+func (g *goGen) function(params []*ast.Identifier, body []ast.Statement) {
+	scope := &goScope{outer: g.scope, params: map[string]bool{}, lets: map[string]bool{}, read: map[string]bool{}}
+	for _, param := range params {
+		scope.params[param.Value] = true
+	}
+	collectLets(body, scope.lets)
+	g.scope = scope
+
+	// El cuerpo se genera aparte para saber qué variables se leen.
+	outer := g.out
+	g.out = &bytes.Buffer{}
+	g.statements(body, "return ")
+	if !endsWithValue(body) {
+		g.out.WriteString("return evaluator.NULL\n")
+	}
+	code := g.out
+	g.out = outer
+	g.scope = scope.outer
+
+	if len(params) > 0 {
+		g.printf("if len(args) < %d {\nreturn evaluator.WrongArguments(%d, len(args))\n}\n", len(params), len(params))
+		for i, param := range params {
+			g.printf("%s := args[%d]\n", goName(param.Value), i)
+		}
+	}
+	for _, name := range sortedNames(scope.lets) {
+		if !scope.params[name] {
+			g.printf("var %s object.Object\n", goName(name))
+		}
+	}
+	// Go no admite variables que no se leen.
+	for _, name := range sortedNames(scope.params, scope.lets) {
+		if !scope.read[name] {
+			g.printf("_ = %s\n", goName(name))
+		}
+	}
+	g.out.Write(code.Bytes())
+}
+
+// endsWithValue indica si la última sentencia de stmts da el valor de la
+// función, es decir, si no es un let.
+func endsWithValue(stmts []ast.Statement) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch stmts[len(stmts)-1].(type) {
+	case *ast.ReturnStatement, *ast.ExpressionStatement:
+		return true
+	}
+	return false
+}
+
+// collectLets agrega a names las variables que definen los let de stmts,
+// sin entrar en las funciones.
+func collectLets(stmts []ast.Statement, names map[string]bool) {
+	var expr func(ast.Expression)
+	expr = func(e ast.Expression) {
+		switch e := e.(type) {
+		case *ast.IfExpression:
+			expr(e.Condition)
+			collectLets(e.Consequence.Statements, names)
+			if e.Alternative != nil {
+				collectLets(e.Alternative.Statements, names)
+			}
+		case *ast.PrefixExpression:
+			expr(e.Right)
+		case *ast.InfixExpression:
+			expr(e.Left)
+			expr(e.Right)
+		case *ast.CallExpression:
+			expr(e.Function)
+			for _, arg := range e.Arguments {
+				expr(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, el := range e.Elements {
+				expr(el)
+			}
+		case *ast.IndexExpression:
+			expr(e.Left)
+			expr(e.Index)
+		case *ast.MemberExpression:
+			expr(e.Object)
+		case *ast.HashLiteral:
+			for _, key := range e.Keys {
+				expr(key)
+				expr(e.Pairs[key])
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			names[stmt.Name.Value] = true
+			expr(stmt.Value)
+		case *ast.ReturnStatement:
+			expr(stmt.ReturnValue)
+		case *ast.ExpressionStatement:
+			expr(stmt.Expression)
+		}
+	}
+}
+
+func sortedNames(sets ...map[string]bool) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, set := range sets {
+		for name := range set {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statements escribe stmts. El valor de la última sentencia se asigna
+// con last, que es "return " o "tN = ".
+func (g *goGen) statements(stmts []ast.Statement, last string) {
+	for i, stmt := range stmts {
+		assign := "_ = "
+		if i == len(stmts)-1 {
+			assign = last
+		}
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			g.printf("%s = %s\n", goName(stmt.Name.Value), g.expr(stmt.Value))
+		case *ast.ReturnStatement:
+			g.printf("return %s\n", g.expr(stmt.ReturnValue))
+		case *ast.ExpressionStatement:
+			g.printf("%s%s\n", assign, g.expr(stmt.Expression))
+		default:
+			g.fail(stmt, "invalid syntax: %s", stmt.TokenLiteral())
+		}
+	}
+}
+
+func (g *goGen) fail(node ast.Node, format string, args ...interface{}) {
+	if g.err == nil {
+		pos := node.Pos()
+		g.err = fmt.Errorf("%d:%d: %s", pos.Line, pos.Column, fmt.Sprintf(format, args...))
+	}
+}
+
+// check guarda en un temporal el valor de call, una operación que puede
+// fallar, y retorna si es un error. Retorna el temporal.
+func (g *goGen) check(node ast.Node, call string) string {
+	t := g.temp()
+	pos := node.Pos()
+	g.printf("%s := evaluator.At(%s, %d, %d)\n", t, call, pos.Line, pos.Column)
+	g.printf("if evaluator.IsError(%s) {\nreturn %s\n}\n", t, t)
+	return t
+}
+
+// expr escribe el código que calcula e y retorna una expresión Go, sin
+// efectos, con su valor.
+func (g *goGen) expr(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("object.NewInteger(%d)", e.Value)
+	case *ast.Boolean:
+		if e.Value {
+			return "evaluator.TRUE"
+		}
+		return "evaluator.FALSE"
+	case *ast.StringLiteral:
+		return fmt.Sprintf("&object.String{Value: %q}", e.Value)
+	case *ast.Identifier:
+		scope := g.scope.resolve(e.Value)
+		if scope == nil {
+			return g.check(e, fmt.Sprintf("evaluator.Lookup(nil, %q)", e.Value))
+		}
+		scope.read[e.Value] = true
+		if scope.params[e.Value] && !scope.lets[e.Value] {
+			return goName(e.Value)
+		}
+		// Un let puede no haberse ejecutado todavía.
+		return g.check(e, fmt.Sprintf("evaluator.Lookup(%s, %q)", goName(e.Value), e.Value))
+	case *ast.PrefixExpression:
+		right := g.expr(e.Right)
+		return g.check(e, fmt.Sprintf("evaluator.Prefix(%q, %s)", e.Operator, right))
+	case *ast.InfixExpression:
+		left := g.expr(e.Left)
+		right := g.expr(e.Right)
+		return g.check(e, fmt.Sprintf("evaluator.Infix(%q, %s, %s)", e.Operator, left, right))
+	case *ast.IndexExpression:
+		left := g.expr(e.Left)
+		index := g.expr(e.Index)
+		return g.check(e, fmt.Sprintf("evaluator.Index(%s, %s)", left, index))
+	case *ast.MemberExpression:
+		return g.check(e, fmt.Sprintf("evaluator.Member(%s, %q)", g.expr(e.Object), e.Property.Value))
+	case *ast.ArrayLiteral:
+		return fmt.Sprintf("&object.Array{Elements: []object.Object{%s}}", g.exprList(e.Elements))
+	case *ast.HashLiteral:
+		var kv []ast.Expression
+		for _, key := range e.Keys {
+			kv = append(kv, key, e.Pairs[key])
+		}
+		return g.check(e, fmt.Sprintf("evaluator.NewHash(%s)", g.exprList(kv)))
+	case *ast.CallExpression:
+		if len(e.Keywords) > 0 {
+			g.fail(e, "keyword arguments are not supported")
+		}
+		fn := g.expr(e.Function)
+		args := g.exprList(e.Arguments)
+		if args != "" {
+			args = ", " + args
+		}
+		return g.check(e, fmt.Sprintf("evaluator.Apply(%s%s)", fn, args))
+	case *ast.IfExpression:
+		cond := g.expr(e.Condition)
+		t := g.temp()
+		g.printf("var %s object.Object = evaluator.NULL\n", t)
+		g.printf("if evaluator.Truthy(%s) {\n", cond)
+		g.statements(e.Consequence.Statements, t+" = ")
+		if e.Alternative != nil {
+			g.out.WriteString("} else {\n")
+			g.statements(e.Alternative.Statements, t+" = ")
+		}
+		g.out.WriteString("}\n")
+		return t
+	case *ast.FunctionLiteral:
+		t := g.temp()
+		g.printf("%s := &object.Builtin{Fn: func(args ...object.Object) object.Object {\n", t)
+		g.function(e.Parameters, e.Body.Statements)
+		g.out.WriteString("}}\n")
+		return t
+	default:
+		g.fail(e, "invalid syntax: %s", e.TokenLiteral())
+		return "evaluator.NULL"
+	}
+}
+
+// exprList calcula es en orden y retorna sus valores separados por comas.
+func (g *goGen) exprList(es []ast.Expression) string {
+	var out bytes.Buffer
+	for i, e := range es {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(g.expr(e))
+	}
+	return out.String()
+}
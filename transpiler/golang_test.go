@@ -0,0 +1,158 @@
+package transpiler
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"monkey/ast"
+	"monkey/lexer"
+	mparser "monkey/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := mparser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestToGo(t *testing.T) {
+	tests := []string{
+		"",
+		"let x = 1;",
+		"1 + 2 * 3",
+		`let greet = fn(name) { "hola " + name }; puts(greet("mundo"))`,
+		"let f = fn(a, b) { a }; f(1)",
+		"let f = fn(x) { let y = x; if (y > 1) { let z = 2; return z; } else { 3 } }; f(2)",
+		"if (true) { let x = 1 }; x",
+		`let h = {"a": [1, 2], true: fn() { 1 }}; h["a"][0]`,
+		"let len = fn(x) { x }; len(1)",
+		"return 1; 2",
+		"exit(3)",
+	}
+	for _, input := range tests {
+		src, err := ToGo(parse(t, input))
+		if err != nil {
+			t.Errorf("%q: ToGo failed: %s", input, err)
+			continue
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0); err != nil {
+			t.Errorf("%q: generated code does not parse: %s\n%s", input, err, src)
+		}
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a) { a }; f(a: 1)", "1:22: keyword arguments are not supported"},
+	}
+	for _, tt := range tests {
+		_, err := ToGo(parse(t, tt.input))
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// TestToGoRun compila y ejecuta los programas generados. Necesita el
+// comando go y es lento, así que no se ejecuta con -short.
+func TestToGoRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go build in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string
+		code   int
+	}{
+		{
+			input: `let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+let adder = fn(a) { fn(b) { a + b } };
+let h = {"k": [1, 2, 3]};
+puts(fib(15), adder(2)(3), h["k"][1], memo(fn(x) { x * 10 })(2));
+if (true) { let leaked = "si" };
+puts(leaked, -h["k"][2], !true, "a" + "b", len(rest(h["k"])), h["z"]);`,
+			stdout: "610\n5\n2\n20\nsi\n-3\nfalse\nab\n2\nnull\n",
+		},
+		{
+			input:  `let h = {"a": {"b": fn(x) { x * 2 }}}; puts(h.a.b(21), h.z); 1.x`,
+			stdout: "42\nnull\n",
+			stderr: "ERROR: member access not supported: INTEGER (line 1, column 62)\n",
+			code:   1,
+		},
+		{
+			input:  "let f = fn() { g() };\nlet g = fn() { 7 };\nputs(f())",
+			stdout: "7\n",
+		},
+		{
+			input:  `puts("a"); exit(3); puts("b")`,
+			stdout: "a\n",
+			code:   3,
+		},
+		{
+			input:  "let f = fn(x) { x + true };\nf(1)",
+			stderr: "ERROR: type mismatch: INTEGER + BOOLEAN (line 1, column 17)\n",
+			code:   1,
+		},
+		{
+			input:  "let f = fn(a, b) { a };\nf(1)",
+			stderr: "ERROR: wrong number of arguments: want=2, got=1 (line 2, column 1)\n",
+			code:   1,
+		},
+	}
+	// El directorio tiene que estar dentro del código de monkey para que
+	// el programa pueda importar sus paquetes.
+	dir, err := os.MkdirTemp(".", "gorun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	main := filepath.Join(dir, "main.go")
+	bin := filepath.Join(dir, "prog")
+	for _, tt := range tests {
+		src, err := ToGo(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("%q: ToGo failed: %s", tt.input, err)
+		}
+		if err := os.WriteFile(main, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command(goTool, "build", "-o", bin, main).CombinedOutput(); err != nil {
+			t.Fatalf("%q: go build failed: %s\n%s", tt.input, err, out)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(bin)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		code := 0
+		if err := cmd.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("%q: %s", tt.input, err)
+			}
+			code = exitErr.ExitCode()
+		}
+		if stdout.String() != tt.stdout || stderr.String() != tt.stderr || code != tt.code {
+			t.Errorf("%q: wrong result.\nstdout=%q, want=%q\nstderr=%q, want=%q\ncode=%d, want=%d",
+				tt.input, stdout.String(), tt.stdout, stderr.String(), tt.stderr, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package transpiler
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"strings"
+)
+
+//go:embed runtime.js
+var jsRuntime string
+
+// ToJS traduce program a JavaScript. El resultado incluye las funciones
+// que necesita (ver runtime.js), así que se puede ejecutar tal cual en un
+// navegador o con node. El código conserva la forma del original: cada
+// let es un var, cada función Monkey una función JavaScript y cada if un
+// if, para que se pueda leer junto al programa Monkey.
+//
+// Los enteros son números de JavaScript, exactos hasta 2^53. Solo están
+// los builtins len, first, last, rest, push, puts y exit; usar otro es un
+// error. Los errores de ejecución no tienen posición.
+func ToJS(program *ast.Program) (string, error) {
+	g := &jsGen{out: &bytes.Buffer{}}
+	g.out.WriteString("// Code generated by monkey transpile. DO NOT EDIT.\n\n")
+	g.out.WriteString(jsRuntime)
+	g.out.WriteString("\nconst code = $.run(function () {\n")
+	g.indent++
+	g.function(nil, program.Statements)
+	g.indent--
+	g.out.WriteString("});\nif (typeof process !== \"undefined\") {\n\tprocess.exitCode = code;\n}\n")
+	if g.err != nil {
+		return "", g.err
+	}
+	return g.out.String(), nil
+}
+
+// jsBuiltins son los builtins que implementa runtime.js.
+var jsBuiltins = map[string]bool{
+	"len": true, "first": true, "last": true, "rest": true, "push": true, "puts": true, "exit": true,
+}
+
+// jsOperators es la función de runtime.js de cada operador.
+var jsOperators = map[string]string{
+	"+": "add", "-": "sub", "*": "mul", "/": "div", "**": "pow",
+	"<": "lt", ">": "gt", "==": "eq", "!=": "ne",
+}
+
+// jsReserved son los nombres que no se pueden usar como variables en
+// JavaScript. Una variable Monkey con uno de estos nombres termina en $,
+// que no puede aparecer en un identificador de Monkey.
+var jsReserved = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`arguments await break case catch class const continue
+		debugger default delete do else enum eval export extends false finally for function if
+		implements import in instanceof interface let new null package private protected public
+		return static super switch this throw true try typeof undefined var void while with yield`) {
+		jsReserved[word] = true
+	}
+}
+
+// jsName es el nombre de la variable JavaScript que guarda la variable name.
+func jsName(name string) string {
+	if jsReserved[name] {
+		return name + "$"
+	}
+	return name
+}
+
+// jsScope son las variables de una función, igual que goScope.
+type jsScope struct {
+	outer *jsScope
+	names map[string]bool
+}
+
+func (s *jsScope) defines(name string) bool {
+	for ; s != nil; s = s.outer {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+type jsGen struct {
+	out    *bytes.Buffer
+	indent int
+	scope  *jsScope
+	temps  int
+	err    error
+}
+
+func (g *jsGen) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat("\t", g.indent))
+	fmt.Fprintf(g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+func (g *jsGen) fail(node ast.Node, format string, args ...interface{}) {
+	if g.err == nil {
+		pos := node.Pos()
+		g.err = fmt.Errorf("%d:%d: %s", pos.Line, pos.Column, fmt.Sprintf(format, args...))
+	}
+}
+
+// function escribe el cuerpo de una función con los parámetros params.
+// Los let se declaran donde aparecen: var tiene el alcance de la función
+// entera, como los let de Monkey dentro de un if.
+func (g *jsGen) function(params []*ast.Identifier, body []ast.Statement) {
+	scope := &jsScope{outer: g.scope, names: map[string]bool{}}
+	for _, param := range params {
+		scope.names[param.Value] = true
+	}
+	collectLets(body, scope.names)
+	g.scope = scope
+	g.block(body, "return ")
+	g.scope = scope.outer
+}
+
+// block escribe stmts. El valor de la última sentencia se usa con target,
+// que es "return ", "x = " o "" si el valor no se usa.
+func (g *jsGen) block(stmts []ast.Statement, target string) {
+	for i, stmt := range stmts {
+		t := ""
+		if i == len(stmts)-1 {
+			t = target
+		}
+		g.statement(stmt, t)
+	}
+	if target != "" && !endsWithValue(stmts) {
+		g.line("%snull;", target)
+	}
+}
+
+func (g *jsGen) statement(stmt ast.Statement, target string) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		name := jsName(stmt.Name.Value)
+		if ife, ok := stmt.Value.(*ast.IfExpression); ok && !g.simple(ife) {
+			g.line("var %s;", name)
+			g.ifStatement(ife, name+" = ")
+			return
+		}
+		g.line("var %s = %s;", name, g.expr(stmt.Value))
+	case *ast.ReturnStatement:
+		if ife, ok := stmt.ReturnValue.(*ast.IfExpression); ok && !g.simple(ife) {
+			g.ifStatement(ife, "return ")
+			return
+		}
+		g.line("return %s;", g.expr(stmt.ReturnValue))
+	case *ast.ExpressionStatement:
+		if ife, ok := stmt.Expression.(*ast.IfExpression); ok {
+			g.ifStatement(ife, target)
+			return
+		}
+		g.line("%s%s;", target, g.expr(stmt.Expression))
+	default:
+		g.fail(stmt, "invalid syntax: %s", stmt.TokenLiteral())
+	}
+}
+
+// ifStatement escribe e como un if de JavaScript que usa su valor con
+// target, igual que block.
+func (g *jsGen) ifStatement(e *ast.IfExpression, target string) {
+	g.line("if ($.truthy(%s)) {", g.expr(e.Condition))
+	g.indent++
+	g.block(e.Consequence.Statements, target)
+	g.indent--
+	if e.Alternative != nil {
+		g.line("} else {")
+		g.indent++
+		g.block(e.Alternative.Statements, target)
+		g.indent--
+	} else if target != "" {
+		g.line("} else {")
+		g.indent++
+		g.line("%snull;", target)
+		g.indent--
+	}
+	g.line("}")
+}
+
+// simple indica si el if e se puede escribir con el operador ?: porque
+// cada rama es una sola expresión que también es simple.
+func (g *jsGen) simple(e *ast.IfExpression) bool {
+	branch := func(block *ast.BlockStatement) bool {
+		if block == nil {
+			return true
+		}
+		if len(block.Statements) != 1 {
+			return false
+		}
+		stmt, ok := block.Statements[0].(*ast.ExpressionStatement)
+		return ok && g.simpleExpr(stmt.Expression)
+	}
+	return g.simpleExpr(e.Condition) && branch(e.Consequence) && branch(e.Alternative)
+}
+
+// simpleExpr indica si e no tiene un if que haya que sacar a una
+// sentencia aparte.
+func (g *jsGen) simpleExpr(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.IfExpression:
+		return g.simple(e)
+	case *ast.PrefixExpression:
+		return g.simpleExpr(e.Right)
+	case *ast.InfixExpression:
+		return g.simpleExpr(e.Left) && g.simpleExpr(e.Right)
+	case *ast.IndexExpression:
+		return g.simpleExpr(e.Left) && g.simpleExpr(e.Index)
+	case *ast.MemberExpression:
+		return g.simpleExpr(e.Object)
+	case *ast.CallExpression:
+		return g.simpleExpr(e.Function) && g.simpleList(e.Arguments)
+	case *ast.ArrayLiteral:
+		return g.simpleList(e.Elements)
+	case *ast.HashLiteral:
+		for _, key := range e.Keys {
+			if !g.simpleExpr(key) || !g.simpleExpr(e.Pairs[key]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g *jsGen) simpleList(es []ast.Expression) bool {
+	for _, e := range es {
+		if !g.simpleExpr(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// expr retorna la expresión JavaScript de e. Un if que no es simple se
+// escribe antes, como sentencia, y su valor queda en una variable
+// temporal.
+func (g *jsGen) expr(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprint(e.Value)
+	case *ast.Boolean:
+		return fmt.Sprint(e.Value)
+	case *ast.StringLiteral:
+		var out bytes.Buffer
+		enc := json.NewEncoder(&out)
+		enc.SetEscapeHTML(false)
+		enc.Encode(e.Value)
+		return strings.TrimSuffix(out.String(), "\n")
+	case *ast.Identifier:
+		return g.identifier(e)
+	case *ast.PrefixExpression:
+		right := g.expr(e.Right)
+		switch e.Operator {
+		case "!":
+			return fmt.Sprintf("$.not(%s)", right)
+		case "-":
+			return fmt.Sprintf("$.neg(%s)", right)
+		}
+		g.fail(e, "unsupported operator %s", e.Operator)
+		return "null"
+	case *ast.InfixExpression:
+		op, ok := jsOperators[e.Operator]
+		if !ok {
+			g.fail(e, "unsupported operator %s", e.Operator)
+		}
+		return fmt.Sprintf("$.%s(%s, %s)", op, g.expr(e.Left), g.expr(e.Right))
+	case *ast.IndexExpression:
+		return fmt.Sprintf("$.index(%s, %s)", g.expr(e.Left), g.expr(e.Index))
+	case *ast.MemberExpression:
+		return fmt.Sprintf("$.member(%s, %q)", g.expr(e.Object), e.Property.Value)
+	case *ast.ArrayLiteral:
+		return "[" + g.exprList(e.Elements) + "]"
+	case *ast.HashLiteral:
+		pairs := make([]string, len(e.Keys))
+		for i, key := range e.Keys {
+			pairs[i] = fmt.Sprintf("[%s, %s]", g.expr(key), g.expr(e.Pairs[key]))
+		}
+		return "$.hash([" + strings.Join(pairs, ", ") + "])"
+	case *ast.CallExpression:
+		if len(e.Keywords) > 0 {
+			g.fail(e, "keyword arguments are not supported")
+		}
+		fn := g.expr(e.Function)
+		args := g.exprList(e.Arguments)
+		// Los builtins comprueban sus propios argumentos.
+		if strings.HasPrefix(fn, "$.builtins.") {
+			return fmt.Sprintf("%s(%s)", fn, args)
+		}
+		if args != "" {
+			args = ", " + args
+		}
+		return fmt.Sprintf("$.call(%s%s)", fn, args)
+	case *ast.IfExpression:
+		if g.simple(e) {
+			alt := "null"
+			if e.Alternative != nil {
+				alt = g.branchExpr(e.Alternative)
+			}
+			return fmt.Sprintf("($.truthy(%s) ? %s : %s)", g.expr(e.Condition), g.branchExpr(e.Consequence), alt)
+		}
+		g.temps++
+		t := fmt.Sprintf("$t%d", g.temps)
+		g.line("var %s;", t)
+		g.ifStatement(e, t+" = ")
+		return t
+	case *ast.FunctionLiteral:
+		return g.functionLiteral(e)
+	default:
+		g.fail(e, "invalid syntax: %s", e.TokenLiteral())
+		return "null"
+	}
+}
+
+// branchExpr retorna la expresión de una rama de un if simple.
+func (g *jsGen) branchExpr(block *ast.BlockStatement) string {
+	return g.expr(block.Statements[0].(*ast.ExpressionStatement).Expression)
+}
+
+func (g *jsGen) functionLiteral(e *ast.FunctionLiteral) string {
+	params := make([]string, len(e.Parameters))
+	for i, param := range e.Parameters {
+		params[i] = jsName(param.Value)
+	}
+	// El cuerpo se escribe aparte, con la sangría de la sentencia actual.
+	out, indent := g.out, g.indent
+	g.out = &bytes.Buffer{}
+	g.indent++
+	g.function(e.Parameters, e.Body.Statements)
+	body := g.out.String()
+	g.out, g.indent = out, indent
+	return fmt.Sprintf("function (%s) {\n%s%s}", strings.Join(params, ", "), body, strings.Repeat("\t", indent))
+}
+
+func (g *jsGen) identifier(e *ast.Identifier) string {
+	name := e.Value
+	if g.scope.defines(name) {
+		return jsName(name)
+	}
+	if jsBuiltins[name] {
+		return "$.builtins." + name
+	}
+	// Los demás builtins y módulos del evaluador no están en runtime.js.
+	if !evaluator.IsError(evaluator.Lookup(nil, name)) {
+		g.fail(e, "%s is not available in JavaScript", name)
+	}
+	return fmt.Sprintf("$.lookup(%q)", name)
+}
+
+func (g *jsGen) exprList(es []ast.Expression) string {
+	list := make([]string, len(es))
+	for i, e := range es {
+		list[i] = g.expr(e)
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,148 @@
+package transpiler
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToJS(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;", "\tvar x = 1;\n\treturn null;\n"},
+		{`puts("a\b")`, "\treturn $.builtins.puts(\"a\\\\b\");\n"},
+		{"let class = 1; class", "\tvar class$ = 1;\n\treturn class$;\n"},
+		{"let add = fn(a, b) { a + b }; add(1, 2)",
+			"\tvar add = function (a, b) {\n\t\treturn $.add(a, b);\n\t};\n\treturn $.call(add, 1, 2);\n"},
+		{"let x = if (1 > 2) { 10 } else { 20 };", "\tvar x = ($.truthy($.gt(1, 2)) ? 10 : 20);\n\treturn null;\n"},
+		{"let x = if (true) { let y = 1; y };",
+			"\tvar x;\n\tif ($.truthy(true)) {\n\t\tvar y = 1;\n\t\tx = y;\n\t} else {\n\t\tx = null;\n\t}\n\treturn null;\n"},
+		{"[if (true) { let y = 1; y }]",
+			"\tvar $t1;\n\tif ($.truthy(true)) {\n\t\tvar y = 1;\n\t\t$t1 = y;\n\t} else {\n\t\t$t1 = null;\n\t}\n\treturn [$t1];\n"},
+		{`{"a": [1], true: -x}["a"]`, "\treturn $.index($.hash([[\"a\", [1]], [true, $.neg($.lookup(\"x\"))]]), \"a\");\n"},
+		{"h.a.b(1)", "\treturn $.call($.member($.member($.lookup(\"h\"), \"a\"), \"b\"), 1);\n"},
+	}
+	for _, tt := range tests {
+		src, err := ToJS(parse(t, tt.input))
+		if err != nil {
+			t.Errorf("%q: ToJS failed: %s", tt.input, err)
+			continue
+		}
+		start := strings.Index(src, "$.run(function () {\n") + len("$.run(function () {\n")
+		end := strings.Index(src, "});\nif (typeof process")
+		if got := src[start:end]; got != tt.expected {
+			t.Errorf("%q: wrong code.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestToJSErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a) { a }; f(a: 1)", "1:22: keyword arguments are not supported"},
+		{"memo(fn(x) { x })", "1:1: memo is not available in JavaScript"},
+		{`path["join"]`, "1:1: path is not available in JavaScript"},
+	}
+	for _, tt := range tests {
+		_, err := ToJS(parse(t, tt.input))
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// TestToJSRun ejecuta los programas generados con node, si está instalado.
+func TestToJSRun(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node command not found")
+	}
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string
+		code   int
+	}{
+		{
+			input: `let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+let max = fn(a, b) { if (a > b) { a } else { b } };
+let h = {"k": [1, 2, 3], true: "t"};
+puts(fib(15), max(3, 9), h["k"][1], h[true], h);
+if (true) { let leaked = "sí" };
+let class = if (len(leaked) > 2) { let z = 2; z * 10 } else { 0 };
+puts(leaked, class, -h["k"][2], !true, "a" + "b", 7 / 2, 2 ** 10, push(rest(h["k"]), 4), h["z"], [1] == [1]);`,
+			stdout: "610\n9\n2\nt\n{k: [1, 2, 3], true: t}\nsí\n20\n-3\nfalse\nab\n3\n1024\n[2, 3, 4]\nnull\nfalse\n",
+		},
+		{
+			input:  "let f = fn() { g() };\nlet g = fn() { return 7; 8 };\nputs(f())",
+			stdout: "7\n",
+		},
+		{
+			input:  `puts("a"); exit(3); puts("b")`,
+			stdout: "a\n",
+			code:   3,
+		},
+		{
+			input:  "let f = fn(x) { x + true };\nf(1)",
+			stderr: "ERROR: type mismatch: INTEGER + BOOLEAN\n",
+			code:   1,
+		},
+		{
+			input:  "let f = fn(a, b) { a };\nf(1)",
+			stderr: "ERROR: wrong number of arguments: want=2, got=1\n",
+			code:   1,
+		},
+		{
+			input:  `let h = {"a": {"b": fn(x) { x * 2 }}}; puts(h.a.b(21), h.z); 1.x`,
+			stdout: "42\nnull\n",
+			stderr: "ERROR: member access not supported: INTEGER\n",
+			code:   1,
+		},
+		{
+			input:  `{[1]: 2}`,
+			stderr: "ERROR: unusable as hash key: ARRAY\n",
+			code:   1,
+		},
+		{
+			input:  `nope(1)`,
+			stderr: "ERROR: identifier not found: nope\n",
+			code:   1,
+		},
+	}
+	path := filepath.Join(t.TempDir(), "program.js")
+	for _, tt := range tests {
+		src, err := ToJS(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("%q: ToJS failed: %s", tt.input, err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(node, path)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		code := 0
+		if err := cmd.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("%q: %s", tt.input, err)
+			}
+			code = exitErr.ExitCode()
+		}
+		if stdout.String() != tt.stdout || stderr.String() != tt.stderr || code != tt.code {
+			t.Errorf("%q: wrong result.\nstdout=%q, want=%q\nstderr=%q, want=%q\ncode=%d, want=%d",
+				tt.input, stdout.String(), tt.stdout, stderr.String(), tt.stderr, code, tt.code)
+		}
+	}
+}
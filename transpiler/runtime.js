@@ -0,0 +1,257 @@
+// Funciones que usan los programas Monkey traducidos a JavaScript (ver
+// ToJS). Los enteros son números, los strings son strings, los booleanos
+// son booleanos, null es null, los arrays son arrays, los hashes son
+// $.Hash y las funciones son funciones. Un error de Monkey se lanza como
+// una excepción $.Error.
+const $ = (() => {
+	class MonkeyError extends Error {}
+
+	class Exit {
+		constructor(code) {
+			this.code = code;
+		}
+	}
+
+	class Hash {
+		constructor() {
+			this.pairs = new Map();
+		}
+	}
+
+	const fail = (message) => {
+		throw new MonkeyError(message);
+	};
+
+	const type = (obj) => {
+		switch (typeof obj) {
+			case "number":
+				return "INTEGER";
+			case "string":
+				return "STRING";
+			case "boolean":
+				return "BOOLEAN";
+			case "function":
+				return "FUNCTION";
+		}
+		if (obj === null) {
+			return "NULL";
+		}
+		if (Array.isArray(obj)) {
+			return "ARRAY";
+		}
+		if (obj instanceof Hash) {
+			return "HASH";
+		}
+		return fail("identifier not found");
+	};
+
+	const hashKey = (key) => {
+		switch (type(key)) {
+			case "INTEGER":
+			case "STRING":
+			case "BOOLEAN":
+				return type(key) + ":" + key;
+		}
+		return fail(`unusable as hash key: ${type(key)}`);
+	};
+
+	const inspect = (obj) => {
+		switch (type(obj)) {
+			case "NULL":
+				return "null";
+			case "ARRAY":
+				return "[" + obj.map(inspect).join(", ") + "]";
+			case "HASH":
+				return "{" + [...obj.pairs.values()].map(([k, v]) => inspect(k) + ": " + inspect(v)).join(", ") + "}";
+			case "FUNCTION":
+				return "fn";
+		}
+		return String(obj);
+	};
+
+	const integers = (op, a, b) => {
+		switch (op) {
+			case "+":
+				return a + b;
+			case "-":
+				return a - b;
+			case "*":
+				return a * b;
+			case "/":
+				return b === 0 ? fail("division by zero") : Math.trunc(a / b);
+			case "**":
+				return b < 0 ? fail(`negative exponent: ${a} ** ${b}`) : a ** b;
+			case "<":
+				return a < b;
+			case ">":
+				return a > b;
+			case "==":
+				return a === b;
+			case "!=":
+				return a !== b;
+		}
+	};
+
+	const infix = (op, a, b) => {
+		const ta = type(a);
+		const tb = type(b);
+		if (ta === "INTEGER" && tb === "INTEGER") {
+			return integers(op, a, b);
+		}
+		if (ta === "STRING" && tb === "STRING") {
+			return op === "+" ? a + b : fail(`unknown operator: ${ta} ${op} ${tb}`);
+		}
+		if (op === "==") {
+			return a === b;
+		}
+		if (op === "!=") {
+			return a !== b;
+		}
+		if (ta !== tb) {
+			return fail(`type mismatch: ${ta} ${op} ${tb}`);
+		}
+		return fail(`unknown operator: ${ta} ${op} ${tb}`);
+	};
+
+	const truthy = (obj) => obj !== null && obj !== false;
+
+	const args = (list, n) => {
+		if (list.length !== n) {
+			fail(`wrong number of arguments. got=${list.length}, want=${n}`);
+		}
+	};
+
+	const array = (name, obj) => {
+		if (!Array.isArray(obj)) {
+			fail(`argument to \`${name}\` must be ARRAY, got ${type(obj)}`);
+		}
+		return obj;
+	};
+
+	const builtins = {
+		len: (...a) => {
+			args(a, 1);
+			switch (type(a[0])) {
+				case "STRING":
+					return new TextEncoder().encode(a[0]).length;
+				case "ARRAY":
+					return a[0].length;
+			}
+			return fail(`argument to \`len\` not supported, got ${type(a[0])}`);
+		},
+		first: (...a) => {
+			args(a, 1);
+			const arr = array("first", a[0]);
+			return arr.length > 0 ? arr[0] : null;
+		},
+		last: (...a) => {
+			args(a, 1);
+			const arr = array("last", a[0]);
+			return arr.length > 0 ? arr[arr.length - 1] : null;
+		},
+		rest: (...a) => {
+			args(a, 1);
+			const arr = array("rest", a[0]);
+			return arr.length > 0 ? arr.slice(1) : null;
+		},
+		push: (...a) => {
+			args(a, 2);
+			return [...array("push", a[0]), a[1]];
+		},
+		puts: (...a) => {
+			a.forEach((obj) => runtime.print(inspect(obj)));
+			return null;
+		},
+		exit: (...a) => {
+			if (a.length > 1) {
+				fail(`wrong number of arguments. got=${a.length}, want=0 or 1`);
+			}
+			if (a.length === 1 && type(a[0]) !== "INTEGER") {
+				fail(`argument to \`exit\` must be INTEGER, got ${type(a[0])}`);
+			}
+			throw new Exit(a.length === 0 ? 0 : a[0]);
+		},
+	};
+
+	const runtime = {
+		Error: MonkeyError,
+		Hash,
+		builtins,
+		inspect,
+		truthy,
+		// print escribe una línea de salida. Un playground puede reemplazarla.
+		print: (line) => console.log(line),
+		// printError escribe un mensaje de error.
+		printError: (message) => console.error(message),
+
+		add: (a, b) => infix("+", a, b),
+		sub: (a, b) => infix("-", a, b),
+		mul: (a, b) => infix("*", a, b),
+		div: (a, b) => infix("/", a, b),
+		pow: (a, b) => infix("**", a, b),
+		lt: (a, b) => infix("<", a, b),
+		gt: (a, b) => infix(">", a, b),
+		eq: (a, b) => infix("==", a, b),
+		ne: (a, b) => infix("!=", a, b),
+		not: (a) => !truthy(a),
+		neg: (a) => (type(a) === "INTEGER" ? -a : fail(`unknown operator: -${type(a)}`)),
+
+		index: (left, index) => {
+			if (Array.isArray(left) && type(index) === "INTEGER") {
+				return index >= 0 && index < left.length ? left[index] : null;
+			}
+			if (left instanceof Hash) {
+				const pair = left.pairs.get(hashKey(index));
+				return pair === undefined ? null : pair[1];
+			}
+			return fail(`index operator not supported: ${type(left)}`);
+		},
+		member: (obj, name) => {
+			if (obj instanceof Hash) {
+				const pair = obj.pairs.get(hashKey(name));
+				return pair === undefined ? null : pair[1];
+			}
+			return fail(`member access not supported: ${type(obj)}`);
+		},
+
+		hash: (pairs) => {
+			const hash = new Hash();
+			pairs.forEach(([key, value]) => hash.pairs.set(hashKey(key), [key, value]));
+			return hash;
+		},
+
+		call: (fn, ...a) => {
+			if (typeof fn !== "function") {
+				fail(`not a function: ${type(fn)}`);
+			}
+			if (a.length < fn.length) {
+				fail(`wrong number of arguments: want=${fn.length}, got=${a.length}`);
+			}
+			return fn(...a);
+		},
+
+		lookup: (name) => fail(`identifier not found: ${name}`),
+
+		// run ejecuta el programa traducido y retorna su código de salida.
+		run: (program) => {
+			try {
+				program();
+				return 0;
+			} catch (e) {
+				if (e instanceof Exit) {
+					return e.code;
+				}
+				if (e instanceof MonkeyError) {
+					runtime.printError("ERROR: " + e.message);
+					return 1;
+				}
+				if (e instanceof RangeError) {
+					runtime.printError("ERROR: maximum recursion depth exceeded");
+					return 1;
+				}
+				throw e;
+			}
+		},
+	};
+	return runtime;
+})();
@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/object"
+	"sort"
+)
+
+// builtinTable resolves the operands of OpGetBuiltin, which index
+// evaluator.BuiltinNames(), to the builtins themselves. They are looked
+// up once when the VM is created, so the sandbox checks of
+// evaluator.BuiltinLookup don't run on every access. The table is never
+// modified afterwards, which lets the VMs that run closures for builtins,
+// maybe in other goroutines, share their caller's.
+type builtinTable struct {
+	names    []string
+	builtins []object.Object // nil if the builtin doesn't exist anymore
+
+	// fiber is the fiber builtin, which the VM replaces for closures.
+	fiber object.Object
+}
+
+func newBuiltinTable(opts Options) *builtinTable {
+	lookup := evaluator.LookupBuiltin
+	if opts.Sandboxed || opts.Stdout != nil || opts.Stderr != nil || opts.Stdin != nil || opts.Args != nil || opts.Context != nil {
+		lookup = evaluator.BuiltinLookup(evaluator.Options{
+			Sandboxed:    opts.Sandboxed,
+			Capabilities: opts.Capabilities,
+			Stdout:       opts.Stdout,
+			Stderr:       opts.Stderr,
+			Stdin:        opts.Stdin,
+			Args:         opts.Args,
+			Context:      opts.Context,
+		})
+	}
+	names := evaluator.BuiltinNames()
+	t := &builtinTable{names: names, builtins: make([]object.Object, len(names))}
+	for i, name := range names {
+		if builtin, ok := lookup(name); ok {
+			t.builtins[i] = builtin
+			if name == "fiber" {
+				t.fiber = builtin
+			}
+		}
+	}
+	return t
+}
+
+// lookup returns the builtin name, as get does, and whether it exists.
+func (t *builtinTable) lookup(name string) (object.Object, bool, error) {
+	index := sort.SearchStrings(t.names, name)
+	if index == len(t.names) || t.names[index] != name || t.builtins[index] == nil {
+		return nil, false, nil
+	}
+	builtin, err := t.get(index)
+	return builtin, true, err
+}
+
+// get returns the builtin number index, or an error if it isn't
+// available, e.g. because of the sandbox.
+func (t *builtinTable) get(index int) (object.Object, error) {
+	switch builtin := t.builtins[index].(type) {
+	case nil:
+		return nil, fmt.Errorf("identifier not found: %s", t.names[index])
+	case *object.Error:
+		return nil, fmt.Errorf("%s", builtin.Message)
+	default:
+		return builtin, nil
+	}
+}
@@ -0,0 +1,19 @@
+package vm
+
+import "monkey/object"
+
+// cell holds a local that closures capture and a later let can change,
+// so that the function and its closures share it (see OpMakeCell). A nil
+// value is a local whose let hasn't run.
+type cell struct {
+	name  string
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string {
+	if c.value == nil {
+		return "null"
+	}
+	return c.value.Inspect()
+}
@@ -0,0 +1,180 @@
+package vm
+
+import (
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/object"
+	"testing"
+)
+
+// Programs that must give the same result with the evaluator and with
+// the compiler + VM. The result is compared through Inspect, or through
+// the message if the program fails.
+var engineTests = []string{
+	"1 + 2 * 3 - 4 / 2",
+	"2 ** 3 ** 2",
+	"-2 ** 2",
+	"let x = 3; -x ** 2 + (-x) ** 2",
+	"-(5 - 10)",
+	"1 < 2 == true",
+	"!(if (false) { 1 })",
+	"!!0",
+	`"mon" + "key"`,
+	"[1, 2 + 3, [4]]",
+	`{"a": 1, true: [2], 3: "c"}`,
+	`{"a": 1}["a"]`,
+	"[1, 2, 3][5]",
+	"if (1 > 2) { 10 } else { 20 }",
+	"if (false) { 10 }",
+	"if (1 + 1 == 2) { 3 * 4 } else { 0 }",
+	"let x = if (false) { 1 }; x",
+	"let x = 0; 1; true; x; 2",
+	"let a = 1; let b = a + 1; let a = b * 10; a",
+	"let add = fn(a, b) { a + b }; add(2, add(3, 4))",
+	"let max = fn(a, b) { if (a > b) { return a; } b }; max(3, 7) + max(9, 1)",
+	"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(15)",
+	"let apply = fn(f, x) { f(x) }; let double = fn(x) { x * 2 }; apply(double, 21)",
+	"let f = fn(a) { a }; f(1, 2)",
+	"let f = fn() { let x = 1; let y = x + 1; [x, y] }; f()",
+	"return 1; 2",
+	`len("hello") + len([1, 2])`,
+	"push(rest([1, 2, 3]), 4)",
+	`let len = fn(x) { 0 }; len("abc")`,
+	"let adder = fn(a) { fn(b) { a + b } }; let add2 = adder(2); [add2(1), adder(10)(5)]",
+	"let compose = fn(f, g) { fn(x) { g(f(x)) } }; compose(fn(x) { x + 1 }, fn(x) { x * 10 })(4)",
+	"let f = fn() { let loop = fn(n) { if (n > 0) { loop(n - 1) } else { n } }; loop(20) }; f()",
+	"let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)",
+	"let count = fn(n) { if (n == 0) { return 0; } return count(n - 1); }; count(50000)",
+	"let depth = fn(n) { if (n == 0) { 0 } else { 1 + depth(n - 1) } }; depth(9000)",
+	`let join = fn(a, b) { a + b }; [join(1, 2), join("x", "y")]`,
+	`let shout = fn(s) { s + "!" }; shout("hi")`,
+	"let n = 10; [n - 1, n + 1, n * 2]",
+	"let f = fiber(fn(yield) { yield(1); yield(2); 3 }); [resume(f), resume(f), resume(f), is_done(f)]",
+	"let f = fiber(fn(yield, x) { let y = yield(x + 1); y * 2 }); [resume(f, 10), is_done(f), resume(f, 5)]",
+	`let gen = fiber(fn(yield) { let loop = fn(i) { yield(i * i); loop(i + 1) }; loop(1) });
+	[resume(gen), resume(gen), resume(gen), resume(gen)]`,
+	"let f = fiber(fn(yield) { let g = fiber(fn(y) { y(1); 2 }); yield(resume(g)); resume(g) }); [resume(f), resume(f)]",
+	"let f = fiber(fn(yield) { yield() }); [resume(f), f]",
+	"let sq = memo(fn(x) { x * x }); sq(7) + sq(7)",
+	"let f = fn(x) { x }; let a = push([], f); [a[0] == f, a[0](2), take(lazy_map([f], fn(g) { g == f }), 1)]",
+	"let f = fn(x) { x }; assert_eq(f, f)",
+	"let fib = memo(fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }); fib(60)",
+	`let calls = atomic();
+	let fib = memo(fn(n) { atomic_add(calls, 1); if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } });
+	[fib(30), fib(31), atomic_load(calls)]`,
+	"let sub = fn(x, y) { x - y }; [sub(y: 1, x: 10), sub(10, y: 3), sub(1, 2, 3)]",
+	"let mk = fn(n) { fn(step) { n + step } }; mk(1)(step: 2)",
+	// Globals are looked up when the code runs, so functions can refer
+	// to the ones defined after them.
+	`let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+	let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+	[isEven(10), isOdd(7)]`,
+	"let f = fn() { later * 2 }; let later = 21; f()",
+	// Functions with an empty body return null, also through builtins.
+	"fn() {}()",
+	"puts(fn() {}())",
+	"fn() {}() == 1",
+	"await(async(fn() {}))",
+	"let f = async(fn() {}); await(f); f",
+	"recv(spawn(fn() {}))",
+	"resume(fiber(fn(y) {}))",
+	// Errors
+	"5 + true",
+	"5 + true; 10",
+	"-true",
+	`"a" == "a"`,
+	"true + false",
+	"2 ** -2",
+	"1 / 0",
+	"let n = 0; 10 / n",
+	"let f = fn(x) { x + [] }; f(1)",
+	"1(2)",
+	"let f = fiber(fn(yield) { 1 }); resume(f); resume(f)",
+	"let f = 0; let f = fiber(fn(yield) { resume(f) }); resume(f)",
+	"let f = fiber(fn(yield) { yield }); let y = resume(f); y(1)",
+	"let f = fiber(fn(yield) { yield(1); 1 + true }); resume(f); resume(f)",
+	"let f = fn(a, b) { a + b }; f(1, true)",
+	"let x = true; x - 1",
+	`let s = "a"; s - 1`,
+	"let f = fn() { 1 + f() }; f()",
+	"let a = fn() { b() }; let b = fn() { a() + 1 }; a()",
+	"nope",
+	"puts(1); puts(nope)",
+	"let f = fn() { nope }; f()",
+	"let f = [f, fn() { f }]",
+	"fn(a, b) { a }(1)",
+	"let sub = fn(x, y) { x - y }; sub(1, 2, y: 3)",
+	"let sub = fn(x, y) { x - y }; sub(x: 1, z: 2)",
+	"let sub = fn(x, y) { x - y }; sub(y: 1)",
+	"len(x: [])",
+	"let outer = fn(x) { fn() { x + true } }; outer(1)()",
+	`len(1)`,
+	`first(1)`,
+	`{"a": 1}[[]]`,
+	`{[1]: 2}`,
+	`999[1]`,
+	`path.join("a", "b")`,
+	`path.dirname("a/b/c")`,
+	`path["ext"]("a.mk")`,
+	`path`,
+	`let f = fn() { path.basename("a/b") }; f()`,
+	`let path = 1; path`,
+	`"a" < "b"`,
+	`1 < "b"`,
+	`2 < 1`,
+	"if (true) {} else {}",
+	"if (false) { 1 } else {}",
+	"if (true) {}",
+	"fn() { if (true) {} }()",
+	"let f = fn() { let g = fn() { h() }; let h = fn() { 1 }; g() }; f()",
+	"let f = fn() { let g = fn() { h() }; let x = g(); let h = fn() { 1 }; x }; f()",
+	"fn() { let a = 1; let g = fn() { a }; let a = 2; g() }()",
+	"fn() { let a = 1; let g = fn() { fn() { a } }; let a = 2; g()() }()",
+	"fn(n) { let g = fn() { n }; let n = 5; g() }(1)",
+	"fn(n) { let g = fn() { n }; g() }(1)",
+	"fn(a, a) { a }(1, 2)",
+	"fn() { if (false) { let y = 1 }; y }()",
+	"fn() { if (false) { let len = 1 }; len([1]) }()",
+	"fn() { let g = fn() { y }; if (false) { let y = 1 }; g() }()",
+	"fn() { let x = x; x }()",
+	"let x = 1; fn() { let y = x; let x = 2; y }()",
+}
+
+func TestEnginesAgree(t *testing.T) {
+	for _, input := range engineTests {
+		want := runEvaluator(input)
+		if got := runVM(t, input, false); got != want {
+			t.Errorf("%q: engines disagree.\neval=%q\nvm=  %q", input, want, got)
+		}
+		if got := runVM(t, input, true); got != want {
+			t.Errorf("%q: optimized bytecode disagrees.\neval=%q\nvm=  %q", input, want, got)
+		}
+	}
+}
+
+func runEvaluator(input string) string {
+	result := evaluator.Eval(parse(input), object.NewEnvironment())
+	if errObj, ok := result.(*object.Error); ok {
+		return "error: " + errObj.Message
+	}
+	return result.Inspect()
+}
+
+func runVM(t *testing.T, input string, optimize bool) string {
+	t.Helper()
+
+	comp := compiler.New()
+	comp.SetFoldConstants(optimize)
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("%q: compiler error: %s", input, err)
+	}
+	bytecode := comp.Bytecode()
+	if optimize {
+		bytecode = bytecode.Optimize().Fuse()
+	}
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		return "error: " + err.Error()
+	}
+	return vm.LastPoppedStackElem().Inspect()
+}
@@ -0,0 +1,55 @@
+package vm
+
+import "monkey/object"
+
+// Maximum number of calls kept in the stack of a RuntimeError. In a very
+// deep recursion only the innermost ones are kept, as in the evaluator.
+const maxStackFrames = 64
+
+// RuntimeError is an error raised while running bytecode. It carries the
+// source line of the failing instruction and the calls that were active,
+// taken from the line tables of the compiled functions.
+type RuntimeError struct {
+	Message string
+	Line    int                 // 0 if unknown
+	Stack   []object.StackFrame // innermost call first
+}
+
+// Error returns just the message, so it reads the same as the
+// evaluator's errors.
+func (e *RuntimeError) Error() string { return e.Message }
+
+// Object returns the error as the evaluator reports it, with its line
+// and the backtrace.
+func (e *RuntimeError) Object() *object.Error {
+	return &object.Error{Message: e.Message, Line: e.Line, Stack: e.Stack}
+}
+
+// runtimeError turns err, returned while running the current frame, into
+// a *RuntimeError with its line and the calls that led to it. An error
+// that already has a line comes from a function called by a builtin, and
+// only gets the calls of this VM added to its stack.
+func (vm *VM) runtimeError(err error) *RuntimeError {
+	rt, ok := err.(*RuntimeError)
+	if !ok {
+		rt = &RuntimeError{Message: err.Error()}
+	}
+	line := vm.currentFrame().line()
+	if rt.Line == 0 {
+		rt.Line = line
+	}
+	// A function run by a builtin is called from the line of the call to
+	// the builtin.
+	for i := range rt.Stack {
+		if rt.Stack[i].Line == 0 {
+			rt.Stack[i].Line = line
+		}
+	}
+	for i := vm.framesIndex - 1; i > 0 && len(rt.Stack) < maxStackFrames; i-- {
+		rt.Stack = append(rt.Stack, object.StackFrame{
+			Function: vm.frames[i].name(),
+			Line:     vm.frames[i-1].line(),
+		})
+	}
+	return rt
+}
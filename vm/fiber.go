@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/code"
+	"monkey/object"
+	"sync/atomic"
+)
+
+// yieldFn is the body of the yield function of every fiber.
+var yieldFn = &object.CompiledFunction{
+	Instructions: code.Make(code.OpYield),
+	Name:         "yield",
+}
+
+// fiber is the state of a fiber created by the VM (see the fiber
+// builtin). Its function runs on a VM of its own, which keeps its stack
+// and frames while the fiber is paused, so resuming it just continues
+// the run loop where the OpYield left it.
+type fiber struct {
+	// yield is the function the fiber's function receives. Calling it
+	// from code running in the fiber pauses the fiber; anywhere else its
+	// OpYield fails.
+	yield *object.Closure
+
+	// suspended is set when a call to yield stops the fiber, with the
+	// value it yields.
+	suspended bool
+	value     object.Object
+}
+
+// newFiber returns a fiber that runs cl on its own VM, sharing the
+// constants and globals of vm.
+func (vm *VM) newFiber(cl *object.Closure) *object.Fiber {
+	return vm.runner.newFiber(cl, vm.currentFrame().line())
+}
+
+// newFiber returns a fiber of the program that runs cl, created by the
+// code at line.
+func (r *closureRunner) newFiber(cl *object.Closure, line int) *object.Fiber {
+	fb := &fiber{yield: &object.Closure{Fn: yieldFn, Runner: r}}
+	result := &object.Fiber{}
+	result.Copy = func() *object.Fiber {
+		return r.newFiber(cl, line)
+	}
+
+	var sub *VM
+	result.Resume = func(value object.Object) (object.Object, bool) {
+		if sub == nil {
+			result.Copy = nil
+			main := &object.CompiledFunction{
+				Instructions: code.Make(code.OpCall, 2),
+				Lines:        code.LineTable{}.Add(0, line),
+			}
+			sub = r.newVM(main)
+			sub.fiber = fb
+			sub.stack[0], sub.stack[1], sub.stack[2] = cl, fb.yield, value
+			sub.sp = 3
+		} else if err := sub.push(value); err != nil {
+			// value is what the pending yield returns.
+			return &object.Error{Message: err.Error()}, true
+		}
+
+		// Each resume continues the depth of the code that calls it.
+		sub.base = int(atomic.LoadInt64(sub.nested))
+		fb.suspended = false
+		if err := sub.Run(); err != nil {
+			return err.(*RuntimeError).Object(), true
+		}
+		if sub.exit != nil {
+			return sub.exit, true
+		}
+		if fb.suspended {
+			return fb.value, false
+		}
+		return sub.StackTop(), true
+	}
+	return result
+}
+
+// yield runs OpYield: it pauses the fiber run by vm, returning from the
+// call to its yield function. Its first argument, if any, is the value
+// yielded; resuming the fiber pushes the result of the call.
+func (vm *VM) yield() error {
+	// The frame of the yield function is gone either way, so an error
+	// points at the call, as a builtin's does.
+	frame := vm.popFrame()
+	if vm.fiber == nil || frame.cl != vm.fiber.yield {
+		return fmt.Errorf("yield called outside its fiber")
+	}
+	var value object.Object = Null
+	if frame.numArgs > 0 {
+		value = vm.stack[frame.basePointer]
+	}
+	vm.sp = frame.basePointer - 1
+
+	vm.fiber.suspended = true
+	vm.fiber.value = value
+	return nil
+}
@@ -0,0 +1,48 @@
+package vm
+
+import (
+	"monkey/code"
+	"monkey/object"
+)
+
+// Frame is the state of a function call: the closure being run, its
+// instruction pointer and where its locals start on the stack.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+	// numArgs is how many arguments the call passed, which may be more
+	// than the function's parameters.
+	numArgs int
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// line returns the source line of the instruction being run, or 0 if it
+// is unknown.
+func (f *Frame) line() int {
+	return f.cl.Fn.Lines.Line(f.ip)
+}
+
+// name returns the name of the function being run.
+func (f *Frame) name() string {
+	if f.cl.Fn.Name == "" {
+		return "<anonymous>"
+	}
+	return f.cl.Fn.Name
+}
+
+// localName returns the name of local number index of the function being
+// run, or "" if it is unknown.
+func (f *Frame) localName(index int) string {
+	if index < len(f.cl.Fn.Locals) {
+		return f.cl.Fn.Locals[index]
+	}
+	return ""
+}
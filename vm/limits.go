@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/object"
+	"sync/atomic"
+)
+
+// How often, in instructions, the VM checks whether Options.Context was
+// cancelled.
+const cancelCheckInterval = 256
+
+// limits enforces the MaxSteps, MaxMemory and Context of Options. A VM
+// shares its limits with the VMs it creates to run closures for builtins
+// and fibers, maybe in other goroutines, so the budget covers the whole
+// program and steps is updated atomically.
+type limits struct {
+	opts  Options
+	steps int64
+	// done is opts.Context.Done(), or nil if the run can't be cancelled.
+	done <-chan struct{}
+	// alloc counts what the program allocates; nil without MaxMemory.
+	alloc *object.Allocations
+}
+
+// newLimits returns the limits of opts, or nil if it sets none.
+func newLimits(opts Options) *limits {
+	if opts.MaxSteps <= 0 && opts.MaxMemory <= 0 && opts.Context == nil {
+		return nil
+	}
+	l := &limits{opts: opts}
+	if opts.Context != nil {
+		l.done = opts.Context.Done()
+	}
+	if opts.MaxMemory > 0 {
+		l.alloc = object.NewAllocations(opts.MaxMemory)
+	}
+	return l
+}
+
+// step counts an instruction and returns an error if the program must
+// stop.
+func (l *limits) step() error {
+	steps := atomic.AddInt64(&l.steps, 1)
+	if l.opts.MaxSteps > 0 && steps > l.opts.MaxSteps {
+		return fmt.Errorf("fuel exhausted: more than %d instructions", l.opts.MaxSteps)
+	}
+	if l.done != nil && steps%cancelCheckInterval == 0 {
+		select {
+		case <-l.done:
+			return fmt.Errorf("evaluation cancelled: %s", l.opts.Context.Err())
+		default:
+		}
+	}
+	return nil
+}
+
+// allocate counts size bytes the program is about to allocate and returns
+// an error if that takes it over MaxMemory.
+func (l *limits) allocate(size int64) error {
+	if l.alloc != nil && !l.alloc.Add(size) {
+		return l.memoryError()
+	}
+	return nil
+}
+
+// allocateResult counts result, the value a builtin called with args
+// returned.
+func (l *limits) allocateResult(result object.Object, args []object.Object) error {
+	if l.alloc != nil && !l.alloc.AddResult(result, args) {
+		return l.memoryError()
+	}
+	return nil
+}
+
+func (l *limits) memoryError() error {
+	return fmt.Errorf("memory limit exceeded: more than %d bytes", l.opts.MaxMemory)
+}
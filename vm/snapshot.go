@@ -0,0 +1,76 @@
+package vm
+
+import "monkey/object"
+
+// Snapshot is the state of a VM at some point: its stack, its frames and
+// its globals. Objects with mutable state, such as atomics, are copied
+// (see object.CopyState), so restoring a snapshot brings back the values
+// the program saw then. Those whose state can't be copied, like channels
+// and fibers that have started, are shared.
+type Snapshot struct {
+	stack   []object.Object
+	frames  []Frame
+	globals []object.Object
+	exit    *object.Exit
+}
+
+// Snapshot captures the state of vm. The VM must not be running: it can
+// be taken before Run, after Run returns (also with an error, to look at
+// the calls that were in progress), or while a fiber is paused.
+func (vm *VM) Snapshot() *Snapshot {
+	copies := map[object.Object]object.Object{}
+	s := &Snapshot{
+		stack:   copyState(vm.stack[:vm.sp], copies),
+		frames:  make([]Frame, vm.framesIndex),
+		globals: copyState(vm.globals, copies),
+		exit:    vm.exit,
+	}
+	for i, frame := range vm.frames[:vm.framesIndex] {
+		s.frames[i] = *frame
+	}
+	return s
+}
+
+// Restore brings vm back to the state captured in s, which must come from
+// a VM running the same bytecode. The next Run continues from where the
+// snapshot was taken. The globals are copied into the VM's store, so a
+// store shared through Options.Globals sees them too.
+func (vm *VM) Restore(s *Snapshot) error {
+	if err := vm.ensureStack(len(s.stack)); err != nil {
+		return err
+	}
+	copies := map[object.Object]object.Object{}
+	copy(vm.stack, copyState(s.stack, copies))
+	for i := len(s.stack); i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = len(s.stack)
+
+	for len(vm.frames) < len(s.frames) {
+		vm.frames = append(vm.frames, nil)
+	}
+	for i := range s.frames {
+		frame := s.frames[i]
+		vm.frames[i] = &frame
+	}
+	vm.framesIndex = len(s.frames)
+
+	n := copy(vm.globals, copyState(s.globals, copies))
+	for i := n; i < len(vm.globals); i++ {
+		vm.globals[i] = nil
+	}
+	vm.exit = s.exit
+	return nil
+}
+
+// copyState returns a copy of objs and of their mutable state, sharing
+// copies with the other parts of the same snapshot.
+func copyState(objs []object.Object, copies map[object.Object]object.Object) []object.Object {
+	c := make([]object.Object, len(objs))
+	for i, obj := range objs {
+		if obj != nil {
+			c[i] = object.CopyState(obj, copies)
+		}
+	}
+	return c
+}
@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/code"
+	"strings"
+)
+
+// Longest value shown in a trace line; longer ones are cut with "...".
+const maxTraceValue = 40
+
+// trace writes to opts.Trace the instruction at ip of the current frame,
+// which is about to run, with the call depth, the function it belongs to
+// and the value on top of the stack at that point:
+//
+//	depth  function      offset instruction   top of stack
+//	1      fact          0004   OpGetLocal 0  5
+func (vm *VM) trace(ins code.Instructions, ip int) {
+	frame := vm.currentFrame()
+	name := "main"
+	if vm.framesIndex > 1 {
+		name = frame.name()
+	}
+
+	text := "?"
+	if def, err := code.Lookup(ins[ip]); err == nil {
+		operands, _ := code.ReadOperands(def, ins[ip+1:])
+		parts := []string{def.Name}
+		for _, o := range operands {
+			parts = append(parts, fmt.Sprint(o))
+		}
+		text = strings.Join(parts, " ")
+	}
+
+	top := "-"
+	if obj := vm.StackTop(); obj != nil {
+		top = obj.Inspect()
+		if len(top) > maxTraceValue {
+			top = top[:maxTraceValue-3] + "..."
+		}
+		top = strings.Replace(top, "\n", " ", -1)
+	}
+
+	fmt.Fprintf(vm.opts.Trace, "%-6d %-13s %04d   %-20s %s\n",
+		vm.framesIndex-1, name, ip, text, top)
+}
@@ -1,45 +1,313 @@
 package vm
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"monkey/code"
 	"monkey/compiler"
+	"monkey/evaluator"
 	"monkey/object"
+	"sync/atomic"
 )
 
-const StackSize = 2048
+// Defaults for the zero fields of Options.
+const (
+	StackSize    = 2048    // initial number of stack slots
+	MaxStackSize = 1 << 20 // the stack grows up to this many slots
+	GlobalsSize  = 65536
+)
+
+// Options configures a VM (see NewWithOptions). The zero value uses the
+// defaults above, and evaluator.MaxDepth as the limit of nested calls.
+type Options struct {
+	// StackSize is the initial size of the stack, which doubles whenever
+	// it fills up until it reaches MaxStackSize. Going beyond that fails
+	// with a "stack overflow" error.
+	StackSize    int
+	MaxStackSize int
+	// MaxFrames is the maximum number of nested function calls.
+	MaxFrames int
+	// Globals, if not nil, stores the global variables, so that several
+	// programs can share them. Otherwise GlobalsSize slots are allocated.
+	Globals     []object.Object
+	GlobalsSize int
+	// Trace, if not nil, gets a line for each instruction run: the call
+	// depth, the function, the instruction and the top of the stack
+	// before it runs.
+	Trace io.Writer
+
+	// MaxSteps, if greater than 0, is the number of instructions the
+	// program may run before failing with "fuel exhausted". Functions
+	// called back from builtins and fibers count too.
+	MaxSteps int64
+	// MaxMemory, if greater than 0, is how many bytes the strings,
+	// arrays and hashes the program creates may take before it fails
+	// with "memory limit exceeded". It counts what the program
+	// allocates, not what is still alive (see object.Allocations).
+	MaxMemory int64
+	// Context, if not nil, stops the program with an error when it is
+	// cancelled. The builtins that block (sleep, recv, lock...) stop
+	// waiting too.
+	Context context.Context
+
+	// Sandboxed, Capabilities, Stdout, Stderr, Stdin and Args are
+	// applied to the builtins as in evaluator.Options. Without Sandboxed
+	// the evaluator's Sandbox and AllowExec variables apply.
+	Sandboxed    bool
+	Capabilities evaluator.Capability
+	Stdout       io.Writer
+	Stderr       io.Writer
+	Stdin        io.Reader
+	Args         []string
+
+	// CheckedArithmetic makes +, -, *, ** and unary - on integers fail
+	// with "integer overflow" when the result doesn't fit in an int64,
+	// as in evaluator.Options, instead of wrapping around.
+	CheckedArithmetic bool
+}
 
-var True = &object.Boolean{Value: true}
-var False = &object.Boolean{Value: false}
-var Null = &object.Null{}
+func (o Options) withDefaults() Options {
+	if o.StackSize <= 0 {
+		o.StackSize = StackSize
+	}
+	if o.MaxStackSize <= 0 {
+		o.MaxStackSize = MaxStackSize
+	}
+	if o.StackSize > o.MaxStackSize {
+		o.StackSize = o.MaxStackSize
+	}
+	if o.MaxFrames <= 0 {
+		o.MaxFrames = evaluator.MaxDepth
+	}
+	if o.GlobalsSize <= 0 {
+		o.GlobalsSize = GlobalsSize
+	}
+	if o.Globals == nil {
+		o.Globals = make([]object.Object, o.GlobalsSize)
+	}
+	return o
+}
 
-const GlobalsSize = 65536
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL
 
 type VM struct {
-	constants    []object.Object
-	instructions code.Instructions
+	constants []object.Object
 
 	stack   []object.Object
 	sp      int // Always points to the next value. Top of stack is stack[sp-1]
 	globals []object.Object
+
+	frames      []*Frame
+	framesIndex int
+
+	// numGlobals is the number of globals the program uses; Run checks
+	// once that they fit in globals.
+	numGlobals int
+
+	builtins *builtinTable
+	opts     Options
+
+	// exit is the result of a call to exit, once the program has made one.
+	exit *object.Exit
+	// fiber is set when this VM runs the function of a fiber.
+	fiber *fiber
+	// steps counts the instructions run (see Steps).
+	steps int64
+	// limits is nil if opts sets no limits.
+	limits *limits
+	// nested counts the calls of the VMs that are waiting for a builtin
+	// to return. vm shares it with the VMs it creates to run closures for
+	// builtins and fibers, and base is its value when this VM started, so
+	// that MaxFrames covers calls that go through builtins, such as a
+	// function that recurses through lazy_map.
+	nested *int64
+	base   int
+	// runner runs the closures of the program for builtins. It is shared
+	// by all the VMs of the program.
+	runner *closureRunner
+}
+
+// closureRunner runs the closures created by a program when a builtin or
+// the code embedding the VM calls them (see object.Closure.Call). Each
+// call runs on a new VM with the constants, globals, options and limits
+// of the program.
+type closureRunner struct {
+	constants []object.Object
+	opts      Options
+	builtins  *builtinTable
+	limits    *limits
+	nested    *int64
+	// globalNames are the names of the globals by slot, if known.
+	globalNames []string
+}
+
+func newClosureRunner(constants []object.Object, opts Options) *closureRunner {
+	return &closureRunner{
+		constants: constants,
+		opts:      opts,
+		builtins:  newBuiltinTable(opts),
+		limits:    newLimits(opts),
+		nested:    new(int64),
+	}
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithOptions(bytecode, Options{})
+}
+
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	return NewWithOptions(bytecode, Options{Globals: s})
+}
+
+func NewWithOptions(bytecode *compiler.Bytecode, opts Options) *VM {
+	opts = opts.withDefaults()
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Lines:        bytecode.Lines,
+	}
+	r := newClosureRunner(bytecode.Constants, opts)
+	r.globalNames = bytecode.GlobalNames
+	vm := newVM(mainFn, r)
+	vm.numGlobals = bytecode.NumGlobals
+	return vm
+}
+
+func newVM(mainFn *object.CompiledFunction, r *closureRunner) *VM {
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
 	return &VM{
-		instructions: bytecode.Instructions,
-		constants:    bytecode.Constants,
+		constants: r.constants,
 
-		stack: make([]object.Object, StackSize),
+		stack: make([]object.Object, r.opts.StackSize),
 		sp:    0,
 
-		globals: make([]object.Object, GlobalsSize),
+		globals: r.opts.Globals,
+
+		frames:      []*Frame{mainFrame},
+		framesIndex: 1,
+
+		builtins: r.builtins,
+		opts:     r.opts,
+		limits:   r.limits,
+		nested:   r.nested,
+		runner:   r,
 	}
 }
 
-func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
-	vm := New(bytecode)
-	vm.globals = s
-	return vm
+// newSubVM returns a VM that runs main with the constants, globals,
+// options and limits of vm, continuing its call depth.
+func (vm *VM) newSubVM(main *object.CompiledFunction) *VM {
+	return vm.runner.newVM(main)
+}
+
+// newVM returns a VM of the program that runs main, continuing the call
+// depth of the VMs waiting for a builtin.
+func (r *closureRunner) newVM(main *object.CompiledFunction) *VM {
+	sub := newVM(main, r)
+	sub.base = int(atomic.LoadInt64(r.nested))
+	return sub
+}
+
+// missingGlobal returns the value of the global at index read before a
+// let gave it one. The compiler gives a slot to every name it can't
+// resolve, so, as in the evaluator, that is the builtin or module of that
+// name, or "identifier not found".
+func (r *closureRunner) missingGlobal(index int) (object.Object, error) {
+	if index >= len(r.globalNames) || r.globalNames[index] == "" {
+		return nil, fmt.Errorf("global variable used before its let ran")
+	}
+	return r.lookupName(r.globalNames[index])
+}
+
+// local returns the value of local number index of the function being
+// run, or, if its let hasn't run, what its name refers to (see
+// unsetLocal).
+func (vm *VM) local(index int) (object.Object, error) {
+	frame := vm.currentFrame()
+	if local := vm.stack[frame.basePointer+index]; local != nil {
+		return local, nil
+	}
+	name := frame.localName(index)
+	if name == "" {
+		return nil, fmt.Errorf("local variable used before its let ran")
+	}
+	return vm.runner.unsetLocal(name)
+}
+
+// unsetLocal returns what the local name refers to before its let runs:
+// as in the evaluator, the global of that name if it has a value, and
+// otherwise its builtin or module. The evaluator looks first in the
+// functions around the one running, whose locals the VM doesn't keep.
+func (r *closureRunner) unsetLocal(name string) (object.Object, error) {
+	for i, global := range r.globalNames {
+		if global == name && i < len(r.opts.Globals) && r.opts.Globals[i] != nil {
+			return r.opts.Globals[i], nil
+		}
+	}
+	return r.lookupName(name)
+}
+
+// lookupName returns what name refers to when no variable of that name
+// has a value: the builtin or module of that name, as in the evaluator.
+func (r *closureRunner) lookupName(name string) (object.Object, error) {
+	if builtin, ok, err := r.builtins.lookup(name); ok {
+		return builtin, err
+	}
+	if module, ok := evaluator.Module(name); ok {
+		return module, nil
+	}
+	return nil, fmt.Errorf("identifier not found: %s", name)
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+// pushFrame enters f. The main frame doesn't count as a call, so up to
+// opts.MaxFrames calls can be nested, as with the evaluator's MaxDepth.
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.base+vm.framesIndex > vm.opts.MaxFrames {
+		return fmt.Errorf("maximum recursion depth exceeded (%d)", vm.opts.MaxFrames)
+	}
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// ensureStack grows the stack so that it has at least n slots, failing
+// if that would exceed opts.MaxStackSize.
+func (vm *VM) ensureStack(n int) error {
+	if n <= len(vm.stack) {
+		return nil
+	}
+	if n > vm.opts.MaxStackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	size := 2 * len(vm.stack)
+	for size < n {
+		size *= 2
+	}
+	if size > vm.opts.MaxStackSize {
+		size = vm.opts.MaxStackSize
+	}
+	stack := make([]object.Object, size)
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+	return nil
 }
 
 func (vm *VM) StackTop() object.Object {
@@ -49,30 +317,91 @@ func (vm *VM) StackTop() object.Object {
 	return vm.stack[vm.sp-1]
 }
 
+// Run executes the bytecode until the main function ends. Runtime errors
+// are *RuntimeError and carry the same messages as the evaluator's. If
+// the program calls exit, Run stops and LastPoppedStackElem returns the
+// *object.Exit. A VM that runs a fiber also stops when the fiber yields,
+// and the next Run continues from there.
 func (vm *VM) Run() error {
-	for ip := 0; ip < len(vm.instructions); ip++ {
-		op := code.Opcode(vm.instructions[ip])
+	if err := vm.run(); err != nil {
+		return vm.runtimeError(err)
+	}
+	return nil
+}
+
+func (vm *VM) run() error {
+	if vm.numGlobals > len(vm.globals) {
+		return fmt.Errorf("too many global variables (%d)", len(vm.globals))
+	}
+
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+		vm.steps++
+		if vm.limits != nil {
+			if err := vm.limits.step(); err != nil {
+				return err
+			}
+		}
+
+		if vm.opts.Trace != nil {
+			vm.trace(ins, ip)
+		}
 
 		switch op {
 		case code.OpConstant:
-			constIndex := code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
 			err := vm.push(vm.constants[constIndex])
 			if err != nil {
 				return err
 			}
 
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpPow,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
 			}
 
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
-			err := vm.executeComparison(op)
+		case code.OpAddConstant, code.OpSubConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			binaryOp := code.OpAdd
+			if op == code.OpSubConstant {
+				binaryOp = code.OpSub
+			}
+			err := vm.executeFusedOperation(binaryOp, vm.pop(), vm.constants[constIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpAddLocals:
+			left := code.ReadUint8(ins[ip+1:])
+			right := code.ReadUint8(ins[ip+2:])
+			vm.currentFrame().ip += 2
+
+			leftValue, err := vm.local(int(left))
+			if err != nil {
+				return err
+			}
+			rightValue, err := vm.local(int(right))
 			if err != nil {
 				return err
 			}
+			err = vm.executeFusedOperation(code.OpAdd, leftValue, rightValue)
+			if err != nil {
+				return err
+			}
+
 		case code.OpPop:
 			vm.pop()
 
@@ -101,15 +430,15 @@ func (vm *VM) Run() error {
 			}
 
 		case code.OpJump:
-			pos := int(code.ReadUint16(vm.instructions[ip+1:]))
-			ip = pos - 1
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
 
 		case code.OpJumpNotTruthy:
-			pos := int(code.ReadUint16(vm.instructions[ip+1:]))
-			ip += 2
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
 			condition := vm.pop()
 			if !isTruthy(condition) {
-				ip = pos - 1
+				vm.currentFrame().ip = pos - 1
 			}
 
 		case code.OpNull:
@@ -119,27 +448,115 @@ func (vm *VM) Run() error {
 			}
 
 		case code.OpSetGlobal:
-			globalIndex := code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
 
 			vm.globals[globalIndex] = vm.pop()
 
 		case code.OpGetGlobal:
-			globalIndex := code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			global := vm.globals[globalIndex]
+			if global == nil {
+				var err error
+				global, err = vm.runner.missingGlobal(int(globalIndex))
+				if err != nil {
+					return err
+				}
+			}
+			err := vm.push(global)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			local, err := vm.local(int(localIndex))
+			if err != nil {
+				return err
+			}
+			err = vm.push(local)
+			if err != nil {
+				return err
+			}
+
+		case code.OpMakeCell:
+			localIndex := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+localIndex] = &cell{
+				name:  frame.localName(localIndex),
+				value: vm.stack[frame.basePointer+localIndex],
+			}
+
+		case code.OpGetLocalCell, code.OpSetLocalCell, code.OpGetFreeCell:
+			index := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			var slot object.Object
+			if op == code.OpGetFreeCell {
+				slot = frame.cl.Free[index]
+			} else {
+				slot = vm.stack[frame.basePointer+index]
+			}
+			c, ok := slot.(*cell)
+			if !ok {
+				return fmt.Errorf("variable %d is not in a cell", index)
+			}
+			if op == code.OpSetLocalCell {
+				c.value = vm.pop()
+				continue
+			}
+			value := c.value
+			if value == nil {
+				var err error
+				value, err = vm.runner.unsetLocal(c.name)
+				if err != nil {
+					return err
+				}
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
 
-			err := vm.push(vm.globals[globalIndex])
+			builtin, err := vm.builtins.get(int(builtinIndex))
+			if err != nil {
+				return err
+			}
+			err = vm.push(builtin)
 			if err != nil {
 				return err
 			}
 
 		case code.OpArray:
-			numElements := int(code.ReadUint16(vm.instructions[ip+1:]))
-			ip += 2
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
 
 			array := vm.buildArray(vm.sp-numElements, vm.sp)
 			vm.sp = vm.sp - numElements
 
+			if vm.limits != nil {
+				if err := vm.limits.allocate(object.Size(array)); err != nil {
+					return err
+				}
+			}
 			err := vm.push(array)
 
 			if err != nil {
@@ -147,8 +564,8 @@ func (vm *VM) Run() error {
 			}
 
 		case code.OpHash:
-			numElements := int(code.ReadUint16(vm.instructions[ip+1:]))
-			ip += 2
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
 
 			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
 			if err != nil {
@@ -156,6 +573,12 @@ func (vm *VM) Run() error {
 			}
 			vm.sp = vm.sp - numElements
 
+			if vm.limits != nil {
+				if err := vm.limits.allocate(object.Size(hash)); err != nil {
+					return err
+				}
+			}
+
 			err = vm.push(hash)
 			if err != nil {
 				return err
@@ -169,12 +592,333 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+
+		case code.OpMember:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			name := vm.constants[constIndex].(*object.String)
+			err := vm.executeMemberExpression(vm.pop(), name)
+			if err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			stop, err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+
+		case code.OpCallKeywords:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			numKeywords := int(code.ReadUint8(ins[ip+2:]))
+			vm.currentFrame().ip += 2
+
+			numBound, err := vm.bindKeywords(numArgs, numKeywords)
+			if err != nil {
+				return err
+			}
+			stop, err := vm.executeCall(numBound)
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpCurrentClosure:
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure)
+			if err != nil {
+				return err
+			}
+
+		case code.OpTailCall:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			// Only closures reuse the frame; a builtin returns its value to
+			// the OpReturnValue that follows.
+			if cl, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure); ok && vm.framesIndex > 1 {
+				err := vm.tailCallClosure(cl, numArgs)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			stop, err := vm.executeCall(numArgs)
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+
+		case code.OpYield:
+			if err := vm.yield(); err != nil {
+				return err
+			}
+			return nil
+
+		case code.OpReturnValue, code.OpReturn:
+			var returnValue object.Object = Null
+			if op == code.OpReturnValue {
+				returnValue = vm.pop()
+			}
+
+			// A return at the top level ends the program.
+			if vm.framesIndex == 1 {
+				if err := vm.ensureStack(vm.sp + 1); err != nil {
+					return err
+				}
+				vm.stack[vm.sp] = returnValue
+				return nil
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// executeCall calls the function below the numArgs arguments on top of
+// the stack. It reports whether Run must stop: after a call to exit,
+// which ends the program with the *object.Exit as its last value.
+func (vm *VM) executeCall(numArgs int) (bool, error) {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return false, vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	case object.Callable:
+		return vm.callBuiltin(&object.Builtin{Fn: callee.Call}, numArgs)
+	default:
+		return false, fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+// bindKeywords replaces the numArgs positional arguments and the
+// numKeywords name and value pairs on top of the stack with the
+// arguments in the order of the parameters of the callee, as the
+// evaluator does, and returns how many there are.
+func (vm *VM) bindKeywords(numArgs, numKeywords int) (int, error) {
+	base := vm.sp - numArgs - 2*numKeywords
+	callee := vm.stack[base-1]
+	cl, ok := callee.(*object.Closure)
+	if !ok {
+		return 0, fmt.Errorf("keyword arguments not supported by %s", callee.Type())
+	}
+	params := cl.Fn.Parameters
+	bound := make([]object.Object, len(params))
+	if numArgs > len(bound) {
+		bound = make([]object.Object, numArgs)
+	}
+	copy(bound, vm.stack[base:base+numArgs])
+	for i := base + numArgs; i < vm.sp; i += 2 {
+		name := vm.stack[i].(*object.String).Value
+		idx := -1
+		// If a parameter repeats, the last one wins, as in the evaluator.
+		for j, param := range params {
+			if param == name {
+				idx = j
+			}
+		}
+		if idx < 0 {
+			return 0, fmt.Errorf("unknown keyword argument: %s", name)
+		}
+		if bound[idx] != nil {
+			return 0, fmt.Errorf("multiple values for argument: %s", name)
+		}
+		bound[idx] = vm.stack[i+1]
+	}
+	for i, param := range params {
+		if bound[i] == nil {
+			return 0, fmt.Errorf("missing argument: %s", param)
 		}
 	}
+	// Every parameter is bound once, so bound fits where the arguments were.
+	copy(vm.stack[base:], bound)
+	for i := base + len(bound); i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = base + len(bound)
+	return len(bound), nil
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	fn := cl.Fn
+	if numArgs < fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
+			fn.NumParameters, numArgs)
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	frame.numArgs = numArgs
+	err := vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+
+	// Extra arguments are ignored, as in the evaluator.
+	sp := frame.basePointer + fn.NumLocals
+	if err := vm.ensureStack(sp); err != nil {
+		return err
+	}
+	for i := frame.basePointer + fn.NumParameters; i < sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = sp
 
 	return nil
 }
 
+// tailCallClosure replaces the current frame with a call to cl, moving
+// cl and its arguments to where the current function and its arguments
+// were. The callee then returns straight to our caller, so recursion in
+// tail position runs in constant frame space. The replaced calls don't
+// show up in the stack of a RuntimeError.
+func (vm *VM) tailCallClosure(cl *object.Closure, numArgs int) error {
+	fn := cl.Fn
+	if numArgs < fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
+			fn.NumParameters, numArgs)
+	}
+
+	basePointer := vm.currentFrame().basePointer
+	copy(vm.stack[basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+	frame := NewFrame(cl, basePointer)
+	frame.numArgs = numArgs
+	vm.frames[vm.framesIndex-1] = frame
+
+	sp := basePointer + fn.NumLocals
+	if err := vm.ensureStack(sp); err != nil {
+		return err
+	}
+	for i := basePointer + fn.NumParameters; i < sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = sp
+
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) (bool, error) {
+	// fiber runs closures on a VM that it can pause, instead of calling
+	// them through their Runner.
+	if builtin == vm.builtins.fiber && numArgs == 1 {
+		if cl, ok := vm.stack[vm.sp-1].(*object.Closure); ok {
+			vm.sp = vm.sp - numArgs - 1
+			return false, vm.push(vm.newFiber(cl))
+		}
+	}
+
+	// Builtins may keep their arguments, so they get a copy of the stack.
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+	// The calls of this VM count for the VMs the builtin may create.
+	calls := int64(vm.framesIndex - 1)
+	atomic.AddInt64(vm.nested, calls)
+	result := builtin.Fn(args...)
+	atomic.AddInt64(vm.nested, -calls)
+	vm.sp = vm.sp - numArgs - 1
+
+	switch result := result.(type) {
+	case nil:
+		return false, vm.push(Null)
+	case *object.Error:
+		return false, &RuntimeError{Message: result.Message, Line: result.Line, Stack: result.Stack}
+	case *object.Exit:
+		vm.exit = result
+		vm.stack[vm.sp] = result
+		return true, nil
+	default:
+		if vm.limits != nil {
+			if err := vm.limits.allocateResult(result, args); err != nil {
+				return false, err
+			}
+		}
+		return false, vm.push(result)
+	}
+}
+
+// RunClosure runs cl on a new VM of the program. Runtime errors become
+// *object.Error and a call to exit is passed on as its *object.Exit. The
+// call has no line of its own: the VM that called the builtin fills it in
+// (see runtimeError).
+func (r *closureRunner) RunClosure(cl *object.Closure, args ...object.Object) object.Object {
+	if len(args) > 255 {
+		return &object.Error{Message: fmt.Sprintf("too many arguments: %d", len(args))}
+	}
+
+	sub := r.newVM(&object.CompiledFunction{Instructions: code.Make(code.OpCall, len(args))})
+	if err := sub.ensureStack(1 + len(args)); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	sub.stack[0] = cl
+	copy(sub.stack[1:], args)
+	sub.sp = 1 + len(args)
+
+	if err := sub.Run(); err != nil {
+		return err.(*RuntimeError).Object()
+	}
+	if sub.exit != nil {
+		return sub.exit
+	}
+	return sub.StackTop()
+}
+
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free, Runner: vm.runner}
+	return vm.push(closure)
+}
+
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
@@ -182,10 +926,43 @@ func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	case left.Type() == object.HASH_OBJ:
 		return vm.executeHashIndex(left, index)
 	default:
+		if indexable, ok := left.(object.Indexable); ok {
+			return vm.pushResult(indexable.Index(index))
+		}
 		return fmt.Errorf("index operator not supported: %s", left.Type())
 	}
 }
 
+// executeMemberExpression pushes obj.name: the member of an
+// object.HasMembers or, on a hash, the same as obj["name"].
+func (vm *VM) executeMemberExpression(obj object.Object, name *object.String) error {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		return vm.executeHashIndex(obj, name)
+	case object.HasMembers:
+		member, ok := obj.Member(name.Value)
+		if !ok {
+			return fmt.Errorf("unknown member %s.%s", obj.Type(), name.Value)
+		}
+		return vm.push(member)
+	default:
+		return fmt.Errorf("member access not supported: %s", obj.Type())
+	}
+}
+
+// pushResult pushes the result of a host object's operation, which
+// reports failure as an *object.Error.
+func (vm *VM) pushResult(result object.Object) error {
+	switch result := result.(type) {
+	case nil:
+		return vm.push(Null)
+	case *object.Error:
+		return &RuntimeError{Message: result.Message, Line: result.Line, Stack: result.Stack}
+	default:
+		return vm.push(result)
+	}
+}
+
 func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 
@@ -215,7 +992,7 @@ func (vm *VM) executeArrayIndex(array, index object.Object) error {
 }
 
 func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
-	hashedPairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
@@ -228,10 +1005,10 @@ func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
 
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey.HashKey(), pair)
 	}
 
-	return &object.Hash{Pairs: hashedPairs}, nil
+	return hash, nil
 }
 
 func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
@@ -259,8 +1036,10 @@ func isTruthy(obj object.Object) bool {
 }
 
 func (vm *VM) push(o object.Object) error {
-	if vm.sp >= StackSize {
-		return fmt.Errorf("stack overflow")
+	if vm.sp >= len(vm.stack) {
+		if err := vm.ensureStack(vm.sp + 1); err != nil {
+			return err
+		}
 	}
 
 	vm.stack[vm.sp] = o
@@ -275,6 +1054,22 @@ func (vm *VM) pop() object.Object {
 	return o
 }
 
+// Callable returns a builtin that calls cl, a closure from a program
+// compiled with constants, on a new VM with opts. It lets code outside
+// the VM, like the evaluator, call compiled functions.
+func Callable(cl *object.Closure, constants []object.Object, opts Options) *object.Builtin {
+	r := newClosureRunner(constants, opts.withDefaults())
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return r.RunClosure(cl, args...)
+	}}
+}
+
+// Steps returns the number of instructions this VM has run. Functions
+// called back from builtins run on their own VMs and are not counted.
+func (vm *VM) Steps() int64 {
+	return vm.steps
+}
+
 func (vm *VM) LastPoppedStackElem() object.Object {
 	return vm.stack[vm.sp]
 }
@@ -298,13 +1093,53 @@ func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
 
 	if operand.Type() != object.INTEGER_OBJ {
-		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
+		return fmt.Errorf("unknown operator: -%s", operand.Type())
 	}
 
 	value := operand.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -value})
+	if vm.opts.CheckedArithmetic && value == math.MinInt64 {
+		return fmt.Errorf("integer overflow: -(%d)", value)
+	}
+	return vm.push(object.NewInteger(-value))
 }
 
+// executeFusedOperation runs op, OpAdd or OpSub, on operands that a
+// superinstruction took from somewhere other than the stack. Integers
+// are added or subtracted right away, unless overflow is checked;
+// anything else goes through executeBinaryOperation.
+func (vm *VM) executeFusedOperation(op code.Opcode, left, right object.Object) error {
+	l, ok := left.(*object.Integer)
+	if r, ok2 := right.(*object.Integer); ok && ok2 && !vm.opts.CheckedArithmetic {
+		if op == code.OpAdd {
+			return vm.push(object.NewInteger(l.Value + r.Value))
+		}
+		return vm.push(object.NewInteger(l.Value - r.Value))
+	}
+
+	if err := vm.push(left); err != nil {
+		return err
+	}
+	if err := vm.push(right); err != nil {
+		return err
+	}
+	return vm.executeBinaryOperation(op)
+}
+
+// Source operators of the binary opcodes, for error messages.
+var binaryOperators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpPow:         "**",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+// executeBinaryOperation follows the same rules as the evaluator's
+// infix expressions, including its error messages.
 func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
@@ -317,9 +1152,16 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 		return vm.executeBinaryIntegerOperation(op, left, right)
 	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
 		return vm.executeBinaryStringOperation(op, left, right)
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case leftType != rightType:
+		return fmt.Errorf("type mismatch: %s %s %s",
+			leftType, binaryOperators[op], rightType)
 	default:
-		return fmt.Errorf("unsupported types for binary operation: %s %s",
-			leftType, rightType)
+		return fmt.Errorf("unknown operator: %s %s %s",
+			leftType, binaryOperators[op], rightType)
 	}
 }
 
@@ -328,59 +1170,23 @@ func (vm *VM) executeBinaryStringOperation(
 	left, right object.Object,
 ) error {
 	if op != code.OpAdd {
-		return fmt.Errorf("unknown string operator: %d", op)
+		return fmt.Errorf("unknown operator: %s %s %s",
+			left.Type(), binaryOperators[op], right.Type())
 	}
 
 	leftValue := left.(*object.String).Value
 	rightValue := right.(*object.String).Value
-
-	return vm.push(&object.String{Value: leftValue + rightValue})
-}
-
-func (vm *VM) executeComparison(op code.Opcode) error {
-	right := vm.pop()
-	left := vm.pop()
-
-	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
-		return vm.executeIntegerComparison(op, left, right)
-	}
-
-	switch op {
-	case code.OpEqual:
-		return vm.push(nativeBoolToBooleanObject(right == left))
-	case code.OpNotEqual:
-		return vm.push(nativeBoolToBooleanObject(right != left))
-	default:
-		return fmt.Errorf("unknown operator: %d (%s %s)",
-			op, left.Type(), right.Type())
+	if vm.limits != nil {
+		if err := vm.limits.allocate(int64(len(leftValue)) + int64(len(rightValue))); err != nil {
+			return err
+		}
 	}
-}
-
-func (vm *VM) executeIntegerComparison(
-	op code.Opcode,
-	left, right object.Object,
-) error {
-	leftValue := left.(*object.Integer).Value
-	rightValue := right.(*object.Integer).Value
 
-	switch op {
-	case code.OpEqual:
-		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
-	case code.OpNotEqual:
-		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
-	case code.OpGreaterThan:
-		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
-	default:
-		return fmt.Errorf("unknown operator: %d", op)
-	}
+	return vm.push(&object.String{Value: leftValue + rightValue})
 }
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
-	if input {
-		return True
-	} else {
-		return False
-	}
+	return object.NativeBoolToBooleanObject(input)
 }
 
 func (vm *VM) executeBinaryIntegerOperation(
@@ -391,19 +1197,68 @@ func (vm *VM) executeBinaryIntegerOperation(
 	rightValue := right.(*object.Integer).Value
 
 	var result int64
+	// overflow tells whether result wrapped around.
+	var overflow bool
 
 	switch op {
 	case code.OpAdd:
 		result = leftValue + rightValue
+		overflow = (rightValue > 0 && result < leftValue) || (rightValue < 0 && result > leftValue)
 	case code.OpSub:
 		result = leftValue - rightValue
+		overflow = (rightValue > 0 && result > leftValue) || (rightValue < 0 && result < leftValue)
 	case code.OpMul:
-		result = leftValue * rightValue
+		result, overflow = mulOverflow(leftValue, rightValue)
 	case code.OpDiv:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
 		result = leftValue / rightValue
+	case code.OpPow:
+		if rightValue < 0 {
+			return fmt.Errorf("negative exponent: %d ** %d", leftValue, rightValue)
+		}
+		result, overflow = intPow(leftValue, rightValue)
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
 	default:
-		return fmt.Errorf("unknown integer operator: %d", op)
+		return fmt.Errorf("unknown operator: %s %s %s",
+			left.Type(), binaryOperators[op], right.Type())
+	}
+
+	if overflow && vm.opts.CheckedArithmetic {
+		return fmt.Errorf("integer overflow: %d %s %d", leftValue, binaryOperators[op], rightValue)
 	}
+	return vm.push(object.NewInteger(result))
+}
+
+// intPow computes a ** b for b >= 0 by repeated squaring. On overflow the
+// result wraps around like the other integer operations, and overflow is
+// true.
+func intPow(a, b int64) (result int64, overflow bool) {
+	result = 1
+	for b > 0 {
+		if b&1 == 1 {
+			r, o := mulOverflow(result, a)
+			result, overflow = r, overflow || o
+		}
+		b >>= 1
+		if b > 0 {
+			sq, o := mulOverflow(a, a)
+			a, overflow = sq, overflow || o
+		}
+	}
+	return result, overflow
+}
 
-	return vm.push(&object.Integer{Value: result})
+// mulOverflow returns a * b and whether it wrapped around.
+func mulOverflow(a, b int64) (int64, bool) {
+	result := a * b
+	return result, a != 0 && (result/a != b || (a == -1 && b == math.MinInt64))
 }
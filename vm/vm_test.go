@@ -1,13 +1,19 @@
 package vm
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"monkey/ast"
+	"monkey/code"
 	"monkey/compiler"
+	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 type vmTestCase struct {
@@ -26,6 +32,9 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"50 / 2 * 2 + 10 - 5", 55},
 		{"5 + 5 + 5 + 5 - 10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
+		{"2 ** 10", 1024},
+		{"-2 ** 2", -4},
+		{"(-2) ** 2", 4},
 		{"5 * 2 + 10", 20},
 		{"5 * (2 + 10)", 60},
 		{"-5", -5},
@@ -158,6 +167,616 @@ func TestIndexExpressions(t *testing.T) {
 	runVmtTests(t, tests)
 }
 
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"let one = fn() { 1; }; let two = fn() { 2; }; one() + two()", 3},
+		{"let a = fn() { 1 }; let b = fn() { a() + 1 }; b()", 2},
+		{"let earlyExit = fn() { return 99; 100; }; earlyExit();", 99},
+		{"let noReturn = fn() { }; noReturn();", Null},
+		{"let f = fn() { let x = 1; }; f();", Null},
+		{"let returnsOne = fn() { 1; }; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();", 1},
+		{"let identity = fn(a) { a; }; identity(4);", 4},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{"let first = fn(a) { a }; first(1, 2, 3)", 1},
+		{"let globalSeed = 50; let minusOne = fn() { let num = 1; globalSeed - num; }; minusOne()", 49},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", 120},
+		{"let f = fn(x) { if (x) { let y = 1 } }; f(true)", Null},
+		{"return 7; 8", 7},
+	}
+
+	runVmtTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let newClosure = fn(a) { fn() { a; }; }; let closure = newClosure(99); closure();", 99},
+		{"let newAdder = fn(a, b) { fn(c) { a + b + c }; }; let adder = newAdder(1, 2); adder(8);", 11},
+		{"let newAdder = fn(a) { fn(b) { fn(c) { a + b + c } } }; newAdder(1)(2)(3)", 6},
+		{"let global = 10; let f = fn(a) { let b = a * 2; fn(c) { global + b + c } }; f(1)(3)", 15},
+		{
+			`let wrapper = fn() {
+				let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1); };
+				countDown(5);
+			};
+			wrapper();`,
+			0,
+		},
+		{
+			`let fib = fn(n) {
+				let go = fn(a, b, i) { if (i == 0) { a } else { go(b, a + b, i - 1) } };
+				go(0, 1, n)
+			};
+			fib(10)`,
+			55,
+		},
+		{"let double = memo(fn(x) { x * 2 }); double(21)", 42},
+		// Without memo these calls would take far too long.
+		{"let fib = memo(fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }); fib(80)", 23416728348467685},
+		{
+			`let calls = atomic();
+			let fib = memo(fn(n) { atomic_add(calls, 1); if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } });
+			fib(30);
+			atomic_load(calls)`,
+			31,
+		},
+		{"let k = 3; take(lazy_map(naturals(), fn(x) { x * k }), 3)", []int{0, 3, 6}},
+	}
+
+	runVmtTests(t, tests)
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+		{"let count = fn(n) { if (n == 0) { return 0; } return count(n - 1); }; count(50000)", 0},
+		{"let f = fn(self, n) { if (n == 0) { true } else { self(self, n - 1) } }; f(f, 30000)", true},
+		{
+			`let loop = fn() {
+				let go = fn(i, acc) { if (i == 0) { acc } else { go(i - 1, push(acc, i)) } };
+				len(go(3000, []))
+			};
+			loop()`,
+			3000,
+		},
+		{"let size = fn(x) { len(x) }; size([1, 2]) + 1", 3},
+		{"let f = fn(a) { fn(b) { a + b } }; let g = fn() { f(1)(2) }; g() * 10", 30},
+		{"let id = fn(x) { x }; let f = fn(a, b) { let c = a + b; id(c) }; f(1, 2)", 3},
+	}
+
+	runVmtTests(t, tests)
+}
+
+func TestFibers(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fiber(fn(yield) { yield(1); yield(2); 3 }); [resume(f), resume(f), resume(f)]", []int{1, 2, 3}},
+		{
+			`let naturals = fiber(fn(yield) { let loop = fn(i) { yield(i); loop(i + 1) }; loop(0) });
+			let take = fn(n, acc) { if (n == 0) { acc } else { take(n - 1, acc + resume(naturals)) } };
+			take(10000, 0)`,
+			49995000,
+		},
+		{
+			`let f = fiber(fn(yield) { let helper = fn(x) { yield(x * 2) + 1 }; helper(helper(1)) });
+			[resume(f), resume(f, 10), resume(f, 100)]`,
+			[]int{2, 22, 101},
+		},
+		{"let f = fiber(fn(yield, a) { a + yield(a) }); resume(f, 4); resume(f, 5)", 9},
+		// A yield in tail position replaces the frame of its caller.
+		{"let f = fiber(fn(yield) { let g = fn(x) { yield(x) }; g(1) + 1 }); [resume(f), resume(f, 7)]", []int{1, 8}},
+		{"let f = fiber(fn(yield) { yield(1, 2, 3) }); resume(f)", 1},
+		{"let f = fiber(fn(yield) { yield() }); resume(f)", Null},
+	}
+
+	runVmtTests(t, tests)
+
+	// The yield function is a closure that runs OpYield.
+	comp := compiler.New()
+	if err := comp.Compile(parse("resume(fiber(fn(yield) { yield }))")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	yield, ok := vm.LastPoppedStackElem().(*object.Closure)
+	if !ok || string(yield.Fn.Instructions) != string(code.Make(code.OpYield)) {
+		t.Errorf("yield is not a closure running OpYield. got=%s", vm.LastPoppedStackElem().Inspect())
+	}
+
+	comp = compiler.New()
+	if err := comp.Compile(parse("let f = fiber(fn(yield) {\n  yield(1);\n  1 + true\n});\nresume(f);\nresume(f)")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	err := New(comp.Bytecode()).Run()
+	rt, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a *RuntimeError, got=%T (%v)", err, err)
+	}
+	expected := "ERROR: type mismatch: INTEGER + BOOLEAN (line 3)\n\tat <anonymous> (line 1)"
+	if got := rt.Object().Inspect(); got != expected {
+		t.Errorf("wrong error.\nwant=%q\ngot= %q", expected, got)
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len([1, 2, 3])`, 3},
+		{`first([1, 2, 3])`, 1},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`push([], 1)`, []int{1}},
+		{`let len = fn(x) { 42 }; len("a")`, 42},
+	}
+
+	runVmtTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"2 ** -1", "negative exponent: 2 ** -1"},
+		{"1()", "not a function: INTEGER"},
+		{"fn(a, b) { a }(1)", "wrong number of arguments: want=2, got=1"},
+		{"len(1)", "argument to `len` not supported, got INTEGER"},
+		{"let f = fn() { 1 + f() }; f()", "maximum recursion depth exceeded (10000)"},
+		{"let f = memo(fn(x) { x + true }); f(1)", "type mismatch: INTEGER + BOOLEAN"},
+		// Calls made through builtins run on other VMs but count too.
+		{"let f = fn(n) { take(lazy_map([n], f), 1) }; f(1)", "maximum recursion depth exceeded (10000)"},
+		{"let f = fn(n) { take(lazy_filter([n], f), 1) }; f(1)", "maximum recursion depth exceeded (10000)"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		err := New(comp.Bytecode()).Run()
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestRuntimeErrorPositionsAndStack(t *testing.T) {
+	tests := []struct {
+		input   string
+		inspect string
+	}{
+		{
+			"let inner = fn(x) {\n  x + true\n};\nlet outer = fn() {\n  1 + inner(1)\n};\nouter();",
+			"ERROR: type mismatch: INTEGER + BOOLEAN (line 2)" +
+				"\n\tat inner (line 5)" +
+				"\n\tat outer (line 7)",
+		},
+		// A tail call replaces the frame of the caller.
+		{
+			"let inner = fn(x) {\n  x + true\n};\nlet outer = fn() {\n  inner(1)\n};\nouter();",
+			"ERROR: type mismatch: INTEGER + BOOLEAN (line 2)" +
+				"\n\tat inner (line 7)",
+		},
+		{"let a = 1;\nlen(a)", "ERROR: argument to `len` not supported, got INTEGER (line 2)"},
+		{"fn(x) {\n  -x\n}(true)", "ERROR: unknown operator: -BOOLEAN (line 2)\n\tat <anonymous> (line 1)"},
+		// A function called by a builtin is shown as called from the line
+		// of the call to the builtin.
+		{
+			"let f = memo(fn(x) {\n  x + true\n});\nlet g = fn() { f(1) };\n\ng()",
+			"ERROR: type mismatch: INTEGER + BOOLEAN (line 2)" +
+				"\n\tat <anonymous> (line 4)" +
+				"\n\tat g (line 6)",
+		},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		err := New(comp.Bytecode()).Run()
+		rt, ok := err.(*RuntimeError)
+		if !ok {
+			t.Errorf("%q: expected a *RuntimeError, got=%T (%v)", tt.input, err, err)
+			continue
+		}
+		if got := rt.Object().Inspect(); got != tt.inspect {
+			t.Errorf("%q: wrong error.\nwant=%q\ngot= %q", tt.input, tt.inspect, got)
+		}
+	}
+}
+
+func TestSandboxedBuiltins(t *testing.T) {
+	defer func() { evaluator.Sandbox = false }()
+
+	// Builtins are resolved when the VM is created, not on each access.
+	tests := []struct {
+		sandbox  bool
+		expected string
+	}{
+		{false, "read_file: open does-not-exist.txt: no such file or directory"},
+		{true, "`read_file` is disabled in sandbox mode"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(`read_file("does-not-exist.txt")`)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		evaluator.Sandbox = tt.sandbox
+		vm := New(comp.Bytecode())
+		evaluator.Sandbox = !tt.sandbox
+
+		err := vm.Run()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = vm.LastPoppedStackElem().Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("sandbox=%t: want=%q, got=%q", tt.sandbox, tt.expected, got)
+		}
+	}
+}
+
+func TestExit(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let f = fn() { exit(3); 1 }; f(); 2")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	exit, ok := vm.LastPoppedStackElem().(*object.Exit)
+	if !ok || exit.Code != 3 {
+		t.Errorf("expected exit(3), got=%v", vm.LastPoppedStackElem())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	countDown := "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(%d)"
+	lazyCountDown := "let f = fn(n) { if (n == 0) { 0 } else { 1 + take(lazy_map([n - 1], f), 1)[0] } }; f(%d)"
+	tests := []struct {
+		input    string
+		opts     Options
+		expected interface{} // int64 result or error message
+	}{
+		{fmt.Sprintf(countDown, 500), Options{StackSize: 1}, int64(500)},
+		{fmt.Sprintf(countDown, 20000), Options{MaxFrames: 30000}, int64(20000)},
+		{fmt.Sprintf(countDown, 30), Options{MaxFrames: 20}, "maximum recursion depth exceeded (20)"},
+		{fmt.Sprintf(countDown, 19), Options{MaxFrames: 20}, int64(19)},
+		{fmt.Sprintf(lazyCountDown, 30), Options{MaxFrames: 20}, "maximum recursion depth exceeded (20)"},
+		{fmt.Sprintf(lazyCountDown, 5), Options{MaxFrames: 20}, int64(5)},
+		{fmt.Sprintf(countDown, 500), Options{StackSize: 8, MaxStackSize: 100}, "stack overflow"},
+		{"[1, 2, 3, 4, 5, 6]", Options{MaxStackSize: 4}, "stack overflow"},
+		{"let a = 1; let b = 2; b", Options{GlobalsSize: 1}, "too many global variables (1)"},
+		{fmt.Sprintf(countDown, 30), Options{MaxSteps: 100}, "fuel exhausted: more than 100 instructions"},
+		{fmt.Sprintf(countDown, 10), Options{MaxSteps: 1000}, int64(10)},
+		// Closures run by builtins share the budget.
+		{"let loop = fn() { loop() }; recv(spawn(loop))", Options{MaxSteps: 1000}, "fuel exhausted: more than 1000 instructions"},
+		{memoryHog, Options{MaxMemory: 1 << 20}, "memory limit exceeded: more than 1048576 bytes"},
+		{memoryHog, Options{MaxMemory: 1 << 30}, int64(2000)},
+		{"9223372036854775807 + 1", Options{}, int64(-9223372036854775808)},
+		{"9223372036854775807 + 1", Options{CheckedArithmetic: true}, "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", Options{CheckedArithmetic: true}, "integer overflow: -9223372036854775807 - 2"},
+		{"let min = -9223372036854775807 - 1; min * -1", Options{CheckedArithmetic: true}, "integer overflow: -9223372036854775808 * -1"},
+		{"let min = -9223372036854775807 - 1; -min", Options{CheckedArithmetic: true}, "integer overflow: -(-9223372036854775808)"},
+		{"2 ** 63", Options{CheckedArithmetic: true}, "integer overflow: 2 ** 63"},
+		{"(-2) ** 63", Options{CheckedArithmetic: true}, int64(-9223372036854775808)},
+		{"-4611686018427387904 * 2", Options{CheckedArithmetic: true}, int64(-9223372036854775808)},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := NewWithOptions(comp.Bytecode(), tt.opts)
+		err := vm.Run()
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			if err != nil {
+				t.Errorf("%q: vm error: %s", tt.input, err)
+				continue
+			}
+			if err := testIntegerObject(expected, vm.LastPoppedStackElem()); err != nil {
+				t.Errorf("%q: %s", tt.input, err)
+			}
+		case string:
+			if err == nil || err.Error() != expected {
+				t.Errorf("%q: wrong error. want=%q, got=%v", tt.input, expected, err)
+			}
+		}
+	}
+}
+
+func TestHostObjects(t *testing.T) {
+	point := &object.HostType{
+		Name: "POINT",
+		Methods: map[string]object.Method{
+			"x": func(self *object.Host, args ...object.Object) object.Object {
+				return &object.Integer{Value: self.Value.([2]int64)[0]}
+			},
+		},
+		Index: func(self *object.Host, index object.Object) object.Object {
+			return &object.Integer{Value: self.Value.([2]int64)[index.(*object.Integer).Value]}
+		},
+		Call: func(self *object.Host, args ...object.Object) object.Object {
+			return &object.Error{Message: "points can't be called"}
+		},
+	}
+	tests := []struct {
+		input    string
+		expected interface{} // int64 result or error message
+	}{
+		{"p.x()", int64(3)},
+		{"let get = fn(o) { o.x }; get(p)()", int64(3)},
+		{"p[1] * 10", int64(40)},
+		{`let h = {"a": {"b": 2}}; h.a.b`, int64(2)},
+		{`let h = {"twice": fn(x) { x * 2 }}; h.twice(21)`, int64(42)},
+		{`{"a": 1}.b`, Null},
+		{"p.y", "unknown member POINT.y"},
+		{"p(1)", "points can't be called"},
+		{"let f = fn(x) { x.y }; f(1)", "member access not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		symbolTable := compiler.NewSymbolTable()
+		compiler.DefineBuiltins(symbolTable)
+		globals := make([]object.Object, GlobalsSize)
+		globals[symbolTable.Define("p").Index] = point.New([2]int64{3, 4})
+
+		comp := compiler.NewWithState(symbolTable, nil)
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := NewWithGlobalsStore(comp.Bytecode(), globals)
+		err := vm.Run()
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			if err != nil {
+				t.Errorf("%q: vm error: %s", tt.input, err)
+				continue
+			}
+			if err := testIntegerObject(expected, vm.LastPoppedStackElem()); err != nil {
+				t.Errorf("%q: %s", tt.input, err)
+			}
+		case *object.Null:
+			if err != nil || vm.LastPoppedStackElem() != Null {
+				t.Errorf("%q: expected null. got=%v (%v)", tt.input, vm.LastPoppedStackElem(), err)
+			}
+		case string:
+			if err == nil || err.Error() != expected {
+				t.Errorf("%q: wrong error. want=%q, got=%v", tt.input, expected, err)
+			}
+		}
+	}
+}
+
+// memoryHog collects 2000 distinct 4 KiB strings, about 8 MiB.
+const memoryHog = `
+let double = fn(s, n) { if (n == 0) { s } else { double(s + s, n - 1) } };
+let s = double("x", 12);
+let grow = fn(a, n) { if (n == 0) { len(a) } else { grow(push(a, s + "!"), n - 1) } };
+grow([], 2000)`
+
+func TestContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// This takes seconds; the context stops it long before.
+	comp := compiler.New()
+	if err := comp.Compile(parse("let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(35)")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	start := time.Now()
+	err := NewWithOptions(comp.Bytecode(), Options{Context: ctx}).Run()
+	if err == nil || !strings.HasPrefix(err.Error(), "evaluation cancelled: context deadline exceeded") {
+		t.Fatalf("expected cancellation error. got=%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took too long: %s", elapsed)
+	}
+}
+
+func TestBuiltinOptions(t *testing.T) {
+	var out, errOut bytes.Buffer
+	comp := compiler.New()
+	input := `puts(input("name? ")); eputs("oops"); read_file("x.txt")`
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := NewWithOptions(comp.Bytecode(), Options{
+		Sandboxed: true,
+		Stdout:    &out,
+		Stderr:    &errOut,
+		Stdin:     strings.NewReader("Ana\n"),
+	})
+	err := vm.Run()
+	if err == nil || err.Error() != "`read_file` requires the fs capability" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+	if got, want := out.String(), "name? Ana\n"; got != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, got)
+	}
+	if got, want := errOut.String(), "oops\n"; got != want {
+		t.Errorf("wrong error output. want=%q, got=%q", want, got)
+	}
+}
+
+// Builtins get the closures themselves, not a wrapper around them.
+func TestBuiltinsGetClosures(t *testing.T) {
+	var out bytes.Buffer
+	comp := compiler.New()
+	if err := comp.Compile(parse("let f = fn(x) { x }; puts(f); f")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := NewWithOptions(comp.Bytecode(), Options{Stdout: &out})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	cl, ok := vm.LastPoppedStackElem().(*object.Closure)
+	if !ok {
+		t.Fatalf("expected a closure. got=%T", vm.LastPoppedStackElem())
+	}
+	if got, want := out.String(), cl.Inspect()+"\n"; got != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, got)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let double = fn(x) { x * 2 }; double(21)")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	var out bytes.Buffer
+	vm := NewWithOptions(comp.Bytecode(), Options{Trace: &out})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	expected := `0      main          0000   OpClosure 1 0        -
+0      main          0004   OpSetGlobal 0        Closure[%[1]p]
+0      main          0007   OpGetGlobal 0        -
+0      main          0010   OpConstant 2         Closure[%[1]p]
+0      main          0013   OpCall 1             21
+1      double        0000   OpGetLocal 0         21
+1      double        0002   OpConstant 0         21
+1      double        0005   OpMul                2
+1      double        0006   OpReturnValue        42
+0      main          0015   OpPop                42
+`
+	closure := vm.globals[0]
+	if want := fmt.Sprintf(expected, closure); out.String() != want {
+		t.Errorf("wrong trace.\nwant=\n%s\ngot=\n%s", want, out.String())
+	}
+}
+
+func TestSteps(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let double = fn(x) { x * 2 }; double(21)")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	// The same instructions as in TestTrace.
+	if got := vm.Steps(); got != 10 {
+		t.Errorf("wrong number of steps. want=10, got=%d", got)
+	}
+}
+
+func TestCallable(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let base = 10; fn(x, y) { base + x * y }")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+	opts := Options{Globals: make([]object.Object, GlobalsSize)}
+	vm := NewWithOptions(bytecode, opts)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	cl := vm.LastPoppedStackElem().(*object.Closure)
+
+	fn := Callable(cl, bytecode.Constants, opts)
+	if err := testIntegerObject(16, fn.Fn(&object.Integer{Value: 2}, &object.Integer{Value: 3})); err != nil {
+		t.Error(err)
+	}
+	if err, ok := fn.Fn().(*object.Error); !ok || err.Message != "wrong number of arguments: want=2, got=0" {
+		t.Errorf("expected an arity error. got=%v", fn.Fn())
+	}
+}
+
+func TestSharedGlobals(t *testing.T) {
+	globals := make([]object.Object, 4)
+	symbols := compiler.NewSymbolTable()
+	var constants []object.Object
+
+	for _, input := range []string{"let x = 40;", "let y = x + 2;", "y"} {
+		comp := compiler.NewWithState(symbols, constants)
+		if err := comp.Compile(parse(input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+
+		vm := NewWithOptions(bytecode, Options{Globals: globals})
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		if input == "y" {
+			if err := testIntegerObject(42, vm.LastPoppedStackElem()); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let a = 20; let b = a + 1; b * 2")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := NewWithOptions(comp.Bytecode(), Options{GlobalsSize: 4})
+	before := vm.Snapshot()
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	after := vm.Snapshot()
+
+	// Restoring the first snapshot runs the program again from the start.
+	if err := vm.Restore(before); err != nil {
+		t.Fatal(err)
+	}
+	if vm.globals[0] != nil || vm.globals[1] != nil {
+		t.Errorf("globals not restored. got=%v", vm.globals[:2])
+	}
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(42, vm.LastPoppedStackElem()); err != nil {
+		t.Error(err)
+	}
+	if err := vm.Restore(after); err != nil {
+		t.Fatal(err)
+	}
+	if err := testIntegerObject(21, vm.globals[1]); err != nil {
+		t.Error(err)
+	}
+
+	// After an error the snapshot keeps the calls that were in progress.
+	comp = compiler.New()
+	if err := comp.Compile(parse(`let f = fn(n) { if (n == 0) { -"x" } else { 1 + f(n - 1) } }; f(3)`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm = New(comp.Bytecode())
+	if err := vm.Run(); err == nil {
+		t.Fatal("expected an error")
+	}
+	crash := vm.Snapshot()
+	if len(crash.frames) != 5 {
+		t.Fatalf("wrong number of frames. want=5, got=%d", len(crash.frames))
+	}
+	if got := crash.frames[4].name(); got != "f" {
+		t.Errorf("wrong innermost frame. want=f, got=%s", got)
+	}
+}
+
 func runVmtTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -302,3 +921,33 @@ func testBooleanObject(expected bool, actual object.Object) error {
 
 	return nil
 }
+
+func BenchmarkSuperinstructions(b *testing.B) {
+	input := `
+let sum = fn(n, acc) {
+  if (n == 0) { acc } else { sum(n - 1, acc + n + 1) }
+};
+sum(100000, 0);`
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		b.Fatalf("compiler error: %s", err)
+	}
+	optimized := comp.Bytecode().Optimize()
+
+	benchmarks := []struct {
+		name     string
+		bytecode *compiler.Bytecode
+	}{
+		{"optimized", optimized},
+		{"fused", optimized.Fuse()},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := New(bm.bytecode).Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}